@@ -103,6 +103,7 @@ CREATE TABLE dbtable_schema.form_version_submissions (
   enabled BOOLEAN NOT NULL DEFAULT true
 );
 
+-- costs are whole currency units
 CREATE TABLE dbtable_schema.services (
   id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
   name VARCHAR (50) NOT NULL,
@@ -171,7 +172,7 @@ CREATE TABLE dbtable_schema.service_tiers (
   id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
   service_id uuid NOT NULL REFERENCES dbtable_schema.services (id) ON DELETE CASCADE,
   name VARCHAR (500) NOT NULL,
-  multiplier INTEGER NOT NULL,
+  multiplier INTEGER NOT NULL, -- a percentage where 100 is 1x
   created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
   created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
   updated_on TIMESTAMP,
@@ -196,6 +197,7 @@ CREATE TABLE dbtable_schema.service_tier_forms (
 CREATE TABLE dbtable_schema.service_tier_addons (
   service_tier_id uuid NOT NULL REFERENCES dbtable_schema.service_tiers (id) ON DELETE CASCADE,
   service_addon_id uuid NOT NULL REFERENCES dbtable_schema.service_addons (id) ON DELETE CASCADE,
+  cost INTEGER,
   created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
   created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
   updated_on TIMESTAMP,
@@ -304,7 +306,7 @@ CREATE TABLE dbtable_schema.schedule_brackets (
   group_id uuid NOT NULL REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
   schedule_id uuid NOT NULL REFERENCES dbtable_schema.schedules (id) ON DELETE CASCADE,
   duration INTEGER NOT NULL,
-  multiplier INTEGER NOT NULL,
+  multiplier INTEGER NOT NULL, -- a percentage where 100 is 1x
   automatic BOOLEAN NOT NULL DEFAULT false,
   created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
   created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
@@ -356,6 +358,10 @@ CREATE POLICY table_insert ON dbtable_schema.schedule_bracket_slot_exclusions FO
 CREATE POLICY table_update ON dbtable_schema.schedule_bracket_slot_exclusions FOR UPDATE TO $PG_WORKER USING ($HAS_GROUP);
 CREATE POLICY table_delete ON dbtable_schema.schedule_bracket_slot_exclusions FOR DELETE TO $PG_WORKER USING ($HAS_GROUP);
 
+CREATE UNIQUE INDEX idx_unique_enabled_slot_exclusions
+ON dbtable_schema.schedule_bracket_slot_exclusions(schedule_bracket_slot_id, exclusion_date)
+WHERE enabled = true;
+
 CREATE TABLE dbtable_schema.schedule_bracket_services (
   id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
   group_id uuid NOT NULL REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
@@ -374,6 +380,28 @@ CREATE POLICY table_insert ON dbtable_schema.schedule_bracket_services FOR INSER
 CREATE POLICY table_update ON dbtable_schema.schedule_bracket_services FOR UPDATE TO $PG_WORKER USING ($HAS_GROUP);
 CREATE POLICY table_delete ON dbtable_schema.schedule_bracket_services FOR DELETE TO $PG_WORKER USING ($HAS_GROUP);
 
+-- recurring requests for the same slot, each occurrence is a quote linked by series_id
+CREATE TABLE dbtable_schema.quote_series (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_id uuid NOT NULL REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
+  schedule_bracket_slot_id uuid NOT NULL REFERENCES dbtable_schema.schedule_bracket_slots (id),
+  service_tier_id uuid NOT NULL REFERENCES dbtable_schema.service_tiers (id),
+  start_date DATE NOT NULL,
+  interval_weeks SMALLINT NOT NULL CHECK (interval_weeks > 0),
+  occurrences SMALLINT NOT NULL CHECK (occurrences > 0),
+  until_date DATE,
+  slot_created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  updated_on TIMESTAMP,
+  updated_sub uuid REFERENCES dbtable_schema.users (sub),
+  enabled BOOLEAN NOT NULL DEFAULT true
+);
+ALTER TABLE dbtable_schema.quote_series ENABLE ROW LEVEL SECURITY;
+CREATE POLICY table_select ON dbtable_schema.quote_series FOR SELECT TO $PG_WORKER USING ($IS_CREATOR OR slot_created_sub = $USER_SUB);
+CREATE POLICY table_insert ON dbtable_schema.quote_series FOR INSERT TO $PG_WORKER WITH CHECK ($IS_CREATOR);
+CREATE POLICY table_update ON dbtable_schema.quote_series FOR UPDATE TO $PG_WORKER USING ($HAS_GROUP);
+
 CREATE TABLE dbtable_schema.quotes (
   id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
   group_id uuid NOT NULL REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
@@ -383,12 +411,15 @@ CREATE TABLE dbtable_schema.quotes (
   service_form_version_submission_id uuid REFERENCES dbtable_schema.form_version_submissions (id),
   tier_form_version_submission_id uuid REFERENCES dbtable_schema.form_version_submissions (id),
   slot_created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  series_id uuid REFERENCES dbtable_schema.quote_series (id),
+  price JSONB, -- line items priced when the quote was requested, copied to its booking
   created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
   created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
   updated_on TIMESTAMP,
   updated_sub uuid REFERENCES dbtable_schema.users (sub),
   enabled BOOLEAN NOT NULL DEFAULT true
 );
+CREATE INDEX quotes_series_id_idx ON dbtable_schema.quotes (series_id, slot_date) WHERE series_id IS NOT NULL;
 ALTER TABLE dbtable_schema.quotes ENABLE ROW LEVEL SECURITY;
 CREATE POLICY table_select ON dbtable_schema.quotes FOR SELECT TO $PG_WORKER USING (
   $IS_CREATOR OR EXISTS(
@@ -467,6 +498,7 @@ CREATE TABLE dbtable_schema.bookings (
   tier_survey_version_submission_id uuid REFERENCES dbtable_schema.form_version_submissions (id),
   rating SMALLINT,
   quote_created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  price JSONB,
   created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
   created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
   updated_on TIMESTAMP,
@@ -506,6 +538,51 @@ CREATE TABLE dbtable_schema.booking_service_tier_form_version_submissions (
   enabled BOOLEAN NOT NULL DEFAULT true
 );
 
+-- staff own the booked slot and clients own the quote, either can see the booking's transcripts
+CREATE OR REPLACE FUNCTION dbfunc_schema.session_user_is_booking_participant(p_booking_id uuid)
+RETURNS BOOLEAN AS $$
+DECLARE
+  v_user_sub uuid;
+BEGIN
+  v_user_sub := nullif(current_setting('app_session.user_sub', true), '')::uuid;
+
+  IF v_user_sub IS NULL THEN
+    RETURN FALSE;
+  END IF;
+
+  RETURN EXISTS (
+    SELECT 1
+    FROM dbtable_schema.bookings b
+    JOIN dbtable_schema.schedule_bracket_slots sbs ON sbs.id = b.schedule_bracket_slot_id
+    JOIN dbtable_schema.quotes q ON q.id = b.quote_id
+    WHERE b.id = p_booking_id
+      AND (sbs.created_sub = v_user_sub OR q.created_sub = v_user_sub)
+  );
+END;
+$$ LANGUAGE plpgsql STABLE SECURITY DEFINER;
+
+CREATE TABLE dbtable_schema.booking_transcripts (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  booking_id uuid NOT NULL REFERENCES dbtable_schema.bookings (id) ON DELETE CASCADE,
+  topic TEXT, -- the exchange topic the transcript was collected from, if any
+  messages JSONB NOT NULL DEFAULT '[]'::JSONB,
+  started_on TIMESTAMP NOT NULL,
+  ended_on TIMESTAMP NOT NULL,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  updated_on TIMESTAMP,
+  updated_sub uuid REFERENCES dbtable_schema.users (sub),
+  enabled BOOLEAN NOT NULL DEFAULT true,
+  UNIQUE (topic, started_on) -- one transcript per exchange session, however many participants leave at once
+);
+CREATE INDEX booking_transcripts_booking_index ON dbtable_schema.booking_transcripts (booking_id, started_on);
+CREATE INDEX booking_transcripts_topic_index ON dbtable_schema.booking_transcripts (topic, ended_on);
+ALTER TABLE dbtable_schema.booking_transcripts ENABLE ROW LEVEL SECURITY;
+CREATE POLICY table_select ON dbtable_schema.booking_transcripts FOR SELECT TO $PG_WORKER USING (dbfunc_schema.session_user_is_booking_participant(booking_id));
+CREATE POLICY table_insert ON dbtable_schema.booking_transcripts FOR INSERT TO $PG_WORKER WITH CHECK ($IS_CREATOR AND dbfunc_schema.session_user_is_booking_participant(booking_id));
+CREATE POLICY table_update ON dbtable_schema.booking_transcripts FOR UPDATE TO $PG_WORKER USING (dbfunc_schema.session_user_is_booking_participant(booking_id));
+CREATE POLICY table_delete ON dbtable_schema.booking_transcripts FOR DELETE TO $PG_WORKER USING ($IS_CREATOR AND dbfunc_schema.session_user_is_booking_participant(booking_id));
+
 CREATE TABLE dbtable_schema.sock_connections (
   id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
   connection_id TEXT NOT NULL,
@@ -528,9 +605,10 @@ CREATE POLICY table_insert ON dbtable_schema.sock_connections FOR INSERT TO $PG_
 CREATE POLICY table_delete ON dbtable_schema.sock_connections FOR DELETE TO $PG_WORKER USING ($IS_WORKER);
 
 CREATE TABLE dbtable_schema.topic_messages (
-  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(), -- chat messages are given time ordered ids before they're sent
   connection_id TEXT NOT NULL,
   topic TEXT NOT NULL,
+  booking_id uuid REFERENCES dbtable_schema.bookings (id) ON DELETE CASCADE,
   message TEXT NOT NULL,
   created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
   created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
@@ -539,9 +617,29 @@ CREATE TABLE dbtable_schema.topic_messages (
   enabled BOOLEAN NOT NULL DEFAULT true
 );
 ALTER TABLE dbtable_schema.topic_messages ENABLE ROW LEVEL SECURITY;
-CREATE POLICY table_select ON dbtable_schema.topic_messages FOR SELECT TO $PG_WORKER USING ($HAS_TOPIC);
+CREATE POLICY table_select ON dbtable_schema.topic_messages FOR SELECT TO $PG_WORKER USING ($HAS_TOPIC OR dbfunc_schema.session_user_is_booking_participant(booking_id));
 CREATE POLICY table_insert ON dbtable_schema.topic_messages FOR INSERT TO $PG_WORKER WITH CHECK ($HAS_TOPIC);
-CREATE INDEX topic_index ON dbtable_schema.topic_messages (topic);
+CREATE POLICY table_update ON dbtable_schema.topic_messages FOR UPDATE TO $PG_WORKER USING ($HAS_TOPIC AND $IS_CREATOR);
+CREATE INDEX topic_index ON dbtable_schema.topic_messages (topic, created_on);
+CREATE INDEX topic_messages_booking_index ON dbtable_schema.topic_messages (booking_id);
+
+-- the newest message each participant has seen on a topic
+CREATE TABLE dbtable_schema.topic_message_reads (
+  topic TEXT NOT NULL,
+  booking_id uuid REFERENCES dbtable_schema.bookings (id) ON DELETE CASCADE,
+  last_read_id uuid NOT NULL REFERENCES dbtable_schema.topic_messages (id) ON DELETE CASCADE,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  updated_on TIMESTAMP,
+  updated_sub uuid REFERENCES dbtable_schema.users (sub),
+  enabled BOOLEAN NOT NULL DEFAULT true,
+  PRIMARY KEY (topic, created_sub)
+);
+ALTER TABLE dbtable_schema.topic_message_reads ENABLE ROW LEVEL SECURITY;
+CREATE POLICY table_select ON dbtable_schema.topic_message_reads FOR SELECT TO $PG_WORKER USING ($HAS_TOPIC OR dbfunc_schema.session_user_is_booking_participant(booking_id));
+CREATE POLICY table_insert ON dbtable_schema.topic_message_reads FOR INSERT TO $PG_WORKER WITH CHECK ($HAS_TOPIC AND $IS_CREATOR);
+CREATE POLICY table_update ON dbtable_schema.topic_message_reads FOR UPDATE TO $PG_WORKER USING ($HAS_TOPIC AND $IS_CREATOR);
+CREATE INDEX topic_message_reads_booking_index ON dbtable_schema.topic_message_reads (booking_id);
 
 CREATE TABLE dbtable_schema.topic_canvas_elements (
   connection_id TEXT NOT NULL,
@@ -549,6 +647,7 @@ CREATE TABLE dbtable_schema.topic_canvas_elements (
   element_id TEXT NOT NULL,
   element_type TEXT NOT NULL DEFAULT 'box',
   properties JSONB NOT NULL,
+  version INTEGER NOT NULL DEFAULT 1,
   created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
   created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
   updated_on TIMESTAMP,
@@ -560,10 +659,40 @@ ALTER TABLE dbtable_schema.topic_canvas_elements ENABLE ROW LEVEL SECURITY;
 CREATE POLICY table_modify_state ON dbtable_schema.topic_canvas_elements FOR ALL TO $PG_WORKER USING ($HAS_TOPIC);
 CREATE INDEX topic_element_index ON dbtable_schema.topic_canvas_elements (topic, element_id);
 
+-- every change to a canvas element, ordered per topic by seq, so changes can be undone and sessions replayed
+CREATE TABLE dbtable_schema.topic_canvas_operations (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  seq BIGINT NOT NULL,
+  topic TEXT NOT NULL,
+  booking_id uuid REFERENCES dbtable_schema.bookings (id) ON DELETE CASCADE,
+  connection_id TEXT NOT NULL,
+  element_id TEXT NOT NULL,
+  operation TEXT NOT NULL CHECK (operation IN ('create', 'update', 'delete', 'undo', 'redo')),
+  before JSONB, -- null when the operation created the element
+  after JSONB, -- null when the operation deleted the element
+  version INTEGER NOT NULL,
+  target_id uuid REFERENCES dbtable_schema.topic_canvas_operations (id), -- the operation an undo or redo applied to
+  undone BOOLEAN NOT NULL DEFAULT false,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  updated_on TIMESTAMP,
+  updated_sub uuid REFERENCES dbtable_schema.users (sub),
+  enabled BOOLEAN NOT NULL DEFAULT true,
+  UNIQUE (topic, seq)
+);
+ALTER TABLE dbtable_schema.topic_canvas_operations ENABLE ROW LEVEL SECURITY;
+CREATE POLICY table_select ON dbtable_schema.topic_canvas_operations FOR SELECT TO $PG_WORKER USING ($HAS_TOPIC OR dbfunc_schema.session_user_is_booking_participant(booking_id));
+CREATE POLICY table_insert ON dbtable_schema.topic_canvas_operations FOR INSERT TO $PG_WORKER WITH CHECK ($HAS_TOPIC AND $IS_CREATOR);
+CREATE POLICY table_update ON dbtable_schema.topic_canvas_operations FOR UPDATE TO $PG_WORKER USING ($HAS_TOPIC AND $IS_CREATOR);
+CREATE INDEX topic_canvas_operations_booking_index ON dbtable_schema.topic_canvas_operations (booking_id, seq);
+
 CREATE TABLE dbtable_schema.exchange_call_log (
   id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
   booking_id uuid NOT NULL REFERENCES dbtable_schema.bookings (id),
-  style TEXT NOT NULL,
+  group_id uuid NOT NULL REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
+  style TEXT NOT NULL, -- text, call or whiteboard
+  socket_id TEXT, -- the participant's socket, one user may be connected from several
+  node_id TEXT, -- the node holding the socket, rows left open by a node which went away are closed by a sweep
   connected TIMESTAMP NOT NULL,
   disconnected TIMESTAMP,
   transcript JSONB, -- this denotes audio based chat logs
@@ -573,6 +702,13 @@ CREATE TABLE dbtable_schema.exchange_call_log (
   updated_sub uuid REFERENCES dbtable_schema.users (sub),
   enabled BOOLEAN NOT NULL DEFAULT true
 );
+CREATE INDEX exchange_call_log_booking_index ON dbtable_schema.exchange_call_log (booking_id, connected);
+CREATE INDEX exchange_call_log_open_index ON dbtable_schema.exchange_call_log (socket_id, booking_id) WHERE disconnected IS NULL;
+ALTER TABLE dbtable_schema.exchange_call_log ENABLE ROW LEVEL SECURITY;
+CREATE POLICY table_select ON dbtable_schema.exchange_call_log FOR SELECT TO $PG_WORKER USING ($IS_WORKER OR ($HAS_GROUP AND ($IS_GROUP_ADMIN OR dbfunc_schema.session_user_is_booking_participant(booking_id))));
+CREATE POLICY table_insert ON dbtable_schema.exchange_call_log FOR INSERT TO $PG_WORKER WITH CHECK ($IS_CREATOR AND $HAS_GROUP AND dbfunc_schema.session_user_is_booking_participant(booking_id));
+CREATE POLICY table_update ON dbtable_schema.exchange_call_log FOR UPDATE TO $PG_WORKER USING ($IS_WORKER OR $IS_CREATOR);
+CREATE POLICY table_delete ON dbtable_schema.exchange_call_log FOR DELETE TO $PG_WORKER USING ($HAS_GROUP AND $IS_GROUP_ADMIN);
 
 CREATE TABLE dbtable_schema.feedback (
   id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -600,7 +736,117 @@ CREATE POLICY table_insert ON dbtable_schema.group_feedback FOR INSERT TO $PG_WO
 CREATE POLICY table_update ON dbtable_schema.group_feedback FOR UPDATE TO $PG_WORKER USING ($HAS_GROUP);
 CREATE POLICY table_delete ON dbtable_schema.group_feedback FOR DELETE TO $PG_WORKER USING ($HAS_GROUP);
 
-CREATE TYPE dbtable_schema.payment_status AS ENUM ('pending', 'paid', 'void');
+CREATE TABLE dbtable_schema.user_notes (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_id uuid NOT NULL REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
+  user_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub) ON DELETE CASCADE, -- the client the note is about
+  name VARCHAR (500) NOT NULL,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  updated_on TIMESTAMP,
+  updated_sub uuid REFERENCES dbtable_schema.users (sub),
+  enabled BOOLEAN NOT NULL DEFAULT true
+);
+CREATE INDEX user_notes_user_sub_index ON dbtable_schema.user_notes (group_id, user_sub);
+ALTER TABLE dbtable_schema.user_notes ENABLE ROW LEVEL SECURITY;
+-- notes are private to the staff member who wrote them, and visible to group admins
+CREATE POLICY table_select ON dbtable_schema.user_notes FOR SELECT TO $PG_WORKER USING ($HAS_GROUP AND ($IS_CREATOR OR $IS_GROUP_ADMIN));
+CREATE POLICY table_insert ON dbtable_schema.user_notes FOR INSERT TO $PG_WORKER WITH CHECK ($HAS_GROUP AND $IS_CREATOR);
+CREATE POLICY table_update ON dbtable_schema.user_notes FOR UPDATE TO $PG_WORKER USING ($HAS_GROUP AND ($IS_CREATOR OR $IS_GROUP_ADMIN));
+CREATE POLICY table_delete ON dbtable_schema.user_notes FOR DELETE TO $PG_WORKER USING ($HAS_GROUP AND ($IS_CREATOR OR $IS_GROUP_ADMIN));
+
+CREATE TABLE dbtable_schema.user_note_versions (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_note_id uuid NOT NULL REFERENCES dbtable_schema.user_notes (id) ON DELETE CASCADE,
+  form JSONB NOT NULL,
+  submission JSONB NOT NULL,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  updated_on TIMESTAMP,
+  updated_sub uuid REFERENCES dbtable_schema.users (sub),
+  enabled BOOLEAN NOT NULL DEFAULT true
+);
+CREATE INDEX user_note_versions_note_index ON dbtable_schema.user_note_versions (user_note_id);
+ALTER TABLE dbtable_schema.user_note_versions ENABLE ROW LEVEL SECURITY;
+CREATE POLICY table_select ON dbtable_schema.user_note_versions FOR SELECT TO $PG_WORKER USING (
+  EXISTS(
+    SELECT 1 FROM dbtable_schema.user_notes un
+    WHERE un.id = dbtable_schema.user_note_versions.user_note_id
+    AND un.$HAS_GROUP AND (un.$IS_CREATOR OR $IS_GROUP_ADMIN)
+  )
+);
+CREATE POLICY table_insert ON dbtable_schema.user_note_versions FOR INSERT TO $PG_WORKER WITH CHECK (
+  $IS_CREATOR AND EXISTS(
+    SELECT 1 FROM dbtable_schema.user_notes un
+    WHERE un.id = dbtable_schema.user_note_versions.user_note_id
+    AND un.$HAS_GROUP AND (un.$IS_CREATOR OR $IS_GROUP_ADMIN)
+  )
+);
+
+CREATE TABLE dbtable_schema.group_kiosk_tokens (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_id uuid NOT NULL REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
+  name VARCHAR (100) NOT NULL,
+  token_hash VARCHAR (64) NOT NULL UNIQUE, -- sha256 of the token, the token itself is only shown once
+  last_used_on TIMESTAMP,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  updated_on TIMESTAMP,
+  updated_sub uuid REFERENCES dbtable_schema.users (sub),
+  enabled BOOLEAN NOT NULL DEFAULT true
+);
+ALTER TABLE dbtable_schema.group_kiosk_tokens ENABLE ROW LEVEL SECURITY;
+-- the worker looks up tokens for unauthenticated kiosk displays
+CREATE POLICY table_select ON dbtable_schema.group_kiosk_tokens FOR SELECT TO $PG_WORKER USING ($IS_WORKER OR ($HAS_GROUP AND $IS_GROUP_ADMIN));
+CREATE POLICY table_insert ON dbtable_schema.group_kiosk_tokens FOR INSERT TO $PG_WORKER WITH CHECK ($HAS_GROUP AND $IS_GROUP_ADMIN);
+CREATE POLICY table_update ON dbtable_schema.group_kiosk_tokens FOR UPDATE TO $PG_WORKER USING ($IS_WORKER OR ($HAS_GROUP AND $IS_GROUP_ADMIN));
+CREATE POLICY table_delete ON dbtable_schema.group_kiosk_tokens FOR DELETE TO $PG_WORKER USING ($HAS_GROUP AND $IS_GROUP_ADMIN);
+
+CREATE TABLE dbtable_schema.user_calendar_tokens (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_id uuid NOT NULL REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
+  token_hash VARCHAR (64) NOT NULL UNIQUE, -- sha256 of the token, the token itself is only shown once
+  last_used_on TIMESTAMP,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  updated_on TIMESTAMP,
+  updated_sub uuid REFERENCES dbtable_schema.users (sub),
+  enabled BOOLEAN NOT NULL DEFAULT true
+);
+-- each user has one calendar feed per group, creating a new one replaces it
+CREATE UNIQUE INDEX user_calendar_tokens_created_sub_group_id_idx ON dbtable_schema.user_calendar_tokens (created_sub, group_id);
+ALTER TABLE dbtable_schema.user_calendar_tokens ENABLE ROW LEVEL SECURITY;
+-- the worker looks up tokens for calendar apps subscribed to a feed
+CREATE POLICY table_select ON dbtable_schema.user_calendar_tokens FOR SELECT TO $PG_WORKER USING ($IS_WORKER OR $IS_CREATOR);
+CREATE POLICY table_insert ON dbtable_schema.user_calendar_tokens FOR INSERT TO $PG_WORKER WITH CHECK ($IS_CREATOR AND $HAS_GROUP);
+CREATE POLICY table_update ON dbtable_schema.user_calendar_tokens FOR UPDATE TO $PG_WORKER USING ($IS_WORKER OR $IS_CREATOR);
+CREATE POLICY table_delete ON dbtable_schema.user_calendar_tokens FOR DELETE TO $PG_WORKER USING ($IS_CREATOR);
+
+CREATE TABLE dbtable_schema.group_invites (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_id uuid NOT NULL REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
+  group_role_id uuid NOT NULL REFERENCES dbtable_schema.group_roles (id) ON DELETE CASCADE,
+  email VARCHAR (255) NOT NULL,
+  token_hash VARCHAR (64) NOT NULL UNIQUE, -- sha256 of the signed token, the token itself is only sent by email
+  expires_on TIMESTAMP NOT NULL,
+  accepted_on TIMESTAMP,
+  accepted_sub uuid REFERENCES dbtable_schema.users (sub) ON DELETE SET NULL,
+  revoked_on TIMESTAMP,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  updated_on TIMESTAMP,
+  updated_sub uuid REFERENCES dbtable_schema.users (sub),
+  enabled BOOLEAN NOT NULL DEFAULT true
+);
+CREATE INDEX group_invites_group_email_index ON dbtable_schema.group_invites (group_id, LOWER(email));
+ALTER TABLE dbtable_schema.group_invites ENABLE ROW LEVEL SECURITY;
+-- the worker validates invites during registration, before the user exists
+CREATE POLICY table_select ON dbtable_schema.group_invites FOR SELECT TO $PG_WORKER USING ($IS_WORKER OR ($HAS_GROUP AND $IS_GROUP_ADMIN));
+CREATE POLICY table_insert ON dbtable_schema.group_invites FOR INSERT TO $PG_WORKER WITH CHECK ($HAS_GROUP AND $IS_GROUP_ADMIN);
+CREATE POLICY table_update ON dbtable_schema.group_invites FOR UPDATE TO $PG_WORKER USING ($HAS_GROUP AND $IS_GROUP_ADMIN);
+CREATE POLICY table_delete ON dbtable_schema.group_invites FOR DELETE TO $PG_WORKER USING ($HAS_GROUP AND $IS_GROUP_ADMIN);
+
+CREATE TYPE dbtable_schema.payment_status AS ENUM ('pending', 'paid', 'failed', 'void');
 
 CREATE TABLE dbtable_schema.seat_payments (
   id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -611,6 +857,7 @@ CREATE TABLE dbtable_schema.seat_payments (
   amount INTEGER NOT NULL,
   paid_on TIMESTAMP,
   check_no TEXT,
+  payment_method TEXT,
   created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
   created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
   updated_on TIMESTAMP,
@@ -652,3 +899,188 @@ CREATE TABLE dbtable_schema.group_seat_usage (
 ALTER TABLE dbtable_schema.group_seat_usage ENABLE ROW LEVEL SECURITY;
 CREATE POLICY table_select ON dbtable_schema.group_seat_usage FOR SELECT TO $PG_WORKER USING ($IS_WORKER);
 CREATE POLICY table_insert ON dbtable_schema.group_seat_usage FOR INSERT TO $PG_WORKER WITH CHECK ($IS_WORKER);
+
+-- identity provider changes which must happen once a transaction commits, or which could not be
+-- undone when a request failed. rows outlive the requests and users which queued them
+CREATE TABLE dbtable_schema.identity_outbox (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  operation TEXT NOT NULL,
+  action JSONB NOT NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT,
+  claimed_until TIMESTAMP, -- set while a worker is applying the action, the claim lapses if the worker goes away
+  completed_on TIMESTAMP,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL,
+  updated_on TIMESTAMP,
+  updated_sub uuid,
+  enabled BOOLEAN NOT NULL DEFAULT true
+);
+ALTER TABLE dbtable_schema.identity_outbox ENABLE ROW LEVEL SECURITY;
+CREATE POLICY table_select ON dbtable_schema.identity_outbox FOR SELECT TO $PG_WORKER USING ($IS_WORKER);
+CREATE POLICY table_insert ON dbtable_schema.identity_outbox FOR INSERT TO $PG_WORKER WITH CHECK ($IS_WORKER OR $IS_CREATOR);
+CREATE POLICY table_update ON dbtable_schema.identity_outbox FOR UPDATE TO $PG_WORKER USING ($IS_WORKER);
+CREATE INDEX identity_outbox_pending_idx ON dbtable_schema.identity_outbox (created_on) WHERE completed_on IS NULL;
+
+-- notifications waiting to be delivered, one row per recipient and channel. reminders run later on,
+-- everything else as soon as it's queued. rows are processed by the worker and never seen by users
+CREATE TABLE dbtable_schema.notification_jobs (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  kind TEXT NOT NULL,
+  channel TEXT NOT NULL,
+  recipient_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub) ON DELETE CASCADE,
+  group_id uuid NOT NULL REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
+  quote_id uuid REFERENCES dbtable_schema.quotes (id) ON DELETE CASCADE,
+  booking_id uuid REFERENCES dbtable_schema.bookings (id) ON DELETE CASCADE,
+  run_at TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT,
+  claimed_until TIMESTAMP, -- set while a worker is sending, the claim lapses if the worker goes away
+  completed_on TIMESTAMP,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL,
+  updated_on TIMESTAMP,
+  updated_sub uuid,
+  enabled BOOLEAN NOT NULL DEFAULT true
+);
+ALTER TABLE dbtable_schema.notification_jobs ENABLE ROW LEVEL SECURITY;
+CREATE POLICY table_select ON dbtable_schema.notification_jobs FOR SELECT TO $PG_WORKER USING ($IS_WORKER);
+CREATE POLICY table_insert ON dbtable_schema.notification_jobs FOR INSERT TO $PG_WORKER WITH CHECK ($IS_WORKER OR $IS_CREATOR);
+CREATE POLICY table_update ON dbtable_schema.notification_jobs FOR UPDATE TO $PG_WORKER USING ($IS_WORKER);
+-- each recipient hears about a quote or booking event once per channel
+CREATE UNIQUE INDEX notification_jobs_unique_idx ON dbtable_schema.notification_jobs (kind, channel, recipient_sub, COALESCE(booking_id, quote_id));
+CREATE INDEX notification_jobs_pending_idx ON dbtable_schema.notification_jobs (run_at) WHERE completed_on IS NULL;
+
+-- endpoints in a group's own systems which are told about events in the group. the secret signs
+-- each delivery, and events lists which event types are sent
+CREATE TABLE dbtable_schema.group_webhooks (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_id uuid NOT NULL REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  secret TEXT NOT NULL,
+  events TEXT[] NOT NULL,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  updated_on TIMESTAMP,
+  updated_sub uuid REFERENCES dbtable_schema.users (sub),
+  enabled BOOLEAN NOT NULL DEFAULT true
+);
+ALTER TABLE dbtable_schema.group_webhooks ENABLE ROW LEVEL SECURITY;
+CREATE POLICY table_select ON dbtable_schema.group_webhooks FOR SELECT TO $PG_WORKER USING ($IS_WORKER OR ($HAS_GROUP AND $IS_GROUP_ADMIN));
+CREATE POLICY table_insert ON dbtable_schema.group_webhooks FOR INSERT TO $PG_WORKER WITH CHECK ($HAS_GROUP AND $IS_GROUP_ADMIN);
+CREATE POLICY table_update ON dbtable_schema.group_webhooks FOR UPDATE TO $PG_WORKER USING ($HAS_GROUP AND $IS_GROUP_ADMIN);
+CREATE POLICY table_delete ON dbtable_schema.group_webhooks FOR DELETE TO $PG_WORKER USING ($HAS_GROUP AND $IS_GROUP_ADMIN);
+
+-- one row per event sent to a webhook, kept as the group's delivery log. replays are new rows with
+-- the same event_id, so receivers can tell them apart from new events
+CREATE TABLE dbtable_schema.group_webhook_deliveries (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_webhook_id uuid NOT NULL REFERENCES dbtable_schema.group_webhooks (id) ON DELETE CASCADE,
+  group_id uuid NOT NULL REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
+  event_id uuid NOT NULL,
+  event TEXT NOT NULL,
+  payload JSONB NOT NULL,
+  replay_of uuid REFERENCES dbtable_schema.group_webhook_deliveries (id) ON DELETE SET NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  response_status INTEGER,
+  last_error TEXT,
+  delivered_on TIMESTAMP,
+  failed_on TIMESTAMP,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL,
+  updated_on TIMESTAMP,
+  updated_sub uuid,
+  enabled BOOLEAN NOT NULL DEFAULT true
+);
+ALTER TABLE dbtable_schema.group_webhook_deliveries ENABLE ROW LEVEL SECURITY;
+CREATE POLICY table_select ON dbtable_schema.group_webhook_deliveries FOR SELECT TO $PG_WORKER USING ($IS_WORKER OR ($HAS_GROUP AND $IS_GROUP_ADMIN));
+CREATE POLICY table_insert ON dbtable_schema.group_webhook_deliveries FOR INSERT TO $PG_WORKER WITH CHECK ($IS_WORKER OR ($HAS_GROUP AND $IS_GROUP_ADMIN));
+CREATE POLICY table_update ON dbtable_schema.group_webhook_deliveries FOR UPDATE TO $PG_WORKER USING ($IS_WORKER);
+CREATE INDEX group_webhook_deliveries_group_idx ON dbtable_schema.group_webhook_deliveries (group_id, created_on DESC);
+CREATE INDEX group_webhook_deliveries_pending_idx ON dbtable_schema.group_webhook_deliveries (next_attempt_on) WHERE delivered_on IS NULL AND failed_on IS NULL;
+
+-- payee and tax details printed on the group's client invoices. tax_rate is in basis points, 825 is 8.25%
+CREATE TABLE dbtable_schema.group_invoice_settings (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_id uuid NOT NULL UNIQUE REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
+  payee_name TEXT NOT NULL DEFAULT '',
+  payee_addr1 TEXT NOT NULL DEFAULT '',
+  payee_addr2 TEXT NOT NULL DEFAULT '',
+  tax_label TEXT NOT NULL DEFAULT '',
+  tax_rate INTEGER NOT NULL DEFAULT 0 CHECK (tax_rate >= 0 AND tax_rate <= 10000),
+  tax_id TEXT NOT NULL DEFAULT '',
+  number_prefix VARCHAR (16) NOT NULL DEFAULT 'INV-',
+  next_number INTEGER NOT NULL DEFAULT 1,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  updated_on TIMESTAMP,
+  updated_sub uuid REFERENCES dbtable_schema.users (sub),
+  enabled BOOLEAN NOT NULL DEFAULT true
+);
+ALTER TABLE dbtable_schema.group_invoice_settings ENABLE ROW LEVEL SECURITY;
+CREATE POLICY table_select ON dbtable_schema.group_invoice_settings FOR SELECT TO $PG_WORKER USING ($HAS_GROUP);
+CREATE POLICY table_insert ON dbtable_schema.group_invoice_settings FOR INSERT TO $PG_WORKER WITH CHECK ($HAS_GROUP AND $IS_GROUP_ADMIN);
+CREATE POLICY table_update ON dbtable_schema.group_invoice_settings FOR UPDATE TO $PG_WORKER USING ($HAS_GROUP AND $IS_GROUP_ADMIN);
+
+CREATE TYPE dbtable_schema.invoice_status AS ENUM ('draft', 'sent', 'paid', 'void');
+
+-- invoices copy the booking's priced line items and the group's tax settings when they are drafted.
+-- amounts are in cents. clients only see invoices once they have been sent
+CREATE TABLE dbtable_schema.booking_invoices (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_id uuid NOT NULL REFERENCES dbtable_schema.groups (id) ON DELETE CASCADE,
+  booking_id uuid NOT NULL REFERENCES dbtable_schema.bookings (id) ON DELETE CASCADE,
+  number INTEGER NOT NULL,
+  code TEXT NOT NULL,
+  status dbtable_schema.invoice_status NOT NULL DEFAULT 'draft',
+  line_items JSONB NOT NULL,
+  subtotal INTEGER NOT NULL,
+  tax_label TEXT NOT NULL DEFAULT '',
+  tax_rate INTEGER NOT NULL DEFAULT 0,
+  tax_id TEXT NOT NULL DEFAULT '',
+  tax_amount INTEGER NOT NULL DEFAULT 0,
+  total INTEGER NOT NULL,
+  amount_paid INTEGER NOT NULL DEFAULT 0,
+  sent_on TIMESTAMP,
+  paid_on TIMESTAMP,
+  voided_on TIMESTAMP,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  updated_on TIMESTAMP,
+  updated_sub uuid REFERENCES dbtable_schema.users (sub),
+  enabled BOOLEAN NOT NULL DEFAULT true,
+  UNIQUE (group_id, number)
+);
+-- a voided invoice can be reissued, otherwise a booking has one invoice
+CREATE UNIQUE INDEX booking_invoices_booking_idx ON dbtable_schema.booking_invoices (booking_id) WHERE status <> 'void';
+ALTER TABLE dbtable_schema.booking_invoices ENABLE ROW LEVEL SECURITY;
+CREATE POLICY table_select ON dbtable_schema.booking_invoices FOR SELECT TO $PG_WORKER USING (
+  $IS_CREATOR
+  OR ($HAS_GROUP AND $IS_GROUP_ADMIN)
+  OR (status <> 'draft' AND dbfunc_schema.session_user_is_booking_participant(booking_id))
+);
+CREATE POLICY table_insert ON dbtable_schema.booking_invoices FOR INSERT TO $PG_WORKER WITH CHECK ($IS_CREATOR AND $HAS_GROUP AND dbfunc_schema.session_user_is_booking_participant(booking_id));
+CREATE POLICY table_update ON dbtable_schema.booking_invoices FOR UPDATE TO $PG_WORKER USING ($IS_CREATOR OR ($HAS_GROUP AND $IS_GROUP_ADMIN));
+
+CREATE TABLE dbtable_schema.booking_invoice_payments (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  booking_invoice_id uuid NOT NULL REFERENCES dbtable_schema.booking_invoices (id) ON DELETE CASCADE,
+  amount INTEGER NOT NULL CHECK (amount > 0),
+  payment_method TEXT NOT NULL,
+  reference TEXT NOT NULL DEFAULT '',
+  paid_on TIMESTAMP NOT NULL,
+  created_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW()),
+  created_sub uuid NOT NULL REFERENCES dbtable_schema.users (sub),
+  updated_on TIMESTAMP,
+  updated_sub uuid REFERENCES dbtable_schema.users (sub),
+  enabled BOOLEAN NOT NULL DEFAULT true
+);
+CREATE INDEX booking_invoice_payments_invoice_idx ON dbtable_schema.booking_invoice_payments (booking_invoice_id, paid_on);
+ALTER TABLE dbtable_schema.booking_invoice_payments ENABLE ROW LEVEL SECURITY;
+CREATE POLICY table_select ON dbtable_schema.booking_invoice_payments FOR SELECT TO $PG_WORKER USING (
+  EXISTS(SELECT 1 FROM dbtable_schema.booking_invoices bi WHERE bi.id = dbtable_schema.booking_invoice_payments.booking_invoice_id)
+);
+CREATE POLICY table_insert ON dbtable_schema.booking_invoice_payments FOR INSERT TO $PG_WORKER WITH CHECK (
+  $IS_CREATOR AND EXISTS(SELECT 1 FROM dbtable_schema.booking_invoices bi WHERE bi.id = dbtable_schema.booking_invoice_payments.booking_invoice_id)
+);
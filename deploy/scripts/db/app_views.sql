@@ -102,6 +102,56 @@ SELECT
 FROM
   dbview_schema.enabled_forms ef;
 
+CREATE
+OR REPLACE VIEW dbview_schema.enabled_user_note_versions AS
+SELECT
+  id,
+  user_note_id as "userNoteId",
+  form,
+  submission,
+  created_sub as "createdSub",
+  created_on as "createdOn"
+FROM
+  dbtable_schema.user_note_versions
+WHERE
+  enabled = true;
+
+CREATE
+OR REPLACE VIEW dbview_schema.enabled_user_notes AS
+SELECT
+  un.id,
+  un.group_id as "groupId",
+  un.user_sub as "userSub",
+  un.name,
+  un.created_sub as "createdSub",
+  un.created_on as "createdOn",
+  eunv.version
+FROM
+  dbtable_schema.user_notes un
+  LEFT JOIN LATERAL (
+    SELECT
+      TO_JSONB(vrs) as version
+    FROM
+      (
+        SELECT
+          unv.id,
+          unv."userNoteId",
+          unv.form,
+          unv.submission,
+          unv."createdSub",
+          unv."createdOn"
+        FROM
+          dbview_schema.enabled_user_note_versions unv
+        WHERE
+          unv."userNoteId" = un.id
+        ORDER BY
+          unv."createdOn" DESC
+        LIMIT 1
+      ) vrs
+  ) as eunv ON true
+WHERE
+  un.enabled = true;
+
 CREATE
 OR REPLACE VIEW dbview_schema.enabled_forms_active AS
 SELECT
@@ -314,6 +364,7 @@ SELECT
   amount,
   paid_on as "paidOn",
   check_no as "checkNo",
+  payment_method as "paymentMethod",
   group_id as "groupId",
   created_sub as "createdSub",
   created_on as "createdOn"
@@ -347,7 +398,8 @@ FROM
       (
         SELECT
           esa.id,
-          esa.name
+          esa.name,
+          sta.cost
         FROM
           dbtable_schema.service_tier_addons sta
           LEFT JOIN dbview_schema.enabled_service_addons esa ON esa.id = sta.service_addon_id
@@ -473,7 +525,9 @@ SELECT
   q.tier_form_version_submission_id as "tierFormVersionSubmissionId",
   q.created_on as "createdOn",
   q.created_sub as "createdSub",
-  q.slot_created_sub as "slotCreatedSub"
+  q.slot_created_sub as "slotCreatedSub",
+  q.series_id as "seriesId",
+  q.price
 FROM
   dbtable_schema.quotes q
 JOIN dbview_schema.enabled_schedule_bracket_slots esbs ON esbs.id = q.schedule_bracket_slot_id
@@ -515,7 +569,8 @@ SELECT
   ROW_TO_JSON(q.*) as quote,
   ROW_TO_JSON(es.*) as service,
   ROW_TO_JSON(esbs.*) as "scheduleBracketSlot",
-  ROW_TO_JSON(est.*) as "serviceTier"
+  ROW_TO_JSON(est.*) as "serviceTier",
+  b.price
 FROM
   dbtable_schema.bookings b
 JOIN dbview_schema.enabled_quotes q ON q.id = b.quote_id
@@ -525,6 +580,23 @@ JOIN dbview_schema.enabled_services_ext es ON es.id = est."serviceId"
 WHERE
   b.enabled = true;
 
+CREATE
+OR REPLACE VIEW dbview_schema.enabled_booking_transcripts AS
+SELECT
+  id,
+  booking_id as "bookingId",
+  topic,
+  messages,
+  JSONB_ARRAY_LENGTH(messages) as "messageCount",
+  started_on as "startedOn",
+  ended_on as "endedOn",
+  created_sub as "createdSub",
+  created_on as "createdOn"
+FROM
+  dbtable_schema.booking_transcripts
+WHERE
+  enabled = true;
+
 CREATE
 OR REPLACE VIEW dbview_schema.enabled_bookings_ext AS
 SELECT
@@ -23,4 +23,9 @@ FROM
     )
   ) rep ON true
 WHERE
-  sbs.enabled = false;
+  sbs.enabled = false
+  -- slots excluded on the quoted date, i.e. staff time off
+  OR EXISTS (
+    SELECT 1 FROM dbtable_schema.schedule_bracket_slot_exclusions sbse
+    WHERE sbse.schedule_bracket_slot_id = sbs.id AND sbse.exclusion_date = q.slot_date AND sbse.enabled = true
+  );
@@ -94,15 +94,31 @@ RETURN QUERY
   JOIN dbtable_schema.service_tiers reptier ON reptier.service_id = repserv.service_id
   JOIN dbtable_schema.users usr ON usr.sub = repslot.created_sub
   LEFT JOIN dbtable_schema.quotes repq ON repq.schedule_bracket_slot_id = repslot.id AND repq.slot_date = p_slot_date AND repq.enabled = true
+  LEFT JOIN dbtable_schema.schedule_bracket_slot_exclusions repex ON repex.schedule_bracket_slot_id = repslot.id AND repex.exclusion_date = p_slot_date AND repex.enabled = true
   WHERE user_sched.user_schedule_id = ANY(p_user_schedule_ids::UUID[])
     AND repslot.start_time = p_start_time
     AND reptier.name = p_tier_name
     AND repq.id IS NULL
+    AND repex.id IS NULL
     AND repslot.enabled = true
   LIMIT 1;
 END;
 $$ LANGUAGE PLPGSQL;
 
+-- slot start times are offsets from the start of the week, or from the start of the 28 day cycle
+-- of the group schedule, this is the wall clock start of the slot occurrence on or around p_day
+CREATE OR REPLACE FUNCTION dbfunc_schema.slot_occurrence_start(
+  p_day DATE,
+  p_start_time INTERVAL,
+  p_schedule_time_unit TEXT,
+  p_schedule_start_date DATE
+) RETURNS TIMESTAMP AS $$
+  SELECT CASE
+    WHEN p_schedule_time_unit = 'week' THEN DATE_TRUNC('week', p_day::TIMESTAMP) + p_start_time
+    ELSE p_schedule_start_date + INTERVAL '28 days' * FLOOR((p_day - p_schedule_start_date) / 28.0) + p_start_time
+  END;
+$$ LANGUAGE SQL IMMUTABLE;
+
 CREATE FUNCTION dbfunc_schema.is_slot_taken(p_slot_id uuid, p_date date) 
 RETURNS boolean AS $$
 BEGIN
@@ -110,6 +126,11 @@ BEGIN
     SELECT 1 FROM dbtable_schema.bookings
     WHERE schedule_bracket_slot_id = p_slot_id 
     AND slot_date = p_date
+  ) OR EXISTS (
+    SELECT 1 FROM dbtable_schema.schedule_bracket_slot_exclusions
+    WHERE schedule_bracket_slot_id = p_slot_id
+    AND exclusion_date = p_date
+    AND enabled = true
   );
 END;
 $$ LANGUAGE plpgsql SECURITY DEFINER;
@@ -124,17 +145,17 @@ BEGIN
     SET balance = balance + NEW.seats, updated_sub = NEW.created_sub, updated_on = NOW()
     WHERE group_id = NEW.group_id;
   
-  -- Payment Updated to VOID -> Remove Seats
+  -- Payment Updated to VOID or FAILED -> Remove Seats
   ELSIF (TG_OP = 'UPDATE') THEN
-    -- If status of the seat_payments record changed to 'void' from something else, subtract the seats from group_seats
-    IF (NEW.status = 'void' AND OLD.status != 'void') THEN
+    -- If status of the seat_payments record changed to 'void' or 'failed' from a live status, subtract the seats from group_seats
+    IF (NEW.status IN ('void', 'failed') AND OLD.status NOT IN ('void', 'failed')) THEN
       UPDATE dbtable_schema.group_seats
       SET balance = balance - NEW.seats, updated_sub = NEW.created_sub, updated_on = NOW()
       WHERE group_id = NEW.group_id;
     END IF;
     
-    -- un-void (manual correction), add them back
-    IF (NEW.status != 'void' AND OLD.status = 'void') THEN
+    -- un-void (manual correction) or a failed payment later settling, add them back
+    IF (NEW.status NOT IN ('void', 'failed') AND OLD.status IN ('void', 'failed')) THEN
       UPDATE dbtable_schema.group_seats
       SET balance = balance + NEW.seats, updated_sub = NEW.created_sub, updated_on = NOW()
       WHERE group_id = NEW.group_id;
@@ -199,3 +220,112 @@ BEGIN
   RETURN TRUE;
 END;
 $$ LANGUAGE plpgsql SECURITY DEFINER;
+
+-- wakes the api so it can refresh the kiosk_schedule materialized view
+CREATE OR REPLACE FUNCTION dbfunc_schema.notify_kiosk_schedule_changed()
+RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM pg_notify('kiosk_schedule_changed', '');
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- wakes the api so queued identity changes run as soon as their transaction commits
+CREATE OR REPLACE FUNCTION dbfunc_schema.notify_identity_outbox()
+RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM pg_notify('identity_outbox_changed', '');
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- wakes the api so queued notifications are sent once their transaction commits
+CREATE OR REPLACE FUNCTION dbfunc_schema.notify_notification_jobs()
+RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM pg_notify('notification_jobs_changed', '');
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- wakes the api so group webhook deliveries are sent once their transaction commits
+CREATE OR REPLACE FUNCTION dbfunc_schema.notify_group_webhook_deliveries()
+RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM pg_notify('group_webhook_deliveries_changed', '');
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- events are raised by members, and by users joining the group, who can't see the group's webhooks.
+-- every enabled webhook subscribed to the event gets a delivery, all sharing one event id
+CREATE OR REPLACE FUNCTION dbfunc_schema.queue_group_webhook_event(p_group_id uuid, p_event TEXT, p_payload JSONB)
+RETURNS VOID AS $$
+DECLARE
+  v_event_id uuid := gen_random_uuid();
+BEGIN
+  INSERT INTO dbtable_schema.group_webhook_deliveries (group_webhook_id, group_id, event_id, event, payload, created_sub)
+  SELECT gw.id, gw.group_id, v_event_id, p_event, p_payload, current_setting('app_session.user_sub')::uuid
+  FROM dbtable_schema.group_webhooks gw
+  WHERE gw.group_id = p_group_id
+    AND gw.enabled = true
+    AND p_event = ANY(gw.events);
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER;
+
+-- registering users are not in the group yet, so accepting their invite can't go through the table policies.
+-- the update only succeeds once per invite, and only for the invited email
+CREATE OR REPLACE FUNCTION dbfunc_schema.redeem_group_invite(p_token_hash VARCHAR, p_email TEXT)
+RETURNS TABLE (group_id uuid, external_id TEXT) AS $$
+#variable_conflict use_column
+DECLARE
+  v_user_sub uuid;
+BEGIN
+  v_user_sub := nullif(current_setting('app_session.user_sub', true), '')::uuid;
+
+  IF v_user_sub IS NULL THEN
+    RETURN;
+  END IF;
+
+  RETURN QUERY
+  UPDATE dbtable_schema.group_invites gi
+  SET accepted_on = TIMEZONE('utc', NOW()), accepted_sub = v_user_sub, updated_on = TIMEZONE('utc', NOW()), updated_sub = v_user_sub
+  FROM dbtable_schema.group_roles gr
+  WHERE gr.id = gi.group_role_id
+    AND gi.token_hash = p_token_hash
+    AND LOWER(gi.email) = LOWER(p_email)
+    AND gi.accepted_on IS NULL
+    AND gi.revoked_on IS NULL
+    AND gi.enabled = true
+    AND gi.expires_on > TIMEZONE('utc', NOW())
+  RETURNING gi.group_id, gr.external_id::TEXT;
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER;
+
+-- invoice numbers run per group without gaps, claimed in the same transaction as the invoice insert.
+-- staff drafting invoices can't edit the group's settings, which are created with the first invoice
+CREATE OR REPLACE FUNCTION dbfunc_schema.claim_group_invoice_number()
+RETURNS TABLE (number INTEGER, code TEXT) AS $$
+#variable_conflict use_column
+DECLARE
+  v_user_sub uuid;
+  v_group_id uuid;
+BEGIN
+  v_user_sub := nullif(current_setting('app_session.user_sub', true), '')::uuid;
+  v_group_id := nullif(current_setting('app_session.group_id', true), '')::uuid;
+
+  IF v_user_sub IS NULL OR v_group_id IS NULL THEN
+    RETURN;
+  END IF;
+
+  INSERT INTO dbtable_schema.group_invoice_settings (group_id, created_sub)
+  VALUES (v_group_id, v_user_sub)
+  ON CONFLICT (group_id) DO NOTHING;
+
+  RETURN QUERY
+  UPDATE dbtable_schema.group_invoice_settings gis
+  SET next_number = gis.next_number + 1
+  WHERE gis.group_id = v_group_id
+  RETURNING gis.next_number - 1, gis.number_prefix || (gis.next_number - 1)::TEXT;
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER;
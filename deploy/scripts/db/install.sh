@@ -27,4 +27,6 @@ psql -v ON_ERROR_STOP=1 \
   -f $SCRIPT_DIR/app_alterations.sql \
   -f $SCRIPT_DIR/triggers.sql
 
+sh $SCRIPT_DIR/migrate.sh || exit 1
+
 exit 0
@@ -15,7 +15,8 @@ CREATE MATERIALIZED VIEW dbview_schema.kiosk_schedule AS
 SELECT
   g.name,
   g.code,
-  JSONB_OBJECT_AGG(schedules.name, TO_JSONB(schedules.*)) as schedules
+  JSONB_OBJECT_AGG(schedules.name, TO_JSONB(schedules.*)) as schedules,
+  TIMEZONE('utc', NOW()) as updated_on
 FROM dbtable_schema.groups g
 LEFT JOIN LATERAL (
   SELECT 
@@ -57,3 +58,11 @@ WHERE schedules.name IS NOT NULL
 GROUP BY g.name, g.code;
 
 CREATE UNIQUE INDEX ON dbview_schema.kiosk_schedule (name);
+
+-- the view is owned by the installer, so the worker refreshes it through a definer function
+CREATE OR REPLACE FUNCTION dbfunc_schema.refresh_kiosk_schedule()
+RETURNS VOID AS $$
+BEGIN
+  REFRESH MATERIALIZED VIEW CONCURRENTLY dbview_schema.kiosk_schedule;
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER;
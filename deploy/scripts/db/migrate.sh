@@ -0,0 +1,42 @@
+#!/bin/sh
+
+# Applies each migrations/*.sql not yet recorded in dbtable_schema.schema_migrations, in name order.
+# A migration and its record commit together, so a failed migration is retried on the next run.
+# Fresh installs run this after install.sh has created the current schema.
+
+MIGRATIONS_DIR="${MIGRATIONS_DIR:-$SCRIPT_DIR/migrations}"
+
+# the same placeholders install.sh fills in, for migrations which change policies
+ENV_LIST="PG_DB PG_WORKER USER_SUB GROUP_ID IS_WORKER IS_USER IS_CREATOR HAS_GROUP HAS_TOPIC IS_GROUP_ADMIN IS_GROUP_BOOKINGS IS_GROUP_SCHEDULES IS_GROUP_SERVICES IS_GROUP_SCHEDULE_KEYS IS_GROUP_ROLES IS_GROUP_USERS IS_GROUP_PERMISSIONS"
+
+fill_placeholders() {
+  script="$(cat "$1")"
+  for var in $ENV_LIST; do
+    val="$(printenv "$var")"
+    script="$(printf '%s' "$script" | sed "s|\$$var|$(printf '%s' "$val" | sed 's/[&|\\]/\\&/g')|g")"
+  done
+  printf '%s\n' "$script"
+}
+
+psql -v ON_ERROR_STOP=1 --dbname "$PG_DB" -c "
+  CREATE TABLE IF NOT EXISTS dbtable_schema.schema_migrations (
+    version TEXT PRIMARY KEY,
+    applied_on TIMESTAMP NOT NULL DEFAULT TIMEZONE('utc', NOW())
+  );
+" || exit 1
+
+for f in $(ls "$MIGRATIONS_DIR"/*.sql 2>/dev/null | sort); do
+  version=$(basename "$f" .sql)
+
+  applied=$(psql -tA --dbname "$PG_DB" -c "SELECT 1 FROM dbtable_schema.schema_migrations WHERE version = '$version'")
+  if [ "$applied" = "1" ]; then
+    continue
+  fi
+
+  echo "# Applying migration $version."
+
+  { fill_placeholders "$f"; echo "INSERT INTO dbtable_schema.schema_migrations (version) VALUES ('$version');"; } |
+    psql -v ON_ERROR_STOP=1 --single-transaction --dbname "$PG_DB" -f - || exit 1
+done
+
+exit 0
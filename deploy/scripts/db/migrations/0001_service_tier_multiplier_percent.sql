@@ -0,0 +1,2 @@
+-- tier multipliers were once saved as the multiplier itself, between 1 and 5, instead of a percentage
+UPDATE dbtable_schema.service_tiers SET multiplier = multiplier * 100 WHERE multiplier BETWEEN 1 AND 5;
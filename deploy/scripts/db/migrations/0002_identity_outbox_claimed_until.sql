@@ -0,0 +1,2 @@
+-- outbox rows are claimed with a lease instead of being locked while the identity provider is called
+ALTER TABLE dbtable_schema.identity_outbox ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMP;
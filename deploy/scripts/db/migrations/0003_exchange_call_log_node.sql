@@ -0,0 +1,8 @@
+-- open call logs keep the node holding the socket, so the worker can close the ones a stopped node left open
+ALTER TABLE dbtable_schema.exchange_call_log ADD COLUMN IF NOT EXISTS node_id TEXT;
+
+DROP POLICY IF EXISTS table_select ON dbtable_schema.exchange_call_log;
+CREATE POLICY table_select ON dbtable_schema.exchange_call_log FOR SELECT TO $PG_WORKER USING ($IS_WORKER OR ($HAS_GROUP AND ($IS_GROUP_ADMIN OR dbfunc_schema.session_user_is_booking_participant(booking_id))));
+
+DROP POLICY IF EXISTS table_update ON dbtable_schema.exchange_call_log;
+CREATE POLICY table_update ON dbtable_schema.exchange_call_log FOR UPDATE TO $PG_WORKER USING ($IS_WORKER OR $IS_CREATOR);
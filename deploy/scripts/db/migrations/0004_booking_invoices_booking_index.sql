@@ -0,0 +1,10 @@
+-- a booking has one invoice which isn't void. Drafts raced in beside an earlier invoice are voided; a
+-- duplicate which was already sent or paid stops the migration so it can be settled by hand.
+UPDATE dbtable_schema.booking_invoices bi
+SET status = 'void', voided_on = TIMEZONE('utc', NOW())
+WHERE bi.status = 'draft' AND EXISTS (
+  SELECT 1 FROM dbtable_schema.booking_invoices earlier
+  WHERE earlier.booking_id = bi.booking_id AND earlier.status <> 'void'
+  AND (earlier.created_on, earlier.id) < (bi.created_on, bi.id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS booking_invoices_booking_idx ON dbtable_schema.booking_invoices (booking_id) WHERE status <> 'void';
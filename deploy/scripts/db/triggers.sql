@@ -4,3 +4,21 @@ CREATE TRIGGER set_group_code AFTER INSERT ON dbtable_schema.seat_payments FOR E
 
 -- seat handling
 CREATE TRIGGER trg_seat_payments_balance AFTER INSERT OR UPDATE ON dbtable_schema.seat_payments FOR EACH ROW EXECUTE FUNCTION dbfunc_schema.trg_handle_seat_payment();
+
+-- kiosk schedule refresh
+CREATE TRIGGER trg_kiosk_groups AFTER INSERT OR UPDATE OR DELETE ON dbtable_schema.groups FOR EACH STATEMENT EXECUTE FUNCTION dbfunc_schema.notify_kiosk_schedule_changed();
+CREATE TRIGGER trg_kiosk_schedules AFTER INSERT OR UPDATE OR DELETE ON dbtable_schema.schedules FOR EACH STATEMENT EXECUTE FUNCTION dbfunc_schema.notify_kiosk_schedule_changed();
+CREATE TRIGGER trg_kiosk_group_schedules AFTER INSERT OR UPDATE OR DELETE ON dbtable_schema.group_schedules FOR EACH STATEMENT EXECUTE FUNCTION dbfunc_schema.notify_kiosk_schedule_changed();
+CREATE TRIGGER trg_kiosk_group_user_schedules AFTER INSERT OR UPDATE OR DELETE ON dbtable_schema.group_user_schedules FOR EACH STATEMENT EXECUTE FUNCTION dbfunc_schema.notify_kiosk_schedule_changed();
+CREATE TRIGGER trg_kiosk_schedule_brackets AFTER INSERT OR UPDATE OR DELETE ON dbtable_schema.schedule_brackets FOR EACH STATEMENT EXECUTE FUNCTION dbfunc_schema.notify_kiosk_schedule_changed();
+CREATE TRIGGER trg_kiosk_schedule_bracket_slots AFTER INSERT OR UPDATE OR DELETE ON dbtable_schema.schedule_bracket_slots FOR EACH STATEMENT EXECUTE FUNCTION dbfunc_schema.notify_kiosk_schedule_changed();
+CREATE TRIGGER trg_kiosk_schedule_bracket_services AFTER INSERT OR UPDATE OR DELETE ON dbtable_schema.schedule_bracket_services FOR EACH STATEMENT EXECUTE FUNCTION dbfunc_schema.notify_kiosk_schedule_changed();
+
+-- identity outbox processing
+CREATE TRIGGER trg_identity_outbox AFTER INSERT ON dbtable_schema.identity_outbox FOR EACH STATEMENT EXECUTE FUNCTION dbfunc_schema.notify_identity_outbox();
+
+-- notification delivery
+CREATE TRIGGER trg_notification_jobs AFTER INSERT ON dbtable_schema.notification_jobs FOR EACH STATEMENT EXECUTE FUNCTION dbfunc_schema.notify_notification_jobs();
+
+-- group webhook delivery
+CREATE TRIGGER trg_group_webhook_deliveries AFTER INSERT ON dbtable_schema.group_webhook_deliveries FOR EACH STATEMENT EXECUTE FUNCTION dbfunc_schema.notify_group_webhook_deliveries();
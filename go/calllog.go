@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/api"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const callLogSweepEvery = 5 * time.Minute
+
+// Closes exchange call logs left open by nodes which stopped without closing them, on startup
+// and every callLogSweepEvery
+func setupCallLogSweep(ctx context.Context, a *api.API) {
+	ticker := time.NewTicker(callLogSweepEvery)
+	defer ticker.Stop()
+
+	for {
+		if err := a.SweepExchangeCallLogs(ctx); err != nil {
+			util.ErrorLog.Printf("could not sweep exchange call logs, err: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
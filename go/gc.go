@@ -6,10 +6,12 @@ import (
 	"time"
 
 	"github.com/keybittech/awayto-v3/go/pkg/api"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
 )
 
-func setupGc(a *api.API, stopChan chan struct{}) {
+func setupGc(ctx context.Context, a *api.API) {
 	generalCleanupTicker := time.NewTicker(5 * time.Minute)
+	defer generalCleanupTicker.Stop()
 	connLen := 0
 	for {
 		select {
@@ -20,10 +22,13 @@ func setupGc(a *api.API, stopChan chan struct{}) {
 				return true
 			})
 
+			// nodes which went away without cleaning up leave their sockets in topics
+			if err := a.Handlers.Redis.RemoveStoppedSocketNodes(ctx); err != nil {
+				util.ErrorLog.Printf("could not remove stopped socket nodes, err: %v", err)
+			}
+
 			// can be removed when no longer needed
-			ctx := context.Background()
-			defer ctx.Done()
-			socketConnections, err := a.Handlers.Redis.RedisClient.SMembers(ctx, "socket_server_connections").Result()
+			socketConnections, err := a.Handlers.Redis.RedisClient.HKeys(ctx, "socket_server_connections").Result()
 			sockLen := len(socketConnections)
 			if err != nil {
 				println("reading socket connection err", err.Error())
@@ -32,7 +37,7 @@ func setupGc(a *api.API, stopChan chan struct{}) {
 				connLen = sockLen
 				fmt.Printf("got socket connection list new count :%d %+v\n", len(socketConnections), socketConnections)
 			}
-		case <-stopChan:
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/api"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const (
+	identityOutboxRetry    = time.Minute
+	identityReconcileEvery = 15 * time.Minute
+)
+
+// Applies queued identity provider changes and repairs drift between group roles and their
+// subgroups. The outbox trigger notifies on commit, failed actions are retried every
+// identityOutboxRetry, and group roles are reconciled every identityReconcileEvery.
+func setupIdentityReconciler(ctx context.Context, a *api.API) {
+	reconciler := a.Handlers.NewIdentityReconciler()
+	changed, stopped := listenForChanges(ctx, "identity outbox", a.Handlers.Database.DatabaseClient.ListenIdentityOutbox)
+	defer func() { <-stopped }()
+
+	retryTicker := time.NewTicker(identityOutboxRetry)
+	defer retryTicker.Stop()
+
+	reconcileTicker := time.NewTicker(identityReconcileEvery)
+	defer reconcileTicker.Stop()
+
+	for {
+		select {
+		case <-changed:
+			drainQueue(ctx, "identity outbox", a.Handlers.ProcessIdentityOutbox)
+		case <-retryTicker.C:
+			drainQueue(ctx, "identity outbox", a.Handlers.ProcessIdentityOutbox)
+		case <-reconcileTicker.C:
+			if err := reconciler.ReconcileGroupRoles(ctx); err != nil {
+				util.ErrorLog.Printf("could not reconcile group roles, err: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/testutil"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+func testIntegrationBookingCalendar(t *testing.T) {
+	staff1 := testutil.IntegrationTest.TestUsers[1]
+	member1 := testutil.IntegrationTest.TestUsers[4]
+
+	var feedUrl string
+
+	t.Run("users can create a calendar feed url", func(tt *testing.T) {
+		postCalendarTokenResponse := &types.PostBookingCalendarTokenResponse{}
+		err := staff1.DoHandler(http.MethodPost, "/api/v1/bookings/calendar/token", []byte("{}"), nil, postCalendarTokenResponse)
+		if err != nil {
+			t.Fatalf("staff post calendar token error %v", err)
+		}
+
+		if postCalendarTokenResponse.GetToken() == "" {
+			t.Fatal("calendar token was empty")
+		}
+
+		if !strings.HasPrefix(postCalendarTokenResponse.GetUrl(), util.E_APP_HOST_URL+"/api/ics/bookings/") {
+			t.Fatalf("calendar feed url was not a bookings feed, %s", postCalendarTokenResponse.GetUrl())
+		}
+
+		feedUrl = postCalendarTokenResponse.GetUrl()
+	})
+
+	t.Run("calendar feeds require a valid token", func(tt *testing.T) {
+		_, err := testutil.GetBookingsCalendar(util.E_APP_HOST_URL + "/api/ics/bookings/not-a-calendar-token.ics")
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Fatalf("calendar feed with a bad token was not 404, %v", err)
+		}
+	})
+
+	t.Run("calendar feed lists the user's upcoming bookings", func(tt *testing.T) {
+		calendar, err := testutil.GetBookingsCalendar(feedUrl)
+		if err != nil {
+			t.Fatalf("calendar feed error %v", err)
+		}
+
+		if !strings.HasPrefix(calendar, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(calendar, "END:VCALENDAR\r\n") {
+			t.Fatalf("calendar feed was not an icalendar document, %s", calendar)
+		}
+
+		if len(testutil.IntegrationTest.Bookings) == 0 {
+			t.Fatal("no bookings to find in the calendar feed")
+		}
+
+		if !strings.Contains(calendar, "UID:"+testutil.IntegrationTest.Bookings[0].Id+"@") {
+			t.Fatalf("calendar feed did not contain booking %s, %s", testutil.IntegrationTest.Bookings[0].Id, calendar)
+		}
+
+		if !strings.Contains(calendar, "DTSTART;TZID=") {
+			t.Fatalf("calendar feed events were not in the schedule timezone, %s", calendar)
+		}
+	})
+
+	t.Run("clients can subscribe to their own bookings", func(tt *testing.T) {
+		postCalendarTokenResponse := &types.PostBookingCalendarTokenResponse{}
+		err := member1.DoHandler(http.MethodPost, "/api/v1/bookings/calendar/token", []byte("{}"), nil, postCalendarTokenResponse)
+		if err != nil {
+			t.Fatalf("member post calendar token error %v", err)
+		}
+
+		if postCalendarTokenResponse.GetUrl() == feedUrl {
+			t.Fatal("member received the staff calendar feed url")
+		}
+
+		calendar, err := testutil.GetBookingsCalendar(postCalendarTokenResponse.GetUrl())
+		if err != nil {
+			t.Fatalf("member calendar feed error %v", err)
+		}
+
+		if !strings.HasPrefix(calendar, "BEGIN:VCALENDAR\r\n") {
+			t.Fatalf("member calendar feed was not an icalendar document, %s", calendar)
+		}
+	})
+
+	t.Run("a new calendar token replaces the previous feed", func(tt *testing.T) {
+		postCalendarTokenResponse := &types.PostBookingCalendarTokenResponse{}
+		err := staff1.DoHandler(http.MethodPost, "/api/v1/bookings/calendar/token", []byte("{}"), nil, postCalendarTokenResponse)
+		if err != nil {
+			t.Fatalf("staff post calendar token error %v", err)
+		}
+
+		_, err = testutil.GetBookingsCalendar(feedUrl)
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Fatalf("replaced calendar feed was not 404, %v", err)
+		}
+
+		feedUrl = postCalendarTokenResponse.GetUrl()
+
+		_, err = testutil.GetBookingsCalendar(feedUrl)
+		if err != nil {
+			t.Fatalf("new calendar feed error %v", err)
+		}
+	})
+
+	t.Run("deleted calendar feeds are rejected", func(tt *testing.T) {
+		err := staff1.DoHandler(http.MethodDelete, "/api/v1/bookings/calendar/token", nil, nil, &types.DeleteBookingCalendarTokenResponse{})
+		if err != nil {
+			t.Fatalf("staff delete calendar token error %v", err)
+		}
+
+		_, err = testutil.GetBookingsCalendar(feedUrl)
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Fatalf("deleted calendar feed was not 404, %v", err)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package main_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/testutil"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func testIntegrationBookingInvoices(t *testing.T) {
+	admin := testutil.IntegrationTest.TestUsers[0]
+	staff1 := testutil.IntegrationTest.TestUsers[1]
+
+	if len(testutil.IntegrationTest.Bookings) < 2 {
+		t.Fatal("not enough bookings to invoice")
+	}
+
+	t.Run("admin can save invoice settings", func(tt *testing.T) {
+		patchGroupInvoiceSettingsBytes, err := protojson.Marshal(&types.PatchGroupInvoiceSettingsRequest{
+			PayeeName: proto.String("Integration Group"),
+			TaxLabel:  proto.String("Tax"),
+			TaxRate:   proto.Int32(500),
+		})
+		if err != nil {
+			t.Fatalf("error marshalling patch group invoice settings request %v", err)
+		}
+
+		err = admin.DoHandler(http.MethodPatch, "/api/v1/group/invoices/settings", patchGroupInvoiceSettingsBytes, nil, nil)
+		if err != nil {
+			t.Fatalf("admin patch group invoice settings error %v", err)
+		}
+
+		getGroupInvoiceSettingsResponse := &types.GetGroupInvoiceSettingsResponse{}
+		err = admin.DoHandler(http.MethodGet, "/api/v1/group/invoices/settings", nil, nil, getGroupInvoiceSettingsResponse)
+		if err != nil {
+			t.Fatalf("admin get group invoice settings error %v", err)
+		}
+
+		if getGroupInvoiceSettingsResponse.GetSettings().GetTaxRate() != 500 {
+			t.Fatalf("group invoice settings were not saved, got %v", getGroupInvoiceSettingsResponse.GetSettings())
+		}
+	})
+
+	postBookingInvoice := func(bookingId string) error {
+		postBookingInvoiceBytes, err := protojson.Marshal(&types.PostBookingInvoiceRequest{BookingId: bookingId})
+		if err != nil {
+			t.Fatalf("error marshalling post booking invoice request %v", err)
+		}
+		return staff1.DoHandler(http.MethodPost, "/api/v1/bookings/"+bookingId+"/invoices", postBookingInvoiceBytes, nil, &types.PostBookingInvoiceResponse{})
+	}
+
+	t.Run("a booking has one invoice", func(tt *testing.T) {
+		bookingId := testutil.IntegrationTest.Bookings[0].Id
+
+		err := postBookingInvoice(bookingId)
+		if err != nil {
+			t.Fatalf("staff post booking invoice error %v", err)
+		}
+
+		err = postBookingInvoice(bookingId)
+		if err == nil {
+			t.Fatal("a second invoice was drafted for the booking")
+		}
+	})
+
+	t.Run("invoices drafted at the same time still give one invoice", func(tt *testing.T) {
+		bookingId := testutil.IntegrationTest.Bookings[1].Id
+
+		var wg sync.WaitGroup
+		errs := make([]error, 4)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = postBookingInvoice(bookingId)
+			}()
+		}
+		wg.Wait()
+
+		var drafted int
+		for _, err := range errs {
+			if err == nil {
+				drafted++
+			}
+		}
+
+		if drafted != 1 {
+			t.Fatalf("expected %d invoice to be drafted, drafted %d, %v", 1, drafted, errs)
+		}
+
+		getBookingInvoicesResponse := &types.GetBookingInvoicesResponse{}
+		err := staff1.DoHandler(http.MethodGet, "/api/v1/bookings/"+bookingId+"/invoices", nil, nil, getBookingInvoicesResponse)
+		if err != nil {
+			t.Fatalf("staff get booking invoices error %v", err)
+		}
+
+		if len(getBookingInvoicesResponse.GetInvoices()) != 1 {
+			t.Fatalf("expected %d booking invoice, received %d", 1, len(getBookingInvoicesResponse.GetInvoices()))
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package main_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/testutil"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func testIntegrationBookingTranscripts(t *testing.T) {
+	staff1 := testutil.IntegrationTest.TestUsers[1]
+	staff2 := testutil.IntegrationTest.TestUsers[2]
+
+	if len(testutil.IntegrationTest.Bookings) == 0 {
+		t.Fatal("no bookings to attach transcripts to")
+	}
+
+	bookingId := testutil.IntegrationTest.Bookings[0].Id
+	missingTranscriptId := "00000000-0000-0000-0000-000000000000"
+
+	var transcriptId string
+
+	t.Run("booking participants can add a transcript", func(tt *testing.T) {
+		postBookingTranscriptBytes, err := protojson.Marshal(&types.PostBookingTranscriptRequest{
+			BookingTranscripts: []*types.IBookingTranscript{{
+				BookingId: bookingId,
+				Messages: []*types.ITranscriptMessage{{
+					Words: "hello",
+					Style: "written",
+				}},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("error marshalling post booking transcript request %v", err)
+		}
+
+		postBookingTranscriptResponse := &types.PostBookingTranscriptResponse{}
+		err = staff1.DoHandler(http.MethodPost, "/api/v1/booking_transcripts", postBookingTranscriptBytes, nil, postBookingTranscriptResponse)
+		if err != nil {
+			t.Fatalf("staff post booking transcript error %v", err)
+		}
+
+		if len(postBookingTranscriptResponse.GetIds()) != 1 || !util.IsUUID(postBookingTranscriptResponse.GetIds()[0]) {
+			t.Fatalf("booking transcript ids were not a single uuid %v", postBookingTranscriptResponse.GetIds())
+		}
+
+		transcriptId = postBookingTranscriptResponse.GetIds()[0]
+	})
+
+	patchBookingTranscriptBytes := func(id, words string) []byte {
+		patchBytes, err := protojson.Marshal(&types.PatchBookingTranscriptRequest{
+			BookingTranscript: &types.IBookingTranscript{
+				Id: id,
+				Messages: []*types.ITranscriptMessage{{
+					Words: words,
+					Style: "written",
+				}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("error marshalling patch booking transcript request %v", err)
+		}
+		return patchBytes
+	}
+
+	t.Run("non participants cannot change a transcript", func(tt *testing.T) {
+		err := staff2.DoHandler(http.MethodPatch, "/api/v1/booking_transcripts", patchBookingTranscriptBytes(transcriptId, "changed"), nil, nil)
+		if err == nil {
+			t.Fatal("non participant could patch a booking transcript")
+		}
+
+		err = staff2.DoHandler(http.MethodPatch, "/api/v1/booking_transcripts/"+transcriptId+"/disable", nil, nil, nil)
+		if err == nil {
+			t.Fatal("non participant could disable a booking transcript")
+		}
+
+		err = staff2.DoHandler(http.MethodDelete, "/api/v1/booking_transcripts/"+transcriptId, nil, nil, nil)
+		if err == nil {
+			t.Fatal("non participant could delete a booking transcript")
+		}
+	})
+
+	t.Run("missing transcripts cannot be changed", func(tt *testing.T) {
+		err := staff1.DoHandler(http.MethodPatch, "/api/v1/booking_transcripts", patchBookingTranscriptBytes(missingTranscriptId, "missing"), nil, nil)
+		if err == nil {
+			t.Fatal("patching a missing booking transcript did not error")
+		}
+
+		err = staff1.DoHandler(http.MethodPatch, "/api/v1/booking_transcripts/"+missingTranscriptId+"/disable", nil, nil, nil)
+		if err == nil {
+			t.Fatal("disabling a missing booking transcript did not error")
+		}
+
+		err = staff1.DoHandler(http.MethodDelete, "/api/v1/booking_transcripts/"+missingTranscriptId, nil, nil, nil)
+		if err == nil {
+			t.Fatal("deleting a missing booking transcript did not error")
+		}
+	})
+
+	t.Run("booking participants can change their transcript", func(tt *testing.T) {
+		err := staff1.DoHandler(http.MethodPatch, "/api/v1/booking_transcripts", patchBookingTranscriptBytes(transcriptId, "hello again"), nil, nil)
+		if err != nil {
+			t.Fatalf("staff patch booking transcript error %v", err)
+		}
+
+		getBookingTranscriptByIdResponse := &types.GetBookingTranscriptByIdResponse{}
+		err = staff1.DoHandler(http.MethodGet, "/api/v1/booking_transcripts/"+transcriptId, nil, nil, getBookingTranscriptByIdResponse)
+		if err != nil {
+			t.Fatalf("staff get booking transcript error %v", err)
+		}
+
+		messages := getBookingTranscriptByIdResponse.GetBookingTranscript().GetMessages()
+		if len(messages) != 1 || messages[0].GetWords() != "hello again" {
+			t.Fatalf("booking transcript was not patched, got %v", messages)
+		}
+
+		err = staff1.DoHandler(http.MethodDelete, "/api/v1/booking_transcripts/"+transcriptId, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("staff delete booking transcript error %v", err)
+		}
+
+		err = staff1.DoHandler(http.MethodDelete, "/api/v1/booking_transcripts/"+transcriptId, nil, nil, nil)
+		if err == nil {
+			t.Fatal("deleting a booking transcript twice did not error")
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package main_test
 
 import (
+	"net/http"
 	"strings"
 	"testing"
 
@@ -12,6 +13,7 @@ import (
 func testIntegrationBookings(t *testing.T) {
 	admin := testutil.IntegrationTest.TestUsers[0]
 	staff1 := testutil.IntegrationTest.TestUsers[1]
+	staff2 := testutil.IntegrationTest.TestUsers[2]
 	member1 := testutil.IntegrationTest.TestUsers[4]
 	member2 := testutil.IntegrationTest.TestUsers[5]
 	member3 := testutil.IntegrationTest.TestUsers[6]
@@ -144,6 +146,50 @@ func testIntegrationBookings(t *testing.T) {
 		testutil.IntegrationTest.Bookings = append(testutil.IntegrationTest.Bookings, bookings...)
 	})
 
+	t.Run("booking ratings are throttled per user", func(tt *testing.T) {
+		bookingId := testutil.IntegrationTest.Bookings[0].Id
+
+		err := staff1.PatchBookingRating(bookingId, 5)
+		if err != nil {
+			t.Fatalf("first booking rating error %v", err)
+		}
+
+		err = staff1.PatchBookingRating(bookingId, 4)
+		if err == nil {
+			t.Fatal("second booking rating inside the throttle window was allowed")
+		}
+
+		if !strings.Contains(err.Error(), "429") {
+			t.Fatalf("second booking rating was not 429, %v", err)
+		}
+
+		err = staff2.PatchBookingRating(bookingId, 4)
+		if err != nil {
+			t.Fatalf("throttle for one user affected another user, %v", err)
+		}
+	})
+
+	t.Run("APP_GROUP_ADMIN is required to audit a booking call log", func(tt *testing.T) {
+		bookingId := testutil.IntegrationTest.Bookings[0].Id
+
+		err := staff1.DoHandler(http.MethodGet, "/api/v1/bookings/"+bookingId+"/call_log", nil, nil, &types.GetBookingCallLogResponse{})
+		if err == nil || !strings.Contains(err.Error(), "403") {
+			t.Fatalf("staff call log request was not 403, %v", err)
+		}
+
+		callLogResponse := &types.GetBookingCallLogResponse{}
+		err = admin.DoHandler(http.MethodGet, "/api/v1/bookings/"+bookingId+"/call_log", nil, nil, callLogResponse)
+		if err != nil {
+			t.Fatalf("admin call log request error %v", err)
+		}
+
+		for _, entry := range callLogResponse.GetCallLog() {
+			if entry.GetBookingId() != bookingId {
+				t.Fatalf("call log entry %s belongs to booking %s", entry.GetId(), entry.GetBookingId())
+			}
+		}
+	})
+
 	t.Run("master schedules can be disabled, preserving all records", func(tt *testing.T) {
 
 	})
@@ -0,0 +1,157 @@
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/testutil"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func testIntegrationGroupInvites(t *testing.T) {
+	admin := testutil.IntegrationTest.TestUsers[0]
+	member1 := testutil.IntegrationTest.TestUsers[4]
+	staffRole := testutil.IntegrationTest.StaffRole
+
+	inviteId := fmt.Sprint(time.Now().UnixNano())
+	invitedEmail := "invited@" + inviteId
+	revokedEmail := "revoked@" + inviteId
+
+	postInvites := func(user *testutil.TestUsersStruct, emails ...string) (*types.InviteGroupUsersResponse, error) {
+		inviteUsers := make([]*types.IGroupInviteUser, 0, len(emails))
+		for _, email := range emails {
+			inviteUsers = append(inviteUsers, &types.IGroupInviteUser{Email: email, RoleId: staffRole.GetRoleId()})
+		}
+
+		inviteRequestBytes, err := protojson.Marshal(&types.InviteGroupUsersRequest{Users: inviteUsers})
+		if err != nil {
+			return nil, err
+		}
+
+		inviteResponse := &types.InviteGroupUsersResponse{}
+		err = user.DoHandler(http.MethodPost, "/api/v1/group/invites", inviteRequestBytes, nil, inviteResponse)
+		return inviteResponse, err
+	}
+
+	t.Run("APP_GROUP_ADMIN is required to invite users", func(tt *testing.T) {
+		_, err := postInvites(member1, invitedEmail)
+		if err == nil || !strings.Contains(err.Error(), "403") {
+			t.Fatalf("member invite was not 403, %v", err)
+		}
+	})
+
+	var revokedInviteId string
+
+	t.Run("admin can invite users by email", func(tt *testing.T) {
+		inviteResponse, err := postInvites(admin, invitedEmail, revokedEmail)
+		if err != nil {
+			t.Fatalf("admin invite error %v", err)
+		}
+
+		if len(inviteResponse.GetInvites()) != 2 {
+			t.Fatalf("expected %d invites, received %d", 2, len(inviteResponse.GetInvites()))
+		}
+
+		revokedInviteId = inviteResponse.GetInvites()[1].GetId()
+	})
+
+	t.Run("revoked invites can't be used to register", func(tt *testing.T) {
+		err := admin.DoHandler(http.MethodPatch, "/api/v1/group/invites/"+revokedInviteId+"/revoke", nil, nil, nil)
+		if err != nil {
+			t.Fatalf("admin revoke invite error %v", err)
+		}
+
+		invite, err := testutil.GetInviteFromMail(revokedEmail)
+		if err != nil {
+			t.Fatalf("could not read revoked invite mail %v", err)
+		}
+
+		revokedUser := testutil.NewTestUser(inviteId, revokedEmail, "1")
+		err = revokedUser.RegisterKeycloakUserViaInvite(invite)
+		if err == nil {
+			t.Fatal("registered with a revoked invite")
+		}
+	})
+
+	t.Run("invites must be used with the invited email", func(tt *testing.T) {
+		invite, err := testutil.GetInviteFromMail(invitedEmail)
+		if err != nil {
+			t.Fatalf("could not read invite mail %v", err)
+		}
+
+		otherUser := testutil.NewTestUser(inviteId, "other@"+inviteId, "1")
+		err = otherUser.RegisterKeycloakUserViaInvite(invite)
+		if err == nil {
+			t.Fatal("registered with another user's invite")
+		}
+	})
+
+	t.Run("invited users register straight into the invited role", func(tt *testing.T) {
+		invite, err := testutil.GetInviteFromMail(invitedEmail)
+		if err != nil {
+			t.Fatalf("could not read invite mail %v", err)
+		}
+
+		invitedUser := testutil.NewTestUser(inviteId, invitedEmail, "1")
+		err = invitedUser.RegisterKeycloakUserViaInvite(invite)
+		if err != nil {
+			t.Fatalf("could not register with invite %v", err)
+		}
+
+		_, err = invitedUser.Login()
+		if err != nil {
+			t.Fatalf("could not login as invited user, %v", err)
+		}
+
+		err = invitedUser.GetVaultKey()
+		if err != nil {
+			t.Fatalf("could not get vault key: %v", err)
+		}
+
+		profile, err := invitedUser.GetProfileDetails()
+		if err != nil {
+			t.Fatalf("could not get invited user profile, %v", err)
+		}
+
+		if profile.GetRoleName() != staffRole.GetName() {
+			t.Fatalf("invited user role was %s, expected %s", profile.GetRoleName(), staffRole.GetName())
+		}
+
+		err = invitedUser.Logout()
+		if err != nil {
+			t.Fatalf("could not log out invited user, %v", err)
+		}
+
+		reuseUser := testutil.NewTestUser(inviteId, invitedEmail, "1")
+		err = reuseUser.RegisterKeycloakUserViaInvite(invite)
+		if err == nil {
+			t.Fatal("registered twice with the same invite")
+		}
+	})
+
+	t.Run("admin can see accepted and revoked invites", func(tt *testing.T) {
+		getInvitesResponse := &types.GetGroupInvitesResponse{}
+		err := admin.DoHandler(http.MethodGet, "/api/v1/group/invites", nil, nil, getInvitesResponse)
+		if err != nil {
+			t.Fatalf("admin get invites error %v", err)
+		}
+
+		var accepted, revoked bool
+		for _, invite := range getInvitesResponse.GetInvites() {
+			switch invite.GetEmail() {
+			case invitedEmail:
+				accepted = invite.GetAcceptedOn() != ""
+			case revokedEmail:
+				revoked = invite.GetRevokedOn() != ""
+			}
+		}
+
+		if !accepted || !revoked {
+			t.Fatalf("invite states were not recorded, accepted %t revoked %t", accepted, revoked)
+		}
+	})
+}
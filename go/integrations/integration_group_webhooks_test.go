@@ -0,0 +1,107 @@
+package main_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/testutil"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func testIntegrationGroupWebhooks(t *testing.T) {
+	admin := testutil.IntegrationTest.TestUsers[0]
+	missingWebhookId := "00000000-0000-0000-0000-000000000000"
+
+	var webhookId string
+
+	t.Run("admin can add a webhook", func(tt *testing.T) {
+		postGroupWebhookBytes, err := protojson.Marshal(&types.PostGroupWebhookRequest{
+			Url:    "https://hooks.example.com/awayto",
+			Events: []string{"quote.created"},
+		})
+		if err != nil {
+			t.Fatalf("error marshalling post group webhook request %v", err)
+		}
+
+		postGroupWebhookResponse := &types.PostGroupWebhookResponse{}
+		err = admin.DoHandler(http.MethodPost, "/api/v1/group/webhooks", postGroupWebhookBytes, nil, postGroupWebhookResponse)
+		if err != nil {
+			t.Fatalf("admin post group webhook error %v", err)
+		}
+
+		if !util.IsUUID(postGroupWebhookResponse.GetId()) {
+			t.Fatalf("group webhook id is not a uuid %s", postGroupWebhookResponse.GetId())
+		}
+
+		webhookId = postGroupWebhookResponse.GetId()
+	})
+
+	t.Run("webhooks cannot point at private addresses", func(tt *testing.T) {
+		postGroupWebhookBytes, err := protojson.Marshal(&types.PostGroupWebhookRequest{
+			Url:    "https://100.64.0.1/awayto",
+			Events: []string{"quote.created"},
+		})
+		if err != nil {
+			t.Fatalf("error marshalling post group webhook request %v", err)
+		}
+
+		err = admin.DoHandler(http.MethodPost, "/api/v1/group/webhooks", postGroupWebhookBytes, nil, nil)
+		if err == nil {
+			t.Fatal("admin could add a webhook with a private address")
+		}
+	})
+
+	patchGroupWebhookBytes := func(id string) []byte {
+		patchBytes, err := protojson.Marshal(&types.PatchGroupWebhookRequest{
+			Id:      id,
+			Url:     "https://hooks.example.com/awayto/v2",
+			Events:  []string{"booking.created"},
+			Enabled: true,
+		})
+		if err != nil {
+			t.Fatalf("error marshalling patch group webhook request %v", err)
+		}
+		return patchBytes
+	}
+
+	t.Run("missing webhooks cannot be changed", func(tt *testing.T) {
+		err := admin.DoHandler(http.MethodPatch, "/api/v1/group/webhooks/"+missingWebhookId, patchGroupWebhookBytes(missingWebhookId), nil, nil)
+		if err == nil {
+			t.Fatal("patching a missing group webhook did not error")
+		}
+
+		err = admin.DoHandler(http.MethodDelete, "/api/v1/group/webhooks/"+missingWebhookId, nil, nil, nil)
+		if err == nil {
+			t.Fatal("deleting a missing group webhook did not error")
+		}
+	})
+
+	t.Run("admin can change and remove a webhook", func(tt *testing.T) {
+		err := admin.DoHandler(http.MethodPatch, "/api/v1/group/webhooks/"+webhookId, patchGroupWebhookBytes(webhookId), nil, nil)
+		if err != nil {
+			t.Fatalf("admin patch group webhook error %v", err)
+		}
+
+		getGroupWebhooksResponse := &types.GetGroupWebhooksResponse{}
+		err = admin.DoHandler(http.MethodGet, "/api/v1/group/webhooks", nil, nil, getGroupWebhooksResponse)
+		if err != nil {
+			t.Fatalf("admin get group webhooks error %v", err)
+		}
+
+		if len(getGroupWebhooksResponse.GetWebhooks()) != 1 || getGroupWebhooksResponse.GetWebhooks()[0].GetUrl() != "https://hooks.example.com/awayto/v2" {
+			t.Fatalf("group webhook was not patched, got %v", getGroupWebhooksResponse.GetWebhooks())
+		}
+
+		err = admin.DoHandler(http.MethodDelete, "/api/v1/group/webhooks/"+webhookId, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("admin delete group webhook error %v", err)
+		}
+
+		err = admin.DoHandler(http.MethodDelete, "/api/v1/group/webhooks/"+webhookId, nil, nil, nil)
+		if err == nil {
+			t.Fatal("deleting a group webhook twice did not error")
+		}
+	})
+}
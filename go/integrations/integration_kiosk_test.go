@@ -0,0 +1,114 @@
+package main_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/testutil"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func testIntegrationKiosk(t *testing.T) {
+	admin := testutil.IntegrationTest.TestUsers[0]
+	staff1 := testutil.IntegrationTest.TestUsers[1]
+	member1 := testutil.IntegrationTest.TestUsers[4]
+	groupName := testutil.IntegrationTest.Group.GetName()
+
+	var kioskTokenId, kioskToken string
+
+	t.Run("APP_GROUP_ADMIN is required to create a kiosk token", func(tt *testing.T) {
+		postKioskTokenBytes, err := protojson.Marshal(&types.PostGroupKioskTokenRequest{Name: "member kiosk"})
+		if err != nil {
+			t.Fatalf("error marshalling post kiosk token request %v", err)
+		}
+
+		err = member1.DoHandler(http.MethodPost, "/api/v1/group/schedules/kiosk/tokens", postKioskTokenBytes, nil, nil)
+		if err == nil || !strings.Contains(err.Error(), "403") {
+			t.Fatalf("member post kiosk token was not 403, %v", err)
+		}
+	})
+
+	t.Run("admin can create a kiosk token", func(tt *testing.T) {
+		postKioskTokenBytes, err := protojson.Marshal(&types.PostGroupKioskTokenRequest{Name: "lobby display"})
+		if err != nil {
+			t.Fatalf("error marshalling post kiosk token request %v", err)
+		}
+
+		postKioskTokenResponse := &types.PostGroupKioskTokenResponse{}
+		err = admin.DoHandler(http.MethodPost, "/api/v1/group/schedules/kiosk/tokens", postKioskTokenBytes, nil, postKioskTokenResponse)
+		if err != nil {
+			t.Fatalf("admin post kiosk token error %v", err)
+		}
+
+		if postKioskTokenResponse.GetToken() == "" {
+			t.Fatal("kiosk token was empty")
+		}
+
+		kioskTokenId = postKioskTokenResponse.GetId()
+		kioskToken = postKioskTokenResponse.GetToken()
+	})
+
+	t.Run("kiosk schedules require a valid kiosk token", func(tt *testing.T) {
+		_, err := testutil.GetKioskSchedules(groupName, "")
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Fatalf("kiosk schedules without a token was not 401, %v", err)
+		}
+
+		_, err = testutil.GetKioskSchedules(groupName, "not-a-kiosk-token")
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Fatalf("kiosk schedules with a bad token was not 401, %v", err)
+		}
+
+		_, err = testutil.GetKioskSchedules("not-the-group", kioskToken)
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Fatalf("kiosk token was accepted for another group name, %v", err)
+		}
+	})
+
+	t.Run("kiosk token can read the group kiosk schedules", func(tt *testing.T) {
+		_, err := testutil.GetKioskSchedules(groupName, kioskToken)
+		if err != nil {
+			t.Fatalf("kiosk schedules with a valid token error %v", err)
+		}
+
+		getKioskTokensResponse := &types.GetGroupKioskTokensResponse{}
+		err = admin.DoHandler(http.MethodGet, "/api/v1/group/schedules/kiosk/tokens", nil, nil, getKioskTokensResponse)
+		if err != nil {
+			t.Fatalf("admin get kiosk tokens error %v", err)
+		}
+
+		if len(getKioskTokensResponse.GetKioskTokens()) != 1 {
+			t.Fatalf("expected %d kiosk tokens, received %d", 1, len(getKioskTokensResponse.GetKioskTokens()))
+		}
+
+		if getKioskTokensResponse.GetKioskTokens()[0].GetLastUsedOn() == "" {
+			t.Fatal("kiosk token last used on was not set")
+		}
+	})
+
+	t.Run("staff can view the group kiosk schedules", func(tt *testing.T) {
+		err := staff1.DoHandler(http.MethodGet, "/api/v1/group/schedules/kiosk", nil, nil, &types.GetGroupKioskSchedulesResponse{})
+		if err != nil {
+			t.Fatalf("staff get group kiosk schedules error %v", err)
+		}
+	})
+
+	t.Run("deleted kiosk tokens are rejected", func(tt *testing.T) {
+		err := admin.DoHandler(http.MethodDelete, "/api/v1/group/schedules/kiosk/tokens/"+kioskTokenId, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("admin delete kiosk token error %v", err)
+		}
+
+		_, err = testutil.GetKioskSchedules(groupName, kioskToken)
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Fatalf("deleted kiosk token was not 401, %v", err)
+		}
+
+		err = admin.DoHandler(http.MethodDelete, "/api/v1/group/schedules/kiosk/tokens/"+kioskTokenId, nil, nil, nil)
+		if err == nil {
+			t.Fatal("deleting a kiosk token twice did not error")
+		}
+	})
+}
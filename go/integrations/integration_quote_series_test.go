@@ -0,0 +1,164 @@
+package main_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/testutil"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func testIntegrationQuoteSeries(t *testing.T) {
+	staff1 := testutil.IntegrationTest.TestUsers[1]
+	staff2 := testutil.IntegrationTest.TestUsers[2]
+	member1 := testutil.IntegrationTest.TestUsers[4]
+
+	var serviceTierId string
+	for _, tier := range testutil.IntegrationTest.GroupService.Service.Tiers {
+		serviceTierId = tier.Id
+		break
+	}
+
+	// staff1 owns the first date slot, see bookings; the series starts on a later week of it
+	firstSlot := testutil.IntegrationTest.DateSlots[0]
+	var seriesSlot *types.IGroupScheduleDateSlots
+	for _, dateSlot := range testutil.IntegrationTest.DateSlots {
+		if dateSlot.GetScheduleBracketSlotId() == firstSlot.GetScheduleBracketSlotId() && dateSlot.GetStartDate() > firstSlot.GetStartDate() {
+			seriesSlot = dateSlot
+			break
+		}
+	}
+	if seriesSlot == nil {
+		t.Fatalf("no later week of slot %s to start a series on", firstSlot.GetScheduleBracketSlotId())
+	}
+
+	postSeries := func(user *testutil.TestUsersStruct, recurrence *types.IQuoteRecurrence) (*types.PostQuoteSeriesResponse, error) {
+		requestBytes, err := protojson.Marshal(&types.PostQuoteSeriesRequest{
+			ScheduleBracketSlotId: seriesSlot.GetScheduleBracketSlotId(),
+			ServiceTierId:         serviceTierId,
+			SlotDate:              seriesSlot.GetStartDate(),
+			Recurrence:            recurrence,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		seriesResponse := &types.PostQuoteSeriesResponse{}
+		err = user.DoHandler(http.MethodPost, "/api/v1/quotes/series", requestBytes, nil, seriesResponse)
+		return seriesResponse, err
+	}
+
+	disableSeries := func(user *testutil.TestUsersStruct, quoteId string, scope types.QuoteSeriesScope) (*types.DisableQuoteSeriesResponse, error) {
+		requestBytes, err := protojson.Marshal(&types.DisableQuoteSeriesRequest{QuoteId: quoteId, Scope: scope})
+		if err != nil {
+			return nil, err
+		}
+
+		disableResponse := &types.DisableQuoteSeriesResponse{}
+		err = user.DoHandler(http.MethodPatch, "/api/v1/quotes/series/disable", requestBytes, nil, disableResponse)
+		return disableResponse, err
+	}
+
+	t.Run("APP_GROUP_BOOKINGS is required to request a series", func(tt *testing.T) {
+		_, err := postSeries(staff2, &types.IQuoteRecurrence{IntervalWeeks: 1, Count: 3})
+		if err == nil || !strings.Contains(err.Error(), "403") {
+			t.Fatalf("staff series request was not 403, %v", err)
+		}
+	})
+
+	t.Run("a series needs an end", func(tt *testing.T) {
+		_, err := postSeries(member1, &types.IQuoteRecurrence{IntervalWeeks: 1})
+		if err == nil {
+			t.Fatal("series without a count or end date was created")
+		}
+	})
+
+	var series *types.PostQuoteSeriesResponse
+
+	t.Run("weekly series creates linked quotes a week apart", func(tt *testing.T) {
+		var err error
+		series, err = postSeries(member1, &types.IQuoteRecurrence{IntervalWeeks: 1, Count: 3})
+		if err != nil {
+			t.Fatalf("member1 post series error %v", err)
+		}
+
+		if len(series.GetQuotes()) != 3 {
+			t.Fatalf("expected %d quotes in the series, received %d", 3, len(series.GetQuotes()))
+		}
+
+		var previous time.Time
+		for i, quote := range series.GetQuotes() {
+			if quote.GetSeriesId() != series.GetSeriesId() {
+				t.Fatalf("quote %s series id %s, want %s", quote.GetId(), quote.GetSeriesId(), series.GetSeriesId())
+			}
+
+			slotDate, err := time.Parse("2006-01-02", quote.GetSlotDate())
+			if err != nil {
+				t.Fatalf("quote %s slot date invalid %s", quote.GetId(), quote.GetSlotDate())
+			}
+
+			if i > 0 && slotDate.Sub(previous) != 7*24*time.Hour {
+				t.Fatalf("quote %s is not a week after the previous, %s", quote.GetId(), quote.GetSlotDate())
+			}
+			previous = slotDate
+
+			staffQuote, err := staff1.GetQuoteById(quote.GetId())
+			if err != nil {
+				t.Fatalf("staff1 get series quote error %v", err)
+			}
+
+			if staffQuote.GetSeriesId() != series.GetSeriesId() {
+				t.Fatalf("staff quote %s has series id %s, want %s", quote.GetId(), staffQuote.GetSeriesId(), series.GetSeriesId())
+			}
+		}
+	})
+
+	t.Run("requester can cancel one occurrence", func(tt *testing.T) {
+		disableResponse, err := disableSeries(member1, series.GetQuotes()[2].GetId(), types.QuoteSeriesScope_SERIES_THIS)
+		if err != nil {
+			t.Fatalf("member1 cancel occurrence error %v", err)
+		}
+
+		if disableResponse.GetQuotes() != 1 {
+			t.Fatalf("expected %d cancelled quote, received %d", 1, disableResponse.GetQuotes())
+		}
+	})
+
+	t.Run("staff can approve the rest of the series at once", func(tt *testing.T) {
+		requestBytes, err := protojson.Marshal(&types.PostBookingRequest{SeriesId: series.GetSeriesId()})
+		if err != nil {
+			t.Fatalf("marshal series booking error %v", err)
+		}
+
+		bookingResponse := &types.PostBookingResponse{}
+		err = staff1.DoHandler(http.MethodPost, "/api/v1/bookings", requestBytes, nil, bookingResponse)
+		if err != nil {
+			t.Fatalf("staff1 approve series error %v", err)
+		}
+
+		if len(bookingResponse.GetBookings()) != 2 {
+			t.Fatalf("expected %d series bookings, received %d", 2, len(bookingResponse.GetBookings()))
+		}
+	})
+
+	t.Run("requester can't cancel booked occurrences", func(tt *testing.T) {
+		_, err := disableSeries(member1, series.GetQuotes()[0].GetId(), types.QuoteSeriesScope_SERIES_THIS_AND_FOLLOWING)
+		if err == nil {
+			t.Fatal("requester cancelled booked occurrences")
+		}
+	})
+
+	t.Run("staff can cancel an occurrence and those following", func(tt *testing.T) {
+		disableResponse, err := disableSeries(staff1, series.GetQuotes()[0].GetId(), types.QuoteSeriesScope_SERIES_THIS_AND_FOLLOWING)
+		if err != nil {
+			t.Fatalf("staff1 cancel following error %v", err)
+		}
+
+		if disableResponse.GetQuotes() != 2 || disableResponse.GetBookings() != 2 {
+			t.Fatalf("expected 2 cancelled quotes and bookings, received %d and %d", disableResponse.GetQuotes(), disableResponse.GetBookings())
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/testutil"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func testIntegrationSeatPayments(t *testing.T) {
+	admin := testutil.IntegrationTest.TestUsers[0]
+
+	getPayments := func() []*types.IGroupSeatPayment {
+		paymentsResponse := &types.GetGroupSeatPaymentsResponse{}
+		err := admin.DoHandler(http.MethodGet, "/api/v1/group/seat/payments", nil, nil, paymentsResponse)
+		if err != nil {
+			t.Fatalf("admin get seat payments error %v", err)
+		}
+		return paymentsResponse.GetSeatPayments()
+	}
+
+	getPayment := func(code string) *types.IGroupSeatPayment {
+		for _, payment := range getPayments() {
+			if payment.GetCode() == code {
+				return payment
+			}
+		}
+		t.Fatalf("seat payment %s not found", code)
+		return nil
+	}
+
+	// Seat payment codes are generated by the db, so the new payment is found by comparing listings
+	postSeats := func() *types.IGroupSeatPayment {
+		var existing []string
+		for _, payment := range getPayments() {
+			existing = append(existing, payment.GetCode())
+		}
+
+		requestBytes, err := protojson.Marshal(&types.PostGroupSeatRequest{Seats: 1})
+		if err != nil {
+			t.Fatalf("error marshalling seat request %v", err)
+		}
+
+		err = admin.DoHandler(http.MethodPost, "/api/v1/group/seats", requestBytes, nil, &types.PostGroupSeatResponse{})
+		if err != nil {
+			t.Fatalf("admin post seats error %v", err)
+		}
+
+		for _, payment := range getPayments() {
+			if !slices.Contains(existing, payment.GetCode()) {
+				return payment
+			}
+		}
+		t.Fatal("new seat payment was not listed")
+		return nil
+	}
+
+	getReceipt := func(code string) (string, error) {
+		receiptResponse := &types.GetGroupSeatReceiptResponse{}
+		err := admin.DoHandler(http.MethodGet, "/api/v1/group/seats/receipt/"+code, nil, nil, receiptResponse)
+		return receiptResponse.GetHtml(), err
+	}
+
+	postReceipt := func(user *testutil.TestUsersStruct, request *types.PostGroupSeatPaymentReceiptRequest) (*types.PostGroupSeatPaymentReceiptResponse, error) {
+		requestBytes, err := protojson.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+
+		receiptResponse := &types.PostGroupSeatPaymentReceiptResponse{}
+		err = user.DoHandler(http.MethodPost, "/api/v1/group/seats/payments/receipt", requestBytes, nil, receiptResponse)
+		return receiptResponse, err
+	}
+
+	webhookBody := func(code, status, reference string, amount int32) []byte {
+		body, err := json.Marshal(map[string]any{"code": code, "status": status, "reference": reference, "amount": amount})
+		if err != nil {
+			t.Fatalf("error marshalling webhook body %v", err)
+		}
+		return body
+	}
+
+	checkPayment := postSeats()
+
+	t.Run("receipts are only available for paid seat payments", func(tt *testing.T) {
+		if checkPayment.GetStatus() != "pending" {
+			t.Fatalf("new seat payment status was %s", checkPayment.GetStatus())
+		}
+
+		_, err := getReceipt(checkPayment.GetCode())
+		if err == nil {
+			t.Fatal("received a receipt for a pending payment")
+		}
+	})
+
+	t.Run("recording receipts requires the super admin role", func(tt *testing.T) {
+		_, err := postReceipt(admin, &types.PostGroupSeatPaymentReceiptRequest{
+			Code:          checkPayment.GetCode(),
+			PaymentMethod: "check",
+			Reference:     "1042",
+			Amount:        checkPayment.GetAmount(),
+		})
+		if err == nil || !strings.Contains(err.Error(), "403") {
+			t.Fatalf("group admin receipt was not 403, %v", err)
+		}
+	})
+
+	t.Run("super admins can record a check against a purchase order", func(tt *testing.T) {
+		operatorId := fmt.Sprint(time.Now().UnixNano())
+		operator := testutil.NewTestUser(operatorId, "operator@"+operatorId, "1")
+
+		err := operator.RegisterKeycloakUserViaForm()
+		if err != nil {
+			t.Fatalf("could not register operator, %v", err)
+		}
+
+		_, err = operator.Login()
+		if err != nil {
+			t.Fatalf("could not login as operator, %v", err)
+		}
+
+		err = operator.GrantSuperAdmin()
+		if err != nil {
+			t.Fatalf("could not grant super admin, %v", err)
+		}
+
+		// Log in again so the session carries the realm role
+		_, err = operator.Login()
+		if err != nil {
+			t.Fatalf("could not login as super admin, %v", err)
+		}
+
+		err = operator.GetVaultKey()
+		if err != nil {
+			t.Fatalf("could not get vault key: %v", err)
+		}
+
+		_, err = postReceipt(operator, &types.PostGroupSeatPaymentReceiptRequest{
+			Code:          checkPayment.GetCode(),
+			PaymentMethod: "check",
+			Reference:     "1042",
+			Amount:        checkPayment.GetAmount() + 1,
+		})
+		if err == nil {
+			t.Fatal("recorded a receipt for the wrong amount")
+		}
+
+		receiptResponse, err := postReceipt(operator, &types.PostGroupSeatPaymentReceiptRequest{
+			Code:          checkPayment.GetCode(),
+			PaymentMethod: "check",
+			Reference:     "1042",
+			Amount:        checkPayment.GetAmount(),
+		})
+		if err != nil {
+			t.Fatalf("super admin post receipt error %v", err)
+		}
+
+		if receiptResponse.GetSeatPayment().GetStatus() != "paid" || receiptResponse.GetSeatPayment().GetCheckNo() != "1042" {
+			t.Fatalf("recorded payment was %v", receiptResponse.GetSeatPayment())
+		}
+
+		_, err = postReceipt(operator, &types.PostGroupSeatPaymentReceiptRequest{
+			Code:          checkPayment.GetCode(),
+			PaymentMethod: "ach",
+			Reference:     "1043",
+			Amount:        checkPayment.GetAmount(),
+		})
+		if err == nil {
+			t.Fatal("recorded a second receipt for a paid payment")
+		}
+
+		html, err := getReceipt(checkPayment.GetCode())
+		if err != nil {
+			t.Fatalf("admin get receipt error %v", err)
+		}
+
+		if !strings.Contains(html, "1042") || !strings.Contains(html, "<strong>Payment Method:</strong> Check") {
+			t.Fatalf("receipt did not show the recorded check, %s", html)
+		}
+
+		err = operator.Logout()
+		if err != nil {
+			t.Fatalf("could not log out operator, %v", err)
+		}
+	})
+
+	t.Run("payment provider webhooks must be signed", func(tt *testing.T) {
+		payment := postSeats()
+
+		err := testutil.PostFakePaymentWebhook(webhookBody(payment.GetCode(), "paid", "txn_stale", payment.GetAmount()), time.Now().Add(-time.Hour))
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Fatalf("stale webhook was not 401, %v", err)
+		}
+
+		if getPayment(payment.GetCode()).GetStatus() != "pending" {
+			t.Fatal("unsigned webhook changed the payment")
+		}
+	})
+
+	t.Run("payment provider webhooks settle payments as failed or paid", func(tt *testing.T) {
+		failedPayment := postSeats()
+
+		err := testutil.PostFakePaymentWebhook(webhookBody(failedPayment.GetCode(), "failed", "txn_declined", 0), time.Now())
+		if err != nil {
+			t.Fatalf("failed webhook error %v", err)
+		}
+
+		if status := getPayment(failedPayment.GetCode()).GetStatus(); status != "failed" {
+			t.Fatalf("failed webhook left payment %s", status)
+		}
+
+		paidPayment := postSeats()
+		paidBody := webhookBody(paidPayment.GetCode(), "paid", "txn_settled", paidPayment.GetAmount())
+
+		err = testutil.PostFakePaymentWebhook(paidBody, time.Now())
+		if err != nil {
+			t.Fatalf("paid webhook error %v", err)
+		}
+
+		// Redeliveries are acknowledged without changing anything
+		err = testutil.PostFakePaymentWebhook(paidBody, time.Now())
+		if err != nil {
+			t.Fatalf("redelivered webhook error %v", err)
+		}
+
+		paid := getPayment(paidPayment.GetCode())
+		if paid.GetStatus() != "paid" || paid.GetPaidOn() == "" {
+			t.Fatalf("paid webhook left payment %v", paid)
+		}
+
+		html, err := getReceipt(paidPayment.GetCode())
+		if err != nil {
+			t.Fatalf("admin get receipt error %v", err)
+		}
+
+		if !strings.Contains(html, "txn_settled") || !strings.Contains(html, "Online (fake)") {
+			t.Fatalf("receipt did not show the provider payment, %s", html)
+		}
+	})
+}
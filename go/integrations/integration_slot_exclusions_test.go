@@ -0,0 +1,142 @@
+package main_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/testutil"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func testIntegrationSlotExclusions(t *testing.T) {
+	admin := testutil.IntegrationTest.TestUsers[0]
+	staff1 := testutil.IntegrationTest.TestUsers[1]
+	staff2 := testutil.IntegrationTest.TestUsers[2]
+	member1 := testutil.IntegrationTest.TestUsers[4]
+
+	// staff1 owns the slot of member1's first quote, see bookings
+	quote := member1.Quotes[0]
+
+	postExclusions := func(user *testutil.TestUsersStruct, request *types.PostScheduleBracketSlotExclusionsRequest) (*types.PostScheduleBracketSlotExclusionsResponse, error) {
+		requestBytes, err := protojson.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+
+		exclusionsResponse := &types.PostScheduleBracketSlotExclusionsResponse{}
+		err = user.DoHandler(http.MethodPost, "/api/v1/schedules/exclusions", requestBytes, nil, exclusionsResponse)
+		return exclusionsResponse, err
+	}
+
+	hasStub := func() bool {
+		stubsResponse := &types.GetGroupUserScheduleStubsResponse{}
+		err := admin.DoHandler(http.MethodGet, "/api/v1/group/user_schedules_stubs", nil, nil, stubsResponse)
+		if err != nil {
+			t.Fatalf("admin get stubs error %v", err)
+		}
+
+		for _, stub := range stubsResponse.GetGroupUserScheduleStubs() {
+			if stub.GetQuoteId() == quote.GetId() {
+				return true
+			}
+		}
+		return false
+	}
+
+	slotRequest := &types.PostScheduleBracketSlotExclusionsRequest{
+		ScheduleBracketSlotId: quote.GetScheduleBracketSlotId(),
+		StartDate:             quote.GetSlotDate(),
+	}
+
+	t.Run("APP_GROUP_SCHEDULES is required to exclude slots", func(tt *testing.T) {
+		_, err := postExclusions(member1, slotRequest)
+		if err == nil || !strings.Contains(err.Error(), "403") {
+			t.Fatalf("member exclusion was not 403, %v", err)
+		}
+	})
+
+	t.Run("staff can't exclude another user's slots", func(tt *testing.T) {
+		exclusionsResponse, err := postExclusions(staff2, slotRequest)
+		if err != nil {
+			t.Fatalf("staff2 post exclusion error %v", err)
+		}
+
+		if len(exclusionsResponse.GetExclusions()) != 0 {
+			t.Fatalf("staff2 excluded %d slots of another user", len(exclusionsResponse.GetExclusions()))
+		}
+	})
+
+	var exclusionId string
+
+	t.Run("staff can exclude a slot on a date, turning its quotes into stubs", func(tt *testing.T) {
+		if hasStub() {
+			t.Fatal("quote was a stub before its slot was excluded")
+		}
+
+		exclusionsResponse, err := postExclusions(staff1, slotRequest)
+		if err != nil {
+			t.Fatalf("staff1 post exclusion error %v", err)
+		}
+
+		if len(exclusionsResponse.GetExclusions()) != 1 {
+			t.Fatalf("expected %d exclusions, received %d", 1, len(exclusionsResponse.GetExclusions()))
+		}
+
+		exclusionId = exclusionsResponse.GetExclusions()[0].GetId()
+
+		dateSlots, err := admin.GetDateSlots(testutil.IntegrationTest.MasterSchedule.Id)
+		if err != nil {
+			t.Fatalf("date slot retrieval error: %v", err)
+		}
+
+		for _, dateSlot := range dateSlots {
+			if dateSlot.GetScheduleBracketSlotId() == quote.GetScheduleBracketSlotId() && dateSlot.GetStartDate() == quote.GetSlotDate() {
+				t.Fatal("excluded slot was still available")
+			}
+		}
+
+		if !hasStub() {
+			t.Fatal("quote on the excluded slot was not a stub")
+		}
+	})
+
+	t.Run("excluding the same slot again is ignored", func(tt *testing.T) {
+		exclusionsResponse, err := postExclusions(staff1, slotRequest)
+		if err != nil {
+			t.Fatalf("staff1 repeat exclusion error %v", err)
+		}
+
+		if len(exclusionsResponse.GetExclusions()) != 0 {
+			t.Fatalf("slot was excluded twice")
+		}
+	})
+
+	t.Run("staff can list and delete exclusions", func(tt *testing.T) {
+		getExclusionsResponse := &types.GetScheduleBracketSlotExclusionsResponse{}
+		err := staff1.DoHandler(http.MethodGet, "/api/v1/schedules/"+testutil.IntegrationTest.UserSchedule.Id+"/exclusions", nil, nil, getExclusionsResponse)
+		if err != nil {
+			t.Fatalf("staff1 get exclusions error %v", err)
+		}
+
+		var found bool
+		for _, exclusion := range getExclusionsResponse.GetExclusions() {
+			if exclusion.GetId() == exclusionId {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("exclusion %s was not listed", exclusionId)
+		}
+
+		err = staff1.DoHandler(http.MethodDelete, "/api/v1/schedules/exclusions/"+exclusionId, nil, nil, &types.DeleteScheduleBracketSlotExclusionsResponse{})
+		if err != nil {
+			t.Fatalf("staff1 delete exclusion error %v", err)
+		}
+
+		if hasStub() {
+			t.Fatal("quote was still a stub after the exclusion was deleted")
+		}
+	})
+}
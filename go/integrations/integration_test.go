@@ -35,6 +35,7 @@ func TestIntegrations(t *testing.T) {
 	defer testutil.TestPanic(t)
 
 	testIntegrationUser(t)
+	testIntegrationVault(t)
 	testIntegrationGroup(t)
 	testIntegrationRoles(t)
 	testIntegrationService(t)
@@ -44,6 +45,16 @@ func TestIntegrations(t *testing.T) {
 	testIntegrationPromoteUser(t)
 	testIntegrationUserSchedule(t)
 	testIntegrationQuotes(t)
+	testIntegrationSlotExclusions(t)
 	testIntegrationBookings(t)
+	testIntegrationQuoteSeries(t)
+	testIntegrationBookingCalendar(t)
+	testIntegrationBookingTranscripts(t)
+	testIntegrationBookingInvoices(t)
+	testIntegrationUserNotes(t)
+	testIntegrationKiosk(t)
+	testIntegrationGroupInvites(t)
+	testIntegrationSeatPayments(t)
+	testIntegrationGroupWebhooks(t)
 	testIntegrationLogout(t)
 }
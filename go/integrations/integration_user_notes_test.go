@@ -0,0 +1,230 @@
+package main_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/testutil"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func testIntegrationUserNotes(t *testing.T) {
+	admin := testutil.IntegrationTest.TestUsers[0]
+	staff1 := testutil.IntegrationTest.TestUsers[1]
+	staff2 := testutil.IntegrationTest.TestUsers[2]
+	member1 := testutil.IntegrationTest.TestUsers[4]
+
+	member1Profile, err := member1.GetProfileDetails()
+	if err != nil {
+		t.Fatalf("could not get member profile for notes %v", err)
+	}
+	member1Sub := member1Profile.GetSub()
+
+	noteVersion := func(text string) *types.IProtoUserNoteVersion {
+		form, _ := structpb.NewValue(map[string]any{"rows": map[string]any{}})
+		submission, _ := structpb.NewValue(map[string]any{"text": text})
+		return &types.IProtoUserNoteVersion{
+			Form:       form,
+			Submission: submission,
+		}
+	}
+
+	var userNoteId string
+
+	t.Run("APP_GROUP_SCHEDULES is required to write a note", func(tt *testing.T) {
+		postUserNoteBytes, err := protojson.Marshal(&types.PostUserNoteRequest{
+			UserNote: &types.IProtoUserNote{
+				Name:    "member note",
+				UserSub: member1Sub,
+				Version: noteVersion("written by a member"),
+			},
+		})
+		if err != nil {
+			t.Fatalf("error marshalling post user note request %v", err)
+		}
+
+		err = member1.DoHandler(http.MethodPost, "/api/v1/user_notes", postUserNoteBytes, nil, nil)
+		if err == nil || !strings.Contains(err.Error(), "403") {
+			t.Fatalf("member post user note was not 403, %v", err)
+		}
+	})
+
+	t.Run("staff can write a note about a group user", func(tt *testing.T) {
+		postUserNoteBytes, err := protojson.Marshal(&types.PostUserNoteRequest{
+			UserNote: &types.IProtoUserNote{
+				Name:    "intake note",
+				UserSub: member1Sub,
+				Version: noteVersion("first visit"),
+			},
+		})
+		if err != nil {
+			t.Fatalf("error marshalling post user note request %v", err)
+		}
+
+		postUserNoteResponse := &types.PostUserNoteResponse{}
+		err = staff1.DoHandler(http.MethodPost, "/api/v1/user_notes", postUserNoteBytes, nil, postUserNoteResponse)
+		if err != nil {
+			t.Fatalf("staff post user note error %v", err)
+		}
+
+		if !util.IsUUID(postUserNoteResponse.GetId()) {
+			t.Fatalf("user note id is not a uuid %s", postUserNoteResponse.GetId())
+		}
+
+		userNoteId = postUserNoteResponse.GetId()
+	})
+
+	t.Run("staff can add a version to their note", func(tt *testing.T) {
+		postUserNoteVersionBytes, err := protojson.Marshal(&types.PostUserNoteVersionRequest{
+			UserNoteId: userNoteId,
+			Name:       "intake note updated",
+			Version:    noteVersion("second visit"),
+		})
+		if err != nil {
+			t.Fatalf("error marshalling post user note version request %v", err)
+		}
+
+		err = staff1.DoHandler(http.MethodPost, "/api/v1/user_notes/"+userNoteId+"/versions", postUserNoteVersionBytes, nil, nil)
+		if err != nil {
+			t.Fatalf("staff post user note version error %v", err)
+		}
+
+		getUserNoteByIdResponse := &types.GetUserNoteByIdResponse{}
+		err = staff1.DoHandler(http.MethodGet, "/api/v1/user_notes/"+userNoteId, nil, nil, getUserNoteByIdResponse)
+		if err != nil {
+			t.Fatalf("staff get user note by id error %v", err)
+		}
+
+		if getUserNoteByIdResponse.GetUserNote().GetName() != "intake note updated" {
+			t.Fatalf("user note name was not updated, got %s", getUserNoteByIdResponse.GetUserNote().GetName())
+		}
+
+		if len(getUserNoteByIdResponse.GetVersions()) != 2 {
+			t.Fatalf("expected %d user note versions, received %d", 2, len(getUserNoteByIdResponse.GetVersions()))
+		}
+	})
+
+	t.Run("staff can list notes about a user", func(tt *testing.T) {
+		getUserNotesResponse := &types.GetUserNotesResponse{}
+		err := staff1.DoHandler(http.MethodGet, "/api/v1/user_notes/users/"+member1Sub, nil, nil, getUserNotesResponse)
+		if err != nil {
+			t.Fatalf("staff get user notes error %v", err)
+		}
+
+		if len(getUserNotesResponse.GetUserNotes()) != 1 {
+			t.Fatalf("expected %d user notes, received %d", 1, len(getUserNotesResponse.GetUserNotes()))
+		}
+	})
+
+	t.Run("other staff cannot see a note they did not write", func(tt *testing.T) {
+		getUserNotesResponse := &types.GetUserNotesResponse{}
+		err := staff2.DoHandler(http.MethodGet, "/api/v1/user_notes/users/"+member1Sub, nil, nil, getUserNotesResponse)
+		if err != nil {
+			t.Fatalf("other staff get user notes error %v", err)
+		}
+
+		if len(getUserNotesResponse.GetUserNotes()) != 0 {
+			t.Fatalf("other staff could see %d private user notes", len(getUserNotesResponse.GetUserNotes()))
+		}
+
+		err = staff2.DoHandler(http.MethodGet, "/api/v1/user_notes/"+userNoteId, nil, nil, nil)
+		if err == nil {
+			t.Fatal("other staff could get a private user note by id")
+		}
+	})
+
+	t.Run("group admin can see notes written by staff", func(tt *testing.T) {
+		getUserNotesResponse := &types.GetUserNotesResponse{}
+		err := admin.DoHandler(http.MethodGet, "/api/v1/user_notes/users/"+member1Sub, nil, nil, getUserNotesResponse)
+		if err != nil {
+			t.Fatalf("admin get user notes error %v", err)
+		}
+
+		if len(getUserNotesResponse.GetUserNotes()) != 1 {
+			t.Fatalf("expected admin to see %d user notes, received %d", 1, len(getUserNotesResponse.GetUserNotes()))
+		}
+	})
+
+	t.Run("other staff cannot change a note they did not write", func(tt *testing.T) {
+		patchUserNoteBytes, err := protojson.Marshal(&types.PatchUserNoteRequest{
+			Id:   userNoteId,
+			Name: "renamed by other staff",
+		})
+		if err != nil {
+			t.Fatalf("error marshalling patch user note request %v", err)
+		}
+
+		err = staff2.DoHandler(http.MethodPatch, "/api/v1/user_notes/"+userNoteId, patchUserNoteBytes, nil, nil)
+		if err == nil {
+			t.Fatal("other staff could patch a private user note")
+		}
+
+		err = staff2.DoHandler(http.MethodPatch, "/api/v1/user_notes/"+userNoteId+"/disable", nil, nil, nil)
+		if err == nil {
+			t.Fatal("other staff could disable a private user note")
+		}
+
+		err = staff2.DoHandler(http.MethodDelete, "/api/v1/user_notes/"+userNoteId, nil, nil, nil)
+		if err == nil {
+			t.Fatal("other staff could delete a private user note")
+		}
+
+		getUserNoteByIdResponse := &types.GetUserNoteByIdResponse{}
+		err = staff1.DoHandler(http.MethodGet, "/api/v1/user_notes/"+userNoteId, nil, nil, getUserNoteByIdResponse)
+		if err != nil {
+			t.Fatalf("staff get user note after other staff changes error %v", err)
+		}
+
+		if getUserNoteByIdResponse.GetUserNote().GetName() != "intake note updated" {
+			t.Fatalf("user note was changed by other staff, got %s", getUserNoteByIdResponse.GetUserNote().GetName())
+		}
+	})
+
+	t.Run("missing notes cannot be changed", func(tt *testing.T) {
+		missingNoteId := "00000000-0000-0000-0000-000000000000"
+
+		patchUserNoteBytes, err := protojson.Marshal(&types.PatchUserNoteRequest{
+			Id:   missingNoteId,
+			Name: "missing note",
+		})
+		if err != nil {
+			t.Fatalf("error marshalling patch user note request %v", err)
+		}
+
+		err = staff1.DoHandler(http.MethodPatch, "/api/v1/user_notes/"+missingNoteId, patchUserNoteBytes, nil, nil)
+		if err == nil {
+			t.Fatal("patching a missing user note did not error")
+		}
+
+		err = staff1.DoHandler(http.MethodPatch, "/api/v1/user_notes/"+missingNoteId+"/disable", nil, nil, nil)
+		if err == nil {
+			t.Fatal("disabling a missing user note did not error")
+		}
+
+		err = staff1.DoHandler(http.MethodDelete, "/api/v1/user_notes/"+missingNoteId, nil, nil, nil)
+		if err == nil {
+			t.Fatal("deleting a missing user note did not error")
+		}
+	})
+
+	t.Run("disabled notes are not listed", func(tt *testing.T) {
+		err := staff1.DoHandler(http.MethodPatch, "/api/v1/user_notes/"+userNoteId+"/disable", nil, nil, nil)
+		if err != nil {
+			t.Fatalf("staff disable user note error %v", err)
+		}
+
+		getUserNotesResponse := &types.GetUserNotesResponse{}
+		err = staff1.DoHandler(http.MethodGet, "/api/v1/user_notes/users/"+member1Sub, nil, nil, getUserNotesResponse)
+		if err != nil {
+			t.Fatalf("staff get user notes after disable error %v", err)
+		}
+
+		if len(getUserNotesResponse.GetUserNotes()) != 0 {
+			t.Fatalf("disabled user note was still listed")
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package main_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/testutil"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+)
+
+func testIntegrationVault(t *testing.T) {
+	admin := testutil.IntegrationTest.TestUsers[0]
+
+	t.Run("vault headers can't be replayed", func(tt *testing.T) {
+		err := admin.ReplayHandler(http.MethodGet, "/api/v1/profile/details", nil, nil, &types.GetUserProfileDetailsResponse{})
+		if err == nil || !strings.Contains(err.Error(), "409") {
+			t.Fatalf("replayed vault header was not 409, %v", err)
+		}
+	})
+
+	t.Run("vault bodies can't be replayed", func(tt *testing.T) {
+		err := admin.ReplayHandler(http.MethodPatch, "/api/v1/profile/activate", []byte("{}"), nil, &types.ActivateProfileResponse{})
+		if err == nil || !strings.Contains(err.Error(), "409") {
+			t.Fatalf("replayed vault body was not 409, %v", err)
+		}
+	})
+
+	t.Run("fresh encryptions of the same request are accepted", func(tt *testing.T) {
+		for range 2 {
+			_, err := admin.GetProfileDetails()
+			if err != nil {
+				t.Fatalf("repeated request was rejected, %v", err)
+			}
+		}
+	})
+}
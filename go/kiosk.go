@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/api"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const kioskRefreshDebounce = 10 * time.Second
+
+// Keeps dbview_schema.kiosk_schedule current. Schedule and bracket triggers notify on commit,
+// and bursts of changes are collapsed into a single refresh after kioskRefreshDebounce.
+func setupKioskRefresh(ctx context.Context, a *api.API) {
+	dbClient := a.Handlers.Database.DatabaseClient
+	changed, stopped := listenForChanges(ctx, "kiosk schedule", dbClient.ListenKioskScheduleChanges)
+	defer func() { <-stopped }()
+
+	var refresh <-chan time.Time
+	for {
+		select {
+		case <-changed:
+			if refresh == nil {
+				refresh = time.After(kioskRefreshDebounce)
+			}
+		case <-refresh:
+			refresh = nil
+			if err := dbClient.RefreshKioskSchedule(ctx); err != nil {
+				util.ErrorLog.Printf("could not refresh kiosk schedule, err: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
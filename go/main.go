@@ -1,7 +1,12 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
+	"net/http"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/keybittech/awayto-v3/go/pkg/api"
@@ -13,7 +18,8 @@ import (
 func main() {
 	util.ParseEnv()
 
-	crypto.InitVault()
+	vaultKeyStore := newVaultKeyStore()
+	crypto.InitVault(vaultKeyStore)
 
 	util.DebugLog.Printf(
 		"started with flags -httpPort=%d -httpsPort=%d -unixPath=%s -rateLimit=%d -rateLimitBurst=%d",
@@ -49,6 +55,9 @@ func main() {
 	server.InitProtoHandlers()
 	server.InitAuthProxy()
 	server.InitSockServer()
+	server.InitKiosk()
+	server.InitCalendar()
+	server.InitPaymentWebhooks()
 	server.InitStatic()
 
 	rateLimiter := api.NewRateLimit("api", rate.Limit(util.E_RATE_LIMIT), util.E_RATE_LIMIT_BURST, time.Duration(5*time.Minute))
@@ -60,8 +69,19 @@ func main() {
 	handler = server.AccessRequestMiddleware(handler)
 	server.Server.Handler = handler
 
-	stopChan := make(chan struct{}, 1)
-	go setupGc(server, stopChan)
+	server.RunWorker(func(ctx context.Context) { setupGc(ctx, server) })
+
+	server.RunWorker(func(ctx context.Context) { setupKioskRefresh(ctx, server) })
+
+	server.RunWorker(func(ctx context.Context) { setupIdentityReconciler(ctx, server) })
+
+	server.RunWorker(func(ctx context.Context) { setupNotificationScheduler(ctx, server) })
+
+	server.RunWorker(func(ctx context.Context) { setupGroupWebhookDelivery(ctx, server) })
+
+	server.RunWorker(func(ctx context.Context) { setupCallLogSweep(ctx, server) })
+
+	server.RunWorker(func(ctx context.Context) { setupVaultKeyRotation(ctx, vaultKeyStore) })
 
 	// go func() {
 	// 	ticker := time.NewTicker(time.Duration(5 * time.Second))
@@ -86,16 +106,26 @@ func main() {
 	// 	}
 	// }()
 
-	defer func() {
-		server.Close()
-	}()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	util.DebugLog.Printf("Listening on %d ", util.E_GO_HTTPS_PORT)
 	util.DebugLog.Printf("Cert Locations: %s %s", util.E_CERT_LOC, util.E_CERT_KEY_LOC)
 
-	err := server.Server.ListenAndServeTLS(util.E_CERT_LOC, util.E_CERT_KEY_LOC)
-	if err != nil {
-		util.ErrorLog.Println("LISTEN AND SERVE ERROR: ", err.Error())
-		return
-	}
+	go func() {
+		err := server.Server.ListenAndServeTLS(util.E_CERT_LOC, util.E_CERT_KEY_LOC)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			util.ErrorLog.Println("LISTEN AND SERVE ERROR: ", err.Error())
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+
+	util.DebugLog.Println("shutting down, draining connections")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), api.API_SHUTDOWN_TIMEOUT)
+	defer cancel()
+
+	server.Shutdown(shutdownCtx)
 }
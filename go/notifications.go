@@ -0,0 +1,16 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/api"
+)
+
+const notificationPollEvery = time.Minute
+
+// Delivers queued notifications. New jobs are sent as soon as their insert commits, and scheduled
+// reminders and failed deliveries are picked up every notificationPollEvery once they're due.
+func setupNotificationScheduler(ctx context.Context, a *api.API) {
+	runQueueWorker(ctx, "notifications", notificationPollEvery, a.Handlers.Database.DatabaseClient.ListenNotificationJobs, a.Handlers.ProcessNotifications)
+}
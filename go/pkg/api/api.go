@@ -1,11 +1,14 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
 	"github.com/keybittech/awayto-v3/go/pkg/handlers"
 	"github.com/keybittech/awayto-v3/go/pkg/util"
 )
@@ -15,6 +18,7 @@ const (
 	API_READ_TIMEOUT        = 5 * time.Second
 	API_WRITE_TIMEOUT       = 15 * time.Second
 	API_IDLE_TIMEOUT        = 120 * time.Second
+	API_SHUTDOWN_TIMEOUT    = 30 * time.Second
 )
 
 type API struct {
@@ -24,10 +28,24 @@ type API struct {
 	Handlers  *handlers.Handlers
 	Unix      net.Listener
 	CloseChan chan struct{}
+
+	// websocket handlers are hijacked from the server, so they are drained separately
+	sockMu       sync.Mutex
+	sockDraining bool
+	sockDrain    chan struct{}
+	sockConns    sync.WaitGroup
+	callLogs     *exchangeCallLogs
+
+	unixDone chan struct{}
+
+	// background workers share a context ended by Shutdown, which waits for them before closing clients
+	workerCtx   context.Context
+	stopWorkers context.CancelFunc
+	workers     sync.WaitGroup
 }
 
 func NewAPI(httpsPort int) *API {
-	h := handlers.NewHandlers()
+	h := handlers.NewHandlers(clients.InitKeycloak())
 
 	// go func() {
 	// 	ticker := time.NewTicker(time.Duration(5 * time.Second))
@@ -39,6 +57,8 @@ func NewAPI(httpsPort int) *API {
 	// 	}
 	// }()
 
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+
 	return &API{
 		Server: &http.Server{
 			Addr:              fmt.Sprintf("[::]:%d", httpsPort),
@@ -51,17 +71,134 @@ func NewAPI(httpsPort int) *API {
 		Handlers:  h,
 		Cache:     h.Cache,
 		CloseChan: make(chan struct{}),
+		sockDrain: make(chan struct{}),
+		callLogs:  newExchangeCallLogs(),
+		unixDone:  make(chan struct{}),
+
+		workerCtx:   workerCtx,
+		stopWorkers: stopWorkers,
 	}
 }
 
-func (a *API) Close() {
+// RunWorker runs worker in the background until Shutdown ends its context. Workers must return
+// once ctx is done, as the clients they use are closed after they have all returned.
+func (a *API) RunWorker(worker func(ctx context.Context)) {
+	a.workers.Add(1)
+	go func() {
+		defer a.workers.Done()
+		worker(a.workerCtx)
+	}()
+}
+
+// Shutdown stops accepting connections and lets in flight work finish before closing clients.
+// Work still running when ctx ends is abandoned.
+func (a *API) Shutdown(ctx context.Context) {
 	close(a.CloseChan)
-	a.Handlers.Database.DatabaseClient.Close()
+	a.stopWorkers()
+
+	serverShutdown := make(chan error, 1)
+	go func() {
+		serverShutdown <- a.Server.Shutdown(ctx)
+	}()
+
+	if a.Redirect != nil {
+		if err := a.Redirect.Shutdown(ctx); err != nil {
+			util.ErrorLog.Printf("could not shut down redirect server, err: %v", err)
+		}
+	}
+
+	a.drainSockets(ctx)
+
+	if err := <-serverShutdown; err != nil {
+		util.ErrorLog.Printf("could not shut down primary server, err: %v", err)
+	}
+
+	// The unix server handles one backchannel request at a time, it exits after the current one
+	if a.Unix != nil {
+		if err := a.Unix.Close(); err != nil {
+			util.ErrorLog.Printf("could not close unix listener, err: %v", err)
+		}
+
+		select {
+		case <-a.unixDone:
+		case <-ctx.Done():
+			util.ErrorLog.Println("unix backchannel did not drain before shutdown deadline")
+		}
+	}
+
+	a.waitWorkers(ctx)
+
+	a.closeClients()
+	clients.GetGlobalWorkerPool().Stop()
+
+	util.DebugLog.Println("shutdown complete")
+	util.FlushLogs()
+}
+
+// trackSocket registers a websocket handler to be drained, false when already draining
+func (a *API) trackSocket() bool {
+	a.sockMu.Lock()
+	defer a.sockMu.Unlock()
+
+	if a.sockDraining {
+		return false
+	}
+
+	a.sockConns.Add(1)
+	return true
+}
+
+// drainSockets signals websocket handlers to close with a reconnect hint and waits for their teardown
+func (a *API) drainSockets(ctx context.Context) {
+	a.sockMu.Lock()
+	if !a.sockDraining {
+		a.sockDraining = true
+		close(a.sockDrain)
+	}
+	a.sockMu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		a.sockConns.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		util.ErrorLog.Println("websockets did not drain before shutdown deadline")
+	}
+}
+
+// waitWorkers waits for the workers started by RunWorker, which stop when their context ends
+func (a *API) waitWorkers(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		a.workers.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		util.ErrorLog.Println("background workers did not stop before shutdown deadline")
+	}
+}
+
+func (a *API) closeClients() {
 	a.Handlers.Socket.Close()
-	a.Handlers.Keycloak.Close()
+	a.Handlers.Identity.Close()
 	if err := a.Handlers.Redis.RedisClient.Close(); err != nil {
 		util.ErrorLog.Printf("could not close redis client, err: %v", err)
 	}
+	a.Handlers.Database.DatabaseClient.Close()
+}
+
+func (a *API) Close() {
+	close(a.CloseChan)
+	a.stopWorkers()
+	a.workers.Wait()
+	a.closeClients()
 
 	if err := a.Unix.Close(); err != nil {
 		util.ErrorLog.Printf("could not close unix listener, err: %v", err)
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/keybittech/awayto-v3/go/pkg/handlers"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+// Calendar apps subscribe to a plain url and can't send headers, so the feed token is the file name
+func (a *API) InitCalendar() {
+	a.Server.Handler.(*http.ServeMux).HandleFunc("GET /api/ics/bookings/{file}", func(w http.ResponseWriter, req *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				util.ErrorLog.Println(util.ErrCheck(fmt.Errorf("calendar feed panic: %v", p)))
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+
+		token, ok := strings.CutSuffix(req.PathValue("file"), ".ics")
+		if !ok || token == "" {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+
+		calendarBytes, err := a.Handlers.GetBookingsCalendarByToken(req.Context(), token)
+		if err != nil {
+			if errors.Is(err, handlers.ErrInvalidCalendarToken) {
+				http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+				return
+			}
+			util.ErrorLog.Println(util.ErrCheck(err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", util.CalendarContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(calendarBytes)))
+		w.Write(calendarBytes)
+	})
+}
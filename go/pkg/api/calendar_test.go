@@ -0,0 +1,19 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestAPI_InitCalendar(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *API
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.a.InitCalendar()
+		})
+	}
+}
@@ -24,10 +24,15 @@ func TxExecutor(ctx context.Context, w http.ResponseWriter, req *http.Request, s
 		Req:     req,
 		Session: session,
 		Tx:      poolTx,
+		TxHooks: &handlers.TxHooks{},
 	}
 
 	return reqInfo, func(handlerErr error) error {
-		defer poolTx.Rollback(ctx)
+		var committed bool
+		defer func() {
+			poolTx.Rollback(ctx)
+			reqInfo.TxHooks.Run(committed)
+		}()
 
 		// if an err occurred in handler, immediately return it + rollback
 		// don't ErrCheck as the handlers should be doing so
@@ -40,6 +45,8 @@ func TxExecutor(ctx context.Context, w http.ResponseWriter, req *http.Request, s
 			return util.ErrCheck(err)
 		}
 
+		committed = true
+
 		return nil
 	}
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -27,7 +28,7 @@ func (a *API) RedirectHTTP(httpPort int) {
 	util.DebugLog.Println("listening on ", strconv.Itoa(httpPort))
 
 	err := a.Redirect.ListenAndServe()
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		util.ErrorLog.Println(util.ErrCheck(err))
 		return
 	}
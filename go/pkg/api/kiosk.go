@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/keybittech/awayto-v3/go/pkg/handlers"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Wall displays use a group kiosk token as a bearer token instead of a user session,
+// and can only read the group's kiosk schedule
+func (a *API) InitKiosk() {
+	a.Server.Handler.(*http.ServeMux).HandleFunc("GET /api/kiosk/gs/{file}", func(w http.ResponseWriter, req *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				util.ErrorLog.Println(util.ErrCheck(fmt.Errorf("kiosk schedule panic: %v", p)))
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+
+		groupName := strings.TrimSuffix(req.PathValue("file"), ".json")
+
+		token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if groupName == "" || !ok || token == "" {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		kioskSchedules, err := a.Handlers.GetKioskSchedulesByToken(req.Context(), groupName, token)
+		if err != nil {
+			if errors.Is(err, handlers.ErrInvalidKioskToken) {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			util.ErrorLog.Println(util.ErrCheck(err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		kioskBytes, err := protojson.Marshal(kioskSchedules)
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", strconv.Itoa(len(kioskBytes)))
+		w.Write(kioskBytes)
+	})
+}
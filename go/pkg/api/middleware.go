@@ -6,8 +6,10 @@ import (
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"io"
 	"log"
+	"math"
 	"net"
 	"net/http"
 	"strconv"
@@ -103,6 +105,8 @@ type ctxKey string
 
 const CtxVaultKey ctxKey = "vaultKeyProp"
 
+var ErrVaultReplay = errors.New("vault payload was already used")
+
 type VaultResponseWriter struct {
 	http.ResponseWriter
 	buf         *bytes.Buffer
@@ -118,9 +122,22 @@ func (vrw *VaultResponseWriter) Write(b []byte) (int, error) {
 	return vrw.buf.Write(b)
 }
 
+// Public routes authenticate with their own credential, like a token or a provider signature,
+// and are called by clients which have no session to share a vault secret with
+var publicApiPrefixes = []string{"/api/kiosk/", "/api/webhooks/", "/api/ics/"}
+
+func isPublicApiPath(path string) bool {
+	for _, prefix := range publicApiPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *API) VaultMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		if !strings.HasPrefix(req.URL.Path, "/api") || strings.Contains(req.URL.Path, "/vault/key") {
+		if !strings.HasPrefix(req.URL.Path, "/api") || strings.Contains(req.URL.Path, "/vault/key") || isPublicApiPath(req.URL.Path) {
 			next.ServeHTTP(w, req)
 			return
 		}
@@ -132,7 +149,10 @@ func (a *API) VaultMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		var sharedSecret []byte
+		var sharedSecret, ciphertext []byte
+
+		// Clients send the id of the key they encrypted with, so a rotated key can still be used
+		vaultKeyId := req.Header.Get("X-Awayto-Vault-Key-Id")
 
 		ct := req.Header.Get("Content-Type")
 
@@ -142,9 +162,10 @@ func (a *API) VaultMiddleware(next http.Handler) http.Handler {
 			req.Body.Close()
 			if readErr == nil && len(reqBytes) > 0 {
 				var plaintext []byte
-				plaintext, sharedSecret, err = crypto.ServerDecrypt(crypto.VaultKey, reqBytes, sessionId)
+				plaintext, sharedSecret, err = crypto.ServerDecrypt(crypto.VaultKey, vaultKeyId, reqBytes, sessionId)
 
 				if err == nil {
+					ciphertext = reqBytes
 					req.Body = io.NopCloser(bytes.NewBuffer(plaintext))
 					req.Header.Set("Content-Type", "application/json")
 					if oct := req.Header.Get("X-Original-Content-Type"); oct != "" {
@@ -164,9 +185,10 @@ func (a *API) VaultMiddleware(next http.Handler) http.Handler {
 			if vaultHeader := req.Header.Get("X-Awayto-Vault"); vaultHeader != "" {
 				blob, b64Err := base64.StdEncoding.DecodeString(vaultHeader)
 				if b64Err == nil {
-					_, ss, dErr := crypto.ServerDecrypt(crypto.VaultKey, blob, sessionId)
+					_, ss, dErr := crypto.ServerDecrypt(crypto.VaultKey, vaultKeyId, blob, sessionId)
 					if dErr == nil {
 						sharedSecret = ss
+						ciphertext = blob
 					} else {
 						err = util.ErrCheck(dErr)
 					}
@@ -185,6 +207,20 @@ func (a *API) VaultMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// A ciphertext is valid until its timestamp ages out, so each one may only be used once
+		claimed, err := a.Handlers.Redis.ClaimVaultNonce(req.Context(), sessionId, ciphertext, crypto.ReplayCacheDuration)
+		if err != nil {
+			util.ErrorLog.Printf("VaultMiddleware: %v", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if !claimed {
+			util.ErrorLog.Printf("VaultMiddleware: %v", util.ErrCheck(ErrVaultReplay))
+			http.Error(w, ErrVaultReplay.Error(), http.StatusConflict)
+			return
+		}
+
 		ctx := context.WithValue(req.Context(), CtxVaultKey, sharedSecret)
 		req = req.WithContext(ctx)
 
@@ -268,14 +304,28 @@ func (a *API) ValidateSessionMiddleware() func(next SessionHandler) http.Handler
 
 func (a *API) SiteRoleCheckMiddleware(opts *util.HandlerOptions) func(SessionHandler) SessionHandler {
 	siteRole := opts.Unpack().SiteRole
+	superAdmin := opts.Unpack().SuperAdmin
 	unrestricted := int32(types.SiteRoles_UNRESTRICTED)
 	return func(next SessionHandler) SessionHandler {
-		if siteRole == unrestricted {
+		if siteRole == unrestricted && !superAdmin {
 			return func(w http.ResponseWriter, req *http.Request, session *types.ConcurrentUserSession) {
 				next(w, req, session)
 			}
 		}
 
+		// Super admin endpoints are outside of any group, so the realm role is checked instead of role bits
+		if superAdmin {
+			return func(w http.ResponseWriter, req *http.Request, session *types.ConcurrentUserSession) {
+				if !session.GetSuperAdmin() {
+					util.WriteAuthRequest(req, session.GetUserSub(), util.SuperAdminRole)
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+
+				next(w, req, session)
+			}
+		}
+
 		return func(w http.ResponseWriter, req *http.Request, session *types.ConcurrentUserSession) {
 			if session.GetRoleBits()&siteRole == 0 {
 				util.WriteAuthRequest(req, session.GetUserSub(), string(siteRole))
@@ -288,6 +338,45 @@ func (a *API) SiteRoleCheckMiddleware(opts *util.HandlerOptions) func(SessionHan
 	}
 }
 
+func (a *API) ThrottleMiddleware(opts *util.HandlerOptions) func(SessionHandler) SessionHandler {
+	throttle := opts.Unpack().Throttle
+	methodName := opts.ServiceMethodName
+	return func(next SessionHandler) SessionHandler {
+		if throttle == 0 {
+			return next
+		}
+
+		throttleDuration := time.Duration(throttle) * time.Second
+
+		return func(w http.ResponseWriter, req *http.Request, session *types.ConcurrentUserSession) {
+			remaining, err := a.Handlers.Redis.Throttle(req.Context(), session.GetUserSub(), methodName, throttleDuration)
+			if err != nil {
+				util.ErrorLog.Println(util.ErrCheck(err))
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			if remaining > 0 {
+				w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(remaining.Seconds())), 10))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			// The window is claimed up front so concurrent requests are turned away, but only
+			// a successful request keeps it, failed ones can be retried right away
+			rw := newResponseWriter(w)
+			next(rw, req, session)
+
+			if rw.statusCode < 200 || rw.statusCode > 299 {
+				err = a.Handlers.Redis.ReleaseThrottle(context.WithoutCancel(req.Context()), session.GetUserSub(), methodName)
+				if err != nil {
+					util.ErrorLog.Println(util.ErrCheck(err))
+				}
+			}
+		}
+	}
+}
+
 func genETag(data []byte) string {
 	hash := md5.Sum(data)
 	return hex.EncodeToString(hash[:])
@@ -2,9 +2,15 @@ package api
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strconv"
 	"testing"
 
+	"github.com/google/uuid"
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
+	"github.com/keybittech/awayto-v3/go/pkg/handlers"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
 	"github.com/keybittech/awayto-v3/go/pkg/util"
 	"golang.org/x/time/rate"
 )
@@ -30,6 +36,27 @@ func TestAPI_AccessRequestMiddleware(t *testing.T) {
 	}
 }
 
+func Test_isPublicApiPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{name: "kiosk schedule", path: "/api/kiosk/gs/bakery.json", want: true},
+		{name: "payment webhook", path: "/api/webhooks/payments/stripe", want: true},
+		{name: "calendar feed", path: "/api/ics/bookings/token.ics", want: true},
+		{name: "protected api", path: "/api/v1/bookings", want: false},
+		{name: "prefix without separator", path: "/api/kiosks", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPublicApiPath(tt.path); got != tt.want {
+				t.Errorf("isPublicApiPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestAPI_LimitMiddleware(t *testing.T) {
 	type args struct {
 		limit rate.Limit
@@ -73,6 +100,59 @@ func TestAPI_SiteRoleCheckMiddleware(t *testing.T) {
 	}
 }
 
+func TestAPI_ThrottleMiddleware(t *testing.T) {
+	opts, err := util.NewHandlerOptions(util.HandlerOptionsConfig{
+		ServiceMethodName: "TestThrottleMiddleware",
+		Throttle:          5,
+	})
+	if err != nil {
+		t.Fatalf("handler options error %v", err)
+	}
+
+	a := &API{Handlers: &handlers.Handlers{Redis: clients.InitRedis()}}
+	session := types.NewConcurrentUserSession(&types.UserSession{UserSub: uuid.NewString()})
+	otherSession := types.NewConcurrentUserSession(&types.UserSession{UserSub: uuid.NewString()})
+
+	status := http.StatusOK
+	calls := 0
+	handler := a.ThrottleMiddleware(opts)(func(w http.ResponseWriter, req *http.Request, session *types.ConcurrentUserSession) {
+		calls++
+		w.WriteHeader(status)
+	})
+
+	serve := func(session *types.ConcurrentUserSession) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/throttled", nil), session)
+		return recorder
+	}
+
+	status = http.StatusInternalServerError
+	if res := serve(session); res.Code != http.StatusInternalServerError {
+		t.Fatalf("failed request status = %d, want %d", res.Code, http.StatusInternalServerError)
+	}
+
+	status = http.StatusOK
+	if res := serve(session); res.Code != http.StatusOK {
+		t.Fatalf("request after a failure status = %d, want %d, failures should not hold the throttle", res.Code, http.StatusOK)
+	}
+
+	res := serve(session)
+	if res.Code != http.StatusTooManyRequests {
+		t.Fatalf("request inside the throttle window status = %d, want %d", res.Code, http.StatusTooManyRequests)
+	}
+	retryAfter, err := strconv.Atoi(res.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > 5 {
+		t.Errorf("Retry-After = %q, want between 1 and 5 seconds", res.Header().Get("Retry-After"))
+	}
+	if calls != 2 {
+		t.Errorf("handler calls = %d, want 2, a throttled request should not reach the handler", calls)
+	}
+
+	if res := serve(otherSession); res.Code != http.StatusOK {
+		t.Errorf("other user status = %d, want %d, throttles are per user", res.Code, http.StatusOK)
+	}
+}
+
 func TestAPI_CacheMiddleware(t *testing.T) {
 	type args struct {
 		opts *util.HandlerOptions
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
+	"github.com/keybittech/awayto-v3/go/pkg/handlers"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const maxPaymentWebhookBytes = 1 << 16
+
+// Payment processors deliver signed webhooks without a user session. The signature is checked
+// by the named provider, then the seat payment is settled as paid or failed.
+func (a *API) InitPaymentWebhooks() {
+	a.Server.Handler.(*http.ServeMux).HandleFunc("POST /api/webhooks/payments/{provider}", func(w http.ResponseWriter, req *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				util.ErrorLog.Println(util.ErrCheck(fmt.Errorf("payment webhook panic: %v", p)))
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+
+		providerName := req.PathValue("provider")
+
+		provider, ok := a.Handlers.Payments.GetProvider(providerName)
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxPaymentWebhookBytes))
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		event, err := provider.ParseWebhook(req.Header, body)
+		if err != nil {
+			if errors.Is(err, clients.ErrInvalidPaymentSignature) {
+				util.WriteAuthRequest(req, "", "payment_webhook_"+providerName)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			util.ErrorLog.Println(util.ErrCheck(err))
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		err = a.Handlers.ReconcileSeatPayment(req.Context(), provider.Name(), event)
+		if err != nil && !errors.Is(err, handlers.ErrSeatPaymentUnchanged) {
+			util.ErrorLog.Println(util.ErrCheck(err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		// Unchanged events are still acknowledged so the provider stops redelivering them
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(fmt.Errorf("%s payment event for %s %s: %w", providerName, event.Code, event.Status, err)))
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
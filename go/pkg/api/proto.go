@@ -9,10 +9,12 @@ func (a *API) InitProtoHandlers() {
 		a.Server.Handler.(*http.ServeMux).Handle(handlerOpts.Pattern,
 			a.ValidateSessionMiddleware()(
 				a.SiteRoleCheckMiddleware(handlerOpts)(
-					a.CacheMiddleware(handlerOpts)(
-						// a.GroupInfoMiddleware(
-						a.HandleRequest(handlerOpts),
-						// ),
+					a.ThrottleMiddleware(handlerOpts)(
+						a.CacheMiddleware(handlerOpts)(
+							// a.GroupInfoMiddleware(
+							a.HandleRequest(handlerOpts),
+							// ),
+						),
 					),
 				),
 			),
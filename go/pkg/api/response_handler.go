@@ -2,10 +2,10 @@ package api
 
 import (
 	"errors"
+	"mime"
 	"net/http"
 	"strconv"
 
-	"github.com/keybittech/awayto-v3/go/pkg/types"
 	"github.com/keybittech/awayto-v3/go/pkg/util"
 
 	"google.golang.org/protobuf/encoding/protojson"
@@ -35,17 +35,37 @@ func ProtoResponseHandler(w http.ResponseWriter, req *http.Request, results prot
 	return n
 }
 
+// Responses served raw instead of as protojson, like file contents or exports
+type multipartContent interface {
+	GetContent() []byte
+}
+
+// Exports additionally describe what they are so browsers can save them
+type multipartAttachment interface {
+	GetContentType() string
+	GetFileName() string
+}
+
 func MultipartResponseHandler(w http.ResponseWriter, req *http.Request, results proto.Message) int {
 	if results == nil {
 		return 0
 	}
 
-	resData, ok := results.(*types.GetFileContentsResponse)
+	resData, ok := results.(multipartContent)
 	if !ok {
 		panic(util.ErrCheck(errors.New("multipart response is not the right proto")))
 	}
 
-	n, err := w.Write(resData.Content)
+	if attachment, ok := results.(multipartAttachment); ok {
+		if contentType := attachment.GetContentType(); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		if fileName := attachment.GetFileName(); fileName != "" {
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+		}
+	}
+
+	n, err := w.Write(resData.GetContent())
 	if err != nil {
 		panic(util.ErrCheck(err))
 	}
@@ -40,11 +40,13 @@ var (
 	upgrader = websocket.Upgrader{
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
+		Subprotocols:    util.SOCKET_PROTOCOLS,
 		CheckOrigin: func(r *http.Request) bool {
 			// TODO need anything from cors policy?
 			return true
 		},
 	}
+	shutdownCloseMessage = websocket.FormatCloseMessage(websocket.CloseServiceRestart, "reconnect")
 )
 
 // The sock handler takes a typical HTTP request and upgrades it to a websocket connection
@@ -62,6 +64,13 @@ func (a *API) InitSockServer() {
 			return
 		}
 
+		// Refuse new sockets while shutting down, otherwise wait on this one during shutdown
+		if !a.trackSocket() {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		defer a.sockConns.Done()
+
 		conn, err := upgrader.Upgrade(w, req, nil)
 		if err != nil {
 			util.ErrorLog.Println(util.ErrCheck(err))
@@ -70,7 +79,7 @@ func (a *API) InitSockServer() {
 		defer conn.Close()
 
 		// Set limits on reading
-		conn.SetReadLimit(1 << 11) // 2kb limit
+		conn.SetReadLimit(1 << 13) // 8kb limit, enough for a batch of canvas elements
 		conn.SetReadDeadline(time.Time{})
 
 		if req.URL.Query().Get("ticket") == "" {
@@ -161,7 +170,7 @@ func (a *API) InitSockServer() {
 				return
 			}
 
-			if err := a.Handlers.Redis.InitRedisSocketConnection(ctx, socketId); err != nil {
+			if err := a.Handlers.Redis.InitRedisSocketConnection(ctx, socketId, a.Handlers.Socket.NodeId); err != nil {
 				cancel()
 				util.ErrorLog.Println(util.ErrCheck(err))
 				return
@@ -240,6 +249,14 @@ func (a *API) InitSockServer() {
 			case <-req.Context().Done():
 				partSockMessage.WriteString("sock context ended")
 				return
+			case <-a.sockDrain:
+				// Clients treat service restart as a hint to reconnect, reaching another node or the restarted one
+				err := conn.WriteControl(websocket.CloseMessage, shutdownCloseMessage, time.Now().Add(socketEventTimeoutAfter))
+				if err != nil {
+					partSockMessage.WriteString("close frame error " + err.Error() + " ")
+				}
+				partSockMessage.WriteString("server shutting down")
+				return
 			case <-pingTimer.C:
 				// let errors log again
 				if errorFlag {
@@ -275,7 +292,7 @@ func (a *API) InitSockServer() {
 				smResultChan := make(chan *types.SocketMessage, 1)
 
 				go func() {
-					smResultChan <- a.SocketMessageReceiver(data)
+					smResultChan <- a.SocketMessageReceiver(conn.Subprotocol(), data)
 				}()
 
 				select {
@@ -334,18 +351,24 @@ func (a *API) TearDownSocketConnection(socketId, connId string, ds clients.DbSes
 
 	userSub := ds.ConcurrentUserSession.GetUserSub()
 
+	a.CloseExchangeCallLogs(socketId, ds.ConcurrentUserSession)
+
 	var tearDownFailures strings.Builder
 	var wg sync.WaitGroup
 	wg.Add(2)
 
 	go func() {
 		defer wg.Done()
-		topics, err := a.Handlers.Redis.HandleUnsub(ctx, socketId)
+		topics, endedTopics, err := a.Handlers.Redis.HandleUnsub(ctx, socketId)
 		if err != nil {
 			tearDownFailures.WriteString("handle_unsub ")
 			return
 		}
 
+		for _, topic := range endedTopics {
+			a.StoreExchangeTranscript(topic, ds.ConcurrentUserSession)
+		}
+
 		for topic, targets := range topics {
 			err := a.Handlers.Socket.SendMessage(ctx, userSub, targets, &types.SocketMessage{
 				Action: types.SocketActions_UNSUBSCRIBE_TOPIC,
@@ -394,3 +417,23 @@ func (a *API) TearDownSocketConnection(socketId, connId string, ds clients.DbSes
 
 	clients.GetGlobalWorkerPool().CleanUpClientMapping(userSub)
 }
+
+// Runs in the background as exchanges usually end during short lived socket events.
+// Only called from socket handlers, so it is tracked with them and finishes before shutdown.
+func (a *API) StoreExchangeTranscript(topic string, session *types.ConcurrentUserSession) {
+	a.sockConns.Add(1)
+	go func() {
+		defer a.sockConns.Done()
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(socketCleanupTimeout))
+		defer cancel()
+
+		err := clients.DbSession{
+			Topic:                 topic,
+			Pool:                  a.Handlers.Database.DatabaseClient.Pool,
+			ConcurrentUserSession: session,
+		}.StoreExchangeTranscript(ctx)
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(err))
+		}
+	}()
+}
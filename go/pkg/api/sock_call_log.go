@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+// exchangeCallLogs remembers which exchange topics each socket has an open call log on,
+// so repeated stream and RTC messages don't touch the database
+type exchangeCallLogs struct {
+	mu      sync.Mutex
+	open    map[string]map[string]struct{}    // socketId -> topics
+	pending map[string][]exchangeCallLogWrite // socketId -> writes not yet stored, oldest first
+}
+
+type exchangeCallLogWrite struct {
+	topic     string
+	session   *types.ConcurrentUserSession
+	connected bool
+}
+
+func newExchangeCallLogs() *exchangeCallLogs {
+	return &exchangeCallLogs{
+		open:    make(map[string]map[string]struct{}),
+		pending: make(map[string][]exchangeCallLogWrite),
+	}
+}
+
+// queue adds a write for the socket, returning true when no writer is running for it yet
+func (cl *exchangeCallLogs) queue(socketId string, write exchangeCallLogWrite) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	_, running := cl.pending[socketId]
+	cl.pending[socketId] = append(cl.pending[socketId], write)
+	return !running
+}
+
+// next takes the socket's oldest pending write. When there are none left the writer is done,
+// and the next write queued for the socket starts a new one.
+func (cl *exchangeCallLogs) next(socketId string) (exchangeCallLogWrite, bool) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	writes := cl.pending[socketId]
+	if len(writes) == 0 {
+		delete(cl.pending, socketId)
+		return exchangeCallLogWrite{}, false
+	}
+
+	cl.pending[socketId] = writes[1:]
+	return writes[0], true
+}
+
+// start returns true when the topic wasn't already open for the socket
+func (cl *exchangeCallLogs) start(socketId, topic string) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	topics, ok := cl.open[socketId]
+	if !ok {
+		topics = make(map[string]struct{})
+		cl.open[socketId] = topics
+	}
+
+	if _, ok := topics[topic]; ok {
+		return false
+	}
+
+	topics[topic] = struct{}{}
+	return true
+}
+
+// stop returns true when the topic was open for the socket
+func (cl *exchangeCallLogs) stop(socketId, topic string) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	topics, ok := cl.open[socketId]
+	if !ok {
+		return false
+	}
+
+	if _, ok := topics[topic]; !ok {
+		return false
+	}
+
+	delete(topics, topic)
+	if len(topics) == 0 {
+		delete(cl.open, socketId)
+	}
+	return true
+}
+
+// stopAll returns the topics which were open for the socket
+func (cl *exchangeCallLogs) stopAll(socketId string) []string {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	topics := make([]string, 0, len(cl.open[socketId]))
+	for topic := range cl.open[socketId] {
+		topics = append(topics, topic)
+	}
+	delete(cl.open, socketId)
+
+	return topics
+}
+
+// Text and whiteboard participants are connected while subscribed, call participants while streaming.
+// RTC negotiation also counts as streaming, as a participant may answer a call without starting a stream.
+func exchangeCallLogChange(style string, action types.SocketActions) (opens, closes bool) {
+	switch action {
+	case types.SocketActions_SUBSCRIBE:
+		return style != clients.ExchangeCallStyleCall, false
+	case types.SocketActions_START_STREAM, types.SocketActions_RTC:
+		return style == clients.ExchangeCallStyleCall, false
+	case types.SocketActions_STOP_STREAM:
+		return false, style == clients.ExchangeCallStyleCall
+	case types.SocketActions_UNSUBSCRIBE:
+		return false, true
+	}
+	return false, false
+}
+
+// LogExchangeCall records connect and disconnect times of exchange participants from socket lifecycle actions
+func (a *API) LogExchangeCall(socketId string, action types.SocketActions, topic string, session *types.ConcurrentUserSession) {
+	style, _, ok := clients.ExchangeCallStyle(topic)
+	if !ok {
+		return
+	}
+
+	opens, closes := exchangeCallLogChange(style, action)
+	if opens && a.callLogs.start(socketId, topic) {
+		a.storeExchangeCallLog(socketId, topic, session, true)
+	} else if closes && a.callLogs.stop(socketId, topic) {
+		a.storeExchangeCallLog(socketId, topic, session, false)
+	}
+}
+
+// Closes every call log still open for a socket which is going away
+func (a *API) CloseExchangeCallLogs(socketId string, session *types.ConcurrentUserSession) {
+	for _, topic := range a.callLogs.stopAll(socketId) {
+		a.storeExchangeCallLog(socketId, topic, session, false)
+	}
+}
+
+// Runs in the background like transcripts, and is tracked with the socket handlers for shutdown.
+// Each socket's writes are stored one at a time in the order they happened, so a close can't
+// run before the open it closes.
+func (a *API) storeExchangeCallLog(socketId, topic string, session *types.ConcurrentUserSession, connected bool) {
+	write := exchangeCallLogWrite{topic: topic, session: session, connected: connected}
+	if !a.callLogs.queue(socketId, write) {
+		return
+	}
+
+	a.sockConns.Add(1)
+	go func() {
+		defer a.sockConns.Done()
+		for {
+			write, ok := a.callLogs.next(socketId)
+			if !ok {
+				return
+			}
+			a.writeExchangeCallLog(socketId, write)
+		}
+	}()
+}
+
+func (a *API) writeExchangeCallLog(socketId string, write exchangeCallLogWrite) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(socketCleanupTimeout))
+	defer cancel()
+
+	ds := clients.DbSession{
+		Topic:                 write.topic,
+		Pool:                  a.Handlers.Database.DatabaseClient.Pool,
+		ConcurrentUserSession: write.session,
+	}
+
+	var err error
+	if write.connected {
+		err = ds.OpenExchangeCallLog(ctx, socketId, a.Handlers.Socket.NodeId)
+	} else {
+		err = ds.CloseExchangeCallLog(ctx, socketId)
+	}
+	if err != nil {
+		util.ErrorLog.Println(util.ErrCheck(err))
+	}
+}
+
+// SweepExchangeCallLogs closes the call logs left open by nodes which stopped without closing them.
+// Running nodes always listen on their relay channel, so a node with no listener is gone.
+func (a *API) SweepExchangeCallLogs(ctx context.Context) error {
+	ds := clients.DbSession{
+		Pool: a.Handlers.Database.DatabaseClient.Pool,
+		ConcurrentUserSession: types.NewConcurrentUserSession(&types.UserSession{
+			UserSub: "worker",
+		}),
+	}
+
+	nodeIds, err := ds.GetExchangeCallLogNodes(ctx)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	listening, err := a.Handlers.Redis.ListeningSocketNodes(ctx, nodeIds)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	var goneNodeIds []string
+	for _, nodeId := range nodeIds {
+		if nodeId != a.Handlers.Socket.NodeId && !listening[nodeId] {
+			goneNodeIds = append(goneNodeIds, nodeId)
+		}
+	}
+
+	closed, err := ds.CloseNodeExchangeCallLogs(ctx, goneNodeIds)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	if closed > 0 {
+		util.DebugLog.Printf("closed %d call logs left open by stopped nodes %v", closed, goneNodeIds)
+	}
+
+	return nil
+}
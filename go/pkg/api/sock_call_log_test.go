@@ -0,0 +1,103 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+)
+
+func Test_exchangeCallLogs(t *testing.T) {
+	cl := newExchangeCallLogs()
+	socketId := "sub:conn"
+	callTopic := "exchange/1:booking"
+	textTopic := "exchange/0:booking"
+
+	if !cl.start(socketId, callTopic) {
+		t.Errorf("exchangeCallLogs.start(%v, %v) = false on first start", socketId, callTopic)
+	}
+	if cl.start(socketId, callTopic) {
+		t.Errorf("exchangeCallLogs.start(%v, %v) = true on repeated start", socketId, callTopic)
+	}
+	if cl.stop(socketId, textTopic) {
+		t.Errorf("exchangeCallLogs.stop(%v, %v) = true for a topic never started", socketId, textTopic)
+	}
+	if !cl.stop(socketId, callTopic) {
+		t.Errorf("exchangeCallLogs.stop(%v, %v) = false for a started topic", socketId, callTopic)
+	}
+	if cl.stop(socketId, callTopic) {
+		t.Errorf("exchangeCallLogs.stop(%v, %v) = true on repeated stop", socketId, callTopic)
+	}
+
+	cl.start(socketId, callTopic)
+	cl.start(socketId, textTopic)
+	if got := cl.stopAll(socketId); len(got) != 2 {
+		t.Errorf("exchangeCallLogs.stopAll(%v) = %v, want both topics", socketId, got)
+	}
+	if got := cl.stopAll(socketId); len(got) != 0 {
+		t.Errorf("exchangeCallLogs.stopAll(%v) = %v after stopping all", socketId, got)
+	}
+	if len(cl.open) != 0 {
+		t.Errorf("exchangeCallLogs kept %d sockets after stopping all", len(cl.open))
+	}
+}
+
+func Test_exchangeCallLogs_queue(t *testing.T) {
+	cl := newExchangeCallLogs()
+	socketId := "sub:conn"
+	topic := "exchange/1:booking"
+
+	if !cl.queue(socketId, exchangeCallLogWrite{topic: topic, connected: true}) {
+		t.Fatal("exchangeCallLogs.queue() = false, want the first write to start a writer")
+	}
+	if cl.queue(socketId, exchangeCallLogWrite{topic: topic, connected: false}) {
+		t.Fatal("exchangeCallLogs.queue() = true while a writer is running")
+	}
+	if !cl.queue("sub:other", exchangeCallLogWrite{topic: topic, connected: true}) {
+		t.Error("exchangeCallLogs.queue() = false, want another socket to start its own writer")
+	}
+
+	for _, wantConnected := range []bool{true, false} {
+		write, ok := cl.next(socketId)
+		if !ok || write.connected != wantConnected {
+			t.Fatalf("exchangeCallLogs.next() = %+v, %v, want connected %v", write, ok, wantConnected)
+		}
+	}
+
+	if _, ok := cl.next(socketId); ok {
+		t.Fatal("exchangeCallLogs.next() = true with nothing pending")
+	}
+	if !cl.queue(socketId, exchangeCallLogWrite{topic: topic, connected: true}) {
+		t.Error("exchangeCallLogs.queue() = false, want a write after the writer finished to start a new one")
+	}
+}
+
+func Test_exchangeCallLogChange(t *testing.T) {
+	type args struct {
+		style  string
+		action types.SocketActions
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantOpens  bool
+		wantCloses bool
+	}{
+		{name: "text subscribe opens", args: args{"text", types.SocketActions_SUBSCRIBE}, wantOpens: true},
+		{name: "whiteboard subscribe opens", args: args{"whiteboard", types.SocketActions_SUBSCRIBE}, wantOpens: true},
+		{name: "call subscribe waits for a stream", args: args{"call", types.SocketActions_SUBSCRIBE}},
+		{name: "call start stream opens", args: args{"call", types.SocketActions_START_STREAM}, wantOpens: true},
+		{name: "call rtc opens", args: args{"call", types.SocketActions_RTC}, wantOpens: true},
+		{name: "call stop stream closes", args: args{"call", types.SocketActions_STOP_STREAM}, wantCloses: true},
+		{name: "text stop stream is ignored", args: args{"text", types.SocketActions_STOP_STREAM}},
+		{name: "unsubscribe closes", args: args{"whiteboard", types.SocketActions_UNSUBSCRIBE}, wantCloses: true},
+		{name: "text messages are ignored", args: args{"text", types.SocketActions_TEXT}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOpens, gotCloses := exchangeCallLogChange(tt.args.style, tt.args.action)
+			if gotOpens != tt.wantOpens || gotCloses != tt.wantCloses {
+				t.Errorf("exchangeCallLogChange(%v, %v) = %v, %v, want %v, %v", tt.args.style, tt.args.action, gotOpens, gotCloses, tt.wantOpens, tt.wantCloses)
+			}
+		})
+	}
+}
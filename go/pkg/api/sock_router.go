@@ -4,48 +4,24 @@ import (
 	"context"
 	json "encoding/json"
 	"errors"
-	"strconv"
 
 	"github.com/keybittech/awayto-v3/go/pkg/clients"
 	"github.com/keybittech/awayto-v3/go/pkg/types"
 	"github.com/keybittech/awayto-v3/go/pkg/util"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
-func (a *API) SocketMessageReceiver(data []byte) *types.SocketMessage {
+func (a *API) SocketMessageReceiver(protocol string, data []byte) *types.SocketMessage {
 	finish := util.RunTimer()
 	defer finish()
-	var socketMessage types.SocketMessage
 
-	messageParams := make([]string, 7)
-
-	cursor := 0
-	var curr string
-	var err error
-	for i := range 7 {
-		cursor, curr, err = util.ParseMessage(util.DefaultPadding, cursor, data)
-		if err != nil {
-			util.ErrorLog.Println(util.ErrCheck(err))
-			messageParams[i] = ""
-			continue
-		}
-		messageParams[i] = curr
-	}
-
-	actionId, err := strconv.ParseInt(messageParams[0], 10, 32)
+	socketMessage, err := util.ParseProtocolSocketMessage(protocol, data)
 	if err != nil {
-		util.ErrorLog.Println(util.ErrCheck(err))
+		util.ErrorLog.Println(err)
 		return nil
 	}
 
-	socketMessage.Action = types.SocketActions(actionId)
-	socketMessage.Store = messageParams[1] == "t"
-	socketMessage.Historical = messageParams[2] == "t"
-	socketMessage.Timestamp = messageParams[3]
-	socketMessage.Topic = messageParams[4]
-	socketMessage.Sender = messageParams[5]
-	socketMessage.Payload = messageParams[6]
-
-	return &socketMessage
+	return socketMessage
 }
 
 func (a *API) SocketMessageRouter(ctx context.Context, connId, socketId string, sm *types.SocketMessage, ds clients.DbSession) {
@@ -102,7 +78,7 @@ func (a *API) SocketMessageRouter(ctx context.Context, connId, socketId string,
 		}
 
 		// Update user's topic cids
-		err = a.Handlers.Redis.TrackTopicParticipant(ctx, sm.Topic, socketId)
+		err = a.Handlers.Redis.TrackTopicParticipant(ctx, sm.Topic, socketId, a.Handlers.Socket.NodeId)
 		if err != nil {
 			util.ErrorLog.Println(util.ErrCheck(err))
 			return
@@ -135,6 +111,8 @@ func (a *API) SocketMessageRouter(ctx context.Context, connId, socketId string,
 			return
 		}
 
+		a.LogExchangeCall(socketId, sm.Action, sm.Topic, ds.ConcurrentUserSession)
+
 	case types.SocketActions_UNSUBSCRIBE:
 
 		hasTracking, err := a.Handlers.Redis.HasTracking(ctx, sm.Topic, socketId)
@@ -167,6 +145,16 @@ func (a *API) SocketMessageRouter(ctx context.Context, connId, socketId string,
 			util.ErrorLog.Println(util.ErrCheck(err))
 		}
 
+		a.LogExchangeCall(socketId, sm.Action, sm.Topic, ds.ConcurrentUserSession)
+
+		// the last participant out ends the exchange
+		_, remainingTargets, err := a.Handlers.Redis.GetCachedParticipants(ctx, sm.Topic, true)
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(err))
+		} else if remainingTargets == "" {
+			a.StoreExchangeTranscript(sm.Topic, ds.ConcurrentUserSession)
+		}
+
 		_, err = a.Handlers.Socket.SendCommand(ctx, clients.DeleteSubscribedTopicSocketCommand, &types.SocketRequestParams{
 			UserSub: ds.ConcurrentUserSession.GetUserSub(),
 			Topic:   sm.Topic,
@@ -196,6 +184,12 @@ func (a *API) SocketMessageRouter(ctx context.Context, connId, socketId string,
 			return
 		}
 
+		err = ds.GetTopicMessageReceipts(ctx, participants)
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(err))
+			return
+		}
+
 		participantsBytes, err := json.Marshal(participants)
 		if err != nil {
 			util.ErrorLog.Println(util.ErrCheck(err))
@@ -252,13 +246,135 @@ func (a *API) SocketMessageRouter(ctx context.Context, connId, socketId string,
 			}
 		}
 
+	case types.SocketActions_SET_ELEMENTS, types.SocketActions_DELETE_ELEMENTS, types.SocketActions_UNDO_ELEMENTS, types.SocketActions_REDO_ELEMENTS:
+		changes, err := ds.ApplyCanvasAction(ctx, connId, sm)
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(err))
+			return
+		}
+
+		if len(changes.Applied) > 0 {
+			_, cachedParticipantTargets, err := a.Handlers.Redis.GetCachedParticipants(ctx, sm.Topic, true)
+			if err != nil {
+				util.ErrorLog.Println(util.ErrCheck(err))
+				return
+			}
+
+			appliedBytes, err := protojson.Marshal(&types.ICanvasElements{Elements: changes.Applied, Operation: changes.Operation})
+			if err != nil {
+				util.ErrorLog.Println(util.ErrCheck(err))
+				return
+			}
+
+			err = a.Handlers.Socket.SendMessage(ctx, ds.ConcurrentUserSession.GetUserSub(), cachedParticipantTargets, &types.SocketMessage{
+				Action:  types.SocketActions_SET_ELEMENTS,
+				Sender:  connId,
+				Topic:   sm.Topic,
+				Payload: string(appliedBytes),
+			})
+			if err != nil {
+				util.ErrorLog.Println(util.ErrCheck(err))
+				return
+			}
+		}
+
+		// the sender's stale changes are corrected with what's stored
+		if len(changes.Rejected) > 0 {
+			rejectedBytes, err := protojson.Marshal(&types.ICanvasElements{Elements: changes.Rejected, Operation: clients.CANVAS_OPERATION_REJECT})
+			if err != nil {
+				util.ErrorLog.Println(util.ErrCheck(err))
+				return
+			}
+
+			err = a.Handlers.Socket.SendMessage(ctx, ds.ConcurrentUserSession.GetUserSub(), connId, &types.SocketMessage{
+				Action:  types.SocketActions_SET_ELEMENTS,
+				Sender:  connId,
+				Topic:   sm.Topic,
+				Payload: string(rejectedBytes),
+			})
+			if err != nil {
+				util.ErrorLog.Println(util.ErrCheck(err))
+				return
+			}
+		}
+
+		// elements are stored as they're applied, not as topic messages
+		return
+
+	case types.SocketActions_EDIT_MESSAGE, types.SocketActions_DELETE_MESSAGE, types.SocketActions_READ_MESSAGES:
+		change, err := clients.ParseTopicMessageChange(sm.Payload)
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(err))
+			return
+		}
+
+		var changed bool
+		switch sm.Action {
+		case types.SocketActions_EDIT_MESSAGE:
+			changed, err = ds.EditTopicMessage(ctx, change)
+		case types.SocketActions_DELETE_MESSAGE:
+			change.Message = ""
+			changed, err = ds.DeleteTopicMessage(ctx, change.GetId())
+		case types.SocketActions_READ_MESSAGES:
+			change.Message = ""
+			change.Scid = ds.ConcurrentUserSession.GetUserSub()
+			changed, err = ds.ReadTopicMessages(ctx, change.GetId())
+		}
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(err))
+			return
+		}
+
+		// only the author's own messages change, and read receipts only move forward
+		if !changed {
+			return
+		}
+
+		_, cachedParticipantTargets, err := a.Handlers.Redis.GetCachedParticipants(ctx, sm.Topic, true)
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(err))
+			return
+		}
+
+		changeBytes, err := json.Marshal(change)
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(err))
+			return
+		}
+
+		err = a.Handlers.Socket.SendMessage(ctx, ds.ConcurrentUserSession.GetUserSub(), cachedParticipantTargets, &types.SocketMessage{
+			Action:  sm.Action,
+			Sender:  connId,
+			Topic:   sm.Topic,
+			Payload: string(changeBytes),
+		})
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(err))
+			return
+		}
+
+		// changes are stored as they're applied, not as topic messages
+		return
+
 	default:
+		// stored messages are given their id before anyone receives them
+		if sm.Store && sm.Action == types.SocketActions_TEXT {
+			err := clients.StampTopicMessage(sm)
+			if err != nil {
+				util.ErrorLog.Println(util.ErrCheck(err))
+				return
+			}
+		}
+
 		_, cachedParticipantTargets, err := a.Handlers.Redis.GetCachedParticipants(ctx, sm.Topic, true)
 		if err != nil {
 			util.ErrorLog.Println(util.ErrCheck(err))
 			return
 		}
 
+		// stream lifecycle is logged even when nobody else is there to receive it
+		a.LogExchangeCall(socketId, sm.Action, sm.Topic, ds.ConcurrentUserSession)
+
 		err = a.Handlers.Socket.SendMessage(ctx, ds.ConcurrentUserSession.GetUserSub(), cachedParticipantTargets, sm)
 		if err != nil {
 			util.ErrorLog.Println(util.ErrCheck(err))
@@ -11,7 +11,8 @@ import (
 
 func TestAPI_SocketMessageReceiver(t *testing.T) {
 	type args struct {
-		data []byte
+		protocol string
+		data     []byte
 	}
 	tests := []struct {
 		name string
@@ -23,8 +24,8 @@ func TestAPI_SocketMessageReceiver(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.a.SocketMessageReceiver(tt.args.data); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("API.SocketMessageReceiver(%v) = %v, want %v", tt.args.data, got, tt.want)
+			if got := tt.a.SocketMessageReceiver(tt.args.protocol, tt.args.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("API.SocketMessageReceiver(%v, %v) = %v, want %v", tt.args.protocol, tt.args.data, got, tt.want)
 			}
 		})
 	}
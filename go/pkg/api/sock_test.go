@@ -3,6 +3,8 @@ package api
 import (
 	"net/http"
 	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/types"
 )
 
 func TestAPI_InitSockServer(t *testing.T) {
@@ -45,3 +47,22 @@ func TestAPI_TearDownSocketConnection(t *testing.T) {
 		})
 	}
 }
+
+func TestAPI_StoreExchangeTranscript(t *testing.T) {
+	type args struct {
+		topic   string
+		session *types.ConcurrentUserSession
+	}
+	tests := []struct {
+		name string
+		a    *API
+		args args
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.a.StoreExchangeTranscript(tt.args.topic, tt.args.session)
+		})
+	}
+}
@@ -72,9 +72,14 @@ func (a *API) InitUnixServer(unixPath string) {
 
 	util.DebugLog.Println("Listening on", unixPath)
 
+	defer close(a.unixDone)
+
 	for {
 		conn, err := a.Unix.Accept()
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
 			util.ErrorLog.Println("Error accepting unix connection:", err)
 			continue
 		}
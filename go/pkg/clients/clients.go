@@ -21,6 +21,7 @@ var (
 	channelClosedWithoutResponse  = errors.New("reply channel closed without response")
 	channelTimedOutBeforeResponse = errors.New("timed out when receiving command")
 	routingTimeoutError           = errors.New("timed out when routing command after" + routingTimeout.String())
+	workerPoolStopped             = errors.New("worker pool is stopped")
 )
 
 // CommandHandler interface defines a type that can handle commands of a specific type
@@ -51,6 +52,8 @@ type WorkerPool struct {
 	processFuncs  sync.Map // map[string]ProcessFunction
 	wg            sync.WaitGroup
 	queueMutex    sync.RWMutex
+	stopMutex     sync.RWMutex
+	stopped       bool
 	workerQueues  []chan CombinedCommand
 	numWorkers    int
 }
@@ -152,14 +155,30 @@ func (p *WorkerPool) Start() {
 	}
 }
 
+// Stop lets workers finish queued commands, commands routed afterwards are rejected
 func (p *WorkerPool) Stop() {
+	p.stopMutex.Lock()
+	if p.stopped {
+		p.stopMutex.Unlock()
+		return
+	}
+	p.stopped = true
 	for i := range p.numWorkers {
 		close(p.workerQueues[i])
 	}
+	p.stopMutex.Unlock()
+
 	p.wg.Wait()
 }
 
 func (p *WorkerPool) RouteCommand(ctx context.Context, cmd CombinedCommand) error {
+	// queues are closed once stopped
+	p.stopMutex.RLock()
+	defer p.stopMutex.RUnlock()
+	if p.stopped {
+		return workerPoolStopped
+	}
+
 	clientId := cmd.GetClientId()
 	queueIdx := p.getQueueForClient(clientId)
 
@@ -2,6 +2,7 @@ package clients
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"reflect"
@@ -26,7 +27,7 @@ func TestMain(m *testing.M) {
 	util.ParseEnv()
 	testutil.LoadIntegrations()
 
-	testSocket = InitSocket()
+	testSocket = InitSocket(nil)
 	testSocketUserSession = types.NewConcurrentUserSession(&types.UserSession{
 		UserSub:  "user-sub",
 		GroupId:  "group-id",
@@ -360,11 +361,18 @@ func TestWorkerPool_Stop(t *testing.T) {
 		name string
 		p    *WorkerPool
 	}{
-		// TODO: Add test cases.
+		{name: "stopped pool rejects commands", p: newWorkerPool(2, 2)},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			tt.p.Start()
 			tt.p.Stop()
+			tt.p.Stop() // stopping twice must not panic on closed queues
+
+			err := tt.p.RouteCommand(context.Background(), MockCommand{ClientId: "client"})
+			if !errors.Is(err, workerPoolStopped) {
+				t.Errorf("WorkerPool.RouteCommand() after Stop error = %v, want %v", err, workerPoolStopped)
+			}
 		})
 	}
 }
@@ -20,9 +20,13 @@ import (
 )
 
 const (
-	emptyString            = ""
-	emptyInteger           = 0
-	setSessionVariablesSQL = `SELECT dbfunc_schema.set_session_vars($1::VARCHAR, $2::VARCHAR, $3::INTEGER, $4::VARCHAR)`
+	emptyString             = ""
+	emptyInteger            = 0
+	setSessionVariablesSQL  = `SELECT dbfunc_schema.set_session_vars($1::VARCHAR, $2::VARCHAR, $3::INTEGER, $4::VARCHAR)`
+	kioskScheduleChannel    = "kiosk_schedule_changed"
+	identityOutboxChannel   = "identity_outbox_changed"
+	notificationJobsChannel = "notification_jobs_changed"
+	groupWebhooksChannel    = "group_webhook_deliveries_changed"
 )
 
 type Database struct {
@@ -164,6 +168,63 @@ type DatabaseClient struct {
 	*pgxpool.Pool
 }
 
+// Blocks on a dedicated connection, sending to changed whenever a schedule or bracket
+// change is committed. Returns when the context is done or the connection fails.
+func (dc *DatabaseClient) ListenKioskScheduleChanges(ctx context.Context, changed chan<- struct{}) error {
+	return dc.listen(ctx, kioskScheduleChannel, changed)
+}
+
+// Blocks on a dedicated connection, sending to changed whenever identity outbox rows are committed
+func (dc *DatabaseClient) ListenIdentityOutbox(ctx context.Context, changed chan<- struct{}) error {
+	return dc.listen(ctx, identityOutboxChannel, changed)
+}
+
+// Blocks on a dedicated connection, sending to changed whenever notification jobs are committed
+func (dc *DatabaseClient) ListenNotificationJobs(ctx context.Context, changed chan<- struct{}) error {
+	return dc.listen(ctx, notificationJobsChannel, changed)
+}
+
+// Blocks on a dedicated connection, sending to changed whenever group webhook deliveries are committed
+func (dc *DatabaseClient) ListenGroupWebhookDeliveries(ctx context.Context, changed chan<- struct{}) error {
+	return dc.listen(ctx, groupWebhooksChannel, changed)
+}
+
+func (dc *DatabaseClient) listen(ctx context.Context, channel string, changed chan<- struct{}) error {
+	conn, err := dc.Pool.Acquire(ctx)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "LISTEN "+channel)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	for {
+		_, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return util.ErrCheck(err)
+		}
+
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (dc *DatabaseClient) RefreshKioskSchedule(ctx context.Context) error {
+	_, err := dc.Pool.Exec(ctx, `SELECT dbfunc_schema.refresh_kiosk_schedule()`)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+	return nil
+}
+
 func (dc *DatabaseClient) OpenPoolSessionGroupTx(ctx context.Context, session *types.ConcurrentUserSession) (*PoolTx, *types.ConcurrentUserSession, error) {
 	groupSession := types.NewConcurrentUserSession(&types.UserSession{
 		UserSub: session.GetGroupSub(),
@@ -341,6 +402,37 @@ func (ds DbSession) SessionBatchQueryRow(ctx context.Context, query string, para
 	return row, done, nil
 }
 
+// Open a transaction with the session variables, including the topic, set until it ends.
+// For socket actions which need to read and write consistently. Close with SessionCloseTx.
+func (ds DbSession) SessionOpenTx(ctx context.Context) (pgx.Tx, error) {
+	tx, err := ds.Pool.Begin(ctx)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	_, err = tx.Exec(ctx, setSessionVariablesSQL, ds.ConcurrentUserSession.GetUserSub(), ds.ConcurrentUserSession.GetGroupId(), ds.ConcurrentUserSession.GetRoleBits(), ds.Topic)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, util.ErrCheck(err)
+	}
+
+	return tx, nil
+}
+
+func (ds DbSession) SessionCloseTx(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, setSessionVariablesSQL, emptyString, emptyString, emptyInteger, emptyString)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
 // Open a session batch with the intention of adding multiple queries
 func (ds DbSession) SessionOpenBatch(ctx context.Context) *pgx.Batch {
 	batch := &pgx.Batch{}
@@ -0,0 +1,670 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/protovalidate-go"
+	"github.com/jackc/pgx/v5"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Logged operations
+const (
+	CANVAS_OPERATION_CREATE = "create"
+	CANVAS_OPERATION_UPDATE = "update"
+	CANVAS_OPERATION_DELETE = "delete"
+	CANVAS_OPERATION_UNDO   = "undo"
+	CANVAS_OPERATION_REDO   = "redo"
+)
+
+// Operations sent with SET_ELEMENTS, along with delete, undo and redo
+const (
+	CANVAS_OPERATION_LOAD   = "load"
+	CANVAS_OPERATION_SET    = "set"
+	CANVAS_OPERATION_REJECT = "reject"
+)
+
+var errCanvasElementKind = errors.New("canvas element must hold exactly one of box, line, shape, image or stickyNote")
+
+// CanvasChanges are the results of a canvas action. Applied elements are sent to every participant,
+// rejected elements are sent back to the sender as they are currently stored.
+type CanvasChanges struct {
+	Operation string
+	Applied   []*types.ICanvasElement
+	Rejected  []*types.ICanvasElement
+}
+
+func (cc *CanvasChanges) reject(current *types.ICanvasElement, elementId string) {
+	if current == nil {
+		current = &types.ICanvasElement{Id: elementId, Deleted: true}
+	}
+	cc.Rejected = append(cc.Rejected, current)
+}
+
+// CanvasElementType is the stored element_type of an element
+func CanvasElementType(element *types.ICanvasElement) (string, error) {
+	kinds := []struct {
+		name string
+		set  bool
+	}{
+		{"box", element.GetBox() != nil},
+		{"line", element.GetLine() != nil},
+		{"shape", element.GetShape() != nil},
+		{"image", element.GetImage() != nil},
+		{"sticky_note", element.GetStickyNote() != nil},
+	}
+
+	elementType := ""
+	for _, kind := range kinds {
+		if !kind.set {
+			continue
+		}
+		if elementType != "" {
+			return "", errCanvasElementKind
+		}
+		elementType = kind.name
+	}
+
+	if elementType == "" {
+		return "", errCanvasElementKind
+	}
+
+	return elementType, nil
+}
+
+// Before elements were typed, boxes and lines were stored as the whiteboard's own json
+type legacyCanvasBox struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	Color  string  `json:"color"`
+	Text   string  `json:"text"`
+}
+
+type legacyCanvasPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type legacyCanvasLines struct {
+	Lines []struct {
+		StartPoint legacyCanvasPoint `json:"startPoint"`
+		EndPoint   legacyCanvasPoint `json:"endPoint"`
+	} `json:"lines"`
+	Settings struct {
+		Stroke    string `json:"stroke"`
+		Highlight bool   `json:"highlight"`
+	} `json:"settings"`
+}
+
+// readCanvasElement reads an element's stored properties. Rows stored before elements were typed are
+// converted from their box or line json, and are saved typed the next time they change.
+func readCanvasElement(elementType string, properties []byte) (*types.ICanvasElement, error) {
+	element := &types.ICanvasElement{}
+	if err := protojson.Unmarshal(properties, element); err == nil {
+		if _, err := CanvasElementType(element); err == nil {
+			return element, nil
+		}
+	}
+
+	switch elementType {
+	case "box":
+		var box legacyCanvasBox
+		if err := json.Unmarshal(properties, &box); err != nil {
+			return nil, util.ErrCheck(err)
+		}
+		return &types.ICanvasElement{Box: &types.ICanvasBox{
+			X:      box.X,
+			Y:      box.Y,
+			Width:  box.Width,
+			Height: box.Height,
+			Color:  box.Color,
+			Text:   box.Text,
+		}}, nil
+
+	case "line":
+		var lines legacyCanvasLines
+		if err := json.Unmarshal(properties, &lines); err != nil {
+			return nil, util.ErrCheck(err)
+		}
+		if len(lines.Lines) == 0 {
+			return nil, util.ErrCheck(errCanvasElementKind)
+		}
+
+		// segments were drawn end to end, so the line is the first start and every end after it
+		first := lines.Lines[0].StartPoint
+		line := &types.ICanvasLine{
+			Points:    []*types.ICanvasPoint{{X: first.X, Y: first.Y}},
+			Stroke:    lines.Settings.Stroke,
+			Highlight: lines.Settings.Highlight,
+		}
+		for _, segment := range lines.Lines {
+			line.Points = append(line.Points, &types.ICanvasPoint{X: segment.EndPoint.X, Y: segment.EndPoint.Y})
+		}
+		return &types.ICanvasElement{Line: line}, nil
+	}
+
+	return nil, util.ErrCheck(errCanvasElementKind)
+}
+
+// checkCanvasElementChange decides if a participant's change to an element can be applied. Only the participant
+// who created an element can delete it. Other changes must be based on the stored version, unless the participant
+// made the stored version themselves, as their own changes are often in flight together.
+func checkCanvasElementChange(current, element *types.ICanvasElement, lastEditor, userSub string, deleting bool) bool {
+	if current == nil {
+		return !deleting && element.GetVersion() == 0
+	}
+
+	if current.GetDeleted() {
+		return false
+	}
+
+	currentType, err := CanvasElementType(current)
+	if err != nil {
+		return false
+	}
+
+	if deleting {
+		return current.GetCreatedSub() == userSub
+	}
+	elementType, err := CanvasElementType(element)
+	if err != nil || currentType != elementType {
+		return false
+	}
+
+	if element.GetVersion() == current.GetVersion() {
+		return true
+	}
+
+	return element.GetVersion() < current.GetVersion() && lastEditor == userSub
+}
+
+// checkCanvasElementRestore decides if an undo or redo of an operation can put its state back. A deleted element is
+// only brought back when nothing has happened to it since the operation, or since that operation was last undone,
+// so undoing an old change can't revive an element its creator has deleted.
+func checkCanvasElementRestore(current *types.ICanvasElement, state []byte, operationId, lastOperationId, lastTargetId string) bool {
+	if state == nil {
+		_, err := CanvasElementType(current)
+		return err == nil
+	}
+	if !current.GetDeleted() {
+		return true
+	}
+	return lastOperationId == operationId || lastTargetId == operationId
+}
+
+// canvasOperationState is how an element is kept in the operation log, null when it doesn't exist
+func canvasOperationState(element *types.ICanvasElement) ([]byte, error) {
+	if element == nil || element.GetDeleted() {
+		return nil, nil
+	}
+	return protojson.Marshal(element)
+}
+
+// ApplyCanvasAction applies a SET_ELEMENTS, DELETE_ELEMENTS, UNDO_ELEMENTS or REDO_ELEMENTS message to the
+// topic's canvas, logging every change. Actions on a topic are applied one at a time.
+func (ds DbSession) ApplyCanvasAction(ctx context.Context, connId string, message *types.SocketMessage) (*CanvasChanges, error) {
+	finish := util.RunTimer()
+	defer finish()
+
+	changes := &CanvasChanges{}
+	elements := &types.ICanvasElements{}
+
+	switch message.Action {
+	case types.SocketActions_SET_ELEMENTS:
+		changes.Operation = CANVAS_OPERATION_SET
+	case types.SocketActions_DELETE_ELEMENTS:
+		changes.Operation = CANVAS_OPERATION_DELETE
+	case types.SocketActions_UNDO_ELEMENTS:
+		changes.Operation = CANVAS_OPERATION_UNDO
+	case types.SocketActions_REDO_ELEMENTS:
+		changes.Operation = CANVAS_OPERATION_REDO
+	default:
+		return nil, util.ErrCheck(errors.New("not a canvas action"))
+	}
+
+	if changes.Operation == CANVAS_OPERATION_SET || changes.Operation == CANVAS_OPERATION_DELETE {
+		if err := protojson.Unmarshal([]byte(message.Payload), elements); err != nil {
+			return nil, util.ErrCheck(err)
+		}
+		if err := protovalidate.Validate(elements); err != nil {
+			return nil, util.ErrCheck(err)
+		}
+	}
+
+	tx, err := ds.SessionOpenTx(ctx)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+	defer tx.Rollback(ctx)
+
+	canvas := canvasTx{
+		Tx:      tx,
+		topic:   ds.Topic,
+		connId:  connId,
+		userSub: ds.ConcurrentUserSession.GetUserSub(),
+	}
+
+	if _, bookingId, ok := ExchangeCallStyle(ds.Topic); ok {
+		canvas.bookingId = bookingId
+	}
+
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, ds.Topic)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	switch changes.Operation {
+	case CANVAS_OPERATION_SET:
+		for _, element := range elements.GetElements() {
+			if err := canvas.setElement(ctx, element, changes); err != nil {
+				return nil, util.ErrCheck(err)
+			}
+		}
+	case CANVAS_OPERATION_DELETE:
+		for _, element := range elements.GetElements() {
+			if err := canvas.deleteElement(ctx, element, changes); err != nil {
+				return nil, util.ErrCheck(err)
+			}
+		}
+	case CANVAS_OPERATION_UNDO:
+		if err := canvas.undo(ctx, changes); err != nil {
+			return nil, util.ErrCheck(err)
+		}
+	case CANVAS_OPERATION_REDO:
+		if err := canvas.redo(ctx, changes); err != nil {
+			return nil, util.ErrCheck(err)
+		}
+	}
+
+	err = ds.SessionCloseTx(ctx, tx)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return changes, nil
+}
+
+type canvasTx struct {
+	pgx.Tx
+	topic, bookingId, connId, userSub string
+}
+
+// lockElement returns the stored element and the last participant to change it, or nil if it was never stored
+func (c canvasTx) lockElement(ctx context.Context, elementId string) (*types.ICanvasElement, string, error) {
+	var elementType string
+	var properties []byte
+	var version int32
+	var enabled bool
+	var createdSub, lastEditor string
+
+	err := c.QueryRow(ctx, `
+		SELECT element_type, properties, version, enabled, created_sub::TEXT, COALESCE(updated_sub, created_sub)::TEXT
+		FROM dbtable_schema.topic_canvas_elements
+		WHERE topic = $1 AND element_id = $2
+		FOR UPDATE
+	`, c.topic, elementId).Scan(&elementType, &properties, &version, &enabled, &createdSub, &lastEditor)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", util.ErrCheck(err)
+	}
+
+	// an unreadable element holds no kind, so changes to it are rejected until a restore writes a readable state
+	element, err := readCanvasElement(elementType, properties)
+	if err != nil {
+		util.ErrorLog.Println(util.ErrCheck(fmt.Errorf("unreadable canvas element %s on %s: %w", elementId, c.topic, err)))
+		element = &types.ICanvasElement{}
+	}
+
+	element.Id = elementId
+	element.Version = version
+	element.Deleted = !enabled
+	element.CreatedSub = createdSub
+
+	return element, lastEditor, nil
+}
+
+func (c canvasTx) writeElement(ctx context.Context, element *types.ICanvasElement) error {
+	elementType, err := CanvasElementType(element)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	// properties only hold the element's content, the rest has its own columns
+	content := proto.Clone(element).(*types.ICanvasElement)
+	content.Version = 0
+	content.Deleted = false
+	content.CreatedSub = ""
+
+	properties, err := protojson.Marshal(content)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	_, err = c.Exec(ctx, `
+		INSERT INTO dbtable_schema.topic_canvas_elements (created_sub, connection_id, topic, element_id, element_type, properties, version, enabled)
+		VALUES ($1::uuid, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (topic, element_id)
+		DO UPDATE SET properties = EXCLUDED.properties, version = EXCLUDED.version, enabled = EXCLUDED.enabled,
+			updated_sub = $1::uuid, updated_on = TIMEZONE('utc', NOW())
+	`, c.userSub, c.connId, c.topic, element.GetId(), elementType, properties, element.GetVersion(), !element.GetDeleted())
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
+func (c canvasTx) logOperation(ctx context.Context, operation string, before, after *types.ICanvasElement, targetId string) error {
+	beforeState, err := canvasOperationState(before)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	afterState, err := canvasOperationState(after)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	_, err = c.Exec(ctx, `
+		INSERT INTO dbtable_schema.topic_canvas_operations (seq, topic, booking_id, connection_id, element_id, operation, before, after, version, target_id, created_sub)
+		SELECT COALESCE(MAX(seq), 0) + 1, $1, NULLIF($2, '')::uuid, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::uuid, $10::uuid
+		FROM dbtable_schema.topic_canvas_operations
+		WHERE topic = $1
+	`, c.topic, c.bookingId, c.connId, after.GetId(), operation, beforeState, afterState, after.GetVersion(), targetId, c.userSub)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
+func (c canvasTx) setElement(ctx context.Context, element *types.ICanvasElement, changes *CanvasChanges) error {
+	if _, err := CanvasElementType(element); err != nil {
+		return util.ErrCheck(err)
+	}
+
+	current, lastEditor, err := c.lockElement(ctx, element.GetId())
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	if !checkCanvasElementChange(current, element, lastEditor, c.userSub, false) {
+		changes.reject(current, element.GetId())
+		return nil
+	}
+
+	saved := proto.Clone(element).(*types.ICanvasElement)
+	saved.Deleted = false
+
+	operation := CANVAS_OPERATION_CREATE
+	saved.Version = 1
+	saved.CreatedSub = c.userSub
+	if current != nil {
+		operation = CANVAS_OPERATION_UPDATE
+		saved.Version = current.GetVersion() + 1
+		saved.CreatedSub = current.GetCreatedSub()
+	}
+
+	if err := c.writeElement(ctx, saved); err != nil {
+		return util.ErrCheck(err)
+	}
+
+	if err := c.logOperation(ctx, operation, current, saved, ""); err != nil {
+		return util.ErrCheck(err)
+	}
+
+	changes.Applied = append(changes.Applied, saved)
+
+	return nil
+}
+
+func (c canvasTx) deleteElement(ctx context.Context, element *types.ICanvasElement, changes *CanvasChanges) error {
+	current, lastEditor, err := c.lockElement(ctx, element.GetId())
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	if current != nil && current.GetDeleted() {
+		return nil
+	}
+
+	if !checkCanvasElementChange(current, element, lastEditor, c.userSub, true) {
+		changes.reject(current, element.GetId())
+		return nil
+	}
+
+	deleted := proto.Clone(current).(*types.ICanvasElement)
+	deleted.Deleted = true
+	deleted.Version = current.GetVersion() + 1
+
+	if err := c.writeElement(ctx, deleted); err != nil {
+		return util.ErrCheck(err)
+	}
+
+	if err := c.logOperation(ctx, CANVAS_OPERATION_DELETE, current, deleted, ""); err != nil {
+		return util.ErrCheck(err)
+	}
+
+	changes.Applied = append(changes.Applied, deleted)
+
+	return nil
+}
+
+// restoreElement puts an element back to a state from the operation log, deleting it when the state is null.
+// Undo and redo apply over whatever changes were made in between, except bringing back a deleted element, in
+// which case the stored element is returned and nothing changes.
+func (c canvasTx) restoreElement(ctx context.Context, elementId string, state []byte, operation, targetId string) (*types.ICanvasElement, bool, error) {
+	current, _, err := c.lockElement(ctx, elementId)
+	if err != nil {
+		return nil, false, util.ErrCheck(err)
+	}
+
+	if current == nil {
+		return nil, false, util.ErrCheck(errors.New("logged canvas element is missing " + elementId))
+	}
+
+	var lastOperationId, lastTargetId string
+	err = c.QueryRow(ctx, `
+		SELECT id::TEXT, COALESCE(target_id::TEXT, '')
+		FROM dbtable_schema.topic_canvas_operations
+		WHERE topic = $1 AND element_id = $2
+		ORDER BY seq DESC
+		LIMIT 1
+	`, c.topic, elementId).Scan(&lastOperationId, &lastTargetId)
+	if err != nil {
+		return nil, false, util.ErrCheck(err)
+	}
+
+	if !checkCanvasElementRestore(current, state, targetId, lastOperationId, lastTargetId) {
+		return current, false, nil
+	}
+
+	restored := proto.Clone(current).(*types.ICanvasElement)
+	restored.Deleted = true
+	if state != nil {
+		restored = &types.ICanvasElement{}
+		if err := protojson.Unmarshal(state, restored); err != nil {
+			return nil, false, util.ErrCheck(err)
+		}
+	}
+
+	restored.Id = elementId
+	restored.Version = current.GetVersion() + 1
+	restored.CreatedSub = current.GetCreatedSub()
+
+	if err := c.writeElement(ctx, restored); err != nil {
+		return nil, false, util.ErrCheck(err)
+	}
+
+	if err := c.logOperation(ctx, operation, current, restored, targetId); err != nil {
+		return nil, false, util.ErrCheck(err)
+	}
+
+	return restored, true, nil
+}
+
+// undo reverts the participant's latest change which hasn't been undone
+func (c canvasTx) undo(ctx context.Context, changes *CanvasChanges) error {
+	var operationId, elementId string
+	var before []byte
+
+	err := c.QueryRow(ctx, `
+		SELECT id::TEXT, element_id, before
+		FROM dbtable_schema.topic_canvas_operations
+		WHERE topic = $1 AND created_sub = $2::uuid AND operation IN ('create', 'update', 'delete') AND undone = false
+		ORDER BY seq DESC
+		LIMIT 1
+	`, c.topic, c.userSub).Scan(&operationId, &elementId, &before)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	restored, applied, err := c.restoreElement(ctx, elementId, before, CANVAS_OPERATION_UNDO, operationId)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	// the operation is passed over even when it couldn't be applied, so the next undo moves on
+	if err := c.setUndone(ctx, operationId, true); err != nil {
+		return util.ErrCheck(err)
+	}
+
+	if !applied {
+		changes.reject(restored, elementId)
+		return nil
+	}
+
+	changes.Applied = append(changes.Applied, restored)
+
+	return nil
+}
+
+// redo reapplies the participant's earliest undone change, as long as they haven't made a change since
+func (c canvasTx) redo(ctx context.Context, changes *CanvasChanges) error {
+	var operationId, elementId string
+	var after []byte
+
+	err := c.QueryRow(ctx, `
+		SELECT id::TEXT, element_id, after
+		FROM dbtable_schema.topic_canvas_operations
+		WHERE topic = $1 AND created_sub = $2::uuid AND operation IN ('create', 'update', 'delete') AND undone = true
+			AND seq > COALESCE((
+				SELECT MAX(seq)
+				FROM dbtable_schema.topic_canvas_operations
+				WHERE topic = $1 AND created_sub = $2::uuid AND operation IN ('create', 'update', 'delete') AND undone = false
+			), 0)
+		ORDER BY seq ASC
+		LIMIT 1
+	`, c.topic, c.userSub).Scan(&operationId, &elementId, &after)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	restored, applied, err := c.restoreElement(ctx, elementId, after, CANVAS_OPERATION_REDO, operationId)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	// the operation is passed over even when it couldn't be applied, so the next redo moves on
+	if err := c.setUndone(ctx, operationId, false); err != nil {
+		return util.ErrCheck(err)
+	}
+
+	if !applied {
+		changes.reject(restored, elementId)
+		return nil
+	}
+
+	changes.Applied = append(changes.Applied, restored)
+
+	return nil
+}
+
+func (c canvasTx) setUndone(ctx context.Context, operationId string, undone bool) error {
+	_, err := c.Exec(ctx, `
+		UPDATE dbtable_schema.topic_canvas_operations
+		SET undone = $2, updated_sub = $3::uuid, updated_on = TIMEZONE('utc', NOW())
+		WHERE id = $1::uuid
+	`, operationId, undone, c.userSub)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
+// GetTopicElements returns a SET_ELEMENTS message with every element on the topic's canvas
+func (ds DbSession) GetTopicElements(ctx context.Context) ([][]byte, error) {
+	finish := util.RunTimer()
+	defer finish()
+
+	rows, done, err := ds.SessionBatchQuery(ctx, `
+		SELECT element_id, element_type, properties, version, created_sub::TEXT
+		FROM dbtable_schema.topic_canvas_elements
+		WHERE topic = $1 AND enabled = true
+		ORDER BY created_on
+	`, ds.Topic)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+	defer done()
+
+	elements := &types.ICanvasElements{Operation: CANVAS_OPERATION_LOAD}
+
+	for rows.Next() {
+		var elementId, elementType, createdSub string
+		var properties []byte
+		var version int32
+		if err := rows.Scan(&elementId, &elementType, &properties, &version, &createdSub); err != nil {
+			return nil, util.ErrCheck(err)
+		}
+
+		// one bad row shouldn't keep the rest of the canvas from loading
+		element, err := readCanvasElement(elementType, properties)
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(fmt.Errorf("unreadable canvas element %s on %s: %w", elementId, ds.Topic, err)))
+			continue
+		}
+
+		element.Id = elementId
+		element.Version = version
+		element.CreatedSub = createdSub
+
+		elements.Elements = append(elements.Elements, element)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	if len(elements.Elements) == 0 {
+		return nil, nil
+	}
+
+	elementsBytes, err := protojson.Marshal(elements)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return [][]byte{util.GenerateMessage(util.DefaultPadding, &types.SocketMessage{
+		Topic:   ds.Topic,
+		Action:  types.SocketActions_SET_ELEMENTS,
+		Payload: string(elementsBytes),
+	})}, nil
+}
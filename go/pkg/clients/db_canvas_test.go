@@ -0,0 +1,165 @@
+package clients
+
+import (
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func testCanvasBox(version int32, createdSub string) *types.ICanvasElement {
+	return &types.ICanvasElement{
+		Id:         "a3f6a3d2-4b6e-4c1e-9a7c-1f2b3c4d5e6f",
+		Version:    version,
+		CreatedSub: createdSub,
+		Box:        &types.ICanvasBox{X: 10, Y: 20, Width: 100, Height: 50, Text: "note"},
+	}
+}
+
+func TestCanvasElementType(t *testing.T) {
+	tests := []struct {
+		name    string
+		element *types.ICanvasElement
+		want    string
+		wantErr bool
+	}{
+		{"box", &types.ICanvasElement{Box: &types.ICanvasBox{}}, "box", false},
+		{"line", &types.ICanvasElement{Line: &types.ICanvasLine{}}, "line", false},
+		{"shape", &types.ICanvasElement{Shape: &types.ICanvasShape{}}, "shape", false},
+		{"image", &types.ICanvasElement{Image: &types.ICanvasImage{}}, "image", false},
+		{"sticky note", &types.ICanvasElement{StickyNote: &types.ICanvasStickyNote{}}, "sticky_note", false},
+		{"no kind", &types.ICanvasElement{}, "", true},
+		{"two kinds", &types.ICanvasElement{Box: &types.ICanvasBox{}, Line: &types.ICanvasLine{}}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanvasElementType(tt.element)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CanvasElementType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CanvasElementType() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkCanvasElementChange(t *testing.T) {
+	deleted := testCanvasBox(3, "creator")
+	deleted.Deleted = true
+
+	tests := []struct {
+		name       string
+		current    *types.ICanvasElement
+		element    *types.ICanvasElement
+		lastEditor string
+		userSub    string
+		deleting   bool
+		want       bool
+	}{
+		{"create", nil, testCanvasBox(0, ""), "", "creator", false, true},
+		{"update a missing element", nil, testCanvasBox(2, ""), "", "creator", false, false},
+		{"delete a missing element", nil, testCanvasBox(2, ""), "", "creator", true, false},
+		{"update current version", testCanvasBox(2, "creator"), testCanvasBox(2, ""), "creator", "other", false, true},
+		{"update stale version", testCanvasBox(3, "creator"), testCanvasBox(2, ""), "other", "creator", false, false},
+		{"update own in flight version", testCanvasBox(3, "creator"), testCanvasBox(2, ""), "creator", "creator", false, true},
+		{"update own unacknowledged create", testCanvasBox(1, "creator"), testCanvasBox(0, ""), "creator", "creator", false, true},
+		{"update future version", testCanvasBox(2, "creator"), testCanvasBox(5, ""), "creator", "creator", false, false},
+		{"change element type", testCanvasBox(2, "creator"), &types.ICanvasElement{Version: 2, Line: &types.ICanvasLine{}}, "creator", "creator", false, false},
+		{"update deleted element", deleted, testCanvasBox(3, ""), "creator", "creator", false, false},
+		{"delete own element", testCanvasBox(4, "creator"), testCanvasBox(2, ""), "other", "creator", true, true},
+		{"delete another's element", testCanvasBox(2, "creator"), testCanvasBox(2, ""), "creator", "other", true, false},
+		{"delete own unreadable element", &types.ICanvasElement{Version: 2, CreatedSub: "creator"}, testCanvasBox(2, ""), "creator", "creator", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkCanvasElementChange(tt.current, tt.element, tt.lastEditor, tt.userSub, tt.deleting); got != tt.want {
+				t.Errorf("checkCanvasElementChange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkCanvasElementRestore(t *testing.T) {
+	deleted := testCanvasBox(4, "creator")
+	deleted.Deleted = true
+	state := []byte(`{"box":{"x":1,"y":1,"width":10,"height":10}}`)
+
+	tests := []struct {
+		name            string
+		current         *types.ICanvasElement
+		state           []byte
+		lastOperationId string
+		lastTargetId    string
+		want            bool
+	}{
+		{"undo an update", testCanvasBox(3, "creator"), state, "op-3", "", true},
+		{"undo a create", testCanvasBox(1, "creator"), nil, "op-1", "", true},
+		{"undo an update after the creator deleted the element", deleted, state, "op-3", "", false},
+		{"undo an update after another undo deleted the element", deleted, state, "op-4", "op-3", false},
+		{"undo own delete", deleted, state, "op-2", "", true},
+		{"redo a create which was undone", deleted, state, "op-3", "op-2", true},
+		{"delete an already deleted element", deleted, nil, "op-3", "", true},
+		{"undo a create of an unreadable element", &types.ICanvasElement{Version: 2, CreatedSub: "creator"}, nil, "op-2", "", false},
+		{"undo over an unreadable element", &types.ICanvasElement{Version: 2, CreatedSub: "creator"}, state, "op-2", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkCanvasElementRestore(tt.current, tt.state, "op-2", tt.lastOperationId, tt.lastTargetId); got != tt.want {
+				t.Errorf("checkCanvasElementRestore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readCanvasElement(t *testing.T) {
+	typed, err := protojson.Marshal(testCanvasBox(0, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		elementType string
+		properties  string
+		want        *types.ICanvasElement
+		wantErr     bool
+	}{
+		{
+			name:        "typed",
+			elementType: "box",
+			properties:  string(typed),
+			want:        testCanvasBox(0, ""),
+		},
+		{
+			name:        "legacy box",
+			elementType: "box",
+			properties:  `{"id":"b1","x":10,"y":20,"width":100,"height":50,"zIndex":1,"color":"#fff","text":"note"}`,
+			want:        &types.ICanvasElement{Box: &types.ICanvasBox{X: 10, Y: 20, Width: 100, Height: 50, Color: "#fff", Text: "note"}},
+		},
+		{
+			name:        "legacy line",
+			elementType: "line",
+			properties:  `{"id":"c1-1","lines":[{"startPoint":{"x":1,"y":2},"endPoint":{"x":3,"y":4}},{"startPoint":{"x":3,"y":4},"endPoint":{"x":5,"y":6}}],"settings":{"stroke":"red","highlight":true}}`,
+			want: &types.ICanvasElement{Line: &types.ICanvasLine{
+				Points:    []*types.ICanvasPoint{{X: 1, Y: 2}, {X: 3, Y: 4}, {X: 5, Y: 6}},
+				Stroke:    "red",
+				Highlight: true,
+			}},
+		},
+		{name: "legacy line without segments", elementType: "line", properties: `{"lines":[]}`, wantErr: true},
+		{name: "unknown type", elementType: "circle", properties: `{"x":1}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readCanvasElement(tt.elementType, []byte(tt.properties))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readCanvasElement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !proto.Equal(got, tt.want) {
+				t.Errorf("readCanvasElement() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
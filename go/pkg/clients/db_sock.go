@@ -47,6 +47,12 @@ var (
 	exchangeWhiteboardNumCheck = "exchange/" + fmt.Sprint(types.ExchangeActions_EXCHANGE_WHITEBOARD.Number())
 )
 
+const (
+	ExchangeCallStyleText       = "text"
+	ExchangeCallStyleCall       = "call"
+	ExchangeCallStyleWhiteboard = "whiteboard"
+)
+
 var socketAllowanceExec = `
 	SELECT allowed
 	FROM dbfunc_schema.session_query_13($1, $2, $3, $4, $5, $6)
@@ -174,14 +180,17 @@ func (ds DbSession) StoreTopicMessage(ctx context.Context, connId string, messag
 	defer finish()
 	message.Store = false
 	message.Historical = true
-	message.Timestamp = time.Now().Local().String()
+	if message.Timestamp == "" {
+		message.Timestamp = time.Now().Local().String()
+	}
 
 	switch message.Action {
 	case types.SocketActions_TEXT:
 		return ds.storeChatText(ctx, connId, message)
 
 	case types.SocketActions_SET_BOX, types.SocketActions_DRAW_LINES:
-		return ds.upsertCanvasElement(ctx, connId, message)
+		// canvas state is only kept through the element actions, see ApplyCanvasAction
+		return nil
 
 	default:
 		return ds.storeChatText(ctx, connId, message)
@@ -189,90 +198,16 @@ func (ds DbSession) StoreTopicMessage(ctx context.Context, connId string, messag
 }
 
 func (ds DbSession) storeChatText(ctx context.Context, connId string, message *types.SocketMessage) error {
+	_, bookingId, _ := ExchangeCallStyle(message.Topic)
+
 	_, err := ds.SessionBatchExec(ctx, `
-		INSERT INTO dbtable_schema.topic_messages (created_sub, connection_id, topic, message)
-		VALUES ($1, $2, $3, $4)
-	`, ds.ConcurrentUserSession.GetUserSub(), connId, message.Topic, util.GenerateMessage(util.DefaultPadding, message))
+		INSERT INTO dbtable_schema.topic_messages (id, created_sub, connection_id, topic, booking_id, message)
+		VALUES (COALESCE(NULLIF($1, '')::uuid, gen_random_uuid()), $2, $3, $4, NULLIF($5, '')::uuid, $6)
+	`, topicMessageId(message.Payload), ds.ConcurrentUserSession.GetUserSub(), connId, message.Topic, bookingId, util.GenerateMessage(util.DefaultPadding, message))
 
 	return util.ErrCheck(err)
 }
 
-type BoxPayload struct {
-	Boxes []map[string]any
-}
-
-func (ds DbSession) upsertCanvasElement(ctx context.Context, connId string, message *types.SocketMessage) error {
-
-	elementQuery := `
-		INSERT INTO dbtable_schema.topic_canvas_elements (created_sub, connection_id, topic, element_id, element_type, properties, updated_sub, updated_on)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
-		ON CONFLICT (topic, element_id) 
-		DO UPDATE SET properties = EXCLUDED.properties, updated_sub = EXCLUDED.updated_sub, updated_on = NOW();
-	`
-
-	userSub := ds.ConcurrentUserSession.GetUserSub()
-
-	if message.Action == types.SocketActions_SET_BOX {
-		var p BoxPayload
-		if err := json.Unmarshal([]byte(message.Payload), &p); err != nil {
-			return util.ErrCheck(err)
-		}
-
-		for _, box := range p.Boxes {
-			boxId := fmt.Sprintf("%v", box["id"])
-			textStr := fmt.Sprintf("%v", box["text"])
-
-			if textStr == "" {
-				_, err := ds.SessionBatchExec(ctx, `
-					DELETE FROM dbtable_schema.topic_canvas_elements
-					WHERE element_id = $1
-				`, boxId)
-				if err != nil {
-					return util.ErrCheck(err)
-				}
-			} else {
-				boxJSON, _ := json.Marshal(box)
-				_, err := ds.SessionBatchExec(ctx, elementQuery, userSub, connId, message.GetTopic(), boxId, "box", boxJSON, userSub)
-				if err != nil {
-					return util.ErrCheck(err)
-				}
-			}
-		}
-		return nil
-	}
-
-	if message.Action == types.SocketActions_DRAW_LINES {
-		var payload map[string]any
-		if err := json.Unmarshal([]byte(message.Payload), &payload); err != nil {
-			return util.ErrCheck(err)
-		}
-
-		lines, isSlice := payload["lines"].([]any)
-
-		if !isSlice || len(lines) == 0 {
-			_, err := ds.SessionBatchExec(ctx, `
-				DELETE FROM dbtable_schema.topic_canvas_elements
-				WHERE topic = $1 AND element_type = 'line'
-			`, message.GetTopic())
-			if err != nil {
-				return util.ErrCheck(err)
-			}
-		} else {
-			lineId := fmt.Sprintf("%s-%d", connId, time.Now().UnixNano())
-			payload["id"] = lineId
-
-			lineJSON, _ := json.Marshal(payload)
-
-			_, err := ds.SessionBatchExec(ctx, elementQuery, userSub, connId, message.GetTopic(), lineId, "line", lineJSON, userSub)
-			if err != nil {
-				return util.ErrCheck(err)
-			}
-		}
-	}
-
-	return nil
-}
-
 func (ds DbSession) GetTopicMessages(ctx context.Context, page, pageSize int) ([][]byte, error) {
 	finish := util.RunTimer()
 	defer finish()
@@ -280,7 +215,7 @@ func (ds DbSession) GetTopicMessages(ctx context.Context, page, pageSize int) ([
 
 	paginatedQuery := util.WithPagination(`
 		SELECT message FROM dbtable_schema.topic_messages
-		WHERE topic = $1
+		WHERE topic = $1 AND enabled = true
 		ORDER BY created_on DESC
 	`, page, pageSize)
 
@@ -309,58 +244,203 @@ func (ds DbSession) GetTopicMessages(ctx context.Context, page, pageSize int) ([
 	return messages, nil
 }
 
-func (ds DbSession) GetTopicElements(ctx context.Context) ([][]byte, error) {
+type exchangeTextPayload struct {
+	Message string `json:"message"`
+	Style   string `json:"style"`
+}
+
+// Collects the text messages stored for an exchange topic since its last transcript into a new
+// booking transcript. Called when the last participant leaves the exchange.
+func (ds DbSession) StoreExchangeTranscript(ctx context.Context) error {
 	finish := util.RunTimer()
 	defer finish()
 
+	exchangeContext, bookingId, err := util.SplitColonJoined(ds.Topic)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	if exchangeContext != exchangeTextNumCheck && exchangeContext != exchangeCallNumCheck {
+		return nil
+	}
+
 	rows, done, err := ds.SessionBatchQuery(ctx, `
-		SELECT element_type, properties FROM dbtable_schema.topic_canvas_elements
-		WHERE topic = $1
+		SELECT tm.created_sub::TEXT, tm.created_on::TEXT, tm.message
+		FROM dbtable_schema.topic_messages tm
+		WHERE tm.topic = $1 AND tm.enabled = true AND tm.created_on > COALESCE((
+			SELECT MAX(bt.ended_on)
+			FROM dbtable_schema.booking_transcripts bt
+			WHERE bt.topic = $1
+		), '-infinity'::TIMESTAMP)
+		ORDER BY tm.created_on
 	`, ds.Topic)
 	if err != nil {
-		return nil, util.ErrCheck(err)
+		return util.ErrCheck(err)
 	}
-	defer done()
 
-	var allBoxes []map[string]any
-	var allLines []map[string]any
+	var startedOn, endedOn string
+	transcriptMessages := make([]*types.ITranscriptMessage, 0)
 
 	for rows.Next() {
-		var elType string
-		var elProps map[string]any
-		if err := rows.Scan(&elType, &elProps); err != nil {
-			return nil, util.ErrCheck(err)
+		var sender, createdOn string
+		var messageBytes []byte
+		if err := rows.Scan(&sender, &createdOn, &messageBytes); err != nil {
+			done()
+			return util.ErrCheck(err)
+		}
+
+		if startedOn == "" {
+			startedOn = createdOn
+		}
+		endedOn = createdOn
+
+		message, err := util.ParseSocketMessage(util.DefaultPadding, messageBytes)
+		if err != nil || message.Action != types.SocketActions_TEXT {
+			continue
 		}
 
-		if elType == "box" {
-			allBoxes = append(allBoxes, elProps)
-		} else if elType == "line" {
-			allLines = append(allLines, elProps)
+		var payload exchangeTextPayload
+		if err := json.Unmarshal([]byte(message.Payload), &payload); err != nil || payload.Message == "" {
+			continue
 		}
+
+		transcriptMessages = append(transcriptMessages, &types.ITranscriptMessage{
+			Words:     payload.Message,
+			Style:     payload.Style,
+			Sender:    sender,
+			Timestamp: createdOn,
+		})
+	}
+	done()
+
+	if len(transcriptMessages) == 0 {
+		return nil
 	}
 
-	var messages [][]byte
+	messagesJson, err := json.Marshal(transcriptMessages)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
 
-	if len(allBoxes) > 0 {
-		boxesBytes, _ := json.Marshal(map[string]any{"boxes": allBoxes})
+	_, err = ds.SessionBatchExec(ctx, `
+		INSERT INTO dbtable_schema.booking_transcripts (booking_id, topic, messages, started_on, ended_on, created_sub)
+		VALUES ($1::uuid, $2, $3::jsonb, $4::timestamp, $5::timestamp, $6::uuid)
+		ON CONFLICT (topic, started_on) DO NOTHING
+	`, bookingId, ds.Topic, messagesJson, startedOn, endedOn, ds.ConcurrentUserSession.GetUserSub())
+	if err != nil {
+		return util.ErrCheck(err)
+	}
 
-		sm := &types.SocketMessage{
-			Topic:   ds.Topic,
-			Action:  types.SocketActions_SET_BOX,
-			Payload: string(boxesBytes),
-		}
-		messages = append(messages, util.GenerateMessage(util.DefaultPadding, sm))
+	return nil
+}
+
+// ExchangeCallStyle returns the call log style and booking id of an exchange topic,
+// or false when the topic isn't an exchange
+func ExchangeCallStyle(topic string) (string, string, bool) {
+	exchangeContext, bookingId, err := util.SplitColonJoined(topic)
+	if err != nil {
+		return "", "", false
+	}
+
+	switch exchangeContext {
+	case exchangeTextNumCheck:
+		return ExchangeCallStyleText, bookingId, true
+	case exchangeCallNumCheck:
+		return ExchangeCallStyleCall, bookingId, true
+	case exchangeWhiteboardNumCheck:
+		return ExchangeCallStyleWhiteboard, bookingId, true
+	}
+
+	return "", "", false
+}
+
+// Opens a call log row for the session user's socket on the exchange topic, unless one is already open.
+// The node holding the socket is kept so rows it leaves open can be closed if it goes away.
+func (ds DbSession) OpenExchangeCallLog(ctx context.Context, socketId, nodeId string) error {
+	finish := util.RunTimer()
+	defer finish()
+
+	style, bookingId, ok := ExchangeCallStyle(ds.Topic)
+	if !ok {
+		return nil
 	}
 
-	for _, lineProps := range allLines {
-		lineBytes, _ := json.Marshal(lineProps)
-		sm := &types.SocketMessage{
-			Topic:   ds.Topic,
-			Action:  types.SocketActions_DRAW_LINES,
-			Payload: string(lineBytes),
+	_, err := ds.SessionBatchExec(ctx, `
+		INSERT INTO dbtable_schema.exchange_call_log (booking_id, group_id, style, socket_id, node_id, connected, created_sub)
+		SELECT $1::uuid, $2::uuid, $3, $5, $6, TIMEZONE('utc', NOW()), $4::uuid
+		WHERE NOT EXISTS (
+			SELECT 1 FROM dbtable_schema.exchange_call_log
+			WHERE socket_id = $5 AND booking_id = $1::uuid AND style = $3 AND created_sub = $4::uuid AND disconnected IS NULL
+		)
+	`, bookingId, ds.ConcurrentUserSession.GetGroupId(), style, ds.ConcurrentUserSession.GetUserSub(), socketId, nodeId)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
+// Closes the open call log row of the session user's socket on the exchange topic, leaving
+// any other sockets the user has on the exchange connected
+func (ds DbSession) CloseExchangeCallLog(ctx context.Context, socketId string) error {
+	finish := util.RunTimer()
+	defer finish()
+
+	style, bookingId, ok := ExchangeCallStyle(ds.Topic)
+	if !ok {
+		return nil
+	}
+
+	_, err := ds.SessionBatchExec(ctx, `
+		UPDATE dbtable_schema.exchange_call_log
+		SET disconnected = TIMEZONE('utc', NOW()), updated_sub = $3::uuid, updated_on = TIMEZONE('utc', NOW())
+		WHERE socket_id = $4 AND booking_id = $1::uuid AND style = $2 AND created_sub = $3::uuid AND disconnected IS NULL
+	`, bookingId, style, ds.ConcurrentUserSession.GetUserSub(), socketId)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
+// Lists the nodes holding sockets with open call log rows, for a worker session
+func (ds DbSession) GetExchangeCallLogNodes(ctx context.Context) ([]string, error) {
+	rows, done, err := ds.SessionBatchQuery(ctx, `
+		SELECT DISTINCT node_id
+		FROM dbtable_schema.exchange_call_log
+		WHERE disconnected IS NULL AND node_id IS NOT NULL
+	`)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+	defer done()
+
+	var nodeIds []string
+	for rows.Next() {
+		var nodeId string
+		if err := rows.Scan(&nodeId); err != nil {
+			return nil, util.ErrCheck(err)
 		}
-		messages = append(messages, util.GenerateMessage(util.DefaultPadding, sm))
+		nodeIds = append(nodeIds, nodeId)
 	}
 
-	return messages, nil
+	return nodeIds, nil
+}
+
+// Closes the call log rows left open by nodes which went away without closing them, for a worker session
+func (ds DbSession) CloseNodeExchangeCallLogs(ctx context.Context, nodeIds []string) (int64, error) {
+	if len(nodeIds) == 0 {
+		return 0, nil
+	}
+
+	tag, err := ds.SessionBatchExec(ctx, `
+		UPDATE dbtable_schema.exchange_call_log
+		SET disconnected = TIMEZONE('utc', NOW()), updated_on = TIMEZONE('utc', NOW())
+		WHERE disconnected IS NULL AND node_id = ANY($1::TEXT[])
+	`, nodeIds)
+	if err != nil {
+		return 0, util.ErrCheck(err)
+	}
+
+	return tag.RowsAffected(), nil
 }
@@ -320,6 +320,97 @@ func TestDbSession_StoreTopicMessage(t *testing.T) {
 	}
 }
 
+func TestDbSession_StoreExchangeTranscript(t *testing.T) {
+	type args struct {
+		ctx context.Context
+	}
+	tests := []struct {
+		name    string
+		ds      DbSession
+		args    args
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.ds.StoreExchangeTranscript(tt.args.ctx); (err != nil) != tt.wantErr {
+				t.Errorf("DbSession.StoreExchangeTranscript(%v) error = %v, wantErr %v", tt.args.ctx, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExchangeCallStyle(t *testing.T) {
+	bookingId := "0195ec07-e989-71ac-a0c4-f6a08d1f93f6"
+	tests := []struct {
+		name          string
+		topic         string
+		wantStyle     string
+		wantBookingId string
+		wantOk        bool
+	}{
+		{name: "text exchange", topic: "exchange/0:" + bookingId, wantStyle: "text", wantBookingId: bookingId, wantOk: true},
+		{name: "call exchange", topic: "exchange/1:" + bookingId, wantStyle: "call", wantBookingId: bookingId, wantOk: true},
+		{name: "whiteboard exchange", topic: "exchange/2:" + bookingId, wantStyle: "whiteboard", wantBookingId: bookingId, wantOk: true},
+		{name: "unknown exchange action", topic: "exchange/9:" + bookingId},
+		{name: "other topic", topic: "group/0:" + bookingId},
+		{name: "malformed topic", topic: "exchange/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style, bookingId, ok := ExchangeCallStyle(tt.topic)
+			if style != tt.wantStyle || bookingId != tt.wantBookingId || ok != tt.wantOk {
+				t.Errorf("ExchangeCallStyle(%v) = %v, %v, %v, want %v, %v, %v", tt.topic, style, bookingId, ok, tt.wantStyle, tt.wantBookingId, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestDbSession_OpenExchangeCallLog(t *testing.T) {
+	type args struct {
+		ctx      context.Context
+		socketId string
+	}
+	tests := []struct {
+		name    string
+		ds      DbSession
+		args    args
+		wantErr bool
+	}{
+		{name: "ignores topics other than exchanges", ds: DbSession{Topic: "group/0:0195ec07-e989-71ac-a0c4-f6a08d1f93f6"}, args: args{ctx: context.Background(), socketId: "sub:conn"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.ds.OpenExchangeCallLog(tt.args.ctx, tt.args.socketId, "node"); (err != nil) != tt.wantErr {
+				t.Errorf("DbSession.OpenExchangeCallLog(%v, %v) error = %v, wantErr %v", tt.args.ctx, tt.args.socketId, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDbSession_CloseExchangeCallLog(t *testing.T) {
+	type args struct {
+		ctx      context.Context
+		socketId string
+	}
+	tests := []struct {
+		name    string
+		ds      DbSession
+		args    args
+		wantErr bool
+	}{
+		{name: "ignores topics other than exchanges", ds: DbSession{Topic: "group/0:0195ec07-e989-71ac-a0c4-f6a08d1f93f6"}, args: args{ctx: context.Background(), socketId: "sub:conn"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.ds.CloseExchangeCallLog(tt.args.ctx, tt.args.socketId); (err != nil) != tt.wantErr {
+				t.Errorf("DbSession.CloseExchangeCallLog(%v, %v) error = %v, wantErr %v", tt.args.ctx, tt.args.socketId, err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestDbSession_GetTopicMessages(t *testing.T) {
 	type args struct {
 		ctx      context.Context
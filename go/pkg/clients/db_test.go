@@ -212,6 +212,71 @@ func TestDatabase_BuildInserts(t *testing.T) {
 	}
 }
 
+func TestDatabaseClient_ListenKioskScheduleChanges(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		changed chan<- struct{}
+	}
+	tests := []struct {
+		name    string
+		dc      *DatabaseClient
+		args    args
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dc.ListenKioskScheduleChanges(tt.args.ctx, tt.args.changed); (err != nil) != tt.wantErr {
+				t.Errorf("DatabaseClient.ListenKioskScheduleChanges(%v, %v) error = %v, wantErr %v", tt.args.ctx, tt.args.changed, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDatabaseClient_ListenIdentityOutbox(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		changed chan<- struct{}
+	}
+	tests := []struct {
+		name    string
+		dc      *DatabaseClient
+		args    args
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dc.ListenIdentityOutbox(tt.args.ctx, tt.args.changed); (err != nil) != tt.wantErr {
+				t.Errorf("DatabaseClient.ListenIdentityOutbox(%v, %v) error = %v, wantErr %v", tt.args.ctx, tt.args.changed, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDatabaseClient_RefreshKioskSchedule(t *testing.T) {
+	type args struct {
+		ctx context.Context
+	}
+	tests := []struct {
+		name    string
+		dc      *DatabaseClient
+		args    args
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dc.RefreshKioskSchedule(tt.args.ctx); (err != nil) != tt.wantErr {
+				t.Errorf("DatabaseClient.RefreshKioskSchedule(%v) error = %v, wantErr %v", tt.args.ctx, err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestDatabaseClient_OpenPoolSessionGroupTx(t *testing.T) {
 	type args struct {
 		ctx     context.Context
@@ -0,0 +1,229 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/protovalidate-go"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var errEmptyTopicMessage = errors.New("edited message is empty")
+
+// StampTopicMessage gives a message which will be stored its id and timestamp before it's sent, so
+// every participant refers to it the same way. Ids are time ordered, so later messages have greater ids.
+func StampTopicMessage(message *types.SocketMessage) error {
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(message.Payload), &payload); err != nil || payload == nil {
+		return util.ErrCheck(errors.New("topic message payload must be an object"))
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	payload["id"] = id.String()
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	message.Payload = string(payloadBytes)
+	message.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+
+	return nil
+}
+
+// topicMessageId is the id given by StampTopicMessage, if any
+func topicMessageId(payload string) string {
+	var p struct {
+		Id string `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(payload), &p); err != nil {
+		return ""
+	}
+	return p.Id
+}
+
+// editTopicMessagePayload replaces the text of a stored message payload and marks it edited
+func editTopicMessagePayload(payload, text string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", errEmptyTopicMessage
+	}
+
+	var p map[string]any
+	if err := json.Unmarshal([]byte(payload), &p); err != nil || p == nil {
+		return "", errors.New("stored topic message payload is not an object")
+	}
+
+	p["message"] = text
+	p["edited"] = true
+
+	payloadBytes, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+
+	return string(payloadBytes), nil
+}
+
+func ParseTopicMessageChange(payload string) (*types.ITopicMessageChange, error) {
+	change := &types.ITopicMessageChange{}
+	if err := protojson.Unmarshal([]byte(payload), change); err != nil {
+		return nil, util.ErrCheck(err)
+	}
+	if err := protovalidate.Validate(change); err != nil {
+		return nil, util.ErrCheck(err)
+	}
+	change.Scid = ""
+	return change, nil
+}
+
+// EditTopicMessage changes the text of one of the session user's messages, returning false when they have no such message
+func (ds DbSession) EditTopicMessage(ctx context.Context, change *types.ITopicMessageChange) (bool, error) {
+	finish := util.RunTimer()
+	defer finish()
+
+	tx, err := ds.SessionOpenTx(ctx)
+	if err != nil {
+		return false, util.ErrCheck(err)
+	}
+	defer tx.Rollback(ctx)
+
+	var messageBytes []byte
+	err = tx.QueryRow(ctx, `
+		SELECT message FROM dbtable_schema.topic_messages
+		WHERE id = $1::uuid AND topic = $2 AND created_sub = $3::uuid AND enabled = true
+		FOR UPDATE
+	`, change.GetId(), ds.Topic, ds.ConcurrentUserSession.GetUserSub()).Scan(&messageBytes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, util.ErrCheck(err)
+	}
+
+	message, err := util.ParseSocketMessage(util.DefaultPadding, messageBytes)
+	if err != nil {
+		return false, util.ErrCheck(err)
+	}
+
+	message.Payload, err = editTopicMessagePayload(message.Payload, change.GetMessage())
+	if err != nil {
+		return false, util.ErrCheck(err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE dbtable_schema.topic_messages
+		SET message = $2, updated_sub = $3::uuid, updated_on = TIMEZONE('utc', NOW())
+		WHERE id = $1::uuid
+	`, change.GetId(), util.GenerateMessage(util.DefaultPadding, message), ds.ConcurrentUserSession.GetUserSub())
+	if err != nil {
+		return false, util.ErrCheck(err)
+	}
+
+	err = ds.SessionCloseTx(ctx, tx)
+	if err != nil {
+		return false, util.ErrCheck(err)
+	}
+
+	return true, nil
+}
+
+// DeleteTopicMessage removes one of the session user's messages from the topic, returning false when they have no such message
+func (ds DbSession) DeleteTopicMessage(ctx context.Context, id string) (bool, error) {
+	finish := util.RunTimer()
+	defer finish()
+
+	tag, err := ds.SessionBatchExec(ctx, `
+		UPDATE dbtable_schema.topic_messages
+		SET enabled = false, updated_sub = $3::uuid, updated_on = TIMEZONE('utc', NOW())
+		WHERE id = $1::uuid AND topic = $2 AND created_sub = $3::uuid AND enabled = true
+	`, id, ds.Topic, ds.ConcurrentUserSession.GetUserSub())
+	if err != nil {
+		return false, util.ErrCheck(err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReadTopicMessages marks the topic read up to a message for the session user. Receipts only move forward,
+// so false is returned when the user has already seen the message or a newer one.
+func (ds DbSession) ReadTopicMessages(ctx context.Context, id string) (bool, error) {
+	finish := util.RunTimer()
+	defer finish()
+
+	tag, err := ds.SessionBatchExec(ctx, `
+		INSERT INTO dbtable_schema.topic_message_reads (topic, booking_id, last_read_id, created_sub)
+		SELECT tm.topic, tm.booking_id, tm.id, $3::uuid
+		FROM dbtable_schema.topic_messages tm
+		WHERE tm.id = $1::uuid AND tm.topic = $2 AND tm.enabled = true
+		ON CONFLICT (topic, created_sub) DO UPDATE
+		SET last_read_id = EXCLUDED.last_read_id, updated_sub = EXCLUDED.created_sub, updated_on = TIMEZONE('utc', NOW())
+		WHERE (
+			SELECT created_on FROM dbtable_schema.topic_messages WHERE id = EXCLUDED.last_read_id
+		) > (
+			SELECT created_on FROM dbtable_schema.topic_messages WHERE id = dbtable_schema.topic_message_reads.last_read_id
+		)
+	`, id, ds.Topic, ds.ConcurrentUserSession.GetUserSub())
+	if err != nil {
+		return false, util.ErrCheck(err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetTopicMessageReceipts adds each participant's newest seen message and unread count
+func (ds DbSession) GetTopicMessageReceipts(ctx context.Context, participants map[string]*types.SocketParticipant) error {
+	finish := util.RunTimer()
+	defer finish()
+
+	if len(participants) == 0 {
+		return nil
+	}
+
+	subs := make([]string, 0, len(participants))
+	for sub := range participants {
+		subs = append(subs, sub)
+	}
+
+	rows, done, err := ds.SessionBatchQuery(ctx, `
+		SELECT p.sub::TEXT, COALESCE(r.last_read_id::TEXT, ''), (
+			SELECT COUNT(*)
+			FROM dbtable_schema.topic_messages tm
+			WHERE tm.topic = $1 AND tm.enabled = true AND tm.created_sub <> p.sub
+				AND tm.created_on > COALESCE(lr.created_on, '-infinity'::TIMESTAMP)
+		)::INTEGER
+		FROM UNNEST($2::uuid[]) p(sub)
+		LEFT JOIN dbtable_schema.topic_message_reads r ON r.topic = $1 AND r.created_sub = p.sub
+		LEFT JOIN dbtable_schema.topic_messages lr ON lr.id = r.last_read_id
+	`, ds.Topic, subs)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+	defer done()
+
+	for rows.Next() {
+		var sub, lastReadId string
+		var unreadCount int32
+		if err := rows.Scan(&sub, &lastReadId, &unreadCount); err != nil {
+			return util.ErrCheck(err)
+		}
+
+		if participant, ok := participants[sub]; ok {
+			participant.LastReadId = lastReadId
+			participant.UnreadCount = unreadCount
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package clients
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+)
+
+func TestStampTopicMessage(t *testing.T) {
+	first := &types.SocketMessage{Action: types.SocketActions_TEXT, Payload: `{"message":"hello","style":"written"}`}
+	if err := StampTopicMessage(first); err != nil {
+		t.Fatalf("StampTopicMessage() error = %v", err)
+	}
+
+	second := &types.SocketMessage{Action: types.SocketActions_TEXT, Payload: `{"message":"again","style":"written"}`}
+	if err := StampTopicMessage(second); err != nil {
+		t.Fatalf("StampTopicMessage() error = %v", err)
+	}
+
+	firstId, secondId := topicMessageId(first.Payload), topicMessageId(second.Payload)
+	if firstId == "" || first.Timestamp == "" {
+		t.Fatalf("StampTopicMessage() payload = %s timestamp = %s, want an id and timestamp", first.Payload, first.Timestamp)
+	}
+	if secondId <= firstId {
+		t.Errorf("StampTopicMessage() ids %s then %s, want later ids to be greater", firstId, secondId)
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(first.Payload), &payload); err != nil || payload["message"] != "hello" || payload["style"] != "written" {
+		t.Errorf("StampTopicMessage() payload = %s, want the original fields kept", first.Payload)
+	}
+
+	for _, invalid := range []string{"", "PING", `"text"`, "null"} {
+		if err := StampTopicMessage(&types.SocketMessage{Payload: invalid}); err == nil {
+			t.Errorf("StampTopicMessage(%q) error = nil, want an error", invalid)
+		}
+	}
+}
+
+func Test_editTopicMessagePayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{"edit", `{"id":"0192","message":"helo","style":"written"}`, "hello", `{"edited":true,"id":"0192","message":"hello","style":"written"}`, false},
+		{"edit again", `{"edited":true,"id":"0192","message":"hello","style":"written"}`, "hello!", `{"edited":true,"id":"0192","message":"hello!","style":"written"}`, false},
+		{"empty text", `{"id":"0192","message":"helo","style":"written"}`, "  ", "", true},
+		{"not an object", `"helo"`, "hello", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := editTopicMessagePayload(tt.payload, tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("editTopicMessagePayload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("editTopicMessagePayload() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+var (
+	ErrIdentityNotFound = errors.New("identity resource not found")
+	ErrIdentityConflict = errors.New("identity resource already exists")
+)
+
+// IdentityProvider manages the groups, role subgroups, role mappings and users backing
+// app sessions. Each group has one subgroup per group role, whose role mappings grant the
+// site roles of its members. userSub identifies the user making the change.
+type IdentityProvider interface {
+	GetGroupAdminRoles(ctx context.Context, userSub string) ([]*types.KeycloakRole, error)
+	GetGroupSiteRoles(ctx context.Context, userSub, groupId string) ([]*types.ClientRoleMappingRole, error)
+	UpdateUser(ctx context.Context, userSub, id, firstName, lastName string) error
+	DeleteUser(ctx context.Context, userSub string) error
+	CreateGroup(ctx context.Context, userSub, name string) (*types.KeycloakGroup, error)
+	GetGroup(ctx context.Context, userSub, id string) (*types.KeycloakGroup, error)
+	GetGroupSubGroups(ctx context.Context, userSub, groupId string) ([]*types.KeycloakGroup, error)
+	DeleteGroup(ctx context.Context, userSub, id string) error
+	UpdateGroup(ctx context.Context, userSub, id, name string) error
+	CreateOrGetSubGroup(ctx context.Context, userSub, groupExternalId, subGroupName string) (*types.KeycloakGroup, error)
+	AddRolesToGroup(ctx context.Context, userSub, id string, roles []*types.KeycloakRole) error
+	DeleteRolesFromGroup(ctx context.Context, userSub, id string, roles []*types.KeycloakRole) error
+	AddUserToGroup(ctx context.Context, userSub, joiningUserId, groupId string) error
+	DeleteUserFromGroup(ctx context.Context, userSub, deletingUserId, groupId string) error
+	Close()
+}
+
+var (
+	_ IdentityProvider = (*Keycloak)(nil)
+	_ IdentityProvider = (*MemoryIdentity)(nil)
+)
+
+// identityStatusError marks the statuses an identity provider answers with for a missing or
+// existing resource, so callers can match them the same way for every provider
+func identityStatusError(err error) error {
+	var statusErr *util.StatusError
+	if !errors.As(err, &statusErr) {
+		return err
+	}
+
+	switch statusErr.StatusCode {
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %w", ErrIdentityNotFound, err)
+	case http.StatusConflict:
+		return fmt.Errorf("%w: %w", ErrIdentityConflict, err)
+	}
+
+	return err
+}
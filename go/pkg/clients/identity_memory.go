@@ -0,0 +1,415 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+type memoryGroup struct {
+	id, name, parentId string
+	children           []string
+	roleIds            []string
+}
+
+type memoryUser struct {
+	firstName, lastName string
+	groupIds            []string
+}
+
+// MemoryIdentity keeps groups, subgroups, role mappings and memberships in memory, with the
+// same naming and path rules as Keycloak. It lets handlers run without an auth server.
+// Users register through Keycloak forms, so any user id is accepted and created on first use.
+type MemoryIdentity struct {
+	mu         sync.RWMutex
+	adminRoles []*types.KeycloakRole
+	groups     map[string]*memoryGroup
+	users      map[string]*memoryUser
+}
+
+func NewMemoryIdentity(adminRoles []*types.KeycloakRole) *MemoryIdentity {
+	return &MemoryIdentity{
+		adminRoles: adminRoles,
+		groups:     make(map[string]*memoryGroup),
+		users:      make(map[string]*memoryUser),
+	}
+}
+
+func (mi *MemoryIdentity) Close() {}
+
+func (mi *MemoryIdentity) path(group *memoryGroup) string {
+	if group.parentId == "" {
+		return "/" + group.name
+	}
+	return mi.path(mi.groups[group.parentId]) + "/" + group.name
+}
+
+func (mi *MemoryIdentity) toKeycloakGroup(group *memoryGroup, withSubGroups bool) *types.KeycloakGroup {
+	kcGroup := &types.KeycloakGroup{
+		Id:       group.id,
+		Name:     group.name,
+		Path:     mi.path(group),
+		ParentId: group.parentId,
+	}
+	if withSubGroups {
+		kcGroup.SubGroups = make([]*types.KeycloakGroup, 0, len(group.children))
+		for _, childId := range group.children {
+			kcGroup.SubGroups = append(kcGroup.SubGroups, mi.toKeycloakGroup(mi.groups[childId], true))
+		}
+	}
+	return kcGroup
+}
+
+func (mi *MemoryIdentity) getGroup(id string) (*memoryGroup, error) {
+	group, ok := mi.groups[id]
+	if !ok {
+		return nil, util.ErrCheck(fmt.Errorf("group %s: %w", id, ErrIdentityNotFound))
+	}
+	return group, nil
+}
+
+func (mi *MemoryIdentity) getUser(id string) *memoryUser {
+	user, ok := mi.users[id]
+	if !ok {
+		user = &memoryUser{}
+		mi.users[id] = user
+	}
+	return user
+}
+
+// Sibling groups, and top level groups, must have unique names
+func (mi *MemoryIdentity) nameTaken(parentId, name, exceptId string) bool {
+	for _, group := range mi.groups {
+		if group.parentId == parentId && group.name == name && group.id != exceptId {
+			return true
+		}
+	}
+	return false
+}
+
+func (mi *MemoryIdentity) adminRole(id string) (*types.KeycloakRole, bool) {
+	for _, role := range mi.adminRoles {
+		if role.Id == id {
+			return role, true
+		}
+	}
+	return nil, false
+}
+
+func (mi *MemoryIdentity) GetGroupAdminRoles(ctx context.Context, userSub string) ([]*types.KeycloakRole, error) {
+	if userSub == "" {
+		return nil, authCommandMustHaveSub
+	}
+
+	return mi.adminRoles, nil
+}
+
+func (mi *MemoryIdentity) GetGroupSiteRoles(ctx context.Context, userSub, groupId string) ([]*types.ClientRoleMappingRole, error) {
+	if userSub == "" {
+		return nil, authCommandMustHaveSub
+	}
+
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+
+	group, err := mi.getGroup(groupId)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	mappings := make([]*types.ClientRoleMappingRole, 0, len(group.roleIds))
+	for _, roleId := range group.roleIds {
+		role, _ := mi.adminRole(roleId)
+		mappings = append(mappings, &types.ClientRoleMappingRole{
+			Id:          role.Id,
+			Name:        role.Name,
+			Description: role.Description,
+			Composite:   role.Composite,
+			ClientRole:  true,
+		})
+	}
+
+	return mappings, nil
+}
+
+func (mi *MemoryIdentity) UpdateUser(ctx context.Context, userSub, id, firstName, lastName string) error {
+	if userSub == "" {
+		return authCommandMustHaveSub
+	}
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	user := mi.getUser(id)
+	user.firstName = firstName
+	user.lastName = lastName
+
+	return nil
+}
+
+func (mi *MemoryIdentity) DeleteUser(ctx context.Context, userSub string) error {
+	if userSub == "" {
+		return authCommandMustHaveSub
+	}
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	delete(mi.users, userSub)
+
+	return nil
+}
+
+func (mi *MemoryIdentity) CreateGroup(ctx context.Context, userSub, name string) (*types.KeycloakGroup, error) {
+	if userSub == "" {
+		return nil, authCommandMustHaveSub
+	}
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	if mi.nameTaken("", name, "") {
+		return nil, util.ErrCheck(fmt.Errorf("group %s: %w", name, ErrIdentityConflict))
+	}
+
+	group := &memoryGroup{id: uuid.NewString(), name: name}
+	mi.groups[group.id] = group
+
+	// Like keycloak, only the id of the new group is known to the caller
+	return &types.KeycloakGroup{Id: group.id}, nil
+}
+
+func (mi *MemoryIdentity) GetGroup(ctx context.Context, userSub, id string) (*types.KeycloakGroup, error) {
+	if userSub == "" {
+		return nil, authCommandMustHaveSub
+	}
+
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+
+	group, err := mi.getGroup(id)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return mi.toKeycloakGroup(group, true), nil
+}
+
+func (mi *MemoryIdentity) GetGroupSubGroups(ctx context.Context, userSub, groupId string) ([]*types.KeycloakGroup, error) {
+	if userSub == "" {
+		return nil, authCommandMustHaveSub
+	}
+
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+
+	group, err := mi.getGroup(groupId)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return mi.toKeycloakGroup(group, true).SubGroups, nil
+}
+
+func (mi *MemoryIdentity) deleteGroup(group *memoryGroup) {
+	for _, childId := range group.children {
+		mi.deleteGroup(mi.groups[childId])
+	}
+
+	for _, user := range mi.users {
+		user.groupIds = slices.DeleteFunc(user.groupIds, func(id string) bool { return id == group.id })
+	}
+
+	delete(mi.groups, group.id)
+}
+
+// Deletes the group with all of its subgroups and their memberships
+func (mi *MemoryIdentity) DeleteGroup(ctx context.Context, userSub, id string) error {
+	if userSub == "" {
+		return authCommandMustHaveSub
+	}
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	group, err := mi.getGroup(id)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	if parent, ok := mi.groups[group.parentId]; ok {
+		parent.children = slices.DeleteFunc(parent.children, func(childId string) bool { return childId == id })
+	}
+
+	mi.deleteGroup(group)
+
+	return nil
+}
+
+func (mi *MemoryIdentity) UpdateGroup(ctx context.Context, userSub, id, name string) error {
+	if userSub == "" {
+		return authCommandMustHaveSub
+	}
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	group, err := mi.getGroup(id)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	if mi.nameTaken(group.parentId, name, id) {
+		return util.ErrCheck(fmt.Errorf("group %s: %w", name, ErrIdentityConflict))
+	}
+
+	group.name = name
+
+	return nil
+}
+
+func (mi *MemoryIdentity) CreateOrGetSubGroup(ctx context.Context, userSub, groupExternalId, subGroupName string) (*types.KeycloakGroup, error) {
+	if userSub == "" {
+		return nil, authCommandMustHaveSub
+	}
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	parent, err := mi.getGroup(groupExternalId)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	for _, childId := range parent.children {
+		if child := mi.groups[childId]; child.name == subGroupName {
+			return mi.toKeycloakGroup(child, false), nil
+		}
+	}
+
+	subGroup := &memoryGroup{id: uuid.NewString(), name: subGroupName, parentId: parent.id}
+	mi.groups[subGroup.id] = subGroup
+	parent.children = append(parent.children, subGroup.id)
+
+	return mi.toKeycloakGroup(subGroup, false), nil
+}
+
+// Maps app client roles to the group, the roles must be known admin roles
+func (mi *MemoryIdentity) AddRolesToGroup(ctx context.Context, userSub, id string, roles []*types.KeycloakRole) error {
+	if userSub == "" {
+		return authCommandMustHaveSub
+	}
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	group, err := mi.getGroup(id)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	for _, role := range roles {
+		if _, ok := mi.adminRole(role.GetId()); !ok {
+			return util.ErrCheck(fmt.Errorf("role %s: %w", role.GetName(), ErrIdentityNotFound))
+		}
+	}
+
+	for _, role := range roles {
+		if !slices.Contains(group.roleIds, role.GetId()) {
+			group.roleIds = append(group.roleIds, role.GetId())
+		}
+	}
+
+	return nil
+}
+
+func (mi *MemoryIdentity) DeleteRolesFromGroup(ctx context.Context, userSub, id string, roles []*types.KeycloakRole) error {
+	if userSub == "" {
+		return authCommandMustHaveSub
+	}
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	group, err := mi.getGroup(id)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	group.roleIds = slices.DeleteFunc(group.roleIds, func(roleId string) bool {
+		return slices.ContainsFunc(roles, func(role *types.KeycloakRole) bool { return role.GetId() == roleId })
+	})
+
+	return nil
+}
+
+func (mi *MemoryIdentity) AddUserToGroup(ctx context.Context, userSub, joiningUserId, groupId string) error {
+	if userSub == "" {
+		return authCommandMustHaveSub
+	}
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	if _, err := mi.getGroup(groupId); err != nil {
+		return util.ErrCheck(err)
+	}
+
+	user := mi.getUser(joiningUserId)
+	if !slices.Contains(user.groupIds, groupId) {
+		user.groupIds = append(user.groupIds, groupId)
+	}
+
+	return nil
+}
+
+func (mi *MemoryIdentity) DeleteUserFromGroup(ctx context.Context, userSub, deletingUserId, groupId string) error {
+	if userSub == "" {
+		return authCommandMustHaveSub
+	}
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	if _, err := mi.getGroup(groupId); err != nil {
+		return util.ErrCheck(err)
+	}
+
+	if user, ok := mi.users[deletingUserId]; ok {
+		user.groupIds = slices.DeleteFunc(user.groupIds, func(id string) bool { return id == groupId })
+	}
+
+	return nil
+}
+
+// GetUserName is the name last given to UpdateUser
+func (mi *MemoryIdentity) GetUserName(userId string) (string, string) {
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+
+	if user, ok := mi.users[userId]; ok {
+		return user.firstName, user.lastName
+	}
+
+	return "", ""
+}
+
+// GetUserGroupPaths lists the paths of the groups a user belongs to, as a token's groups claim would
+func (mi *MemoryIdentity) GetUserGroupPaths(userId string) []string {
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+
+	var paths []string
+	if user, ok := mi.users[userId]; ok {
+		for _, groupId := range user.groupIds {
+			paths = append(paths, mi.path(mi.groups[groupId]))
+		}
+	}
+
+	return paths
+}
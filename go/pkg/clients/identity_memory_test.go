@@ -0,0 +1,157 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+)
+
+var memoryAdminRoles = []*types.KeycloakRole{
+	{Id: "role-schedule", Name: "APP_GROUP_SCHEDULES"},
+	{Id: "role-users", Name: "APP_GROUP_USERS"},
+}
+
+func TestMemoryIdentity_Groups(t *testing.T) {
+	ctx := context.Background()
+	mi := NewMemoryIdentity(memoryAdminRoles)
+
+	group, err := mi.CreateGroup(ctx, "admin", "bakery")
+	if err != nil {
+		t.Fatalf("MemoryIdentity.CreateGroup() error = %v", err)
+	}
+
+	if _, err := mi.CreateGroup(ctx, "admin", "bakery"); !errors.Is(err, ErrIdentityConflict) {
+		t.Fatalf("MemoryIdentity.CreateGroup() duplicate error = %v, want %v", err, ErrIdentityConflict)
+	}
+
+	if _, err := mi.CreateGroup(ctx, "", "library"); err == nil {
+		t.Fatal("MemoryIdentity.CreateGroup() without a user sub did not error")
+	}
+
+	adminGroup, err := mi.CreateOrGetSubGroup(ctx, "admin", group.Id, "Admin")
+	if err != nil {
+		t.Fatalf("MemoryIdentity.CreateOrGetSubGroup() error = %v", err)
+	}
+
+	if adminGroup.Path != "/bakery/Admin" || adminGroup.ParentId != group.Id {
+		t.Errorf("MemoryIdentity.CreateOrGetSubGroup() = %v, want path /bakery/Admin under %s", adminGroup, group.Id)
+	}
+
+	sameGroup, err := mi.CreateOrGetSubGroup(ctx, "admin", group.Id, "Admin")
+	if err != nil || sameGroup.Id != adminGroup.Id {
+		t.Errorf("MemoryIdentity.CreateOrGetSubGroup() existing = %v, %v, want %s", sameGroup, err, adminGroup.Id)
+	}
+
+	bakerGroup, err := mi.CreateOrGetSubGroup(ctx, "admin", group.Id, "Baker")
+	if err != nil {
+		t.Fatalf("MemoryIdentity.CreateOrGetSubGroup() error = %v", err)
+	}
+
+	if err := mi.UpdateGroup(ctx, "admin", bakerGroup.Id, "Admin"); !errors.Is(err, ErrIdentityConflict) {
+		t.Errorf("MemoryIdentity.UpdateGroup() sibling name error = %v, want %v", err, ErrIdentityConflict)
+	}
+
+	if err := mi.UpdateGroup(ctx, "admin", group.Id, "pastry shop"); err != nil {
+		t.Fatalf("MemoryIdentity.UpdateGroup() error = %v", err)
+	}
+
+	subGroups, err := mi.GetGroupSubGroups(ctx, "worker", group.Id)
+	if err != nil {
+		t.Fatalf("MemoryIdentity.GetGroupSubGroups() error = %v", err)
+	}
+
+	var paths []string
+	for _, sg := range subGroups {
+		paths = append(paths, sg.Path)
+	}
+
+	if want := []string{"/pastry shop/Admin", "/pastry shop/Baker"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("MemoryIdentity.GetGroupSubGroups() paths = %v, want %v", paths, want)
+	}
+
+	if err := mi.DeleteGroup(ctx, "admin", bakerGroup.Id); err != nil {
+		t.Fatalf("MemoryIdentity.DeleteGroup() subgroup error = %v", err)
+	}
+
+	kcGroup, err := mi.GetGroup(ctx, "admin", group.Id)
+	if err != nil {
+		t.Fatalf("MemoryIdentity.GetGroup() error = %v", err)
+	}
+
+	if kcGroup.Name != "pastry shop" || len(kcGroup.SubGroups) != 1 || kcGroup.SubGroups[0].Id != adminGroup.Id {
+		t.Errorf("MemoryIdentity.GetGroup() = %v, want only the admin subgroup", kcGroup)
+	}
+
+	if err := mi.DeleteGroup(ctx, "admin", group.Id); err != nil {
+		t.Fatalf("MemoryIdentity.DeleteGroup() error = %v", err)
+	}
+
+	if _, err := mi.GetGroup(ctx, "admin", adminGroup.Id); !errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("MemoryIdentity.GetGroup() deleted subgroup error = %v, want %v", err, ErrIdentityNotFound)
+	}
+}
+
+func TestMemoryIdentity_RolesAndMembers(t *testing.T) {
+	ctx := context.Background()
+	mi := NewMemoryIdentity(memoryAdminRoles)
+
+	group, _ := mi.CreateGroup(ctx, "admin", "bakery")
+	adminGroup, _ := mi.CreateOrGetSubGroup(ctx, "admin", group.Id, "Admin")
+
+	roles, err := mi.GetGroupAdminRoles(ctx, "admin")
+	if err != nil {
+		t.Fatalf("MemoryIdentity.GetGroupAdminRoles() error = %v", err)
+	}
+
+	if err := mi.AddRolesToGroup(ctx, "admin", adminGroup.Id, roles); err != nil {
+		t.Fatalf("MemoryIdentity.AddRolesToGroup() error = %v", err)
+	}
+
+	if err := mi.AddRolesToGroup(ctx, "admin", adminGroup.Id, []*types.KeycloakRole{{Id: "unknown", Name: "APP_UNKNOWN"}}); !errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("MemoryIdentity.AddRolesToGroup() unknown role error = %v, want %v", err, ErrIdentityNotFound)
+	}
+
+	if err := mi.DeleteRolesFromGroup(ctx, "admin", adminGroup.Id, roles[:1]); err != nil {
+		t.Fatalf("MemoryIdentity.DeleteRolesFromGroup() error = %v", err)
+	}
+
+	mappings, err := mi.GetGroupSiteRoles(ctx, "admin", adminGroup.Id)
+	if err != nil {
+		t.Fatalf("MemoryIdentity.GetGroupSiteRoles() error = %v", err)
+	}
+
+	want := []*types.ClientRoleMappingRole{{Id: "role-users", Name: "APP_GROUP_USERS", ClientRole: true}}
+	if !reflect.DeepEqual(mappings, want) {
+		t.Errorf("MemoryIdentity.GetGroupSiteRoles() = %v, want %v", mappings, want)
+	}
+
+	if err := mi.AddUserToGroup(ctx, "admin", "admin", adminGroup.Id); err != nil {
+		t.Fatalf("MemoryIdentity.AddUserToGroup() error = %v", err)
+	}
+
+	if err := mi.AddUserToGroup(ctx, "admin", "admin", "missing"); !errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("MemoryIdentity.AddUserToGroup() missing group error = %v, want %v", err, ErrIdentityNotFound)
+	}
+
+	if got, want := mi.GetUserGroupPaths("admin"), []string{"/bakery/Admin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MemoryIdentity.GetUserGroupPaths() = %v, want %v", got, want)
+	}
+
+	if err := mi.DeleteUserFromGroup(ctx, "admin", "admin", adminGroup.Id); err != nil {
+		t.Fatalf("MemoryIdentity.DeleteUserFromGroup() error = %v", err)
+	}
+
+	if got := mi.GetUserGroupPaths("admin"); len(got) != 0 {
+		t.Errorf("MemoryIdentity.GetUserGroupPaths() after removal = %v", got)
+	}
+
+	mi.AddUserToGroup(ctx, "admin", "member", adminGroup.Id)
+	mi.DeleteGroup(ctx, "admin", group.Id)
+
+	if got := mi.GetUserGroupPaths("member"); len(got) != 0 {
+		t.Errorf("MemoryIdentity.GetUserGroupPaths() after group deletion = %v", got)
+	}
+}
@@ -0,0 +1,35 @@
+package clients
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+func Test_identityStatusError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantTarget error
+	}{
+		{name: "not found", err: util.ErrCheck(&util.StatusError{StatusCode: http.StatusNotFound}), wantTarget: ErrIdentityNotFound},
+		{name: "conflict", err: util.ErrCheck(&util.StatusError{StatusCode: http.StatusConflict}), wantTarget: ErrIdentityConflict},
+		{name: "server error", err: util.ErrCheck(&util.StatusError{StatusCode: http.StatusInternalServerError})},
+		{name: "not a status", err: errors.New("Not Found")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := identityStatusError(tt.err)
+			if !errors.Is(got, tt.err) {
+				t.Errorf("identityStatusError() = %v, want it to wrap %v", got, tt.err)
+			}
+			for _, target := range []error{ErrIdentityNotFound, ErrIdentityConflict} {
+				if errors.Is(got, target) != (target == tt.wantTarget) {
+					t.Errorf("identityStatusError() = %v, errors.Is(%v) = %v", got, target, !(target == tt.wantTarget))
+				}
+			}
+		})
+	}
+}
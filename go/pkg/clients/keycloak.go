@@ -420,7 +420,7 @@ func (k *Keycloak) SendCommand(ctx context.Context, cmdType int32, request *type
 	res, err := SendCommand(ctx, k, createCmd)
 	err = ChannelError(err, res.Error)
 	if err != nil {
-		return res, util.ErrCheck(err)
+		return res, util.ErrCheck(identityStatusError(err))
 	}
 
 	return res, nil
@@ -593,6 +593,19 @@ func (k *Keycloak) AddRolesToGroup(ctx context.Context, userSub, id string, role
 	return nil
 }
 
+func (k *Keycloak) DeleteRolesFromGroup(ctx context.Context, userSub, id string, roles []*types.KeycloakRole) error {
+	_, err := k.SendCommand(ctx, DeleteRolesFromGroupKeycloakCommand, &types.AuthRequestParams{
+		UserSub: userSub,
+		GroupId: id,
+		Roles:   roles,
+	})
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
 func (k *Keycloak) AddUserToGroup(ctx context.Context, userSub, joiningUserId, groupId string) error {
 	_, err := k.SendCommand(ctx, AddUserToGroupKeycloakCommand, &types.AuthRequestParams{
 		UserSub: userSub,
@@ -180,6 +180,24 @@ func (keycloakClient KeycloakClient) GetAppClientRoles() ([]*types.KeycloakRole,
 	return result, nil
 }
 
+func (keycloakClient KeycloakClient) GetRealmRole(roleName string) (*types.KeycloakRole, error) {
+	resp, err := util.Get(
+		util.E_KC_ADMIN_URL+"/roles/"+url.PathEscape(roleName),
+		keycloakClient.BasicHeaders(),
+	)
+
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	var result *types.KeycloakRole
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return result, nil
+}
+
 func (keycloakClient KeycloakClient) GetRealmClients() ([]*types.KeycloakRealmClient, error) {
 	resp, err := util.Get(
 		util.E_KC_ADMIN_URL+"/clients",
@@ -399,3 +417,24 @@ func (keycloakClient KeycloakClient) MutateUserGroupMembership(method, userId, g
 
 	return nil
 }
+
+func (keycloakClient KeycloakClient) MutateUserRealmRoles(method, userId string, roles []*types.KeycloakRole) error {
+
+	rolesBytes, err := json.Marshal(roles)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	_, err = util.Mutate(
+		method,
+		util.E_KC_ADMIN_URL+"/users/"+userId+"/role-mappings/realm",
+		keycloakClient.BasicHeaders(),
+		rolesBytes,
+	)
+
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
@@ -176,6 +176,33 @@ func TestKeycloakClient_GetAppClientRoles(t *testing.T) {
 	}
 }
 
+func TestKeycloakClient_GetRealmRole(t *testing.T) {
+	type args struct {
+		roleName string
+	}
+	tests := []struct {
+		name           string
+		keycloakClient KeycloakClient
+		args           args
+		want           *types.KeycloakRole
+		wantErr        bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keycloakClient.GetRealmRole(tt.args.roleName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("KeycloakClient.GetRealmRole(%v) error = %v, wantErr %v", tt.args.roleName, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("KeycloakClient.GetRealmRole(%v) = %v, want %v", tt.args.roleName, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestKeycloakClient_GetRealmClients(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -419,3 +446,26 @@ func TestKeycloakClient_MutateUserGroupMembership(t *testing.T) {
 		})
 	}
 }
+
+func TestKeycloakClient_MutateUserRealmRoles(t *testing.T) {
+	type args struct {
+		method string
+		userId string
+		roles  []*types.KeycloakRole
+	}
+	tests := []struct {
+		name           string
+		keycloakClient KeycloakClient
+		args           args
+		wantErr        bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.keycloakClient.MutateUserRealmRoles(tt.args.method, tt.args.userId, tt.args.roles); (err != nil) != tt.wantErr {
+				t.Errorf("KeycloakClient.MutateUserRealmRoles(%v, %v, %v) error = %v, wantErr %v", tt.args.method, tt.args.userId, tt.args.roles, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -15,12 +16,19 @@ import (
 )
 
 const (
-	LLM_MODEL  = "gemini-2.0-flash"
-	LLM_URL    = "https://generativelanguage.googleapis.com/v1beta/models/" + LLM_MODEL + ":generateContent?key="
+	LLM_PROVIDER_GEMINI  = "gemini"
+	LLM_PROVIDER_OPENAI  = "openai"
+	LLM_PROVIDER_FIXTURE = "fixture"
+
+	LLM_GEMINI_MODEL = "gemini-2.0-flash"
+	LLM_GEMINI_URL   = "https://generativelanguage.googleapis.com/v1beta/models/"
+
 	CHAT_USER  = "user"
 	CHAT_MODEL = "model"
 )
 
+var ErrLLMDisabled = errors.New("no llm provider is configured")
+
 var stringArrayGeneration = ResponseSchema{
 	ResponseMimeType: "application/json",
 	ResponseSchema: Schema{
@@ -48,25 +56,23 @@ type ChatContent struct {
 	Role  string `json:"role"`
 }
 
+// ChatRequest is the prompt format used by the app, providers translate it to their own
 type ChatRequest struct {
 	SystemInstruction ChatContent    `json:"system_instruction"`
 	Contents          []ChatContent  `json:"contents"`
 	GenerationConfig  ResponseSchema `json:"generationConfig"`
 }
 
-type Candidate struct {
-	Content ChatContent `json:"content"`
-}
-
-type CandidateResponse struct {
-	Candidates []Candidate `json:"candidates"`
+// LLMProvider completes a prompt, returning the text of the first candidate
+type LLMProvider interface {
+	Complete(ctx context.Context, request ChatRequest) (string, error)
 }
 
 type LLMPrompts map[types.IPrompts]ChatRequest
 
 type LLM struct {
-	Prompts       LLMPrompts
-	APIKey, Model string
+	Provider LLMProvider
+	Prompts  LLMPrompts
 }
 
 func InitLLM() *LLM {
@@ -78,16 +84,24 @@ func InitLLM() *LLM {
 	aiPrompts[types.IPrompts_SUGGEST_SERVICE] = suggestServicesRequest
 	aiPrompts[types.IPrompts_SUGGEST_TIER] = suggestTiersRequest
 
-	apiKey, err := util.GetEnvFilePath("AI_KEY_FILE", 40)
+	// The key is optional, self-hosted endpoints usually don't need one
+	apiKey, err := util.GetEnvFilePath("AI_KEY_FILE", 256)
+	if err != nil {
+		apiKey = ""
+	}
+	apiKey = strings.TrimSpace(strings.TrimRight(apiKey, "\x00"))
+
+	provider, err := newLLMProvider(util.E_LLM_PROVIDER, util.E_LLM_URL, util.E_LLM_MODEL, apiKey)
 	if err != nil {
-		util.ErrorLog.Println(util.ErrCheck(err))
 		log.Fatal(util.ErrCheck(err))
 	}
+	if provider == nil {
+		util.DebugLog.Println("The " + util.E_LLM_PROVIDER + " llm provider is not configured, ai is disabled")
+	}
 
 	aic := &LLM{
-		APIKey:  apiKey,
-		Model:   LLM_MODEL,
-		Prompts: aiPrompts,
+		Provider: provider,
+		Prompts:  aiPrompts,
 	}
 
 	util.DebugLog.Println("Ai Init")
@@ -95,40 +109,221 @@ func InitLLM() *LLM {
 	return aic
 }
 
-func (llm *LLM) GetCandidateResponse(ctx context.Context, promptParts []string, promptType types.IPrompts) (*CandidateResponse, error) {
+// newLLMProvider builds the provider named by LLM_PROVIDER. A known provider missing what it needs to connect is
+// nil, and ai is disabled; the Makefile's AI_ENABLED makes the same decision for the frontend.
+func newLLMProvider(name, url, model, apiKey string) (LLMProvider, error) {
+	switch name {
+	case LLM_PROVIDER_FIXTURE:
+		return &FixtureLLMProvider{}, nil
+	case LLM_PROVIDER_OPENAI:
+		if url == "" {
+			return nil, nil
+		}
+		return &OpenAILLMProvider{URL: url, Model: model, APIKey: apiKey}, nil
+	case LLM_PROVIDER_GEMINI:
+		if apiKey == "" {
+			return nil, nil
+		}
+		if model == "" {
+			model = LLM_GEMINI_MODEL
+		}
+		return &GeminiLLMProvider{URL: LLM_GEMINI_URL, Model: model, APIKey: apiKey}, nil
+	}
+
+	return nil, fmt.Errorf("LLM_PROVIDER must be one of %s, %s or %s, got %q", LLM_PROVIDER_GEMINI, LLM_PROVIDER_OPENAI, LLM_PROVIDER_FIXTURE, name)
+}
 
-	promptTokens := make(map[string]string)
+func (llm *LLM) Enabled() bool {
+	return llm != nil && llm.Provider != nil
+}
 
-	promptRequest := llm.Prompts[promptType]
+// Fills the prompt tokens into a copy of the prompt, the stored prompts are shared between requests
+func (llm *LLM) buildPrompt(promptParts []string, promptType types.IPrompts) (ChatRequest, error) {
+	promptTemplate, ok := llm.Prompts[promptType]
+	if !ok {
+		return ChatRequest{}, util.ErrCheck(fmt.Errorf("unknown prompt type %d", promptType))
+	}
 
+	replacements := make([]string, 0, len(promptParts)*2)
 	for i, prompt := range promptParts {
-		tokenKey := fmt.Sprintf("${prompt%d}", i+1)
-		promptTokens[tokenKey] = prompt
+		replacements = append(replacements, fmt.Sprintf("${prompt%d}", i+1), prompt)
 	}
-
-	for _, message := range promptRequest.Contents {
-		for k, v := range promptTokens {
-			message.Parts[0].Text = strings.ReplaceAll(message.Parts[0].Text, k, v)
+	replacer := strings.NewReplacer(replacements...)
+
+	promptRequest := promptTemplate
+	promptRequest.Contents = make([]ChatContent, len(promptTemplate.Contents))
+	for i, message := range promptTemplate.Contents {
+		parts := make([]Part, len(message.Parts))
+		for j, part := range message.Parts {
+			parts[j] = Part{Text: replacer.Replace(part.Text)}
 		}
+		promptRequest.Contents[i] = ChatContent{Parts: parts, Role: message.Role}
 	}
 
-	jsonBytes, err := json.Marshal(promptRequest)
+	return promptRequest, nil
+}
+
+func (llm *LLM) GetCandidateResponse(ctx context.Context, promptParts []string, promptType types.IPrompts) (string, error) {
+	if !llm.Enabled() {
+		return "", ErrLLMDisabled
+	}
+
+	promptRequest, err := llm.buildPrompt(promptParts, promptType)
 	if err != nil {
-		return nil, util.ErrCheck(err)
+		return "", util.ErrCheck(err)
 	}
 
-	resp, err := util.PostFormData(ctx, LLM_URL+llm.APIKey, http.Header{"Content-Type": {"application/json"}}, bytes.NewReader(jsonBytes))
+	candidate, err := llm.Provider.Complete(ctx, promptRequest)
 	if err != nil {
-		return nil, util.ErrCheck(err)
+		return "", util.ErrCheck(err)
+	}
+
+	return candidate, nil
+}
+
+type Candidate struct {
+	Content ChatContent `json:"content"`
+}
+
+type CandidateResponse struct {
+	Candidates []Candidate `json:"candidates"`
+}
+
+// GeminiLLMProvider sends the prompt as is to the Gemini generateContent API
+type GeminiLLMProvider struct {
+	URL, Model, APIKey string
+}
+
+func (gp *GeminiLLMProvider) Complete(ctx context.Context, request ChatRequest) (string, error) {
+	jsonBytes, err := json.Marshal(request)
+	if err != nil {
+		return "", util.ErrCheck(err)
+	}
+
+	resp, err := util.PostFormData(ctx, gp.URL+gp.Model+":generateContent?key="+gp.APIKey, http.Header{"Content-Type": {"application/json"}}, bytes.NewReader(jsonBytes))
+	if err != nil {
+		return "", util.ErrCheck(err)
 	}
 
 	candidateResponse := &CandidateResponse{}
 	err = json.Unmarshal(resp, &candidateResponse)
 	if err != nil {
-		return nil, util.ErrCheck(err)
+		return "", util.ErrCheck(err)
+	}
+
+	if len(candidateResponse.Candidates) < 1 || len(candidateResponse.Candidates[0].Content.Parts) < 1 {
+		return "", nil
+	}
+
+	return candidateResponse.Candidates[0].Content.Parts[0].Text, nil
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model    string          `json:"model,omitempty"`
+	Messages []openAIMessage `json:"messages"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// OpenAILLMProvider uses the chat completions API, which is also served by llama.cpp, vLLM and others.
+// URL is the API base, such as http://localhost:8000/v1
+type OpenAILLMProvider struct {
+	URL, Model, APIKey string
+}
+
+func toOpenAIMessages(request ChatRequest) []openAIMessage {
+	messages := make([]openAIMessage, 0, len(request.Contents)+1)
+
+	var system []string
+	for _, part := range request.SystemInstruction.Parts {
+		system = append(system, part.Text)
+	}
+	if len(system) > 0 {
+		messages = append(messages, openAIMessage{Role: "system", Content: strings.Join(system, "\n")})
+	}
+
+	for _, content := range request.Contents {
+		role := "user"
+		if content.Role == CHAT_MODEL {
+			role = "assistant"
+		}
+
+		var text []string
+		for _, part := range content.Parts {
+			text = append(text, part.Text)
+		}
+
+		messages = append(messages, openAIMessage{Role: role, Content: strings.Join(text, "\n")})
+	}
+
+	return messages
+}
+
+func (op *OpenAILLMProvider) Complete(ctx context.Context, request ChatRequest) (string, error) {
+	jsonBytes, err := json.Marshal(openAIChatRequest{
+		Model:    op.Model,
+		Messages: toOpenAIMessages(request),
+	})
+	if err != nil {
+		return "", util.ErrCheck(err)
+	}
+
+	headers := http.Header{"Content-Type": {"application/json"}}
+	if op.APIKey != "" {
+		headers.Set("Authorization", "Bearer "+op.APIKey)
+	}
+
+	resp, err := util.PostFormData(ctx, strings.TrimSuffix(op.URL, "/")+"/chat/completions", headers, bytes.NewReader(jsonBytes))
+	if err != nil {
+		return "", util.ErrCheck(err)
+	}
+
+	chatResponse := &openAIChatResponse{}
+	err = json.Unmarshal(resp, chatResponse)
+	if err != nil {
+		return "", util.ErrCheck(err)
+	}
+
+	if len(chatResponse.Choices) < 1 {
+		return "", nil
+	}
+
+	return chatResponse.Choices[0].Message.Content, nil
+}
+
+// FixtureLLMProvider answers without a network call, for tests and offline development.
+// Responses are looked up by the last message of the prompt, otherwise option prompts get
+// five fixed options and other prompts a fixed phrase.
+type FixtureLLMProvider struct {
+	Responses map[string]string
+}
+
+const (
+	fixtureLLMOptions = "Option One|Option Two|Option Three|Option Four|Option Five"
+	fixtureLLMPhrase  = "providing consistent responses for testing"
+)
+
+func (fp *FixtureLLMProvider) Complete(ctx context.Context, request ChatRequest) (string, error) {
+	if n := len(request.Contents); n > 0 && len(request.Contents[n-1].Parts) > 0 {
+		if response, ok := fp.Responses[request.Contents[n-1].Parts[0].Text]; ok {
+			return response, nil
+		}
+	}
+
+	if request.GenerationConfig.ResponseSchema.Pattern != "" {
+		return fixtureLLMOptions, nil
 	}
 
-	return candidateResponse, nil
+	return fixtureLLMPhrase, nil
 }
 
 func GetSuggestionPrompt(prompt string) string {
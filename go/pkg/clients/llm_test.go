@@ -0,0 +1,239 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+)
+
+func rolePrompt(name, purpose string) string {
+	return GenerateExample(`role names for a group named "`+name+`" which is interested in `+purpose, "")
+}
+
+func TestLLM_GetCandidateResponse(t *testing.T) {
+	fixtureLLM := &LLM{
+		Provider: &FixtureLLMProvider{Responses: map[string]string{
+			rolePrompt("A Bakery", "baking"): "Bread|Cake|Pie|Cookie|Tart",
+		}},
+		Prompts: LLMPrompts{
+			types.IPrompts_CONVERT_PURPOSE: convertPurposeRequest,
+			types.IPrompts_SUGGEST_ROLE:    suggestRolesRequest,
+		},
+	}
+
+	tests := []struct {
+		name        string
+		llm         *LLM
+		promptParts []string
+		promptType  types.IPrompts
+		want        string
+		wantErr     bool
+	}{
+		{name: "fixture options", llm: fixtureLLM, promptParts: []string{"A Library", "lending"}, promptType: types.IPrompts_SUGGEST_ROLE, want: fixtureLLMOptions},
+		{name: "fixture override", llm: fixtureLLM, promptParts: []string{"A Bakery", "baking"}, promptType: types.IPrompts_SUGGEST_ROLE, want: "Bread|Cake|Pie|Cookie|Tart"},
+		{name: "fixture phrase", llm: fixtureLLM, promptParts: []string{"A Bakery", "baking"}, promptType: types.IPrompts_CONVERT_PURPOSE, want: fixtureLLMPhrase},
+		{name: "unknown prompt", llm: fixtureLLM, promptParts: []string{"A Bakery"}, promptType: types.IPrompts_SUGGEST_TIER, wantErr: true},
+		{name: "disabled", llm: &LLM{Prompts: fixtureLLM.Prompts}, promptParts: []string{"A Bakery"}, promptType: types.IPrompts_SUGGEST_ROLE, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.llm.GetCandidateResponse(context.Background(), tt.promptParts, tt.promptType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LLM.GetCandidateResponse(%v, %v) error = %v, wantErr %v", tt.promptParts, tt.promptType, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LLM.GetCandidateResponse(%v, %v) = %v, want %v", tt.promptParts, tt.promptType, got, tt.want)
+			}
+		})
+	}
+
+	_, err := (&LLM{}).GetCandidateResponse(context.Background(), nil, types.IPrompts_SUGGEST_ROLE)
+	if !errors.Is(err, ErrLLMDisabled) {
+		t.Errorf("LLM.GetCandidateResponse() without a provider error = %v, want %v", err, ErrLLMDisabled)
+	}
+}
+
+func TestLLM_buildPrompt(t *testing.T) {
+	llm := &LLM{Prompts: LLMPrompts{types.IPrompts_SUGGEST_ROLE: suggestRolesRequest}}
+
+	first, err := llm.buildPrompt([]string{"A Bakery", "baking"}, types.IPrompts_SUGGEST_ROLE)
+	if err != nil {
+		t.Fatalf("LLM.buildPrompt() error = %v", err)
+	}
+
+	second, err := llm.buildPrompt([]string{"A Library", "lending"}, types.IPrompts_SUGGEST_ROLE)
+	if err != nil {
+		t.Fatalf("LLM.buildPrompt() error = %v", err)
+	}
+
+	last := func(request ChatRequest) string {
+		return request.Contents[len(request.Contents)-1].Parts[0].Text
+	}
+
+	if want := rolePrompt("A Bakery", "baking"); last(first) != want {
+		t.Errorf("LLM.buildPrompt() first = %v, want %v", last(first), want)
+	}
+
+	if want := rolePrompt("A Library", "lending"); last(second) != want {
+		t.Errorf("LLM.buildPrompt() second = %v, want %v", last(second), want)
+	}
+
+	if !strings.Contains(last(suggestRolesRequest), "${prompt1}") {
+		t.Errorf("LLM.buildPrompt() replaced tokens in the stored prompt, %v", last(suggestRolesRequest))
+	}
+}
+
+func TestGeminiLLMProvider_Complete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/"+LLM_GEMINI_MODEL+":generateContent" || req.URL.Query().Get("key") != "key" {
+			http.Error(w, "bad request "+req.URL.String(), http.StatusBadRequest)
+			return
+		}
+
+		var request ChatRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil || len(request.Contents) == 0 {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"One|Two|Three|Four|Five"}]}}]}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		gp      *GeminiLLMProvider
+		want    string
+		wantErr bool
+	}{
+		{name: "candidate text", gp: &GeminiLLMProvider{URL: server.URL + "/", Model: LLM_GEMINI_MODEL, APIKey: "key"}, want: "One|Two|Three|Four|Five"},
+		{name: "bad key", gp: &GeminiLLMProvider{URL: server.URL + "/", Model: LLM_GEMINI_MODEL, APIKey: "other"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.gp.Complete(context.Background(), suggestRolesRequest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GeminiLLMProvider.Complete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GeminiLLMProvider.Complete() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAILLMProvider_Complete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, req)
+			return
+		}
+
+		if auth := req.Header.Get("Authorization"); auth != "" && auth != "Bearer key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var request openAIChatRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+
+		if len(request.Messages) < 2 || request.Messages[0].Role != "system" || request.Messages[len(request.Messages)-1].Role != "user" {
+			http.Error(w, "bad messages", http.StatusBadRequest)
+			return
+		}
+
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"One|Two|Three|Four|Five"}}]}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		op      *OpenAILLMProvider
+		want    string
+		wantErr bool
+	}{
+		{name: "keyless local server", op: &OpenAILLMProvider{URL: server.URL + "/v1"}, want: "One|Two|Three|Four|Five"},
+		{name: "hosted with key", op: &OpenAILLMProvider{URL: server.URL + "/v1/", Model: "llama", APIKey: "key"}, want: "One|Two|Three|Four|Five"},
+		{name: "bad key", op: &OpenAILLMProvider{URL: server.URL + "/v1", APIKey: "other"}, wantErr: true},
+		{name: "bad url", op: &OpenAILLMProvider{URL: server.URL}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op.Complete(context.Background(), suggestRolesRequest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OpenAILLMProvider.Complete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("OpenAILLMProvider.Complete() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_toOpenAIMessages(t *testing.T) {
+	request := ChatRequest{
+		SystemInstruction: ChatContent{Parts: []Part{{Text: "be brief"}}},
+		Contents: []ChatContent{
+			{Role: CHAT_USER, Parts: []Part{{Text: "question"}}},
+			{Role: CHAT_MODEL, Parts: []Part{{Text: "answer"}}},
+		},
+	}
+
+	want := []openAIMessage{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "question"},
+		{Role: "assistant", Content: "answer"},
+	}
+
+	got := toOpenAIMessages(request)
+	if len(got) != len(want) {
+		t.Fatalf("toOpenAIMessages() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("toOpenAIMessages()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func Test_newLLMProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		url      string
+		model    string
+		apiKey   string
+		want     LLMProvider
+		wantErr  bool
+	}{
+		{name: "gemini", provider: LLM_PROVIDER_GEMINI, apiKey: "key", want: &GeminiLLMProvider{URL: LLM_GEMINI_URL, Model: LLM_GEMINI_MODEL, APIKey: "key"}},
+		{name: "gemini model", provider: LLM_PROVIDER_GEMINI, model: "gemini-pro", apiKey: "key", want: &GeminiLLMProvider{URL: LLM_GEMINI_URL, Model: "gemini-pro", APIKey: "key"}},
+		{name: "gemini without a key", provider: LLM_PROVIDER_GEMINI},
+		{name: "openai", provider: LLM_PROVIDER_OPENAI, url: "http://localhost:11434/v1", model: "llama3", want: &OpenAILLMProvider{URL: "http://localhost:11434/v1", Model: "llama3"}},
+		{name: "openai without a url", provider: LLM_PROVIDER_OPENAI, apiKey: "key"},
+		{name: "fixture", provider: LLM_PROVIDER_FIXTURE, want: &FixtureLLMProvider{}},
+		{name: "empty", provider: "", apiKey: "key", wantErr: true},
+		{name: "unknown", provider: "claude", apiKey: "key", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newLLMProvider(tt.provider, tt.url, tt.model, tt.apiKey)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newLLMProvider(%q) error = %v, wantErr %v", tt.provider, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newLLMProvider(%q) = %#v, want %#v", tt.provider, got, tt.want)
+			}
+		})
+	}
+}
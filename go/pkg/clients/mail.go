@@ -0,0 +1,140 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const (
+	MAIL_SENDER_FILE = "file"
+	MAIL_SENDER_SMTP = "smtp"
+)
+
+type MailMessage struct {
+	To, Subject, Text string
+}
+
+// MailSender delivers a fully built message, implementations decide the transport
+type MailSender interface {
+	Send(ctx context.Context, from string, msg *MailMessage) error
+}
+
+type Mail struct {
+	Sender MailSender
+	From   string
+}
+
+func InitMail() *Mail {
+	var sender MailSender
+
+	switch util.E_MAIL_SENDER {
+	case MAIL_SENDER_SMTP:
+		smtpPass, err := util.GetEnvFilePath("SMTP_PASS_FILE", 128)
+		if err != nil {
+			log.Fatal(util.ErrCheck(err))
+		}
+		sender = &SMTPMailSender{
+			Addr:     util.E_SMTP_ADDR,
+			Username: util.E_SMTP_USER,
+			Password: smtpPass,
+		}
+	default:
+		// Local environments and tests read outgoing mail from disk
+		sender = &FileMailSender{Dir: util.E_MAIL_DIR}
+	}
+
+	m := &Mail{
+		Sender: sender,
+		From:   util.E_MAIL_FROM,
+	}
+
+	util.DebugLog.Println("Mail Init")
+
+	return m
+}
+
+func (m *Mail) Send(ctx context.Context, msg *MailMessage) error {
+	if msg.To == "" || strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return util.ErrCheck(errors.New("invalid mail headers"))
+	}
+	return m.Sender.Send(ctx, m.From, msg)
+}
+
+func buildMailMessage(from string, msg *MailMessage) []byte {
+	var b bytes.Buffer
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().UTC().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Text, "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// FileMailSender writes each message as an .eml file named <recipient>_<id>.eml
+type FileMailSender struct {
+	Dir string
+}
+
+func (fs *FileMailSender) Send(ctx context.Context, from string, msg *MailMessage) error {
+	if fs.Dir == "" {
+		return util.ErrCheck(errors.New("no mail directory configured"))
+	}
+
+	err := os.MkdirAll(fs.Dir, 0750)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	fileName := fmt.Sprintf("%s_%s.eml", filepath.Base(msg.To), uuid.NewString())
+
+	err = os.WriteFile(filepath.Join(fs.Dir, fileName), buildMailMessage(from, msg), 0640)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
+type SMTPMailSender struct {
+	Addr, Username, Password string
+}
+
+func (ss *SMTPMailSender) Send(ctx context.Context, from string, msg *MailMessage) error {
+	host, _, err := net.SplitHostPort(ss.Addr)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	var auth smtp.Auth
+	if ss.Username != "" {
+		auth = smtp.PlainAuth("", ss.Username, ss.Password, host)
+	}
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- smtp.SendMail(ss.Addr, auth, from, []string{msg.To}, buildMailMessage(from, msg))
+	}()
+
+	select {
+	case err := <-sent:
+		return util.ErrCheck(err)
+	case <-ctx.Done():
+		return util.ErrCheck(ctx.Err())
+	}
+}
@@ -0,0 +1,56 @@
+package clients
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMail_Send(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     *MailMessage
+		wantErr bool
+	}{
+		{name: "writes a message", msg: &MailMessage{To: "user@test.com", Subject: "Hello", Text: "line one\nline two"}},
+		{name: "empty recipient", msg: &MailMessage{To: "", Subject: "Hello"}, wantErr: true},
+		{name: "header injection in recipient", msg: &MailMessage{To: "user@test.com\r\nBcc: other@test.com", Subject: "Hello"}, wantErr: true},
+		{name: "header injection in subject", msg: &MailMessage{To: "user@test.com", Subject: "Hello\nBcc: other@test.com"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			m := &Mail{Sender: &FileMailSender{Dir: dir}, From: "noreply@test.com"}
+
+			err := m.Send(context.Background(), tt.msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Mail.Send(%v) error = %v, wantErr %v", tt.msg, err, tt.wantErr)
+			}
+
+			entries, _ := os.ReadDir(dir)
+			if tt.wantErr {
+				if len(entries) != 0 {
+					t.Errorf("Mail.Send(%v) wrote %d files on error", tt.msg, len(entries))
+				}
+				return
+			}
+
+			if len(entries) != 1 || !strings.HasPrefix(entries[0].Name(), tt.msg.To+"_") {
+				t.Fatalf("Mail.Send(%v) did not write a single message file for the recipient", tt.msg)
+			}
+
+			content, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range []string{"From: noreply@test.com\r\n", "To: user@test.com\r\n", "Subject: Hello\r\n", "\r\n\r\nline one\r\nline two"} {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("Mail.Send(%v) message missing %q, got %q", tt.msg, want, content)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const (
+	NOTIFY_CHANNEL_EMAIL   = "email"
+	NOTIFY_CHANNEL_WEBHOOK = "webhook"
+	NOTIFY_CHANNEL_MEMORY  = "memory"
+)
+
+type Notification struct {
+	Id        string `json:"id"` // stays the same across retries, so receivers can drop repeats
+	Kind      string `json:"kind"`
+	UserSub   string `json:"userSub"`
+	To        string `json:"to"`
+	Subject   string `json:"subject"`
+	Text      string `json:"text"`
+	QuoteId   string `json:"quoteId,omitempty"`
+	BookingId string `json:"bookingId,omitempty"`
+}
+
+// Notifier delivers notifications on a single channel
+type Notifier interface {
+	Channel() string
+	Notify(ctx context.Context, notification *Notification) error
+}
+
+var (
+	_ Notifier = (*EmailNotifier)(nil)
+	_ Notifier = (*WebhookNotifier)(nil)
+	_ Notifier = (*MemoryNotifier)(nil)
+)
+
+type Notifications struct {
+	Notifiers map[string]Notifier
+}
+
+func InitNotifications(mail *Mail) *Notifications {
+	n := &Notifications{Notifiers: make(map[string]Notifier)}
+
+	channels := util.E_NOTIFY_CHANNELS
+	if channels == "" {
+		channels = NOTIFY_CHANNEL_EMAIL
+	}
+
+	for _, channel := range strings.Split(channels, ",") {
+		switch strings.TrimSpace(channel) {
+		case "":
+		case NOTIFY_CHANNEL_EMAIL:
+			n.Add(&EmailNotifier{Mail: mail})
+		case NOTIFY_CHANNEL_WEBHOOK:
+			if util.E_NOTIFY_WEBHOOK_URL == "" {
+				util.ErrorLog.Println("NOTIFY_WEBHOOK_URL is required by the webhook channel, webhook notifications are disabled")
+				continue
+			}
+			secret, err := util.GetEnvFilePath("NOTIFY_WEBHOOK_SECRET_FILE", 128)
+			if err != nil {
+				util.ErrorLog.Println(util.ErrCheck(fmt.Errorf("webhook notifications are disabled: %w", err)))
+				continue
+			}
+			n.Add(&WebhookNotifier{
+				URL:    util.E_NOTIFY_WEBHOOK_URL,
+				Secret: []byte(strings.TrimSpace(strings.TrimRight(secret, "\x00"))),
+				Client: &http.Client{Timeout: webhookTimeout},
+			})
+		case NOTIFY_CHANNEL_MEMORY:
+			n.Add(&MemoryNotifier{})
+		default:
+			util.ErrorLog.Printf("unknown notification channel %s", channel)
+		}
+	}
+
+	util.DebugLog.Println("Notifications Init")
+
+	return n
+}
+
+func (n *Notifications) Add(notifier Notifier) {
+	n.Notifiers[notifier.Channel()] = notifier
+}
+
+// Channels lists the configured channels, each notification is queued once per channel
+func (n *Notifications) Channels() []string {
+	channels := make([]string, 0, len(n.Notifiers))
+	for channel := range n.Notifiers {
+		channels = append(channels, channel)
+	}
+	slices.Sort(channels)
+	return channels
+}
+
+func (n *Notifications) Notify(ctx context.Context, channel string, notification *Notification) error {
+	notifier, ok := n.Notifiers[channel]
+	if !ok {
+		return util.ErrCheck(fmt.Errorf("unknown notification channel %s", channel))
+	}
+	return notifier.Notify(ctx, notification)
+}
+
+// EmailNotifier sends notifications through the configured mail sender
+type EmailNotifier struct {
+	Mail *Mail
+}
+
+func (en *EmailNotifier) Channel() string {
+	return NOTIFY_CHANNEL_EMAIL
+}
+
+func (en *EmailNotifier) Notify(ctx context.Context, notification *Notification) error {
+	if notification.To == "" {
+		return util.ErrCheck(errors.New("recipient has no email address"))
+	}
+
+	err := en.Mail.Send(ctx, &MailMessage{
+		To:      notification.To,
+		Subject: notification.Subject,
+		Text:    notification.Text,
+	})
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
+// WebhookNotifier posts notifications as json to one url, signed with SignWebhook
+type WebhookNotifier struct {
+	URL    string
+	Secret []byte
+	Client *http.Client
+}
+
+func (wn *WebhookNotifier) Channel() string {
+	return NOTIFY_CHANNEL_WEBHOOK
+}
+
+func (wn *WebhookNotifier) Notify(ctx context.Context, notification *Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	_, err = postSignedWebhook(ctx, wn.Client, wn.URL, wn.Secret, nil, body)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
+// MemoryNotifier keeps notifications instead of sending them, for tests
+type MemoryNotifier struct {
+	mu   sync.Mutex
+	sent []*Notification
+}
+
+func (mn *MemoryNotifier) Channel() string {
+	return NOTIFY_CHANNEL_MEMORY
+}
+
+func (mn *MemoryNotifier) Notify(ctx context.Context, notification *Notification) error {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	mn.sent = append(mn.sent, notification)
+	return nil
+}
+
+func (mn *MemoryNotifier) Sent() []*Notification {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	return slices.Clone(mn.sent)
+}
@@ -0,0 +1,113 @@
+package clients
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	notification := &Notification{Id: "job1", Kind: "booking_reminder", UserSub: "sub1", Subject: "Appointment reminder", Text: "Soon", QuoteId: "q1"}
+
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "accepted", status: http.StatusOK},
+		{name: "no content", status: http.StatusNoContent},
+		{name: "rejected", status: http.StatusBadRequest, wantErr: true},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := []byte("secret")
+			var got Notification
+			var gotSig bool
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				_ = json.Unmarshal(body, &got)
+
+				timestamp, v1, _ := strings.Cut(strings.TrimPrefix(r.Header.Get(WebhookSignatureHeader), "t="), ",v1=")
+				mac := hmac.New(sha256.New, secret)
+				mac.Write([]byte(timestamp + "."))
+				mac.Write(body)
+				gotSig = hmac.Equal([]byte(v1), []byte(hex.EncodeToString(mac.Sum(nil))))
+
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			wn := &WebhookNotifier{URL: server.URL, Secret: secret, Client: server.Client()}
+			err := wn.Notify(context.Background(), notification)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WebhookNotifier.Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !gotSig {
+				t.Errorf("WebhookNotifier.Notify() signature did not verify")
+			}
+			if got != *notification {
+				t.Errorf("WebhookNotifier.Notify() posted %+v, want %+v", got, *notification)
+			}
+		})
+	}
+}
+
+func TestEmailNotifier_Notify(t *testing.T) {
+	dir := t.TempDir()
+	en := &EmailNotifier{Mail: &Mail{Sender: &FileMailSender{Dir: dir}, From: "noreply@example.com"}}
+
+	err := en.Notify(context.Background(), &Notification{Subject: "Appointment confirmed", Text: "See you then"})
+	if err == nil {
+		t.Errorf("EmailNotifier.Notify() without an address error = nil, want error")
+	}
+
+	err = en.Notify(context.Background(), &Notification{To: "user@example.com", Subject: "Appointment confirmed", Text: "See you then"})
+	if err != nil {
+		t.Fatalf("EmailNotifier.Notify() error = %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "user@example.com_*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("EmailNotifier.Notify() wrote %d messages, want 1", len(files))
+	}
+	message, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(message), "See you then") {
+		t.Errorf("EmailNotifier.Notify() message = %q, want the notification text", message)
+	}
+}
+
+func TestNotifications_Notify(t *testing.T) {
+	memory := &MemoryNotifier{}
+	n := &Notifications{Notifiers: make(map[string]Notifier)}
+	n.Add(memory)
+	n.Add(&WebhookNotifier{Client: &http.Client{Timeout: time.Second}})
+
+	if got, want := n.Channels(), []string{NOTIFY_CHANNEL_MEMORY, NOTIFY_CHANNEL_WEBHOOK}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Notifications.Channels() = %v, want %v", got, want)
+	}
+
+	notification := &Notification{Id: "job1", Kind: "quote_created"}
+	if err := n.Notify(context.Background(), NOTIFY_CHANNEL_MEMORY, notification); err != nil {
+		t.Fatalf("Notifications.Notify() error = %v", err)
+	}
+	if err := n.Notify(context.Background(), "sms", notification); err == nil {
+		t.Errorf("Notifications.Notify() on an unknown channel error = nil, want error")
+	}
+
+	if sent := memory.Sent(); len(sent) != 1 || sent[0] != notification {
+		t.Errorf("MemoryNotifier.Sent() = %v, want the one notification", sent)
+	}
+}
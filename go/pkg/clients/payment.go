@@ -0,0 +1,151 @@
+package clients
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const (
+	PAYMENT_PROVIDER_FAKE = "fake"
+
+	PAYMENT_STATUS_PAID   = "paid"
+	PAYMENT_STATUS_FAILED = "failed"
+
+	fakePaymentSignatureHeader = "X-Payment-Signature"
+	fakePaymentTolerance       = 5 * time.Minute
+)
+
+var ErrInvalidPaymentSignature = errors.New("invalid payment webhook signature")
+
+// PaymentEvent is a provider neutral settlement of a seat payment, identified by the payment code
+// which is given to the provider as the order reference
+type PaymentEvent struct {
+	Code      string
+	Status    string
+	Reference string
+	Amount    int32
+	PaidOn    time.Time
+}
+
+// PaymentProvider verifies and parses webhook deliveries from a payment processor
+type PaymentProvider interface {
+	Name() string
+	ParseWebhook(header http.Header, body []byte) (*PaymentEvent, error)
+}
+
+type Payments struct {
+	Provider PaymentProvider
+}
+
+func InitPayments() *Payments {
+	var provider PaymentProvider
+
+	switch util.E_PAYMENT_PROVIDER {
+	case PAYMENT_PROVIDER_FAKE:
+		secret, err := util.GetEnvFilePath("PAYMENT_WEBHOOK_SECRET_FILE", 128)
+		if err != nil {
+			log.Fatal(util.ErrCheck(err))
+		}
+		provider = &FakePaymentProvider{Secret: []byte(secret)}
+	default:
+		// Without a provider, seat payments are only settled by recorded receipts
+	}
+
+	p := &Payments{
+		Provider: provider,
+	}
+
+	util.DebugLog.Println("Payments Init")
+
+	return p
+}
+
+// GetProvider returns the configured provider if it goes by name
+func (p *Payments) GetProvider(name string) (PaymentProvider, bool) {
+	if p.Provider == nil || p.Provider.Name() != name {
+		return nil, false
+	}
+	return p.Provider, true
+}
+
+// FakePaymentProvider settles payments from webhooks signed with a shared secret, in the same
+// format as common processors: a header of t=<unix time>,v1=<hex hmac-sha256 of "<unix time>.<body>">.
+// It is used by local environments and integration tests.
+type FakePaymentProvider struct {
+	Secret []byte
+}
+
+type fakePaymentPayload struct {
+	Code      string `json:"code"`
+	Status    string `json:"status"`
+	Reference string `json:"reference"`
+	Amount    int32  `json:"amount"`
+	PaidOn    int64  `json:"paidOn"`
+}
+
+func (fp *FakePaymentProvider) Name() string {
+	return PAYMENT_PROVIDER_FAKE
+}
+
+// Sign builds the signature header value for a body, as the provider would when delivering it
+func (fp *FakePaymentProvider) Sign(body []byte, at time.Time) (string, string) {
+	return fakePaymentSignatureHeader, SignWebhook(fp.Secret, body, at)
+}
+
+func (fp *FakePaymentProvider) ParseWebhook(header http.Header, body []byte) (*PaymentEvent, error) {
+	var timestamp, signature string
+	for _, part := range strings.Split(header.Get(fakePaymentSignatureHeader), ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return nil, ErrInvalidPaymentSignature
+	}
+
+	// Old deliveries are rejected so a captured request can't be replayed later on
+	if age := time.Since(time.Unix(signedAt, 0)); age > fakePaymentTolerance || age < -fakePaymentTolerance {
+		return nil, ErrInvalidPaymentSignature
+	}
+
+	expected := SignWebhook(fp.Secret, body, time.Unix(signedAt, 0))
+	if !hmac.Equal([]byte("t="+timestamp+",v1="+signature), []byte(expected)) {
+		return nil, ErrInvalidPaymentSignature
+	}
+
+	var payload fakePaymentPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	if payload.Code == "" || (payload.Status != PAYMENT_STATUS_PAID && payload.Status != PAYMENT_STATUS_FAILED) {
+		return nil, util.ErrCheck(errors.New("invalid payment webhook payload"))
+	}
+
+	event := &PaymentEvent{
+		Code:      payload.Code,
+		Status:    payload.Status,
+		Reference: payload.Reference,
+		Amount:    payload.Amount,
+	}
+
+	if payload.PaidOn > 0 {
+		event.PaidOn = time.Unix(payload.PaidOn, 0).UTC()
+	}
+
+	return event, nil
+}
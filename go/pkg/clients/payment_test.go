@@ -0,0 +1,90 @@
+package clients
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFakePaymentProvider_ParseWebhook(t *testing.T) {
+	fp := &FakePaymentProvider{Secret: []byte("secret")}
+	paidBody := []byte(`{"code":"abc123","status":"paid","reference":"txn_1","amount":50,"paidOn":1760000000}`)
+
+	signed := func(body []byte, at time.Time) http.Header {
+		name, value := fp.Sign(body, at)
+		header := http.Header{}
+		header.Set(name, value)
+		return header
+	}
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    []byte
+		want    *PaymentEvent
+		wantErr bool
+		badSig  bool
+	}{
+		{
+			name:   "paid event",
+			header: signed(paidBody, time.Now()),
+			body:   paidBody,
+			want:   &PaymentEvent{Code: "abc123", Status: PAYMENT_STATUS_PAID, Reference: "txn_1", Amount: 50, PaidOn: time.Unix(1760000000, 0).UTC()},
+		},
+		{
+			name:   "failed event",
+			header: signed([]byte(`{"code":"abc123","status":"failed","reference":"txn_2"}`), time.Now()),
+			body:   []byte(`{"code":"abc123","status":"failed","reference":"txn_2"}`),
+			want:   &PaymentEvent{Code: "abc123", Status: PAYMENT_STATUS_FAILED, Reference: "txn_2"},
+		},
+		{name: "missing signature", header: http.Header{}, body: paidBody, wantErr: true, badSig: true},
+		{name: "tampered body", header: signed(paidBody, time.Now()), body: []byte(`{"code":"abc123","status":"paid","reference":"txn_1","amount":5}`), wantErr: true, badSig: true},
+		{name: "other secret", header: func() http.Header {
+			other := &FakePaymentProvider{Secret: []byte("other")}
+			name, value := other.Sign(paidBody, time.Now())
+			return http.Header{name: {value}}
+		}(), body: paidBody, wantErr: true, badSig: true},
+		{name: "stale delivery", header: signed(paidBody, time.Now().Add(-time.Hour)), body: paidBody, wantErr: true, badSig: true},
+		{name: "unknown status", header: signed([]byte(`{"code":"abc123","status":"void"}`), time.Now()), body: []byte(`{"code":"abc123","status":"void"}`), wantErr: true},
+		{name: "missing code", header: signed([]byte(`{"status":"paid"}`), time.Now()), body: []byte(`{"status":"paid"}`), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fp.ParseWebhook(tt.header, tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FakePaymentProvider.ParseWebhook(%s) error = %v, wantErr %v", tt.body, err, tt.wantErr)
+			}
+			if errors.Is(err, ErrInvalidPaymentSignature) != tt.badSig {
+				t.Fatalf("FakePaymentProvider.ParseWebhook(%s) error = %v, want signature error %v", tt.body, err, tt.badSig)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *got != *tt.want {
+				t.Errorf("FakePaymentProvider.ParseWebhook(%s) = %+v, want %+v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPayments_GetProvider(t *testing.T) {
+	fp := &FakePaymentProvider{Secret: []byte("secret")}
+	tests := []struct {
+		name     string
+		p        *Payments
+		provider string
+		want     bool
+	}{
+		{name: "configured provider", p: &Payments{Provider: fp}, provider: PAYMENT_PROVIDER_FAKE, want: true},
+		{name: "other provider", p: &Payments{Provider: fp}, provider: "other", want: false},
+		{name: "no provider", p: &Payments{}, provider: PAYMENT_PROVIDER_FAKE, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := tt.p.GetProvider(tt.provider); got != tt.want {
+				t.Errorf("Payments.GetProvider(%v) = %v, want %v", tt.provider, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package clients
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -19,6 +21,11 @@ const (
 	CacheKeySuffixModTime = ":mod"
 
 	socketServerConnectionsKey = "socket_server_connections"
+	participantTopicsPrefix    = "participant_topics:"
+	socketIdTopicsPrefix       = "socket_id:"
+	socketIdTopicsSuffix       = ":topics"
+	socketNodeChannelPrefix    = "socket_node:"
+	socketBroadcastChannel     = "socket_nodes"
 )
 
 type Redis struct {
@@ -57,27 +64,179 @@ func ParticipantTopicsKey(topic string) (string, error) {
 	if topic == "" {
 		return "", util.ErrCheck(errors.New("malformed topic"))
 	}
-	return "participant_topics:" + topic, nil
+	return participantTopicsPrefix + topic, nil
 }
 
 func SocketIdTopicsKey(socketId string) (string, error) {
 	if socketId == "" {
 		return "", util.ErrCheck(errors.New("malformed topic"))
 	}
-	return "socket_id:" + socketId + ":topics", nil
+	return socketIdTopicsPrefix + socketId + socketIdTopicsSuffix, nil
 }
 
+// Each socket node listens on its own channel for messages to connections it holds
+func SocketNodeChannel(nodeId string) string {
+	return socketNodeChannelPrefix + nodeId
+}
+
+func ThrottleKey(userSub, methodName string) string {
+	return "throttle:" + userSub + ":" + methodName
+}
+
+func VaultNonceKey(sessionId string, ciphertext []byte) string {
+	ciphertextHash := sha256.Sum256(ciphertext)
+	return "vault_nonce:" + sessionId + ":" + hex.EncodeToString(ciphertextHash[:])
+}
+
+// Other nodes may be running, so only connections owned by nodes which are no longer
+// listening on their channel are removed
 func (r *Redis) InitKeys(ctx context.Context) {
-	_, err := r.Client().Del(ctx, socketServerConnectionsKey).Result()
+	keyType, err := r.Client().Type(ctx, socketServerConnectionsKey).Result()
 	if err != nil {
 		panic(err)
 	}
+
+	// connections were previously stored in a set without their node
+	if keyType != "hash" && keyType != "none" {
+		_, err := r.Client().Del(ctx, socketServerConnectionsKey).Result()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	err = r.RemoveStoppedSocketNodes(ctx)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// RemoveStoppedSocketNodes removes the connections, topic participants and socket topics recorded by
+// nodes which are no longer listening on their channel, as they went away without cleaning up.
+// Topic keys from before entries kept their node are removed, their sockets resubscribe on reconnect.
+func (r *Redis) RemoveStoppedSocketNodes(ctx context.Context) error {
+	connectionNodes, err := r.Client().HGetAll(ctx, socketServerConnectionsKey).Result()
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	// key -> field -> node
+	topicEntries := make(map[string]map[string]string)
+	for _, pattern := range []string{participantTopicsPrefix + "*", socketIdTopicsPrefix + "*" + socketIdTopicsSuffix} {
+		iter := r.Client().Scan(ctx, 0, pattern, 100).Iterator()
+		for iter.Next(ctx) {
+			key := iter.Val()
+
+			keyType, err := r.Client().Type(ctx, key).Result()
+			if err != nil {
+				return util.ErrCheck(err)
+			}
+
+			if keyType != "hash" {
+				if err := r.Client().Del(ctx, key).Err(); err != nil {
+					return util.ErrCheck(err)
+				}
+				continue
+			}
+
+			entries, err := r.Client().HGetAll(ctx, key).Result()
+			if err != nil {
+				return util.ErrCheck(err)
+			}
+			topicEntries[key] = entries
+		}
+		if err := iter.Err(); err != nil {
+			return util.ErrCheck(err)
+		}
+	}
+
+	nodeSet := make(map[string]struct{})
+	for _, nodeId := range connectionNodes {
+		nodeSet[nodeId] = struct{}{}
+	}
+	for _, entries := range topicEntries {
+		for _, nodeId := range entries {
+			nodeSet[nodeId] = struct{}{}
+		}
+	}
+
+	nodeIds := make([]string, 0, len(nodeSet))
+	for nodeId := range nodeSet {
+		nodeIds = append(nodeIds, nodeId)
+	}
+
+	listening, err := r.ListeningSocketNodes(ctx, nodeIds)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	var staleConnIds []string
+	for connId, nodeId := range connectionNodes {
+		if !listening[nodeId] {
+			staleConnIds = append(staleConnIds, connId)
+		}
+	}
+
+	err = r.RemoveConnectionNodes(ctx, staleConnIds...)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	for key, entries := range topicEntries {
+		var staleFields []string
+		for field, nodeId := range entries {
+			if !listening[nodeId] {
+				staleFields = append(staleFields, field)
+			}
+		}
+
+		if len(staleFields) == 0 {
+			continue
+		}
+
+		err = r.Client().HDel(ctx, key, staleFields...).Err()
+		if err != nil {
+			return util.ErrCheck(err)
+		}
+	}
+
+	return nil
+}
+
+// Returns which of the nodes are still listening on their channel, as a running node always is
+func (r *Redis) ListeningSocketNodes(ctx context.Context, nodeIds []string) (map[string]bool, error) {
+	listening := make(map[string]bool, len(nodeIds))
+	if len(nodeIds) == 0 {
+		return listening, nil
+	}
+
+	channels := make([]string, 0, len(nodeIds))
+	for _, nodeId := range nodeIds {
+		channels = append(channels, SocketNodeChannel(nodeId))
+	}
+
+	listeners, err := r.Client().PubSubNumSub(ctx, channels...).Result()
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	for _, nodeId := range nodeIds {
+		listening[nodeId] = listeners[SocketNodeChannel(nodeId)] > 0
+	}
+
+	return listening, nil
 }
 
-func (r *Redis) InitRedisSocketConnection(ctx context.Context, socketId string) error {
+// Records the node holding the socket's connection, so other nodes can relay messages to it
+func (r *Redis) InitRedisSocketConnection(ctx context.Context, socketId, nodeId string) error {
 	finish := util.RunTimer()
 	defer finish()
-	_, err := r.Client().SAdd(ctx, socketServerConnectionsKey, socketId).Result()
+
+	_, connId, err := util.SplitColonJoined(socketId)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	_, err = r.Client().HSet(ctx, socketServerConnectionsKey, connId, nodeId).Result()
 	if err != nil {
 		return util.ErrCheck(err)
 	}
@@ -85,29 +244,71 @@ func (r *Redis) InitRedisSocketConnection(ctx context.Context, socketId string)
 	return nil
 }
 
-func (r *Redis) HandleUnsub(ctx context.Context, socketId string) (map[string]string, error) {
+// Groups connection ids by the node which holds them, connections without a node are skipped
+func (r *Redis) GetConnectionNodes(ctx context.Context, connIds []string) (map[string]string, error) {
+	finish := util.RunTimer()
+	defer finish()
+
+	nodeTargets := make(map[string]string)
+	if len(connIds) == 0 {
+		return nodeTargets, nil
+	}
+
+	nodeIds, err := r.Client().HMGet(ctx, socketServerConnectionsKey, connIds...).Result()
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	for i, nodeId := range nodeIds {
+		if nodeIdStr, ok := nodeId.(string); ok && nodeIdStr != "" {
+			nodeTargets[nodeIdStr] += connIds[i]
+		}
+	}
+
+	return nodeTargets, nil
+}
+
+func (r *Redis) RemoveConnectionNodes(ctx context.Context, connIds ...string) error {
+	if len(connIds) == 0 {
+		return nil
+	}
+
+	_, err := r.Client().HDel(ctx, socketServerConnectionsKey, connIds...).Result()
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
+// Removes the socket from its topics. Returns the remaining targets of each topic that still
+// has participants, and the topics which no longer have any participants.
+func (r *Redis) HandleUnsub(ctx context.Context, socketId string) (map[string]string, []string, error) {
 	finish := util.RunTimer()
 	defer finish()
 
 	removedTopics := make(map[string]string)
+	var endedTopics []string
 
-	r.Client().SRem(ctx, socketServerConnectionsKey, socketId)
+	if _, connId, err := util.SplitColonJoined(socketId); err == nil {
+		r.Client().HDel(ctx, socketServerConnectionsKey, connId)
+	}
 
 	socketIdTopicsKey, err := SocketIdTopicsKey(socketId)
 	if err != nil {
-		return nil, util.ErrCheck(err)
+		return nil, nil, util.ErrCheck(err)
 	}
 
-	participantTopics, err := r.Client().SMembers(ctx, socketIdTopicsKey).Result()
+	participantTopics, err := r.Client().HKeys(ctx, socketIdTopicsKey).Result()
 	if err != nil {
-		return nil, util.ErrCheck(err)
+		return nil, nil, util.ErrCheck(err)
 	}
 
 	for _, participantTopic := range participantTopics {
 
-		r.Client().SRem(ctx, participantTopic, socketId)
+		r.Client().HDel(ctx, participantTopic, socketId)
 
-		socketIds, err := r.Client().SMembers(ctx, participantTopic).Result()
+		socketIds, err := r.Client().HKeys(ctx, participantTopic).Result()
 		if err != nil {
 			continue
 		}
@@ -126,6 +327,7 @@ func (r *Redis) HandleUnsub(ctx context.Context, socketId string) (map[string]st
 		}
 
 		if len(targets) == 0 {
+			endedTopics = append(endedTopics, strings.TrimPrefix(participantTopic, participantTopicsPrefix))
 			continue
 		}
 
@@ -136,7 +338,7 @@ func (r *Redis) HandleUnsub(ctx context.Context, socketId string) (map[string]st
 
 	r.Client().Del(ctx, socketIdTopicsKey)
 
-	return removedTopics, nil
+	return removedTopics, endedTopics, nil
 }
 
 func (r *Redis) RemoveTopicFromConnection(ctx context.Context, socketId, topic string) error {
@@ -147,7 +349,7 @@ func (r *Redis) RemoveTopicFromConnection(ctx context.Context, socketId, topic s
 		return util.ErrCheck(err)
 	}
 
-	_, err = r.Client().SRem(ctx, participantTopicsKey, socketId).Result()
+	_, err = r.Client().HDel(ctx, participantTopicsKey, socketId).Result()
 	if err != nil {
 		return util.ErrCheck(err)
 	}
@@ -157,7 +359,7 @@ func (r *Redis) RemoveTopicFromConnection(ctx context.Context, socketId, topic s
 		return util.ErrCheck(err)
 	}
 
-	_, err = r.Client().SRem(ctx, socketIdTopicsKey, participantTopicsKey).Result()
+	_, err = r.Client().HDel(ctx, socketIdTopicsKey, participantTopicsKey).Result()
 	if err != nil {
 		return util.ErrCheck(err)
 	}
@@ -173,7 +375,7 @@ func (r *Redis) GetCachedParticipants(ctx context.Context, topic string, targets
 		return nil, "", util.ErrCheck(err)
 	}
 
-	topicSocketIds, err := r.Client().SMembers(ctx, participantTopicsKey).Result()
+	topicSocketIds, err := r.Client().HKeys(ctx, participantTopicsKey).Result()
 	if err != nil {
 		return nil, "", util.ErrCheck(err)
 	}
@@ -209,7 +411,9 @@ func (r *Redis) GetCachedParticipants(ctx context.Context, topic string, targets
 	return sps, participantTargets.String(), nil
 }
 
-func (r *Redis) TrackTopicParticipant(ctx context.Context, topic, socketId string) error {
+// Records the socket as a participant of the topic. Both entries keep the node holding the socket,
+// so they can be removed if the node goes away without cleaning up.
+func (r *Redis) TrackTopicParticipant(ctx context.Context, topic, socketId, nodeId string) error {
 	finish := util.RunTimer()
 	defer finish()
 	participantTopicsKey, err := ParticipantTopicsKey(topic)
@@ -217,7 +421,7 @@ func (r *Redis) TrackTopicParticipant(ctx context.Context, topic, socketId strin
 		return util.ErrCheck(err)
 	}
 
-	err = r.Client().SAdd(ctx, participantTopicsKey, socketId).Err()
+	err = r.Client().HSet(ctx, participantTopicsKey, socketId, nodeId).Err()
 	if err != nil {
 		return util.ErrCheck(err)
 	}
@@ -232,7 +436,7 @@ func (r *Redis) TrackTopicParticipant(ctx context.Context, topic, socketId strin
 		return util.ErrCheck(err)
 	}
 
-	err = r.Client().SAdd(ctx, socketIdTopicsKey, participantTopicsKey).Err()
+	err = r.Client().HSet(ctx, socketIdTopicsKey, participantTopicsKey, nodeId).Err()
 	if err != nil {
 		return util.ErrCheck(err)
 	}
@@ -257,7 +461,7 @@ func (r *Redis) HasTracking(ctx context.Context, topic, socketId string) (bool,
 	}
 
 	// Check if this socket is already subscribed to this topic
-	isMember, err := r.Client().SIsMember(ctx, socketIdTopicsKey, participantTopicsKey).Result()
+	isMember, err := r.Client().HExists(ctx, socketIdTopicsKey, participantTopicsKey).Result()
 	if err != nil {
 		// On error, return false (safer to do the DB check)
 		return false, util.ErrCheck(err)
@@ -266,6 +470,54 @@ func (r *Redis) HasTracking(ctx context.Context, topic, socketId string) (bool,
 	return isMember, nil
 }
 
+// Claims a throttle window for a user's use of a service method. If the window is already
+// claimed, the remaining time before the method can be used again is returned.
+func (r *Redis) Throttle(ctx context.Context, userSub, methodName string, duration time.Duration) (time.Duration, error) {
+	throttleKey := ThrottleKey(userSub, methodName)
+
+	claimed, err := r.Client().SetNX(ctx, throttleKey, 1, duration).Result()
+	if err != nil {
+		return 0, util.ErrCheck(err)
+	}
+
+	if claimed {
+		return 0, nil
+	}
+
+	remaining, err := r.Client().PTTL(ctx, throttleKey).Result()
+	if err != nil {
+		return 0, util.ErrCheck(err)
+	}
+
+	// key expired between calls, or was somehow set without a ttl
+	if remaining <= 0 {
+		remaining = duration
+	}
+
+	return remaining, nil
+}
+
+// Gives up a throttle window claimed by Throttle, for requests which didn't go through
+func (r *Redis) ReleaseThrottle(ctx context.Context, userSub, methodName string) error {
+	err := r.Client().Del(ctx, ThrottleKey(userSub, methodName)).Err()
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
+// Records a vault ciphertext as seen for the session. Returns false if it was already seen,
+// meaning the payload is being replayed.
+func (r *Redis) ClaimVaultNonce(ctx context.Context, sessionId string, ciphertext []byte, duration time.Duration) (bool, error) {
+	claimed, err := r.Client().SetNX(ctx, VaultNonceKey(sessionId, ciphertext), 1, duration).Result()
+	if err != nil {
+		return false, util.ErrCheck(err)
+	}
+
+	return claimed, nil
+}
+
 func (r *Redis) ScanAndDelKeys(ctx context.Context, targetKeys []string, prependStr ...string) {
 	var prepend string
 	if len(prependStr) > 0 {
@@ -4,7 +4,9 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/keybittech/awayto-v3/go/pkg/types"
 	"github.com/redis/go-redis/v9"
 )
@@ -94,6 +96,73 @@ func TestSocketIdTopicsKey(t *testing.T) {
 	}
 }
 
+func TestSocketNodeChannel(t *testing.T) {
+	tests := []struct {
+		name   string
+		nodeId string
+		want   string
+	}{
+		{name: "Channels by node", nodeId: "node", want: "socket_node:node"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SocketNodeChannel(tt.nodeId); got != tt.want {
+				t.Errorf("SocketNodeChannel(%v) = %v, want %v", tt.nodeId, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThrottleKey(t *testing.T) {
+	type args struct {
+		userSub    string
+		methodName string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "Keys by user and method",
+			args: args{userSub: "sub", methodName: "PatchBookingRating"},
+			want: "throttle:sub:PatchBookingRating",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ThrottleKey(tt.args.userSub, tt.args.methodName); got != tt.want {
+				t.Errorf("ThrottleKey(%v, %v) = %v, want %v", tt.args.userSub, tt.args.methodName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultNonceKey(t *testing.T) {
+	type args struct {
+		sessionId  string
+		ciphertext []byte
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "Keys by session and ciphertext hash",
+			args: args{sessionId: "sid", ciphertext: []byte("ciphertext")},
+			want: "vault_nonce:sid:305531dcc50ebca31cf1d5b31e9fc76ed51f66b3b6dd5a030c6539ae6532f979",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VaultNonceKey(tt.args.sessionId, tt.args.ciphertext); got != tt.want {
+				t.Errorf("VaultNonceKey(%v, %v) = %v, want %v", tt.args.sessionId, tt.args.ciphertext, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRedis_InitKeys(t *testing.T) {
 	type args struct {
 		ctx context.Context
@@ -116,6 +185,57 @@ func TestRedis_InitRedisSocketConnection(t *testing.T) {
 	type args struct {
 		ctx      context.Context
 		socketId string
+		nodeId   string
+	}
+	tests := []struct {
+		name    string
+		r       *Redis
+		args    args
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.r.InitRedisSocketConnection(tt.args.ctx, tt.args.socketId, tt.args.nodeId); (err != nil) != tt.wantErr {
+				t.Errorf("Redis.InitRedisSocketConnection(%v, %v) error = %v, wantErr %v", tt.args.socketId, tt.args.nodeId, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedis_GetConnectionNodes(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		connIds []string
+	}
+	tests := []struct {
+		name    string
+		r       *Redis
+		args    args
+		want    map[string]string
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.r.GetConnectionNodes(tt.args.ctx, tt.args.connIds)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Redis.GetConnectionNodes(%v, %v) error = %v, wantErr %v", tt.args.ctx, tt.args.connIds, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Redis.GetConnectionNodes(%v, %v) = %v, want %v", tt.args.ctx, tt.args.connIds, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedis_RemoveConnectionNodes(t *testing.T) {
+	type args struct {
+		ctx     context.Context
+		connIds []string
 	}
 	tests := []struct {
 		name    string
@@ -127,8 +247,8 @@ func TestRedis_InitRedisSocketConnection(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := tt.r.InitRedisSocketConnection(tt.args.ctx, tt.args.socketId); (err != nil) != tt.wantErr {
-				t.Errorf("Redis.InitRedisSocketConnection(%v) error = %v, wantErr %v", tt.args.socketId, err, tt.wantErr)
+			if err := tt.r.RemoveConnectionNodes(tt.args.ctx, tt.args.connIds...); (err != nil) != tt.wantErr {
+				t.Errorf("Redis.RemoveConnectionNodes(%v, %v) error = %v, wantErr %v", tt.args.ctx, tt.args.connIds, err, tt.wantErr)
 			}
 		})
 	}
@@ -144,19 +264,23 @@ func TestRedis_HandleUnsub(t *testing.T) {
 		r       *Redis
 		args    args
 		want    map[string]string
+		want1   []string
 		wantErr bool
 	}{
 		// TODO: Add test cases.
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := tt.r.HandleUnsub(tt.args.ctx, tt.args.socketId)
+			got, got1, err := tt.r.HandleUnsub(tt.args.ctx, tt.args.socketId)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Redis.HandleUnsub(%v) error = %v, wantErr %v", tt.args.socketId, err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("Redis.HandleUnsub(%v) = %v, want %v", tt.args.socketId, got, tt.want)
+				t.Errorf("Redis.HandleUnsub(%v) got = %v, want %v", tt.args.socketId, got, tt.want)
+			}
+			if !reflect.DeepEqual(got1, tt.want1) {
+				t.Errorf("Redis.HandleUnsub(%v) got1 = %v, want %v", tt.args.socketId, got1, tt.want1)
 			}
 		})
 	}
@@ -223,6 +347,7 @@ func TestRedis_TrackTopicParticipant(t *testing.T) {
 		ctx      context.Context
 		topic    string
 		socketId string
+		nodeId   string
 	}
 	tests := []struct {
 		name    string
@@ -234,13 +359,72 @@ func TestRedis_TrackTopicParticipant(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := tt.r.TrackTopicParticipant(tt.args.ctx, tt.args.topic, tt.args.socketId); (err != nil) != tt.wantErr {
-				t.Errorf("Redis.TrackTopicParticipant(%v, %v, %v) error = %v, wantErr %v", tt.args.ctx, tt.args.topic, tt.args.socketId, err, tt.wantErr)
+			if err := tt.r.TrackTopicParticipant(tt.args.ctx, tt.args.topic, tt.args.socketId, tt.args.nodeId); (err != nil) != tt.wantErr {
+				t.Errorf("Redis.TrackTopicParticipant(%v, %v, %v, %v) error = %v, wantErr %v", tt.args.ctx, tt.args.topic, tt.args.socketId, tt.args.nodeId, err, tt.wantErr)
 			}
 		})
 	}
 }
 
+func TestRedis_RemoveStoppedSocketNodes(t *testing.T) {
+	ctx := context.Background()
+	r := InitRedis()
+
+	liveNodeId, stoppedNodeId := uuid.NewString(), uuid.NewString()
+	pubsub := r.Client().Subscribe(ctx, SocketNodeChannel(liveNodeId))
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		t.Fatalf("could not subscribe the live node, err %v", err)
+	}
+
+	topic := "exchange/0:" + uuid.NewString()
+	liveSocketId := uuid.NewString() + ":" + uuid.NewString()
+	stoppedSocketId := uuid.NewString() + ":" + uuid.NewString()
+
+	for socketId, nodeId := range map[string]string{liveSocketId: liveNodeId, stoppedSocketId: stoppedNodeId} {
+		if err := r.InitRedisSocketConnection(ctx, socketId, nodeId); err != nil {
+			t.Fatalf("InitRedisSocketConnection() error = %v", err)
+		}
+		if err := r.TrackTopicParticipant(ctx, topic, socketId, nodeId); err != nil {
+			t.Fatalf("TrackTopicParticipant() error = %v", err)
+		}
+	}
+	defer r.HandleUnsub(ctx, liveSocketId)
+
+	if err := r.RemoveStoppedSocketNodes(ctx); err != nil {
+		t.Fatalf("Redis.RemoveStoppedSocketNodes() error = %v", err)
+	}
+
+	participants, _, err := r.GetCachedParticipants(ctx, topic, false)
+	if err != nil {
+		t.Fatalf("GetCachedParticipants() error = %v", err)
+	}
+	if _, ok := participants[liveSocketId[:36]]; !ok {
+		t.Errorf("participant of the live node was removed")
+	}
+	if _, ok := participants[stoppedSocketId[:36]]; ok {
+		t.Errorf("participant of the stopped node was kept")
+	}
+
+	if tracked, _ := r.HasTracking(ctx, topic, stoppedSocketId); tracked {
+		t.Errorf("topic of the stopped node's socket was kept")
+	}
+	if tracked, _ := r.HasTracking(ctx, topic, liveSocketId); !tracked {
+		t.Errorf("topic of the live node's socket was removed")
+	}
+
+	nodes, err := r.GetConnectionNodes(ctx, []string{liveSocketId[37:], stoppedSocketId[37:]})
+	if err != nil {
+		t.Fatalf("GetConnectionNodes() error = %v", err)
+	}
+	if _, ok := nodes[stoppedNodeId]; ok {
+		t.Errorf("connection of the stopped node was kept")
+	}
+	if _, ok := nodes[liveNodeId]; !ok {
+		t.Errorf("connection of the live node was removed")
+	}
+}
+
 func TestRedis_HasTracking(t *testing.T) {
 	type args struct {
 		ctx      context.Context
@@ -269,3 +453,33 @@ func TestRedis_HasTracking(t *testing.T) {
 		})
 	}
 }
+
+func TestRedis_ClaimVaultNonce(t *testing.T) {
+	type args struct {
+		ctx        context.Context
+		sessionId  string
+		ciphertext []byte
+		duration   time.Duration
+	}
+	tests := []struct {
+		name    string
+		r       *Redis
+		args    args
+		want    bool
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.r.ClaimVaultNonce(tt.args.ctx, tt.args.sessionId, tt.args.ciphertext, tt.args.duration)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Redis.ClaimVaultNonce(%v, %v, %v, %v) error = %v, wantErr %v", tt.args.ctx, tt.args.sessionId, tt.args.ciphertext, tt.args.duration, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Redis.ClaimVaultNonce(%v, %v, %v, %v) = %v, want %v", tt.args.ctx, tt.args.sessionId, tt.args.ciphertext, tt.args.duration, got, tt.want)
+			}
+		})
+	}
+}
@@ -74,11 +74,16 @@ func NewSocketMaps() *SocketMaps {
 }
 
 type Socket struct {
-	handlerId  string
-	SocketMaps *SocketMaps
+	handlerId   string
+	NodeId      string
+	SocketMaps  *SocketMaps
+	redis       *Redis
+	cancelRelay context.CancelFunc
 }
 
-func InitSocket() *Socket {
+// InitSocket starts the socket worker. When r is provided, messages for connections held by
+// other nodes are relayed through redis, otherwise only local connections are reachable.
+func InitSocket(r *Redis) *Socket {
 
 	InitGlobalWorkerPool(4, 8)
 
@@ -233,6 +238,8 @@ func InitSocket() *Socket {
 			// 	connectionIds += k + " "
 			// }
 			// println("tar len", len(cmd.Request.Targets))
+			var unresolvedTargets string
+			frames := &socketFrames{text: cmd.Request.MessageBytes}
 			for i := 0; i+CID_LENGTH <= len(cmd.Request.Targets); i += CID_LENGTH {
 				connId := cmd.Request.Targets[i : i+CID_LENGTH]
 				// println("checking connid", connId)
@@ -247,24 +254,31 @@ func InitSocket() *Socket {
 						// }
 
 						// println("did match connection")
-						sendErr = conn.WriteMessage(websocket.TextMessage, cmd.Request.MessageBytes)
+						sendErr = frames.write(conn)
 						if sendErr != nil {
 							// println("FAILED WITH WRITE ", sendErr.Error())
 							continue
 						}
 						// println("did send success")
 						sentAtLeastOne = true
+					} else {
+						// held by another node, or no longer connected
+						unresolvedTargets += connId
 					}
 
 					attemptedTargets += connId
 				}
 			}
-			if !sentAtLeastOne && sendErr == nil {
+			if !sentAtLeastOne && sendErr == nil && unresolvedTargets == "" {
 				// println("FAILED WITH SEND ONE ")
 				sendErr = noTargetsToSend
 			}
 			cmd.ReplyChan <- SocketResponse{
 				Error: sendErr,
+				SocketResponseParams: &types.SocketResponseParams{
+					UnresolvedTargets: unresolvedTargets,
+					Sent:              sentAtLeastOne,
+				},
 			}
 			// println("============== End Send event ================\n")
 
@@ -344,12 +358,46 @@ func InitSocket() *Socket {
 		return true
 	})
 
-	util.DebugLog.Println("Sock Init")
-
-	return &Socket{
+	s := &Socket{
 		handlerId:  sockHandlerId,
+		NodeId:     uuid.NewString(),
 		SocketMaps: socketMaps,
+		redis:      r,
+	}
+
+	if r != nil {
+		err := s.initRelay()
+		if err != nil {
+			log.Fatal(util.ErrCheck(err))
+		}
+	}
+
+	util.DebugLog.Println("Sock Init")
+
+	return s
+}
+
+// socketFrames holds a message in the padded text format, encoding the protobuf frame once for any
+// targets which negotiated it
+type socketFrames struct {
+	text  []byte
+	proto []byte
+}
+
+func (f *socketFrames) write(conn *websocket.Conn) error {
+	if conn.Subprotocol() != util.SOCKET_PROTOCOL_PROTO {
+		return conn.WriteMessage(websocket.TextMessage, f.text)
+	}
+
+	if f.proto == nil {
+		protoBytes, err := util.TextToProtoMessage(util.DefaultPadding, f.text)
+		if err != nil {
+			return util.ErrCheck(err)
+		}
+		f.proto = protoBytes
 	}
+
+	return conn.WriteMessage(websocket.BinaryMessage, f.proto)
 }
 
 type SocketRequest struct {
@@ -381,6 +429,9 @@ func (s *Socket) RouteCommand(ctx context.Context, cmd SocketCommand) error {
 }
 
 func (s *Socket) Close() {
+	if s.cancelRelay != nil {
+		s.cancelRelay()
+	}
 	GetGlobalWorkerPool().UnregisterProcessFunction(s.handlerId)
 }
 
@@ -475,18 +526,56 @@ func (s *Socket) SendMessageBytes(ctx context.Context, userSub, targets string,
 		return util.ErrCheck(subRequiredToSend)
 	}
 
-	_, err := s.SendCommand(ctx, SendSocketMessageSocketCommand, &types.SocketRequestParams{
-		UserSub:      userSub,
-		Targets:      targets,
-		MessageBytes: messageBytes,
-	})
-	if err != nil {
+	// A failed local write doesn't stop targets held by other nodes from getting the message
+	response, localErr := s.sendLocalMessageBytes(ctx, userSub, targets, messageBytes)
+
+	if response.GetUnresolvedTargets() == "" {
+		return util.ErrCheck(localErr)
+	}
+
+	relayed, err := s.relayMessageBytes(ctx, userSub, response.GetUnresolvedTargets(), messageBytes)
+	if err = errors.Join(localErr, err); err != nil {
 		return util.ErrCheck(err)
 	}
 
+	if !relayed && !response.GetSent() {
+		return util.ErrCheck(noTargetsToSend)
+	}
+
 	return nil
 }
 
+// Writes to the targets connected to this node. The response lists the targets it doesn't hold,
+// and is returned along with any write error.
+func (s *Socket) sendLocalMessageBytes(ctx context.Context, userSub, targets string, messageBytes []byte) (*types.SocketResponseParams, error) {
+	finish := util.RunTimer()
+	defer finish()
+
+	if userSub == "" {
+		return nil, socketCommandMustHaveSub
+	}
+
+	createCmd := func(replyChan chan SocketResponse) SocketCommand {
+		return SocketCommand{
+			WorkerCommandParams: &types.WorkerCommandParams{
+				Ty:       SendSocketMessageSocketCommand,
+				ClientId: userSub,
+			},
+			Request: SocketRequest{
+				SocketRequestParams: &types.SocketRequestParams{
+					UserSub:      userSub,
+					Targets:      targets,
+					MessageBytes: messageBytes,
+				},
+			},
+			ReplyChan: replyChan,
+		}
+	}
+
+	res, err := SendCommand(ctx, s, createCmd)
+	return res.SocketResponseParams, ChannelError(err, res.Error)
+}
+
 func (s *Socket) SendMessage(ctx context.Context, userSub, targets string, message *types.SocketMessage) error {
 	finish := util.RunTimer()
 	defer finish()
@@ -496,7 +585,13 @@ func (s *Socket) SendMessage(ctx context.Context, userSub, targets string, messa
 	return s.SendMessageBytes(ctx, userSub, targets, util.GenerateMessage(util.DefaultPadding, message))
 }
 
+// RoleCall asks every connection of the user, on any node, to refresh its session
 func (s *Socket) RoleCall(userSub string) error {
+	s.broadcastRelay(&socketRelayMessage{Kind: relayRoleCall, UserSub: userSub})
+	return s.localRoleCall(userSub)
+}
+
+func (s *Socket) localRoleCall(userSub string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
@@ -523,7 +618,13 @@ func (s *Socket) RoleCall(userSub string) error {
 	return nil
 }
 
+// GroupRoleCall asks every connection in the group, on any node, to refresh its session
 func (s *Socket) GroupRoleCall(userSub, groupId string) error {
+	s.broadcastRelay(&socketRelayMessage{Kind: relayGroupRoleCall, UserSub: userSub, GroupId: groupId})
+	return s.localGroupRoleCall(userSub, groupId)
+}
+
+func (s *Socket) localGroupRoleCall(userSub, groupId string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
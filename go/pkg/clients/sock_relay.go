@@ -0,0 +1,169 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const (
+	relaySendMessage = iota
+	relayRoleCall
+	relayGroupRoleCall
+)
+
+const socketRelayTimeout = time.Second
+
+// socketRelayMessage carries socket work between nodes. Messages go to the node channel of the
+// node holding the targets, role calls are broadcast as any node may hold the user's connections.
+type socketRelayMessage struct {
+	Node    string `json:"node"`
+	Kind    int    `json:"kind"`
+	UserSub string `json:"userSub,omitempty"`
+	GroupId string `json:"groupId,omitempty"`
+	Targets string `json:"targets,omitempty"`
+	Message []byte `json:"message,omitempty"`
+}
+
+// initRelay subscribes to this node's channel and the broadcast channel. The subscription is
+// confirmed before returning, so connections are never recorded for a node which can't be reached.
+func (s *Socket) initRelay() error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	pubsub := s.redis.Client().Subscribe(ctx, SocketNodeChannel(s.NodeId), socketBroadcastChannel)
+
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		cancel()
+		pubsub.Close()
+		return util.ErrCheck(err)
+	}
+
+	s.cancelRelay = cancel
+
+	go func() {
+		<-ctx.Done()
+		pubsub.Close()
+	}()
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			s.handleRelay([]byte(msg.Payload))
+		}
+	}()
+
+	return nil
+}
+
+func (s *Socket) handleRelay(payload []byte) {
+	relayMessage := &socketRelayMessage{}
+	err := json.Unmarshal(payload, relayMessage)
+	if err != nil {
+		util.ErrorLog.Println(util.ErrCheck(err))
+		return
+	}
+
+	// broadcasts are also received by the node which sent them
+	if relayMessage.Node == s.NodeId {
+		return
+	}
+
+	switch relayMessage.Kind {
+	case relaySendMessage:
+		ctx, cancel := context.WithTimeout(context.Background(), socketRelayTimeout)
+		defer cancel()
+
+		_, err = s.sendLocalMessageBytes(ctx, relayMessage.UserSub, relayMessage.Targets, relayMessage.Message)
+	case relayRoleCall:
+		err = s.localRoleCall(relayMessage.UserSub)
+	case relayGroupRoleCall:
+		err = s.localGroupRoleCall(relayMessage.UserSub, relayMessage.GroupId)
+	}
+
+	if err != nil && !errors.Is(err, noTargetsToSend) {
+		util.ErrorLog.Println(util.ErrCheck(err))
+	}
+}
+
+// relayMessageBytes publishes the message to the nodes holding the targets. Returns false if
+// none of the targets are held by a listening node.
+func (s *Socket) relayMessageBytes(ctx context.Context, userSub, targets string, messageBytes []byte) (bool, error) {
+	if s.redis == nil {
+		return false, nil
+	}
+
+	connIds := make([]string, 0, len(targets)/CID_LENGTH)
+	for i := 0; i+CID_LENGTH <= len(targets); i += CID_LENGTH {
+		connIds = append(connIds, targets[i:i+CID_LENGTH])
+	}
+
+	nodeTargets, err := s.redis.GetConnectionNodes(ctx, connIds)
+	if err != nil {
+		return false, util.ErrCheck(err)
+	}
+
+	var relayed bool
+	for nodeId, targets := range nodeTargets {
+		// recorded for this node but no longer connected
+		if nodeId == s.NodeId {
+			continue
+		}
+
+		payload, err := json.Marshal(&socketRelayMessage{
+			Node:    s.NodeId,
+			Kind:    relaySendMessage,
+			UserSub: userSub,
+			Targets: targets,
+			Message: messageBytes,
+		})
+		if err != nil {
+			return relayed, util.ErrCheck(err)
+		}
+
+		receivers, err := s.redis.Client().Publish(ctx, SocketNodeChannel(nodeId), payload).Result()
+		if err != nil {
+			return relayed, util.ErrCheck(err)
+		}
+
+		// the node went away without cleaning up its connections
+		if receivers == 0 {
+			staleConnIds := make([]string, 0, len(targets)/CID_LENGTH)
+			for i := 0; i+CID_LENGTH <= len(targets); i += CID_LENGTH {
+				staleConnIds = append(staleConnIds, targets[i:i+CID_LENGTH])
+			}
+			if err := s.redis.RemoveConnectionNodes(ctx, staleConnIds...); err != nil {
+				util.ErrorLog.Println(util.ErrCheck(err))
+			}
+			continue
+		}
+
+		relayed = true
+	}
+
+	return relayed, nil
+}
+
+func (s *Socket) broadcastRelay(relayMessage *socketRelayMessage) {
+	if s.redis == nil {
+		return
+	}
+
+	relayMessage.Node = s.NodeId
+
+	payload, err := json.Marshal(relayMessage)
+	if err != nil {
+		util.ErrorLog.Println(util.ErrCheck(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), socketRelayTimeout)
+	defer cancel()
+
+	err = s.redis.Client().Publish(ctx, socketBroadcastChannel, payload).Err()
+	if err != nil {
+		util.ErrorLog.Println(util.ErrCheck(err))
+	}
+}
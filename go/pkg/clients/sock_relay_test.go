@@ -0,0 +1,66 @@
+package clients
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSocket_handleRelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       *Socket
+		payload []byte
+	}{
+		{name: "ignores malformed payloads", s: &Socket{NodeId: "node"}, payload: []byte("{")},
+		{name: "ignores its own broadcasts", s: &Socket{NodeId: "node"}, payload: []byte(`{"node":"node","kind":1,"userSub":"sub"}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.s.handleRelay(tt.payload)
+		})
+	}
+}
+
+func TestSocket_relayMessageBytes(t *testing.T) {
+	type args struct {
+		userSub      string
+		targets      string
+		messageBytes []byte
+	}
+	tests := []struct {
+		name    string
+		s       *Socket
+		args    args
+		want    bool
+		wantErr bool
+	}{
+		{name: "does not relay without redis", s: &Socket{}, args: args{"sub", testConnId, []byte("PING")}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.s.relayMessageBytes(context.Background(), tt.args.userSub, tt.args.targets, tt.args.messageBytes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Socket.relayMessageBytes(%v, %v) error = %v, wantErr %v", tt.args.targets, tt.args.messageBytes, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Socket.relayMessageBytes(%v, %v) = %v, want %v", tt.args.targets, tt.args.messageBytes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSocket_broadcastRelay(t *testing.T) {
+	tests := []struct {
+		name         string
+		s            *Socket
+		relayMessage *socketRelayMessage
+	}{
+		{name: "does nothing without redis", s: &Socket{}, relayMessage: &socketRelayMessage{Kind: relayRoleCall, UserSub: "sub"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.s.broadcastRelay(tt.relayMessage)
+		})
+	}
+}
@@ -22,8 +22,8 @@ func TestInitSocket(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := InitSocket(); got == nil {
-				t.Error("InitSocket() returned nil")
+			if got := InitSocket(nil); got == nil {
+				t.Error("InitSocket(nil) returned nil")
 			}
 		})
 	}
@@ -65,7 +65,7 @@ func getClientData(numClients int, commandType int32, requestParams *types.Socke
 
 // Benchmark the worker pool
 func doSendCommandBench(clientCount int, b *testing.B) {
-	socket := InitSocket()
+	socket := InitSocket(nil)
 	defer socket.Close()
 	_, createCommands, err := getClientData(
 		clientCount,
@@ -0,0 +1,170 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const (
+	WebhookSignatureHeader = "X-Awayto-Signature"
+
+	webhookTimeout       = 10 * time.Second
+	webhookResponseLimit = 1024
+)
+
+var ErrWebhookAddress = errors.New("webhook address is not public")
+
+// SignWebhook builds the signature header value for an outgoing body, in the same format as common
+// payment processors: t=<unix time>,v1=<hex hmac-sha256 of "<unix time>.<body>">
+func SignWebhook(secret, body []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Special purpose ranges from the IANA registries which are not reachable on the public internet, or which
+// could tunnel to an address that isn't (NAT64, 6to4, Teredo)
+var webhookDeniedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("fec0::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// IsPublicWebhookAddr reports whether a webhook may be delivered to the address. IPv4 addresses
+// written in IPv6 form are checked as IPv4.
+func IsPublicWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.Zone() != "" {
+		return false
+	}
+
+	for _, prefix := range webhookDeniedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Group webhook urls are chosen by group admins, so connections are only made to public addresses. The
+// dialer calls this with the resolved address it is about to connect to, so a hostname can't resolve
+// around the check.
+func publicAddressControl(network, address string, c syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	if !IsPublicWebhookAddr(addrPort.Addr()) {
+		return ErrWebhookAddress
+	}
+
+	return nil
+}
+
+// Webhooks delivers signed events to urls outside of the app
+type Webhooks struct {
+	Client *http.Client
+}
+
+func InitWebhooks() *Webhooks {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: publicAddressControl,
+	}
+
+	w := &Webhooks{
+		Client: &http.Client{
+			Timeout: webhookTimeout,
+			Transport: &http.Transport{
+				Proxy:               nil,
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: webhookTimeout,
+			},
+			// A redirect could lead anywhere, receivers have to answer at the url they gave
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+
+	util.DebugLog.Println("Webhooks Init")
+
+	return w
+}
+
+// Post sends a signed json body along with any extra headers. The response status is returned whenever
+// there was a response, and any status outside of 2xx is an error.
+func (w *Webhooks) Post(ctx context.Context, url string, secret []byte, header http.Header, body []byte) (int, error) {
+	return postSignedWebhook(ctx, w.Client, url, secret, header, body)
+}
+
+func postSignedWebhook(ctx context.Context, client *http.Client, url string, secret []byte, header http.Header, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, util.ErrCheck(err)
+	}
+
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, body, time.Now()))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, util.ErrCheck(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+		return resp.StatusCode, util.ErrCheck(fmt.Errorf("webhook responded %s %s", resp.Status, respBody))
+	}
+
+	return resp.StatusCode, nil
+}
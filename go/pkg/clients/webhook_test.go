@@ -0,0 +1,113 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignWebhook(t *testing.T) {
+	at := time.Unix(1760000000, 0)
+	body := []byte(`{"event":"booking.created"}`)
+
+	// The fake payment provider verifies the same format, so a signed body has to parse
+	fp := &FakePaymentProvider{Secret: []byte("secret")}
+	_, want := fp.Sign(body, at)
+
+	if got := SignWebhook([]byte("secret"), body, at); got != want {
+		t.Errorf("SignWebhook() = %s, want %s", got, want)
+	}
+	if got := SignWebhook([]byte("other"), body, at); got == want {
+		t.Errorf("SignWebhook() with another secret = %s, want a different signature", got)
+	}
+}
+
+func Test_publicAddressControl(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: "93.184.216.34:443"},
+		{address: "[2606:2800:220:1:248:1893:25c8:1946]:443"},
+		{address: "127.0.0.1:443", wantErr: true},
+		{address: "[::1]:443", wantErr: true},
+		{address: "10.0.0.8:80", wantErr: true},
+		{address: "172.16.4.1:80", wantErr: true},
+		{address: "192.168.1.20:8080", wantErr: true},
+		{address: "169.254.169.254:80", wantErr: true},
+		{address: "[fe80::1]:80", wantErr: true},
+		{address: "0.0.0.0:80", wantErr: true},
+		{address: "100.64.0.1:443", wantErr: true},
+		{address: "100.127.255.254:443", wantErr: true},
+		{address: "100.128.0.1:443"},
+		{address: "198.18.0.1:443", wantErr: true},
+		{address: "192.0.0.8:443", wantErr: true},
+		{address: "255.255.255.255:443", wantErr: true},
+		{address: "224.0.0.1:443", wantErr: true},
+		{address: "[::ffff:127.0.0.1]:443", wantErr: true},
+		{address: "[::ffff:10.0.0.8]:443", wantErr: true},
+		{address: "[64:ff9b::a00:8]:443", wantErr: true},
+		{address: "[2002:a00:8::1]:443", wantErr: true},
+		{address: "[2001:0:a00:8::1]:443", wantErr: true},
+		{address: "[fd00::1]:443", wantErr: true},
+		{address: "[::]:443", wantErr: true},
+		{address: "[fe80::1%eth0]:80", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := publicAddressControl("tcp", tt.address, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("publicAddressControl(%s) error = %v, wantErr %v", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhooks_Post(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Awayto-Event") != "booking.created" || r.Header.Get(WebhookSignatureHeader) == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	header := http.Header{"X-Awayto-Event": {"booking.created"}}
+
+	t.Run("delivered", func(t *testing.T) {
+		w := &Webhooks{Client: server.Client()}
+		status, err := w.Post(context.Background(), server.URL, []byte("secret"), header, []byte(`{}`))
+		if err != nil || status != http.StatusAccepted {
+			t.Errorf("Webhooks.Post() = %d, %v, want %d", status, err, http.StatusAccepted)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		w := &Webhooks{Client: server.Client()}
+		status, err := w.Post(context.Background(), server.URL, []byte("secret"), nil, []byte(`{}`))
+		if err == nil || status != http.StatusBadRequest {
+			t.Errorf("Webhooks.Post() = %d, %v, want %d and an error", status, err, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("private address refused", func(t *testing.T) {
+		status, err := InitWebhooks().Post(context.Background(), server.URL, []byte("secret"), header, []byte(`{}`))
+		if !errors.Is(err, ErrWebhookAddress) || status != 0 {
+			t.Errorf("Webhooks.Post() to %s = %d, %v, want no response and %v", server.URL, status, err, ErrWebhookAddress)
+		}
+	})
+
+	t.Run("hostname resolving to a private address refused", func(t *testing.T) {
+		_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+		url := "http://localhost:" + port
+		status, err := InitWebhooks().Post(context.Background(), url, []byte("secret"), header, []byte(`{}`))
+		if !errors.Is(err, ErrWebhookAddress) || status != 0 {
+			t.Errorf("Webhooks.Post() to %s = %d, %v, want no response and %v", url, status, err, ErrWebhookAddress)
+		}
+	})
+}
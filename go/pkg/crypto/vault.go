@@ -9,10 +9,12 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/hkdf"
@@ -24,41 +26,126 @@ const (
 	NonceSize         = 12   // AES-GCM Standard Nonce
 	replayWindow      = 5 * time.Minute
 	futureTolerance   = 2 * time.Minute
+
+	// ReplayCacheDuration is how long a ciphertext can pass timestamp verification,
+	// so seen ciphertexts must be remembered at least this long
+	ReplayCacheDuration = replayWindow + futureTolerance
 )
 
 // HybridPrivateKey holds both the Post-Quantum and Classical keys
 type HybridPrivateKey struct {
-	MLKEM  *mlkem.DecapsulationKey1024
-	X25519 *ecdh.PrivateKey
+	Id        string
+	CreatedOn time.Time
+	Encoded   string
+	MLKEM     *mlkem.DecapsulationKey1024
+	X25519    *ecdh.PrivateKey
 }
 
-var VaultKey *HybridPrivateKey
-var EncodedVaultKey string
+// VaultKeyring holds the key handed out to clients, and the key it replaced
+// while clients holding the old public key are still allowed to use it
+type VaultKeyring struct {
+	mu             sync.RWMutex
+	current        *HybridPrivateKey
+	previous       *HybridPrivateKey
+	previousExpiry time.Time
+}
 
-func InitVault() {
-	var err error
-	VaultKey = &HybridPrivateKey{}
+var VaultKey = &VaultKeyring{}
+
+func InitVault(store *VaultKeyStore) {
+	err := store.Sync(VaultKey, time.Now())
+	if err != nil {
+		log.Fatalf("Failed to load vault keys: %v", err)
+	}
+
+	log.Printf("Vault configured with Hybrid Encryption (ML-KEM-1024 + X25519), key %s.", VaultKey.Current().Id)
+}
 
+func NewHybridPrivateKey(createdOn time.Time) (*HybridPrivateKey, error) {
 	// 1. Generate Post-Quantum Key (ML-KEM)
-	VaultKey.MLKEM, err = mlkem.GenerateKey1024()
+	mlkemKey, err := mlkem.GenerateKey1024()
 	if err != nil {
-		log.Fatalf("Failed to generate ML-KEM key: %v", err)
+		return nil, fmt.Errorf("generate ml-kem key: %v", err)
 	}
 
 	// 2. Generate Classical Key (X25519)
-	VaultKey.X25519, err = ecdh.X25519().GenerateKey(rand.Reader)
+	x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
 	if err != nil {
-		log.Fatalf("Failed to generate X25519 key: %v", err)
+		return nil, fmt.Errorf("generate x25519 key: %v", err)
+	}
+
+	return newHybridPrivateKey(mlkemKey, x25519Key, createdOn), nil
+}
+
+func ParseHybridPrivateKey(mlkemSeed, x25519Bytes []byte, createdOn time.Time) (*HybridPrivateKey, error) {
+	mlkemKey, err := mlkem.NewDecapsulationKey1024(mlkemSeed)
+	if err != nil {
+		return nil, fmt.Errorf("parse ml-kem key: %v", err)
+	}
+
+	x25519Key, err := ecdh.X25519().NewPrivateKey(x25519Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse x25519 key: %v", err)
+	}
+
+	return newHybridPrivateKey(mlkemKey, x25519Key, createdOn), nil
+}
+
+func newHybridPrivateKey(mlkemKey *mlkem.DecapsulationKey1024, x25519Key *ecdh.PrivateKey, createdOn time.Time) *HybridPrivateKey {
+	// Create a combined Public Key for the client
+	combinedPub := append(mlkemKey.EncapsulationKey().Bytes(), x25519Key.PublicKey().Bytes()...)
+
+	// The key id is derived from the public key so every instance sharing the key agrees on it
+	pubHash := sha256.Sum256(combinedPub)
+
+	return &HybridPrivateKey{
+		Id:        hex.EncodeToString(pubHash[:8]),
+		CreatedOn: createdOn,
+		Encoded:   base64.StdEncoding.EncodeToString(combinedPub),
+		MLKEM:     mlkemKey,
+		X25519:    x25519Key,
 	}
+}
+
+// Current is the key which should be published to clients
+func (kr *VaultKeyring) Current() *HybridPrivateKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.current
+}
+
+// Set replaces the keyring contents, previous may be nil when there is nothing to phase out
+func (kr *VaultKeyring) Set(current, previous *HybridPrivateKey, previousExpiry time.Time) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.current = current
+	kr.previous = previous
+	kr.previousExpiry = previousExpiry
+}
 
-	// 3. Create a combined Public Key for the client
-	mlkemPub := VaultKey.MLKEM.EncapsulationKey().Bytes()
-	x25519Pub := VaultKey.X25519.PublicKey().Bytes()
+// decryptionKeys lists the keys a blob may have been encrypted with, the key
+// with a matching id when the client provided one, otherwise newest first
+func (kr *VaultKeyring) decryptionKeys(keyId string) []*HybridPrivateKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
 
-	combinedPub := append(mlkemPub, x25519Pub...)
-	EncodedVaultKey = base64.StdEncoding.EncodeToString(combinedPub)
+	keys := make([]*HybridPrivateKey, 0, 2)
+	if kr.current != nil {
+		keys = append(keys, kr.current)
+	}
+	if kr.previous != nil && time.Now().Before(kr.previousExpiry) {
+		keys = append(keys, kr.previous)
+	}
 
-	log.Println("Vault configured with Hybrid Encryption (ML-KEM-1024 + X25519).")
+	if keyId != "" {
+		for _, key := range keys {
+			if key.Id == keyId {
+				return []*HybridPrivateKey{key}
+			}
+		}
+	}
+
+	return keys
 }
 
 func packPlaintext(data []byte) []byte {
@@ -206,7 +293,26 @@ func ClientEncrypt(serverPubKeyBytes, plaintext []byte, sid string) ([]byte, []b
 }
 
 // ServerDecrypt (Server Side)
-func ServerDecrypt(dk *HybridPrivateKey, blob []byte, sid string) ([]byte, []byte, error) {
+// keyId is the id of the key the client encrypted with, if known
+func ServerDecrypt(kr *VaultKeyring, keyId string, blob []byte, sid string) ([]byte, []byte, error) {
+	keys := kr.decryptionKeys(keyId)
+	if len(keys) == 0 {
+		return nil, nil, errors.New("server dec: vault has no keys")
+	}
+
+	var err error
+	for _, dk := range keys {
+		var plaintext, combinedSecret []byte
+		plaintext, combinedSecret, err = serverDecryptWithKey(dk, blob, sid)
+		if err == nil {
+			return plaintext, combinedSecret, nil
+		}
+	}
+
+	return nil, nil, err
+}
+
+func serverDecryptWithKey(dk *HybridPrivateKey, blob []byte, sid string) ([]byte, []byte, error) {
 	minSize := KEMCiphertextSize + X25519PubKeySize + NonceSize
 	if len(blob) < minSize {
 		return nil, nil, errors.New("server dec: payload too short")
@@ -248,7 +354,7 @@ func ServerDecrypt(dk *HybridPrivateKey, blob []byte, sid string) ([]byte, []byt
 	aad := []byte(sid)
 	decryptedPacket, err := symDecrypt(aesKey, aesCT, aad)
 	if err != nil {
-		return nil, nil, fmt.Errorf("server dec: decrypt bad or server restarted and old key failed, %v", err)
+		return nil, nil, fmt.Errorf("server dec: decrypt bad or key %s was not used, %v", dk.Id, err)
 	}
 
 	// 7. Verify timestamp
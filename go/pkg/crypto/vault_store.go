@@ -0,0 +1,237 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	vaultKeyFileAAD     = "awayto_vault_keys"
+	vaultKeyLockTimeout = 10 * time.Second
+	vaultKeyLockStale   = 30 * time.Second
+
+	// DefaultVaultKeyGrace is how long the previous key is accepted when no grace is configured,
+	// enough for clients holding it to pick up the new key with their next session refresh
+	DefaultVaultKeyGrace = time.Hour
+)
+
+// VaultKeyStore keeps the vault keys in a file encrypted with Secret, so that
+// restarts and every instance sharing the file publish the same key. A Grace of
+// zero uses DefaultVaultKeyGrace.
+type VaultKeyStore struct {
+	Path        string
+	Secret      []byte
+	RotateEvery time.Duration
+	Grace       time.Duration
+}
+
+type storedVaultKey struct {
+	CreatedOn time.Time `json:"createdOn"`
+	MLKEM     []byte    `json:"mlkem"`
+	X25519    []byte    `json:"x25519"`
+}
+
+type storedVaultKeys struct {
+	Current   *storedVaultKey `json:"current"`
+	Previous  *storedVaultKey `json:"previous,omitempty"`
+	RotatedOn time.Time       `json:"rotatedOn"`
+}
+
+// Sync loads the stored keys into kr, generating and storing a new current
+// key first if there is none or it is older than RotateEvery
+func (vs *VaultKeyStore) Sync(kr *VaultKeyring, now time.Time) error {
+	if vs.Path == "" || len(vs.Secret) == 0 {
+		return errors.New("vault key store: path and secret are required")
+	}
+
+	unlock, err := vs.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stored, err := vs.read()
+	if err != nil {
+		return err
+	}
+
+	if stored == nil || stored.Current == nil || (vs.RotateEvery > 0 && now.Sub(stored.Current.CreatedOn) >= vs.RotateEvery) {
+		stored, err = vs.rotate(stored, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	current, err := ParseHybridPrivateKey(stored.Current.MLKEM, stored.Current.X25519, stored.Current.CreatedOn)
+	if err != nil {
+		return fmt.Errorf("vault key store: current key: %v", err)
+	}
+
+	// Reuse the loaded key when nothing changed, avoids expanding the same keys on every sync
+	if existing := kr.Current(); existing != nil && existing.Id == current.Id {
+		current = existing
+	}
+
+	var previous *HybridPrivateKey
+	grace := vs.Grace
+	if grace <= 0 {
+		grace = DefaultVaultKeyGrace
+	}
+
+	previousExpiry := stored.RotatedOn.Add(grace)
+	if stored.Previous != nil && now.Before(previousExpiry) {
+		previous, err = ParseHybridPrivateKey(stored.Previous.MLKEM, stored.Previous.X25519, stored.Previous.CreatedOn)
+		if err != nil {
+			return fmt.Errorf("vault key store: previous key: %v", err)
+		}
+	}
+
+	kr.Set(current, previous, previousExpiry)
+
+	return nil
+}
+
+func (vs *VaultKeyStore) rotate(stored *storedVaultKeys, now time.Time) (*storedVaultKeys, error) {
+	key, err := NewHybridPrivateKey(now)
+	if err != nil {
+		return nil, fmt.Errorf("vault key store: %v", err)
+	}
+
+	rotated := &storedVaultKeys{
+		Current: &storedVaultKey{
+			CreatedOn: now,
+			MLKEM:     key.MLKEM.Bytes(),
+			X25519:    key.X25519.Bytes(),
+		},
+		RotatedOn: now,
+	}
+
+	if stored != nil {
+		rotated.Previous = stored.Current
+	}
+
+	err = vs.write(rotated)
+	if err != nil {
+		return nil, err
+	}
+
+	return rotated, nil
+}
+
+func (vs *VaultKeyStore) fileKey() ([]byte, error) {
+	kdf := hkdf.New(sha256.New, vs.Secret, nil, []byte("AWAYTO_VAULT_KEY_FILE_V1"))
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(kdf, key); err != nil {
+		return nil, fmt.Errorf("vault key store: could not read kdf into key, %v", err)
+	}
+	return key, nil
+}
+
+func (vs *VaultKeyStore) read() (*storedVaultKeys, error) {
+	sealed, err := os.ReadFile(vs.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("vault key store: read: %v", err)
+	}
+
+	fileKey, err := vs.fileKey()
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := symDecrypt(fileKey, sealed, []byte(vaultKeyFileAAD))
+	if err != nil {
+		return nil, fmt.Errorf("vault key store: key file could not be decrypted with the configured secret, %v", err)
+	}
+
+	stored := &storedVaultKeys{}
+	err = json.Unmarshal(plaintext, stored)
+	if err != nil {
+		return nil, fmt.Errorf("vault key store: parse: %v", err)
+	}
+
+	return stored, nil
+}
+
+func (vs *VaultKeyStore) write(stored *storedVaultKeys) error {
+	plaintext, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("vault key store: marshal: %v", err)
+	}
+
+	fileKey, err := vs.fileKey()
+	if err != nil {
+		return err
+	}
+
+	sealed, err := symEncrypt(fileKey, plaintext, []byte(vaultKeyFileAAD))
+	if err != nil {
+		return fmt.Errorf("vault key store: %v", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(vs.Path), 0700)
+	if err != nil {
+		return fmt.Errorf("vault key store: %v", err)
+	}
+
+	// Write then rename so readers never see a partial file
+	tmpPath := vs.Path + ".tmp"
+	err = os.WriteFile(tmpPath, sealed, 0600)
+	if err != nil {
+		return fmt.Errorf("vault key store: write: %v", err)
+	}
+
+	err = os.Rename(tmpPath, vs.Path)
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("vault key store: write: %v", err)
+	}
+
+	return nil
+}
+
+// lock serializes rotation between instances sharing the key file
+func (vs *VaultKeyStore) lock() (func(), error) {
+	lockPath := vs.Path + ".lock"
+
+	err := os.MkdirAll(filepath.Dir(lockPath), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("vault key store: %v", err)
+	}
+
+	deadline := time.Now().Add(vaultKeyLockTimeout)
+	for {
+		lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			lockFile.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("vault key store: lock: %v", err)
+		}
+
+		// A crashed holder leaves the lock behind
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > vaultKeyLockStale {
+			os.Remove(lockPath)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New("vault key store: timed out waiting for lock")
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
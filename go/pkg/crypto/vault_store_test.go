@@ -0,0 +1,204 @@
+package crypto
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestVaultKeyStore(t *testing.T) *VaultKeyStore {
+	t.Helper()
+	return &VaultKeyStore{
+		Path:        filepath.Join(t.TempDir(), "vault_keys"),
+		Secret:      []byte("vault key test secret"),
+		RotateEvery: 24 * time.Hour,
+		Grace:       time.Hour,
+	}
+}
+
+func TestVaultKeyStore_Sync(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		grace        time.Duration
+		syncs        []time.Duration // offsets from start of each sync after the first
+		wantRotated  bool
+		wantPrevious bool
+	}{
+		{name: "reloads the stored key", syncs: []time.Duration{time.Hour}, grace: time.Hour},
+		{name: "rotates an expired key", syncs: []time.Duration{24 * time.Hour}, grace: time.Hour, wantRotated: true, wantPrevious: true},
+		{name: "keeps the previous key within grace", syncs: []time.Duration{24 * time.Hour, 24*time.Hour + 59*time.Minute}, grace: time.Hour, wantRotated: true, wantPrevious: true},
+		{name: "drops the previous key after grace", syncs: []time.Duration{24 * time.Hour, 25 * time.Hour}, grace: time.Hour, wantRotated: true},
+		{name: "zero grace uses the default", syncs: []time.Duration{24 * time.Hour, 24*time.Hour + DefaultVaultKeyGrace - time.Minute}, wantRotated: true, wantPrevious: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vs := newTestVaultKeyStore(t)
+			vs.Grace = tt.grace
+
+			kr := &VaultKeyring{}
+			if err := vs.Sync(kr, start); err != nil {
+				t.Fatalf("VaultKeyStore.Sync() error = %v", err)
+			}
+			first := kr.Current()
+
+			for _, offset := range tt.syncs {
+				if err := vs.Sync(kr, start.Add(offset)); err != nil {
+					t.Fatalf("VaultKeyStore.Sync(+%v) error = %v", offset, err)
+				}
+			}
+
+			if rotated := kr.Current().Id != first.Id; rotated != tt.wantRotated {
+				t.Errorf("VaultKeyStore.Sync() rotated = %v, want %v", rotated, tt.wantRotated)
+			}
+
+			kr.mu.RLock()
+			previous := kr.previous
+			kr.mu.RUnlock()
+
+			if (previous != nil) != tt.wantPrevious {
+				t.Fatalf("VaultKeyStore.Sync() previous = %v, want previous %v", previous, tt.wantPrevious)
+			}
+			if previous != nil && previous.Id != first.Id {
+				t.Errorf("VaultKeyStore.Sync() previous = %s, want the rotated key %s", previous.Id, first.Id)
+			}
+		})
+	}
+}
+
+func TestVaultKeyStore_SyncShared(t *testing.T) {
+	vs := newTestVaultKeyStore(t)
+	now := time.Now()
+
+	first, second := &VaultKeyring{}, &VaultKeyring{}
+	if err := vs.Sync(first, now); err != nil {
+		t.Fatalf("VaultKeyStore.Sync() error = %v", err)
+	}
+	if err := vs.Sync(second, now); err != nil {
+		t.Fatalf("VaultKeyStore.Sync() error = %v", err)
+	}
+
+	if first.Current().Id != second.Current().Id {
+		t.Errorf("VaultKeyStore.Sync() gave instances sharing a file keys %s and %s", first.Current().Id, second.Current().Id)
+	}
+}
+
+func TestVaultKeyStore_Sync_errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(vs *VaultKeyStore)
+	}{
+		{name: "no path", setup: func(vs *VaultKeyStore) { vs.Path = "" }},
+		{name: "no secret", setup: func(vs *VaultKeyStore) { vs.Secret = nil }},
+		{name: "wrong secret", setup: func(vs *VaultKeyStore) {
+			if err := vs.Sync(&VaultKeyring{}, time.Now()); err != nil {
+				t.Fatalf("VaultKeyStore.Sync() error = %v", err)
+			}
+			vs.Secret = []byte("some other secret")
+		}},
+		{name: "corrupt file", setup: func(vs *VaultKeyStore) {
+			if err := os.WriteFile(vs.Path, []byte("not a key file"), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vs := newTestVaultKeyStore(t)
+			tt.setup(vs)
+
+			if err := vs.Sync(&VaultKeyring{}, time.Now()); err == nil {
+				t.Errorf("VaultKeyStore.Sync() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestVaultKeyStore_readWrite(t *testing.T) {
+	vs := newTestVaultKeyStore(t)
+
+	stored, err := vs.read()
+	if err != nil || stored != nil {
+		t.Fatalf("VaultKeyStore.read() of a missing file = %v, %v, want nil, nil", stored, err)
+	}
+
+	createdOn := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := &storedVaultKeys{
+		Current:   &storedVaultKey{CreatedOn: createdOn, MLKEM: []byte("mlkem"), X25519: []byte("x25519")},
+		RotatedOn: createdOn,
+	}
+	if err := vs.write(want); err != nil {
+		t.Fatalf("VaultKeyStore.write() error = %v", err)
+	}
+
+	sealed, err := os.ReadFile(vs.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sealed) == 0 || bytes.Contains(sealed, []byte("mlkem")) {
+		t.Errorf("VaultKeyStore.write() stored the keys unencrypted")
+	}
+
+	got, err := vs.read()
+	if err != nil {
+		t.Fatalf("VaultKeyStore.read() error = %v", err)
+	}
+	if got.Previous != nil || !got.RotatedOn.Equal(createdOn) || string(got.Current.MLKEM) != "mlkem" || string(got.Current.X25519) != "x25519" {
+		t.Errorf("VaultKeyStore.read() = %+v, want %+v", got, want)
+	}
+
+	if _, err := os.Stat(vs.Path + ".tmp"); err == nil {
+		t.Errorf("VaultKeyStore.write() left its temp file behind")
+	}
+}
+
+func TestVaultKeyStore_lock(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(lockPath string)
+	}{
+		{name: "free"},
+		{name: "released", setup: func(lockPath string) {
+			if err := os.WriteFile(lockPath, nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+			os.Remove(lockPath)
+		}},
+		{name: "stale", setup: func(lockPath string) {
+			if err := os.WriteFile(lockPath, nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+			old := time.Now().Add(-2 * vaultKeyLockStale)
+			if err := os.Chtimes(lockPath, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vs := newTestVaultKeyStore(t)
+			lockPath := vs.Path + ".lock"
+			if tt.setup != nil {
+				tt.setup(lockPath)
+			}
+
+			unlock, err := vs.lock()
+			if err != nil {
+				t.Fatalf("VaultKeyStore.lock() error = %v", err)
+			}
+
+			if _, err := os.Stat(lockPath); err != nil {
+				t.Errorf("VaultKeyStore.lock() held without a lock file, %v", err)
+			}
+
+			unlock()
+
+			if _, err := os.Stat(lockPath); err == nil {
+				t.Errorf("VaultKeyStore.lock() unlock left the lock file")
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func newTestHybridKey(t *testing.T) *HybridPrivateKey {
+	t.Helper()
+	key, err := NewHybridPrivateKey(time.Now())
+	if err != nil {
+		t.Fatalf("NewHybridPrivateKey() error = %v", err)
+	}
+	return key
+}
+
+func TestVaultKeyring_decryptionKeys(t *testing.T) {
+	current, previous := newTestHybridKey(t), newTestHybridKey(t)
+	inGrace, expired := time.Now().Add(time.Hour), time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name           string
+		previous       *HybridPrivateKey
+		previousExpiry time.Time
+		keyId          string
+		want           []*HybridPrivateKey
+	}{
+		{name: "only current", want: []*HybridPrivateKey{current}},
+		{name: "previous in grace", previous: previous, previousExpiry: inGrace, want: []*HybridPrivateKey{current, previous}},
+		{name: "previous expired", previous: previous, previousExpiry: expired, want: []*HybridPrivateKey{current}},
+		{name: "current by id", previous: previous, previousExpiry: inGrace, keyId: current.Id, want: []*HybridPrivateKey{current}},
+		{name: "previous by id", previous: previous, previousExpiry: inGrace, keyId: previous.Id, want: []*HybridPrivateKey{previous}},
+		{name: "expired previous by id", previous: previous, previousExpiry: expired, keyId: previous.Id, want: []*HybridPrivateKey{current}},
+		{name: "unknown id", previous: previous, previousExpiry: inGrace, keyId: "unknown", want: []*HybridPrivateKey{current, previous}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kr := &VaultKeyring{}
+			kr.Set(current, tt.previous, tt.previousExpiry)
+
+			got := kr.decryptionKeys(tt.keyId)
+			if len(got) != len(tt.want) {
+				t.Fatalf("VaultKeyring.decryptionKeys(%q) = %d keys, want %d", tt.keyId, len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i].Id != tt.want[i].Id {
+					t.Errorf("VaultKeyring.decryptionKeys(%q)[%d] = %s, want %s", tt.keyId, i, got[i].Id, tt.want[i].Id)
+				}
+			}
+		})
+	}
+}
+
+func TestServerDecrypt_rotatedKey(t *testing.T) {
+	current, previous := newTestHybridKey(t), newTestHybridKey(t)
+
+	previousPub, err := base64.StdEncoding.DecodeString(previous.Encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		previousExpiry time.Time
+		keyId          string
+		wantErr        bool
+	}{
+		{name: "in grace", previousExpiry: time.Now().Add(time.Hour), keyId: previous.Id},
+		{name: "in grace without an id", previousExpiry: time.Now().Add(time.Hour)},
+		{name: "after grace", previousExpiry: time.Now().Add(-time.Minute), keyId: previous.Id, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kr := &VaultKeyring{}
+			kr.Set(current, previous, tt.previousExpiry)
+
+			blob, _, err := ClientEncrypt(previousPub, []byte("hello"), "sid")
+			if err != nil {
+				t.Fatalf("ClientEncrypt() error = %v", err)
+			}
+
+			plaintext, _, err := ServerDecrypt(kr, tt.keyId, blob, "sid")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ServerDecrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(plaintext) != "hello" {
+				t.Errorf("ServerDecrypt() = %q, want %q", plaintext, "hello")
+			}
+		})
+	}
+}
@@ -8,7 +8,7 @@ import (
 )
 
 func (h *Handlers) GetSuggestion(info ReqInfo, data *types.GetSuggestionRequest) (*types.GetSuggestionResponse, error) {
-	if !info.Session.GetGroupAi() {
+	if !info.Session.GetGroupAi() || !h.LLM.Enabled() {
 		return &types.GetSuggestionResponse{PromptResult: []string{}}, nil
 
 	}
@@ -22,25 +22,16 @@ func (h *Handlers) GetSuggestion(info ReqInfo, data *types.GetSuggestionRequest)
 
 		for range 3 {
 
-			resp, err := h.LLM.GetCandidateResponse(info.Ctx, promptParts, types.IPrompts(promptType))
+			candidate, err := h.LLM.GetCandidateResponse(info.Ctx, promptParts, types.IPrompts(promptType))
 			if err != nil {
 				return nil, util.ErrCheck(err)
 			}
 
-			if resp == nil {
+			if candidate == "" {
 				continue
 			}
 
-			if len(resp.Candidates) < 1 {
-				continue
-			}
-
-			candidate := resp.Candidates[0]
-			if len(candidate.Content.Parts) < 1 {
-				continue
-			}
-
-			options := strings.Split(strings.Trim(candidate.Content.Parts[0].Text, `"`), "|")
+			options := strings.Split(strings.Trim(candidate, `"`), "|")
 			if len(options) != 5 {
 				continue
 			}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"strings"
 
 	"github.com/keybittech/awayto-v3/go/pkg/clients"
@@ -38,7 +39,12 @@ func (h *Handlers) AuthWebhook_REGISTER(info ReqInfo, authEvent *types.AuthEvent
 		return nil, util.ErrCheck(err)
 	}
 
-	if authEvent.GroupCode != "" {
+	if authEvent.Invite != "" {
+		err := h.redeemGroupInvite(info, authEvent.Invite)
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+	} else if authEvent.GroupCode != "" {
 		_, err := h.JoinGroup(info, &types.JoinGroupRequest{
 			Code:        authEvent.GroupCode,
 			Registering: true,
@@ -76,6 +82,10 @@ func (h *Handlers) AuthWebhook_REGISTER_VALIDATE(info ReqInfo, authEvent *types.
 		}
 	}()
 
+	if authEvent.Invite != "" {
+		return h.validateRegisterInvite(info, authEvent.Invite), nil
+	}
+
 	batch := util.NewBatchable(h.Database.DatabaseClient.Pool, "worker", "", 0)
 
 	// Cast the check for roleId against defaultRoleId as defaultRoleId could be empty
@@ -102,3 +112,51 @@ func (h *Handlers) AuthWebhook_REGISTER_VALIDATE(info ReqInfo, authEvent *types.
 
 	return &types.AuthWebhookResponse{Value: authRes.String()}, nil
 }
+
+type registerInviteValidation struct {
+	Success        bool   `json:"success"`
+	GroupName      string `json:"groupName" db:"groupName"`
+	AllowedDomains string `json:"allowedDomains"`
+	RoleGroupId    string `json:"roleGroupId" db:"roleGroupId"`
+	Email          string `json:"email" db:"email"`
+}
+
+// Invites are validated but not used up here, REGISTER redeems them once keycloak creates the user.
+// The invite's role subgroup is returned in place of the group's default role, and keycloak
+// requires the registration email to match the invited email.
+func (h *Handlers) validateRegisterInvite(info ReqInfo, invite string) *types.AuthWebhookResponse {
+	badInvite := &types.AuthWebhookResponse{Value: `{ "success": false, "reason": "BAD_INVITE" }`}
+
+	err := util.VerifyInviteToken(invite)
+	if err != nil {
+		return badInvite
+	}
+
+	batch := util.NewBatchable(h.Database.DatabaseClient.Pool, "worker", "", 0)
+
+	inviteLookup := util.BatchQuery[registerInviteValidation](batch, `
+		SELECT g.display_name as "groupName", gr.external_id as "roleGroupId", gi.email
+		FROM dbtable_schema.group_invites gi
+		JOIN dbtable_schema.groups g ON g.id = gi.group_id
+		JOIN dbtable_schema.group_roles gr ON gr.id = gi.group_role_id
+		WHERE gi.token_hash = $1 AND gi.accepted_on IS NULL AND gi.revoked_on IS NULL
+			AND gi.enabled = true AND gi.expires_on > TIMEZONE('utc', NOW())
+	`, util.HashToken(invite))
+
+	batch.Send(info.Ctx)
+
+	if len(*inviteLookup) == 0 {
+		return badInvite
+	}
+
+	validation := (*inviteLookup)[0]
+	validation.Success = true
+
+	validationBytes, err := json.Marshal(validation)
+	if err != nil {
+		util.ErrorLog.Println(util.ErrCheck(err))
+		return badInvite
+	}
+
+	return &types.AuthWebhookResponse{Value: string(validationBytes)}
+}
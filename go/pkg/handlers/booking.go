@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	json "encoding/json"
 	"errors"
 	"time"
 
@@ -11,6 +12,14 @@ import (
 func (h *Handlers) PostBooking(info ReqInfo, data *types.PostBookingRequest) (*types.PostBookingResponse, error) {
 	newBookings := make([]*types.IBooking, 0)
 
+	if data.GetSeriesId() != "" {
+		seriesBookings, err := addSeriesBookings(info, data.GetSeriesId(), data.GetBookings())
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+		data.Bookings = seriesBookings
+	}
+
 	var scheduleBracketSlotId string
 	for i, booking := range data.Bookings {
 		if i == 0 {
@@ -37,19 +46,42 @@ func (h *Handlers) PostBooking(info ReqInfo, data *types.PostBookingRequest) (*t
 	}
 
 	for _, booking := range data.Bookings {
+		// The booking keeps the quote's price, later catalog changes don't reprice it
 		var quoteCreatedSub string
+		var priceJson []byte
 		err = info.Tx.QueryRow(info.Ctx, `
-			SELECT created_sub
+			SELECT created_sub, price
 			FROM dbtable_schema.quotes
 			WHERE id = $1
-		`, booking.Quote.Id).Scan(&quoteCreatedSub)
+		`, booking.Quote.Id).Scan(&quoteCreatedSub, &priceJson)
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
 
 		var newBooking types.IBooking
 		err := info.Tx.QueryRow(info.Ctx, `
-			INSERT INTO dbtable_schema.bookings (quote_id, slot_date, schedule_bracket_slot_id, created_sub, quote_created_sub)
-			VALUES ($1::uuid, $2::date, $3::uuid, $4::uuid, $5::uuid)
+			INSERT INTO dbtable_schema.bookings (quote_id, slot_date, schedule_bracket_slot_id, created_sub, quote_created_sub, price)
+			VALUES ($1::uuid, $2::date, $3::uuid, $4::uuid, $5::uuid, $6::jsonb)
 			RETURNING id
-		`, booking.Quote.Id, booking.Quote.SlotDate, scheduleBracketSlotId, info.Session.GetUserSub(), quoteCreatedSub).Scan(&newBooking.Id)
+		`, booking.Quote.Id, booking.Quote.SlotDate, scheduleBracketSlotId, info.Session.GetUserSub(), quoteCreatedSub, priceJson).Scan(&newBooking.Id)
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+
+		if priceJson != nil {
+			newBooking.Price = &types.IPriceSnapshot{}
+			if err := json.Unmarshal(priceJson, newBooking.Price); err != nil {
+				return nil, util.ErrCheck(err)
+			}
+		}
+
+		err = queueGroupWebhookEvent(info, info.Session.GetGroupId(), GROUP_WEBHOOK_BOOKING_CREATED, groupWebhookBooking{
+			BookingId:             newBooking.Id,
+			QuoteId:               booking.Quote.Id,
+			SlotDate:              booking.Quote.SlotDate,
+			ScheduleBracketSlotId: scheduleBracketSlotId,
+			UserSub:               info.Session.GetUserSub(),
+		})
 		if err != nil {
 			return nil, util.ErrCheck(err)
 		}
@@ -63,6 +95,16 @@ func (h *Handlers) PostBooking(info ReqInfo, data *types.PostBookingRequest) (*t
 		newBookings = append(newBookings, &newBooking)
 	}
 
+	bookingIds := make([]string, 0, len(newBookings))
+	for _, newBooking := range newBookings {
+		bookingIds = append(bookingIds, newBooking.Id)
+	}
+
+	err = h.queueBookingNotifications(info, bookingIds)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
 	return &types.PostBookingResponse{Bookings: newBookings}, nil
 }
 
@@ -80,7 +122,7 @@ func (h *Handlers) PatchBooking(info ReqInfo, data *types.PatchBookingRequest) (
 
 func (h *Handlers) GetBookings(info ReqInfo, data *types.GetBookingsRequest) (*types.GetBookingsResponse, error) {
 	bookings := util.BatchQuery[types.IBooking](info.Batch, `
-		SELECT eb.id, eb.rating, eb."slotDate", eb."quoteId", eb."scheduleBracketSlotId", eb."tierSurveyVersionSubmissionId", eb."serviceSurveyVersionSubmissionId", eb."createdOn", eb.quote, eb.service, eb."scheduleBracketSlot", eb."serviceTier"
+		SELECT eb.id, eb.rating, eb."slotDate", eb."quoteId", eb."scheduleBracketSlotId", eb."tierSurveyVersionSubmissionId", eb."serviceSurveyVersionSubmissionId", eb."createdOn", eb.quote, eb.service, eb."scheduleBracketSlot", eb."serviceTier", eb.price
 		FROM dbview_schema.enabled_bookings eb
 		JOIN dbtable_schema.bookings b ON b.id = eb.id
 		LEFT JOIN dbtable_schema.schedule_bracket_slots sbs ON sbs.id = eb.schedule_bracket_slot_id
@@ -94,7 +136,7 @@ func (h *Handlers) GetBookings(info ReqInfo, data *types.GetBookingsRequest) (*t
 
 func (h *Handlers) GetBookingById(info ReqInfo, data *types.GetBookingByIdRequest) (*types.GetBookingByIdResponse, error) {
 	booking := util.BatchQueryRow[types.IBooking](info.Batch, `
-		SELECT eb.id, eb.rating, eb."slotDate", eb."quoteId", eb."scheduleBracketSlotId", eb."tierSurveyVersionSubmissionId", eb."serviceSurveyVersionSubmissionId", eb."createdOn", eb.quote, eb.service, eb."scheduleBracketSlot", eb."serviceTier"
+		SELECT eb.id, eb.rating, eb."slotDate", eb."quoteId", eb."scheduleBracketSlotId", eb."tierSurveyVersionSubmissionId", eb."serviceSurveyVersionSubmissionId", eb."createdOn", eb.quote, eb.service, eb."scheduleBracketSlot", eb."serviceTier", eb.price
 		FROM dbview_schema.enabled_bookings eb
 		WHERE eb.id = $1
 	`, data.Id)
@@ -118,6 +160,28 @@ func (h *Handlers) GetBookingFiles(info ReqInfo, data *types.GetBookingFilesRequ
 	return &types.GetBookingFilesResponse{Files: *files}, nil
 }
 
+// GetBookingCallLog lists when each participant connected to and disconnected from the booking's exchange.
+// Sessions still running are measured up to now.
+func (h *Handlers) GetBookingCallLog(info ReqInfo, data *types.GetBookingCallLogRequest) (*types.GetBookingCallLogResponse, error) {
+	callLog := util.BatchQuery[types.IExchangeCallLog](info.Batch, `
+		SELECT
+			ecl.id,
+			ecl.booking_id as "bookingId",
+			ecl.style,
+			ecl.created_sub as "userSub",
+			ecl.connected::TEXT as connected,
+			COALESCE(ecl.disconnected::TEXT, '') as disconnected,
+			EXTRACT(EPOCH FROM (COALESCE(ecl.disconnected, TIMEZONE('utc', NOW())) - ecl.connected))::INTEGER as "durationSeconds"
+		FROM dbtable_schema.exchange_call_log ecl
+		WHERE ecl.booking_id = $1 AND ecl.enabled = true
+		ORDER BY ecl.connected
+	`, data.Id)
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.GetBookingCallLogResponse{CallLog: *callLog}, nil
+}
+
 func (h *Handlers) PatchBookingRating(info ReqInfo, data *types.PatchBookingRatingRequest) (*types.PatchBookingRatingResponse, error) {
 	util.BatchExec(info.Batch, `
 		UPDATE dbtable_schema.bookings
@@ -131,6 +195,20 @@ func (h *Handlers) PatchBookingRating(info ReqInfo, data *types.PatchBookingRati
 }
 
 func (h *Handlers) DeleteBooking(info ReqInfo, data *types.DeleteBookingRequest) (*types.DeleteBookingResponse, error) {
+	// Queued first, the booking is needed to find its quote
+	err := h.queueQuoteNotifications(info, NOTIFY_QUOTE_CANCELLED, nil, []string{data.Id})
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	err = queueGroupWebhookEvent(info, info.Session.GetGroupId(), GROUP_WEBHOOK_BOOKING_CANCELLED, groupWebhookBooking{
+		BookingId: data.Id,
+		UserSub:   info.Session.GetUserSub(),
+	})
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
 	util.BatchExec(info.Batch, `
 		DELETE FROM dbtable_schema.bookings
 		WHERE id = $1
@@ -148,6 +226,19 @@ func (h *Handlers) DisableBooking(info ReqInfo, data *types.DisableBookingReques
 		WHERE id = $1
 	`, data.Id, time.Now(), info.Session.GetUserSub())
 
+	err := h.queueQuoteNotifications(info, NOTIFY_QUOTE_CANCELLED, nil, []string{data.Id})
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	err = queueGroupWebhookEvent(info, info.Session.GetGroupId(), GROUP_WEBHOOK_BOOKING_CANCELLED, groupWebhookBooking{
+		BookingId: data.Id,
+		UserSub:   info.Session.GetUserSub(),
+	})
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
 	info.Batch.Send(info.Ctx)
 
 	return &types.DisableBookingResponse{Id: data.Id}, nil
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+var ErrInvalidCalendarToken = errors.New("invalid calendar token")
+
+const (
+	bookingCalendarName      = "Bookings"
+	bookingCalendarFeedLimit = 500
+	bookingCalendarFeedPath  = "/api/ics/bookings/"
+)
+
+// Bookings keep the day of the slot, and the slot start time is an offset from the start of the week
+// or schedule cycle. Together they resolve to a wall clock time in the group schedule timezone.
+const bookingCalendarEventsQuery = `
+	SELECT
+		b.id::TEXT || '@' || $1 as uid,
+		se.name || ' - ' || st.name as summary,
+		gs.name as description,
+		gs.timezone as "timeZone",
+		occurrence.start as start,
+		occurrence.start + (s.slot_duration || ' ' || stu.name)::INTERVAL as "end",
+		COALESCE(b.updated_on, b.created_on) as stamp
+	FROM dbtable_schema.bookings b
+	JOIN dbtable_schema.quotes q ON q.id = b.quote_id
+	JOIN dbtable_schema.service_tiers st ON st.id = q.service_tier_id
+	JOIN dbtable_schema.services se ON se.id = st.service_id
+	JOIN dbtable_schema.schedule_bracket_slots sbs ON sbs.id = b.schedule_bracket_slot_id
+	JOIN dbtable_schema.schedule_brackets sb ON sb.id = sbs.schedule_bracket_id
+	JOIN dbtable_schema.schedules s ON s.id = sb.schedule_id
+	JOIN dbtable_schema.time_units stu ON stu.id = s.slot_time_unit_id
+	JOIN dbtable_schema.group_user_schedules gus ON gus.user_schedule_id = s.id
+	JOIN dbtable_schema.schedules gs ON gs.id = gus.group_schedule_id
+	JOIN dbtable_schema.time_units gstu ON gstu.id = gs.schedule_time_unit_id
+	CROSS JOIN LATERAL (
+		SELECT dbfunc_schema.slot_occurrence_start(b.slot_date, sbs.start_time, gstu.name, gs.start_date::DATE) as start
+	) occurrence
+	WHERE b.enabled = true
+`
+
+type calendarTokenOwner struct {
+	UserSub string
+	GroupId string
+}
+
+func (h *Handlers) GetBookingCalendar(info ReqInfo, data *types.GetBookingCalendarRequest) (*types.GetBookingCalendarResponse, error) {
+	events := util.BatchQuery[util.CalendarEvent](info.Batch, bookingCalendarEventsQuery+`
+		AND b.id = $2
+	`, util.E_APP_HOST_NAME, data.GetId())
+
+	info.Batch.Send(info.Ctx)
+
+	if len(*events) == 0 {
+		return nil, util.ErrCheck(util.UserError("Booking not found"))
+	}
+
+	var content bytes.Buffer
+	err := util.WriteCalendar(&content, bookingCalendarName, *events)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return &types.GetBookingCalendarResponse{
+		Content:     content.Bytes(),
+		ContentType: util.CalendarContentType,
+		FileName:    "booking-" + (*events)[0].Start.Format(time.DateOnly) + ".ics",
+	}, nil
+}
+
+// Creating a token replaces the user's previous feed for the group, so the old url stops working
+func (h *Handlers) PostBookingCalendarToken(info ReqInfo, data *types.PostBookingCalendarTokenRequest) (*types.PostBookingCalendarTokenResponse, error) {
+	token := util.GenerateCalendarToken()
+
+	util.BatchExec(info.Batch, `
+		INSERT INTO dbtable_schema.user_calendar_tokens (group_id, token_hash, created_sub)
+		VALUES ($1::uuid, $2, $3::uuid)
+		ON CONFLICT (created_sub, group_id) DO UPDATE
+		SET token_hash = EXCLUDED.token_hash, last_used_on = NULL, enabled = true, updated_sub = EXCLUDED.created_sub, updated_on = $4
+	`, info.Session.GetGroupId(), util.HashToken(token), info.Session.GetUserSub(), time.Now())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.PostBookingCalendarTokenResponse{
+		Token: token,
+		Url:   util.E_APP_HOST_URL + bookingCalendarFeedPath + token + ".ics",
+	}, nil
+}
+
+func (h *Handlers) DeleteBookingCalendarToken(info ReqInfo, data *types.DeleteBookingCalendarTokenRequest) (*types.DeleteBookingCalendarTokenResponse, error) {
+	util.BatchExec(info.Batch, `
+		DELETE FROM dbtable_schema.user_calendar_tokens
+		WHERE created_sub = $1 AND group_id = $2
+	`, info.Session.GetUserSub(), info.Session.GetGroupId())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.DeleteBookingCalendarTokenResponse{Success: true}, nil
+}
+
+// Used by the public calendar feed route, where the only credential is the token in the feed url.
+// Upcoming bookings are read with the token owner's session, so the feed only shows what they could.
+func (h *Handlers) GetBookingsCalendarByToken(ctx context.Context, token string) ([]byte, error) {
+	workerBatch := util.NewBatchable(h.Database.DatabaseClient.Pool, "worker", "", 0)
+
+	owners := util.BatchQuery[calendarTokenOwner](workerBatch, `
+		UPDATE dbtable_schema.user_calendar_tokens
+		SET last_used_on = $2
+		WHERE token_hash = $1 AND enabled = true
+		RETURNING created_sub as "userSub", group_id as "groupId"
+	`, util.HashToken(token), time.Now())
+
+	workerBatch.Send(ctx)
+
+	if len(*owners) == 0 {
+		return nil, ErrInvalidCalendarToken
+	}
+
+	owner := (*owners)[0]
+
+	batch := util.NewBatchable(h.Database.DatabaseClient.Pool, owner.UserSub, owner.GroupId, 0)
+
+	events := util.BatchQuery[util.CalendarEvent](batch, bookingCalendarEventsQuery+`
+		AND (b.created_sub = $2 OR b.quote_created_sub = $2)
+		AND b.slot_date >= CURRENT_DATE - 1
+		ORDER BY occurrence.start
+		LIMIT $3
+	`, util.E_APP_HOST_NAME, owner.UserSub, bookingCalendarFeedLimit)
+
+	batch.Send(ctx)
+
+	var content bytes.Buffer
+	err := util.WriteCalendar(&content, bookingCalendarName, *events)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return content.Bytes(), nil
+}
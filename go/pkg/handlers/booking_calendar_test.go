@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+)
+
+func TestHandlers_GetBookingCalendar(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.GetBookingCalendarRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.GetBookingCalendarResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.GetBookingCalendar(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.GetBookingCalendar(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.GetBookingCalendar(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlers_PostBookingCalendarToken(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.PostBookingCalendarTokenRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.PostBookingCalendarTokenResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.PostBookingCalendarToken(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.PostBookingCalendarToken(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.PostBookingCalendarToken(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlers_DeleteBookingCalendarToken(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.DeleteBookingCalendarTokenRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.DeleteBookingCalendarTokenResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.DeleteBookingCalendarToken(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.DeleteBookingCalendarToken(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.DeleteBookingCalendarToken(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlers_GetBookingsCalendarByToken(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		token string
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    []byte
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.GetBookingsCalendarByToken(tt.args.ctx, tt.args.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.GetBookingsCalendarByToken(%v, %v) error = %v, wantErr %v", tt.args.ctx, tt.args.token, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.GetBookingsCalendarByToken(%v, %v) = %v, want %v", tt.args.ctx, tt.args.token, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,599 @@
+package handlers
+
+import (
+	"bytes"
+	json "encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const (
+	INVOICE_STATUS_DRAFT = "draft"
+	INVOICE_STATUS_SENT  = "sent"
+	INVOICE_STATUS_PAID  = "paid"
+	INVOICE_STATUS_VOID  = "void"
+
+	invoiceTaxRateBase   = 10000 // basis points
+	invoicePaidOnLayout  = "2006-01-02"
+	invoiceDefaultPrefix = "INV-"
+)
+
+// Invoices are drafted by the staff member who owns the booked slot, then sent to the client. Drafts
+// and sent invoices can be voided until a payment has been recorded against them.
+func checkInvoiceStatusChange(current, next string, amountPaid int32) error {
+	switch next {
+	case INVOICE_STATUS_SENT:
+		if current != INVOICE_STATUS_DRAFT {
+			return util.UserError("Only draft invoices can be sent.")
+		}
+	case INVOICE_STATUS_VOID:
+		if current != INVOICE_STATUS_DRAFT && current != INVOICE_STATUS_SENT {
+			return util.UserError("Only draft or sent invoices can be voided.")
+		}
+		if amountPaid > 0 {
+			return util.UserError("Invoices with payments recorded can't be voided.")
+		}
+	default:
+		return util.UserError("Invoices can only be marked as sent or void.")
+	}
+	return nil
+}
+
+// Payments are recorded against sent invoices, which are paid once the balance reaches zero
+func applyInvoicePayment(status string, total, amountPaid, amount int32) (int32, string, error) {
+	if status != INVOICE_STATUS_SENT {
+		return 0, "", util.UserError("Payments can only be recorded on sent invoices.")
+	}
+	if amount <= 0 {
+		return 0, "", util.UserError("Payments must be greater than zero.")
+	}
+
+	balance := total - amountPaid
+	if amount > balance {
+		return 0, "", util.UserError("The payment is more than the " + formatCents(balance) + " balance due.")
+	}
+
+	amountPaid += amount
+	if amountPaid == total {
+		return amountPaid, INVOICE_STATUS_PAID, nil
+	}
+	return amountPaid, INVOICE_STATUS_SENT, nil
+}
+
+// Tax is charged on the subtotal, rounded to the nearest cent
+func invoiceTax(subtotal, taxRate int32) int32 {
+	if subtotal <= 0 || taxRate <= 0 {
+		return 0
+	}
+	return int32((int64(subtotal)*int64(taxRate) + invoiceTaxRateBase/2) / invoiceTaxRateBase)
+}
+
+func formatCents(cents int32) string {
+	sign := ""
+	amount := int64(cents)
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+func formatBasisPoints(rate int32) string {
+	return fmt.Sprintf("%d.%02d%%", rate/100, rate%100)
+}
+
+func invoiceLineDescription(item *types.IPriceLineItem) string {
+	multiplier := fmt.Sprintf(" (%d.%02dx)", item.GetMultiplier()/priceMultiplierBase, item.GetMultiplier()%priceMultiplierBase)
+	switch item.GetKind() {
+	case PRICE_LINE_TIER:
+		return "Tier: " + item.GetName() + multiplier
+	case PRICE_LINE_BRACKET:
+		return item.GetName() + multiplier
+	case PRICE_LINE_ADDON:
+		return "Add-on: " + item.GetName()
+	default:
+		return item.GetName()
+	}
+}
+
+const bookingInvoiceColumns = `
+	bi.id,
+	bi.booking_id as "bookingId",
+	bi.number,
+	bi.code,
+	bi.status::TEXT as status,
+	bi.line_items as "lineItems",
+	bi.subtotal,
+	bi.tax_label as "taxLabel",
+	bi.tax_rate as "taxRate",
+	bi.tax_id as "taxId",
+	bi.tax_amount as "taxAmount",
+	bi.total,
+	bi.amount_paid as "amountPaid",
+	COALESCE(bip.payments, '[]'::JSONB) as payments,
+	COALESCE(bi.sent_on::TEXT, '') as "sentOn",
+	COALESCE(bi.paid_on::TEXT, '') as "paidOn",
+	COALESCE(bi.voided_on::TEXT, '') as "voidedOn",
+	bi.created_on::TEXT as "createdOn"
+FROM dbtable_schema.booking_invoices bi
+LEFT JOIN LATERAL (
+	SELECT JSONB_AGG(JSONB_BUILD_OBJECT(
+		'id', p.id,
+		'amount', p.amount,
+		'paymentMethod', p.payment_method,
+		'reference', p.reference,
+		'paidOn', p.paid_on::TEXT
+	) ORDER BY p.paid_on, p.created_on) as payments
+	FROM dbtable_schema.booking_invoice_payments p
+	WHERE p.booking_invoice_id = bi.id AND p.enabled = true
+) bip ON true
+`
+
+func readBookingInvoice(info ReqInfo, id string) (*types.IBookingInvoice, error) {
+	rows, err := info.Tx.Query(info.Ctx, `
+		SELECT `+bookingInvoiceColumns+`
+		WHERE bi.id = $1
+	`, id)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	invoice, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByNameLax[types.IBookingInvoice])
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return invoice, nil
+}
+
+func (h *Handlers) GetGroupInvoiceSettings(info ReqInfo, data *types.GetGroupInvoiceSettingsRequest) (*types.GetGroupInvoiceSettingsResponse, error) {
+	settings := util.BatchQuery[types.IGroupInvoiceSettings](info.Batch, `
+		SELECT payee_name as "payeeName", payee_addr1 as "payeeAddr1", payee_addr2 as "payeeAddr2",
+			tax_label as "taxLabel", tax_rate as "taxRate", tax_id as "taxId", number_prefix as "numberPrefix"
+		FROM dbtable_schema.group_invoice_settings
+		WHERE group_id = $1
+	`, info.Session.GetGroupId())
+
+	info.Batch.Send(info.Ctx)
+
+	if len(*settings) == 0 {
+		return &types.GetGroupInvoiceSettingsResponse{Settings: &types.IGroupInvoiceSettings{NumberPrefix: invoiceDefaultPrefix}}, nil
+	}
+
+	return &types.GetGroupInvoiceSettingsResponse{Settings: (*settings)[0]}, nil
+}
+
+// Settings only apply to invoices drafted after they change, existing invoices keep the tax they were issued with.
+// Fields left out of the request keep their current value, or the column default for a group's first settings.
+func (h *Handlers) PatchGroupInvoiceSettings(info ReqInfo, data *types.PatchGroupInvoiceSettingsRequest) (*types.PatchGroupInvoiceSettingsResponse, error) {
+	saved := util.BatchExec(info.Batch, `
+		INSERT INTO dbtable_schema.group_invoice_settings (group_id, payee_name, payee_addr1, payee_addr2, tax_label, tax_rate, tax_id, number_prefix, created_sub)
+		VALUES ($1::uuid, COALESCE($2, ''), COALESCE($3, ''), COALESCE($4, ''), COALESCE($5, ''), COALESCE($6, 0), COALESCE($7, ''), COALESCE($8, $11), $9::uuid)
+		ON CONFLICT (group_id) DO UPDATE
+		SET payee_name = COALESCE($2, group_invoice_settings.payee_name),
+			payee_addr1 = COALESCE($3, group_invoice_settings.payee_addr1),
+			payee_addr2 = COALESCE($4, group_invoice_settings.payee_addr2),
+			tax_label = COALESCE($5, group_invoice_settings.tax_label),
+			tax_rate = COALESCE($6, group_invoice_settings.tax_rate),
+			tax_id = COALESCE($7, group_invoice_settings.tax_id),
+			number_prefix = COALESCE($8, group_invoice_settings.number_prefix),
+			updated_sub = $9::uuid, updated_on = $10
+	`, info.Session.GetGroupId(), data.PayeeName, data.PayeeAddr1, data.PayeeAddr2, data.TaxLabel,
+		data.TaxRate, data.TaxId, data.NumberPrefix, info.Session.GetUserSub(), time.Now().UTC(), invoiceDefaultPrefix)
+
+	info.Batch.Send(info.Ctx)
+
+	if (*saved).RowsAffected() == 0 {
+		return nil, util.ErrCheck(util.UserError("Invoice settings could not be saved."))
+	}
+
+	return &types.PatchGroupInvoiceSettingsResponse{Success: true}, nil
+}
+
+// Drafts an invoice from the price the booking was approved at, numbered after the group's last invoice
+func (h *Handlers) PostBookingInvoice(info ReqInfo, data *types.PostBookingInvoiceRequest) (*types.PostBookingInvoiceResponse, error) {
+	var priceJson []byte
+	err := info.Tx.QueryRow(info.Ctx, `
+		SELECT b.price
+		FROM dbtable_schema.bookings b
+		JOIN dbtable_schema.schedule_bracket_slots sbs ON sbs.id = b.schedule_bracket_slot_id
+		WHERE b.id = $1 AND sbs.created_sub = $2 AND b.enabled = true
+	`, data.GetBookingId(), info.Session.GetUserSub()).Scan(&priceJson)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, util.ErrCheck(util.UserError("Booking not found."))
+	}
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	if priceJson == nil {
+		return nil, util.ErrCheck(util.UserError("The booking was made before prices were recorded and can't be invoiced."))
+	}
+
+	var price types.IPriceSnapshot
+	if err := json.Unmarshal(priceJson, &price); err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	var number int32
+	var code string
+	err = info.Tx.QueryRow(info.Ctx, `
+		SELECT number, code FROM dbfunc_schema.claim_group_invoice_number()
+	`).Scan(&number, &code)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	var taxLabel, taxId string
+	var taxRate int32
+	err = info.Tx.QueryRow(info.Ctx, `
+		SELECT tax_label, tax_rate, tax_id
+		FROM dbtable_schema.group_invoice_settings
+		WHERE group_id = $1
+	`, info.Session.GetGroupId()).Scan(&taxLabel, &taxRate, &taxId)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	lineItems, err := json.Marshal(price.GetLineItems())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	subtotal := price.GetTotal()
+	taxAmount := invoiceTax(subtotal, taxRate)
+	if int64(subtotal)+int64(taxAmount) > math.MaxInt32 {
+		return nil, util.ErrCheck(util.UserError("The invoice total is too large."))
+	}
+
+	var invoiceId string
+	err = info.Tx.QueryRow(info.Ctx, `
+		INSERT INTO dbtable_schema.booking_invoices (group_id, booking_id, number, code, line_items, subtotal, tax_label, tax_rate, tax_id, tax_amount, total, created_sub)
+		VALUES ($1::uuid, $2::uuid, $3, $4, $5::jsonb, $6, $7, $8, $9, $10, $11, $12::uuid)
+		RETURNING id
+	`, info.Session.GetGroupId(), data.GetBookingId(), number, code, lineItems, subtotal, taxLabel, taxRate, taxId, taxAmount, subtotal+taxAmount, info.Session.GetUserSub()).Scan(&invoiceId)
+	// unique_violation on booking_invoices_booking_idx, which allows one invoice per booking that isn't void
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "booking_invoices_booking_idx" {
+		return nil, util.ErrCheck(util.UserError("The booking already has an invoice. Void it to issue a new one."))
+	}
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	invoice, err := readBookingInvoice(info, invoiceId)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return &types.PostBookingInvoiceResponse{Invoice: invoice}, nil
+}
+
+func (h *Handlers) GetBookingInvoices(info ReqInfo, data *types.GetBookingInvoicesRequest) (*types.GetBookingInvoicesResponse, error) {
+	invoices := util.BatchQuery[types.IBookingInvoice](info.Batch, `
+		SELECT `+bookingInvoiceColumns+`
+		WHERE bi.booking_id = $1 AND bi.enabled = true
+		ORDER BY bi.created_on DESC
+	`, data.GetBookingId())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.GetBookingInvoicesResponse{Invoices: *invoices}, nil
+}
+
+func (h *Handlers) PatchBookingInvoiceStatus(info ReqInfo, data *types.PatchBookingInvoiceStatusRequest) (*types.PatchBookingInvoiceStatusResponse, error) {
+	var status string
+	var amountPaid int32
+	err := info.Tx.QueryRow(info.Ctx, `
+		SELECT status::TEXT, amount_paid
+		FROM dbtable_schema.booking_invoices
+		WHERE id = $1 AND enabled = true
+		FOR UPDATE
+	`, data.GetId()).Scan(&status, &amountPaid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, util.ErrCheck(util.UserError("Invoice not found."))
+	}
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	err = checkInvoiceStatusChange(status, data.GetStatus(), amountPaid)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	_, err = info.Tx.Exec(info.Ctx, `
+		UPDATE dbtable_schema.booking_invoices
+		SET status = $2::dbtable_schema.invoice_status,
+			sent_on = CASE WHEN $2 = 'sent' THEN $3 ELSE sent_on END,
+			voided_on = CASE WHEN $2 = 'void' THEN $3 ELSE voided_on END,
+			updated_sub = $4, updated_on = $3
+		WHERE id = $1
+	`, data.GetId(), data.GetStatus(), time.Now().UTC(), info.Session.GetUserSub())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	invoice, err := readBookingInvoice(info, data.GetId())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return &types.PatchBookingInvoiceStatusResponse{Invoice: invoice}, nil
+}
+
+// Records a full or partial payment received for an invoice
+func (h *Handlers) PostBookingInvoicePayment(info ReqInfo, data *types.PostBookingInvoicePaymentRequest) (*types.PostBookingInvoicePaymentResponse, error) {
+	now := time.Now().UTC()
+
+	paidOn := now
+	if data.GetPaidOn() != "" {
+		var err error
+		paidOn, err = time.Parse(invoicePaidOnLayout, data.GetPaidOn())
+		if err != nil {
+			return nil, util.ErrCheck(util.UserError("Payment dates must be formatted as YYYY-MM-DD."))
+		}
+	}
+
+	var status string
+	var total, amountPaid int32
+	err := info.Tx.QueryRow(info.Ctx, `
+		SELECT status::TEXT, total, amount_paid
+		FROM dbtable_schema.booking_invoices
+		WHERE id = $1 AND enabled = true
+		FOR UPDATE
+	`, data.GetId()).Scan(&status, &total, &amountPaid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, util.ErrCheck(util.UserError("Invoice not found."))
+	}
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	amountPaid, status, err = applyInvoicePayment(status, total, amountPaid, data.GetAmount())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	_, err = info.Tx.Exec(info.Ctx, `
+		INSERT INTO dbtable_schema.booking_invoice_payments (booking_invoice_id, amount, payment_method, reference, paid_on, created_sub)
+		VALUES ($1::uuid, $2, $3, $4, $5, $6::uuid)
+	`, data.GetId(), data.GetAmount(), data.GetPaymentMethod(), data.GetReference(), paidOn, info.Session.GetUserSub())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	_, err = info.Tx.Exec(info.Ctx, `
+		UPDATE dbtable_schema.booking_invoices
+		SET amount_paid = $2, status = $3::dbtable_schema.invoice_status,
+			paid_on = CASE WHEN $3 = 'paid' THEN $4 ELSE paid_on END,
+			updated_sub = $5, updated_on = $6
+		WHERE id = $1
+	`, data.GetId(), amountPaid, status, paidOn, info.Session.GetUserSub(), now)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	invoice, err := readBookingInvoice(info, data.GetId())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return &types.PostBookingInvoicePaymentResponse{Invoice: invoice}, nil
+}
+
+const bookingInvoiceTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+  <title>{{.Title}} {{.Code}}</title>
+	<link rel="stylesheet" href="/app/print.css">
+</head>
+<body>
+  <div class="header">
+    <div class="title">
+      <h1>{{.Title}}</h1>
+      <span class="status {{.StatusClass}}">Status: {{.Status}}</span>
+    </div>
+    <div class="meta">
+      <p><strong>Invoice #:</strong> {{.Code}}</p>
+      <p><strong>Date:</strong> {{.IssuedOn}}</p>
+      {{if .PaidOn}}<p><strong>Paid On:</strong> {{.PaidOn}}</p>{{end}}
+      {{if .TaxId}}<p><strong>Tax ID:</strong> {{.TaxId}}</p>{{end}}
+    </div>
+  </div>
+
+  <div class="payable-section {{.SectionClass}}">
+    <div>
+      <h3>{{if .IsReceipt}}Payment Received By:{{else}}Pay to the order of:{{end}}</h3>
+      <p><strong>{{.PaymentTo}}</strong><br>
+      {{.PaymentAddr1}}<br>
+      {{.PaymentAddr2}}</p>
+    </div>
+    {{if .Payments}}<div style="text-align: right;">
+      <h3>Payments:</h3>
+      {{range .Payments}}<p><strong>{{.PaidOn}}</strong> {{.Method}}{{if .Reference}} #{{.Reference}}{{end}} ${{.Amount}}</p>
+      {{end}}
+    </div>{{end}}
+  </div>
+
+  <table>
+    <thead>
+      <tr>
+        <th>Description</th>
+        <th class="text-right">Amount</th>
+      </tr>
+    </thead>
+    <tbody>
+      {{range .LineItems}}<tr>
+        <td>{{.Description}}</td>
+        <td class="text-right">${{.Amount}}</td>
+      </tr>
+      {{end}}
+      {{if .TaxAmount}}<tr>
+        <td>Subtotal</td>
+        <td class="text-right">${{.Subtotal}}</td>
+      </tr>
+      <tr>
+        <td>{{.TaxLabel}} ({{.TaxRate}})</td>
+        <td class="text-right">${{.TaxAmount}}</td>
+      </tr>{{end}}
+      <tr class="total-row">
+        <td>Total</td>
+        <td class="text-right">${{.Total}}</td>
+      </tr>
+      <tr>
+        <td>Amount Paid</td>
+        <td class="text-right">${{.AmountPaid}}</td>
+      </tr>
+      <tr class="balance-row">
+        <td>Balance Due</td>
+        <td class="text-right">${{.BalanceDue}}</td>
+      </tr>
+    </tbody>
+  </table>
+
+  <div class="footer">
+    <p>{{if .IsReceipt}}Thank you for your business. This invoice has been paid in full.{{else}}If you have any questions about this invoice, please contact {{.PaymentTo}}.{{end}}</p>
+  </div>
+
+  <center>
+    <button id="print-btn" class="btn-print">Print / Save as PDF</button>
+  </center>
+</body>
+</html>
+`
+
+type bookingInvoiceDocumentLine struct {
+	Description string
+	Amount      string
+}
+
+type bookingInvoiceDocumentPayment struct {
+	PaidOn    string
+	Method    string
+	Reference string
+	Amount    string
+}
+
+type bookingInvoiceDocument struct {
+	Title        string
+	Code         string
+	Status       string
+	StatusClass  string
+	SectionClass string
+	IsReceipt    bool
+	IssuedOn     string
+	PaidOn       string
+	PaymentTo    string
+	PaymentAddr1 string
+	PaymentAddr2 string
+	LineItems    []bookingInvoiceDocumentLine
+	Payments     []bookingInvoiceDocumentPayment
+	Subtotal     string
+	TaxLabel     string
+	TaxRate      string
+	TaxId        string
+	TaxAmount    string
+	Total        string
+	AmountPaid   string
+	BalanceDue   string
+}
+
+func newBookingInvoiceDocument(invoice *types.IBookingInvoice, settings *types.IGroupInvoiceSettings) *bookingInvoiceDocument {
+	doc := &bookingInvoiceDocument{
+		Title:        "Invoice",
+		Code:         invoice.GetCode(),
+		Status:       invoice.GetStatus(),
+		StatusClass:  "status-po",
+		SectionClass: "payable-section-po",
+		IssuedOn:     invoice.GetCreatedOn(),
+		PaidOn:       invoice.GetPaidOn(),
+		PaymentTo:    settings.GetPayeeName(),
+		PaymentAddr1: settings.GetPayeeAddr1(),
+		PaymentAddr2: settings.GetPayeeAddr2(),
+		Subtotal:     formatCents(invoice.GetSubtotal()),
+		TaxLabel:     invoice.GetTaxLabel(),
+		TaxRate:      formatBasisPoints(invoice.GetTaxRate()),
+		TaxId:        invoice.GetTaxId(),
+		Total:        formatCents(invoice.GetTotal()),
+		AmountPaid:   formatCents(invoice.GetAmountPaid()),
+		BalanceDue:   formatCents(invoice.GetTotal() - invoice.GetAmountPaid()),
+	}
+
+	if invoice.GetSentOn() != "" {
+		doc.IssuedOn = invoice.GetSentOn()
+	}
+
+	if invoice.GetTaxAmount() != 0 {
+		doc.TaxAmount = formatCents(invoice.GetTaxAmount())
+	}
+
+	switch invoice.GetStatus() {
+	case INVOICE_STATUS_PAID:
+		doc.Title = "Payment Receipt"
+		doc.IsReceipt = true
+		doc.StatusClass = "status-receipt"
+		doc.SectionClass = "payable-section-receipt"
+	case INVOICE_STATUS_VOID:
+		doc.StatusClass = "status-void"
+	}
+
+	for _, item := range invoice.GetLineItems() {
+		doc.LineItems = append(doc.LineItems, bookingInvoiceDocumentLine{
+			Description: invoiceLineDescription(item),
+			Amount:      formatCents(item.GetAmount()),
+		})
+	}
+
+	for _, payment := range invoice.GetPayments() {
+		doc.Payments = append(doc.Payments, bookingInvoiceDocumentPayment{
+			PaidOn:    payment.GetPaidOn(),
+			Method:    payment.GetPaymentMethod(),
+			Reference: payment.GetReference(),
+			Amount:    formatCents(payment.GetAmount()),
+		})
+	}
+
+	return doc
+}
+
+func (h *Handlers) GetBookingInvoiceDocument(info ReqInfo, data *types.GetBookingInvoiceDocumentRequest) (*types.GetBookingInvoiceDocumentResponse, error) {
+	invoices := util.BatchQuery[types.IBookingInvoice](info.Batch, `
+		SELECT `+bookingInvoiceColumns+`
+		WHERE bi.id = $1 AND bi.enabled = true
+	`, data.GetId())
+
+	settings := util.BatchQuery[types.IGroupInvoiceSettings](info.Batch, `
+		SELECT gis.payee_name as "payeeName", gis.payee_addr1 as "payeeAddr1", gis.payee_addr2 as "payeeAddr2"
+		FROM dbtable_schema.group_invoice_settings gis
+		JOIN dbtable_schema.booking_invoices bi ON bi.group_id = gis.group_id
+		WHERE bi.id = $1
+	`, data.GetId())
+
+	info.Batch.Send(info.Ctx)
+
+	if len(*invoices) == 0 || len(*settings) == 0 {
+		return nil, util.ErrCheck(util.UserError("Invoice not found"))
+	}
+
+	t, err := template.New("booking_invoice").Parse(bookingInvoiceTemplate)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, newBookingInvoiceDocument((*invoices)[0], (*settings)[0])); err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return &types.GetBookingInvoiceDocumentResponse{Html: buf.String()}, nil
+}
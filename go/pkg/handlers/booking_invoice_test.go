@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_invoiceTax(t *testing.T) {
+	tests := []struct {
+		name     string
+		subtotal int32
+		taxRate  int32
+		want     int32
+	}{
+		{"no tax", 4000, 0, 0},
+		{"whole percent", 4000, 1000, 400},
+		{"basis points", 4000, 825, 330},
+		{"rounds half up", 150, 1000, 15},
+		{"rounds to the nearest cent", 177, 825, 15},
+		{"free booking", 0, 825, 0},
+		{"large subtotal", 200000000, 10000, 200000000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := invoiceTax(tt.subtotal, tt.taxRate); got != tt.want {
+				t.Errorf("invoiceTax(%d, %d) = %d, want %d", tt.subtotal, tt.taxRate, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkInvoiceStatusChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		current    string
+		next       string
+		amountPaid int32
+		wantErr    bool
+	}{
+		{"send draft", INVOICE_STATUS_DRAFT, INVOICE_STATUS_SENT, 0, false},
+		{"resend", INVOICE_STATUS_SENT, INVOICE_STATUS_SENT, 0, true},
+		{"void draft", INVOICE_STATUS_DRAFT, INVOICE_STATUS_VOID, 0, false},
+		{"void sent", INVOICE_STATUS_SENT, INVOICE_STATUS_VOID, 0, false},
+		{"void partially paid", INVOICE_STATUS_SENT, INVOICE_STATUS_VOID, 100, true},
+		{"void paid", INVOICE_STATUS_PAID, INVOICE_STATUS_VOID, 4000, true},
+		{"send void", INVOICE_STATUS_VOID, INVOICE_STATUS_SENT, 0, true},
+		{"mark paid directly", INVOICE_STATUS_SENT, INVOICE_STATUS_PAID, 0, true},
+		{"back to draft", INVOICE_STATUS_SENT, INVOICE_STATUS_DRAFT, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkInvoiceStatusChange(tt.current, tt.next, tt.amountPaid)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkInvoiceStatusChange(%s, %s, %d) error = %v, wantErr %v", tt.current, tt.next, tt.amountPaid, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_applyInvoicePayment(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         string
+		total          int32
+		amountPaid     int32
+		amount         int32
+		wantAmountPaid int32
+		wantStatus     string
+		wantErr        bool
+	}{
+		{"partial payment", INVOICE_STATUS_SENT, 4330, 0, 1000, 1000, INVOICE_STATUS_SENT, false},
+		{"final payment", INVOICE_STATUS_SENT, 4330, 1000, 3330, 4330, INVOICE_STATUS_PAID, false},
+		{"paid in full", INVOICE_STATUS_SENT, 4330, 0, 4330, 4330, INVOICE_STATUS_PAID, false},
+		{"overpayment", INVOICE_STATUS_SENT, 4330, 1000, 3331, 0, "", true},
+		{"zero payment", INVOICE_STATUS_SENT, 4330, 0, 0, 0, "", true},
+		{"draft invoice", INVOICE_STATUS_DRAFT, 4330, 0, 1000, 0, "", true},
+		{"paid invoice", INVOICE_STATUS_PAID, 4330, 4330, 1, 0, "", true},
+		{"void invoice", INVOICE_STATUS_VOID, 4330, 0, 1000, 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amountPaid, status, err := applyInvoicePayment(tt.status, tt.total, tt.amountPaid, tt.amount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyInvoicePayment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if amountPaid != tt.wantAmountPaid || status != tt.wantStatus {
+				t.Errorf("applyInvoicePayment() = %d %s, want %d %s", amountPaid, status, tt.wantAmountPaid, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func Test_formatCents(t *testing.T) {
+	tests := []struct {
+		cents int32
+		want  string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{4330, "43.30"},
+		{-800, "-8.00"},
+	}
+	for _, tt := range tests {
+		if got := formatCents(tt.cents); got != tt.want {
+			t.Errorf("formatCents(%d) = %s, want %s", tt.cents, got, tt.want)
+		}
+	}
+}
+
+func Test_invoiceLineDescription(t *testing.T) {
+	// Lines come from the booking's price snapshot, so they're checked against what computePrice produces
+	price, err := computePrice(priceInputs{
+		ServiceName:       "Tutoring",
+		Cost:              40,
+		TierName:          "Advanced",
+		TierMultiplier:    150,
+		BracketMultiplier: 125,
+		Addons:            []priceAddon{{Name: "Materials", Cost: 5}},
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("computePrice() error = %v", err)
+	}
+
+	want := []struct {
+		description string
+		amount      string
+	}{
+		{"Tutoring", "40.00"},
+		{"Tier: Advanced (1.50x)", "20.00"},
+		{"Scheduled time (1.25x)", "15.00"},
+		{"Add-on: Materials", "5.00"},
+	}
+
+	if len(price.GetLineItems()) != len(want) {
+		t.Fatalf("computePrice() returned %d line items, want %d", len(price.GetLineItems()), len(want))
+	}
+	for i, item := range price.GetLineItems() {
+		if got := invoiceLineDescription(item); got != want[i].description {
+			t.Errorf("invoiceLineDescription() line %d = %q, want %q", i, got, want[i].description)
+		}
+		if got := formatCents(item.GetAmount()); got != want[i].amount {
+			t.Errorf("line %d amount = %s, want %s", i, got, want[i].amount)
+		}
+	}
+}
@@ -147,6 +147,34 @@ func TestHandlers_GetBookingFiles(t *testing.T) {
 	}
 }
 
+func TestHandlers_GetBookingCallLog(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.GetBookingCallLogRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.GetBookingCallLogResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.GetBookingCallLog(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.GetBookingCallLog(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.GetBookingCallLog(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestHandlers_PatchBookingRating(t *testing.T) {
 	type args struct {
 		info ReqInfo
@@ -1,41 +1,136 @@
 package handlers
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/keybittech/awayto-v3/go/pkg/types"
 	"github.com/keybittech/awayto-v3/go/pkg/util"
 )
 
+const bookingTranscriptsPageSize = 25
+
 func (h *Handlers) PostBookingTranscript(info ReqInfo, data *types.PostBookingTranscriptRequest) (*types.PostBookingTranscriptResponse, error) {
-	return &types.PostBookingTranscriptResponse{}, nil
+	userSub := info.Session.GetUserSub()
+	ids := make([]string, 0, len(data.GetBookingTranscripts()))
+
+	for _, bookingTranscript := range data.GetBookingTranscripts() {
+		var isParticipant bool
+		err := info.Tx.QueryRow(info.Ctx, `
+			SELECT dbfunc_schema.session_user_is_booking_participant($1::uuid)
+		`, bookingTranscript.GetBookingId()).Scan(&isParticipant)
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+
+		if !isParticipant {
+			return nil, util.ErrCheck(util.UserError("Transcripts can only be added to your own bookings."))
+		}
+
+		messagesJson, err := json.Marshal(bookingTranscript.GetMessages())
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+
+		var id string
+		err = info.Tx.QueryRow(info.Ctx, `
+			INSERT INTO dbtable_schema.booking_transcripts (booking_id, messages, started_on, ended_on, created_sub)
+			VALUES ($1::uuid, $2::jsonb, COALESCE(NULLIF($3, '')::timestamp, $5), COALESCE(NULLIF($4, '')::timestamp, $5), $6::uuid)
+			RETURNING id
+		`, bookingTranscript.GetBookingId(), messagesJson, bookingTranscript.GetStartedOn(), bookingTranscript.GetEndedOn(), time.Now().UTC(), userSub).Scan(&id)
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return &types.PostBookingTranscriptResponse{Ids: ids}, nil
 }
 
 func (h *Handlers) PatchBookingTranscript(info ReqInfo, data *types.PatchBookingTranscriptRequest) (*types.PatchBookingTranscriptResponse, error) {
-	return &types.PatchBookingTranscriptResponse{}, nil
+	bookingTranscript := data.GetBookingTranscript()
+
+	messagesJson, err := json.Marshal(bookingTranscript.GetMessages())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	patched := util.BatchExec(info.Batch, `
+		UPDATE dbtable_schema.booking_transcripts
+		SET messages = $2::jsonb, updated_on = $3, updated_sub = $4
+		WHERE id = $1
+	`, bookingTranscript.GetId(), messagesJson, time.Now(), info.Session.GetUserSub())
+
+	info.Batch.Send(info.Ctx)
+
+	if (*patched).RowsAffected() == 0 {
+		return nil, util.ErrCheck(util.UserError("Booking transcript not found."))
+	}
+
+	return &types.PatchBookingTranscriptResponse{Success: true}, nil
 }
 
 func (h *Handlers) GetBookingTranscripts(info ReqInfo, data *types.GetBookingTranscriptsRequest) (*types.GetBookingTranscriptsResponse, error) {
-	return &types.GetBookingTranscriptsResponse{}, nil
+	page := max(int(data.GetPage()), 1)
+
+	// request one extra row to know if there is another page
+	bookingTranscripts := util.BatchQuery[types.IBookingTranscript](info.Batch, util.WithPagination(`
+		SELECT id, "bookingId", topic, "messageCount", "startedOn", "endedOn", "createdSub", "createdOn"
+		FROM dbview_schema.enabled_booking_transcripts
+		WHERE "bookingId" = $1
+		ORDER BY "startedOn" DESC
+	`, page, bookingTranscriptsPageSize+1), data.GetBookingId())
+
+	info.Batch.Send(info.Ctx)
+
+	transcripts := *bookingTranscripts
+	hasMore := len(transcripts) > bookingTranscriptsPageSize
+	if hasMore {
+		transcripts = transcripts[:bookingTranscriptsPageSize]
+	}
+
+	return &types.GetBookingTranscriptsResponse{BookingTranscripts: transcripts, HasMore: hasMore}, nil
 }
 
 func (h *Handlers) GetBookingTranscriptById(info ReqInfo, data *types.GetBookingTranscriptByIdRequest) (*types.GetBookingTranscriptByIdResponse, error) {
-	return &types.GetBookingTranscriptByIdResponse{}, nil
+	bookingTranscript := util.BatchQueryRow[types.IBookingTranscript](info.Batch, `
+		SELECT id, "bookingId", topic, messages, "messageCount", "startedOn", "endedOn", "createdSub", "createdOn"
+		FROM dbview_schema.enabled_booking_transcripts
+		WHERE id = $1
+	`, data.GetId())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.GetBookingTranscriptByIdResponse{BookingTranscript: *bookingTranscript}, nil
 }
 
 func (h *Handlers) DeleteBookingTranscript(info ReqInfo, data *types.DeleteBookingTranscriptRequest) (*types.DeleteBookingTranscriptResponse, error) {
-	return &types.DeleteBookingTranscriptResponse{}, nil
+	deleted := util.BatchExec(info.Batch, `
+		DELETE FROM dbtable_schema.booking_transcripts
+		WHERE id = $1
+	`, data.GetId())
+
+	info.Batch.Send(info.Ctx)
+
+	if (*deleted).RowsAffected() == 0 {
+		return nil, util.ErrCheck(util.UserError("Booking transcript not found."))
+	}
+
+	return &types.DeleteBookingTranscriptResponse{Success: true}, nil
 }
 
 func (h *Handlers) DisableBookingTranscript(info ReqInfo, data *types.DisableBookingTranscriptRequest) (*types.DisableBookingTranscriptResponse, error) {
-	_, err := info.Tx.Exec(info.Ctx, `
-		UPDATE dbtable_schema.bookings
+	disabled := util.BatchExec(info.Batch, `
+		UPDATE dbtable_schema.booking_transcripts
 		SET enabled = false, updated_on = $2, updated_sub = $3
 		WHERE id = $1
 	`, data.GetId(), time.Now().Local().UTC(), info.Session.GetUserSub())
 
-	if err != nil {
-		return nil, util.ErrCheck(err)
+	info.Batch.Send(info.Ctx)
+
+	if (*disabled).RowsAffected() == 0 {
+		return nil, util.ErrCheck(util.UserError("Booking transcript not found."))
 	}
 
 	return &types.DisableBookingTranscriptResponse{Id: data.GetId()}, nil
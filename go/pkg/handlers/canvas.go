@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+func (h *Handlers) GetBookingCanvasOperations(info ReqInfo, data *types.GetBookingCanvasOperationsRequest) (*types.GetBookingCanvasOperationsResponse, error) {
+	operations := util.BatchQuery[types.ICanvasOperation](info.Batch, `
+		SELECT id, element_id as "elementId", operation, before, after, version,
+			COALESCE(target_id::TEXT, '') as "targetId", undone, created_sub as "createdSub", created_on::TEXT as "createdOn"
+		FROM dbtable_schema.topic_canvas_operations
+		WHERE booking_id = $1 AND enabled = true
+		ORDER BY seq
+	`, data.GetBookingId())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.GetBookingCanvasOperationsResponse{Operations: *operations}, nil
+}
@@ -6,7 +6,6 @@ import (
 	"slices"
 	"time"
 
-	"github.com/keybittech/awayto-v3/go/pkg/clients"
 	"github.com/keybittech/awayto-v3/go/pkg/types"
 	"github.com/keybittech/awayto-v3/go/pkg/util"
 	"github.com/redis/go-redis/v9"
@@ -19,15 +18,8 @@ var groupNameInUseError error = util.UserError("Group name in use.")
 func (h *Handlers) PostGroup(info ReqInfo, data *types.PostGroupRequest) (*types.PostGroupResponse, error) {
 	userSub := info.Session.GetUserSub()
 
-	var undos []func()
-
-	defer func() {
-		if undos != nil && len(undos) > 0 {
-			for _, undo := range undos {
-				undo()
-			}
-		}
-	}()
+	saga := h.NewIdentitySaga(info, "post_group")
+	defer saga.Close()
 
 	check, err := h.CheckGroupName(info, &types.CheckGroupNameRequest{Name: data.Name})
 	if err != nil {
@@ -67,7 +59,7 @@ func (h *Handlers) PostGroup(info ReqInfo, data *types.PostGroupRequest) (*types
 	info.Session.SetGroupId(groupId)
 
 	// Create group resource in Keycloak
-	kcGroupIdOnly, err := h.Keycloak.CreateGroup(info.Ctx, userSub, data.GetName())
+	kcGroupIdOnly, err := h.Identity.CreateGroup(info.Ctx, userSub, data.GetName())
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
@@ -75,18 +67,14 @@ func (h *Handlers) PostGroup(info ReqInfo, data *types.PostGroupRequest) (*types
 	if kcGroupIdOnly.Id != "" {
 		kcGroupExternalId = kcGroupIdOnly.Id
 
-		undos = append(undos, func() {
-			err = h.Keycloak.DeleteGroup(info.Ctx, userSub, kcGroupExternalId)
-			if err != nil {
-				util.ErrorLog.Println(util.ErrCheck(err))
-			}
-		})
+		// Deleting the group also deletes its subgroups and their memberships
+		saga.Compensate(IdentityAction{Action: IDENTITY_DELETE_GROUP, GroupId: kcGroupExternalId})
 	} else {
 		return nil, util.ErrCheck(errors.New("error creating keycloak group"))
 	}
 
 	// Create Admin role subgroup
-	kcAdminSubGroup, err := h.Keycloak.CreateOrGetSubGroup(info.Ctx, userSub, kcGroupExternalId, "Admin")
+	kcAdminSubGroup, err := h.Identity.CreateOrGetSubGroup(info.Ctx, userSub, kcGroupExternalId, "Admin")
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
@@ -104,22 +92,26 @@ func (h *Handlers) PostGroup(info ReqInfo, data *types.PostGroupRequest) (*types
 	}
 
 	// Add admin roles to the admin subgroup
-	roles, err := h.Keycloak.GetGroupAdminRoles(info.Ctx, userSub)
+	roles, err := h.Identity.GetGroupAdminRoles(info.Ctx, userSub)
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
 
-	err = h.Keycloak.AddRolesToGroup(info.Ctx, userSub, kcAdminSubGroupExternalId, roles)
+	err = h.Identity.AddRolesToGroup(info.Ctx, userSub, kcAdminSubGroupExternalId, roles)
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
 
+	saga.Compensate(IdentityAction{Action: IDENTITY_DELETE_ROLES, GroupId: kcAdminSubGroupExternalId, Roles: roles})
+
 	// Attach the user to the admin subgroup
-	err = h.Keycloak.AddUserToGroup(info.Ctx, userSub, userSub, kcAdminSubGroupExternalId)
+	err = h.Identity.AddUserToGroup(info.Ctx, userSub, userSub, kcAdminSubGroupExternalId)
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
 
+	saga.Compensate(IdentityAction{Action: IDENTITY_DELETE_MEMBER, GroupId: kcAdminSubGroupExternalId, UserId: userSub})
+
 	// Update the group with the keycloak reference id and get the group code
 	var groupCode string
 	err = info.Tx.QueryRow(info.Ctx, `
@@ -166,7 +158,7 @@ func (h *Handlers) PostGroup(info ReqInfo, data *types.PostGroupRequest) (*types
 		return nil, util.ErrCheck(err)
 	}
 
-	undos = nil
+	saga.Complete()
 	return &types.PostGroupResponse{Code: groupCode}, nil
 }
 
@@ -209,7 +201,7 @@ func (h *Handlers) PatchGroup(info ReqInfo, data *types.PatchGroupRequest) (*typ
 
 	// If the group name changed, make a new group cache entry
 	if nameChanged {
-		err = h.Keycloak.UpdateGroup(info.Ctx, userSub, info.Session.GetGroupExternalId(), data.Name)
+		err = h.Identity.UpdateGroup(info.Ctx, userSub, info.Session.GetGroupExternalId(), data.Name)
 		if err != nil {
 			return nil, util.ErrCheck(err)
 		}
@@ -240,11 +232,15 @@ func (h *Handlers) PatchGroupAssignments(info ReqInfo, data *types.PatchGroupAss
 		return nil, util.ErrCheck(err)
 	}
 
-	adminRoles, err := h.Keycloak.GetGroupAdminRoles(info.Ctx, userSub)
+	adminRoles, err := h.Identity.GetGroupAdminRoles(info.Ctx, userSub)
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
 
+	// Assignments span many subgroups, a failure part way puts back the ones already changed
+	saga := h.NewIdentitySaga(info, "patch_group_assignments")
+	defer saga.Close()
+
 	for sgPath, assignmentSet := range data.Assignments {
 
 		assignmentNames := []string{}
@@ -271,15 +267,12 @@ func (h *Handlers) PatchGroupAssignments(info ReqInfo, data *types.PatchGroupAss
 			}
 
 			if len(deletions) > 0 {
-				_, err := h.Keycloak.SendCommand(info.Ctx, clients.DeleteRolesFromGroupKeycloakCommand, &types.AuthRequestParams{
-					UserSub: userSub,
-					GroupId: sgRoleActions.Id,
-					Roles:   deletions,
-				})
+				err := h.Identity.DeleteRolesFromGroup(info.Ctx, userSub, sgRoleActions.Id, deletions)
 				if err != nil {
 					return nil, util.ErrCheck(err)
 				}
 
+				saga.Compensate(IdentityAction{Action: IDENTITY_ADD_ROLES, GroupId: sgRoleActions.Id, Roles: deletions})
 			}
 
 			additions := []*types.KeycloakRole{}
@@ -303,21 +296,24 @@ func (h *Handlers) PatchGroupAssignments(info ReqInfo, data *types.PatchGroupAss
 			}
 
 			if len(additions) > 0 {
-				err = h.Keycloak.AddRolesToGroup(info.Ctx, userSub, sgRoleActions.Id, additions)
+				err = h.Identity.AddRolesToGroup(info.Ctx, userSub, sgRoleActions.Id, additions)
 				if err != nil {
-					util.ErrorLog.Println(util.ErrCheck(err))
+					return nil, util.ErrCheck(err)
 				}
+
+				saga.Compensate(IdentityAction{Action: IDENTITY_DELETE_ROLES, GroupId: sgRoleActions.Id, Roles: additions})
 			}
 		}
 	}
 
+	saga.Complete()
 	return &types.PatchGroupAssignmentsResponse{Success: true}, nil
 }
 
 func (h *Handlers) GetGroupAssignments(info ReqInfo, data *types.GetGroupAssignmentsRequest) (*types.GetGroupAssignmentsResponse, error) {
 	userSub := info.Session.GetUserSub()
 
-	kcGroup, err := h.Keycloak.GetGroup(info.Ctx, userSub, info.Session.GetGroupExternalId())
+	kcGroup, err := h.Identity.GetGroup(info.Ctx, userSub, info.Session.GetGroupExternalId())
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
@@ -325,7 +321,7 @@ func (h *Handlers) GetGroupAssignments(info ReqInfo, data *types.GetGroupAssignm
 	assignments := make(map[string]*types.IGroupRoleAuthActions)
 	assignmentsWithoutId := make(map[string]*types.IGroupRoleAuthActions)
 
-	subGroups, err := h.Keycloak.GetGroupSubGroups(info.Ctx, userSub, kcGroup.Id)
+	subGroups, err := h.Identity.GetGroupSubGroups(info.Ctx, userSub, kcGroup.Id)
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
@@ -339,7 +335,7 @@ func (h *Handlers) GetGroupAssignments(info ReqInfo, data *types.GetGroupAssignm
 			Actions: []*types.IGroupRoleAuthAction{},
 		}
 
-		sgRoles, err := h.Keycloak.GetGroupSiteRoles(info.Ctx, userSub, sg.Id)
+		sgRoles, err := h.Identity.GetGroupSiteRoles(info.Ctx, userSub, sg.Id)
 		if err != nil {
 			return nil, util.ErrCheck(err)
 		}
@@ -390,7 +386,7 @@ func (h *Handlers) DeleteGroup(info ReqInfo, data *types.DeleteGroupRequest) (*t
 		return nil, util.ErrCheck(err)
 	}
 
-	err = h.Keycloak.DeleteGroup(info.Ctx, userSub, groupExternalId)
+	err = h.Identity.DeleteGroup(info.Ctx, userSub, groupExternalId)
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bytes"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -396,16 +397,54 @@ func getFormTemplate(info ReqInfo, formVersionId string) (*types.IProtoFormTempl
 	return formTemplate, nil
 }
 
-func (h *Handlers) GetGroupFormVersionData(info ReqInfo, data *types.GetGroupFormVersionDataRequest) (*types.GetGroupFormVersionDataResponse, error) {
-	formTemplate, err := getFormTemplate(info, data.GetFormVersionId())
+// Pivots a form version's submissions into one column per field, or per option for multi-selects.
+// Rows are the raw values of each column, nil, string, bool, float64 or time.Time.
+// Submissions are only loosely typed, so answers which don't read as their field's type export as null instead of
+// failing the cast. Numbers are limited to what fits in a FLOAT8.
+const (
+	formDataBooleanColumn = `CASE
+		WHEN LOWER(TRIM(submission->>%[1]s)) IN ('true', 't', 'yes', 'y', 'on', '1') THEN true
+		WHEN LOWER(TRIM(submission->>%[1]s)) IN ('false', 'f', 'no', 'n', 'off', '0') THEN false
+	END`
+	formDataNumberColumn = `CASE
+		WHEN TRIM(submission->>%[1]s) ~ '^[+-]?([0-9]{1,200}(\.[0-9]{0,200})?|\.[0-9]{1,200})([eE][+-]?[0-9]{1,2})?$'
+		THEN TRIM(submission->>%[1]s)::FLOAT8
+	END`
+)
+
+func getFormVersionData(info ReqInfo, formVersionId string) ([]*types.IProtoFormDataColumn, [][]any, error) {
+	formTemplate, err := getFormTemplate(info, formVersionId)
 	if err != nil {
-		return nil, util.ErrCheck(err)
+		return nil, nil, util.ErrCheck(err)
+	}
+
+	columns := []*types.IProtoFormDataColumn{
+		{Name: "id", Label: "Id", T: "text"},
+		{Name: "created_on", Label: "Created On", T: "timestamp"},
+		{Name: "created_sub", Label: "Created Sub", T: "text"},
 	}
 
 	dataCols := []string{
-		"id",
-		"created_on",
-		"created_sub",
+		"fvs.id::TEXT",
+		"fvs.created_on",
+		"fvs.created_sub::TEXT",
+	}
+
+	args := []any{formVersionId}
+	addArg := func(arg any) string {
+		args = append(args, arg)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	seenNames := make(map[string]int)
+	addColumn := func(colDef string, col *types.IProtoFormDataColumn) {
+		// labels can repeat across a form, keep names unique for the export headers
+		seenNames[col.Name]++
+		if count := seenNames[col.Name]; count > 1 {
+			col.Name = fmt.Sprintf("%s_%d", col.Name, count)
+		}
+		dataCols = append(dataCols, colDef)
+		columns = append(columns, col)
 	}
 
 	rowKeys := make([]string, 0, len(formTemplate.Rows))
@@ -431,20 +470,24 @@ func (h *Handlers) GetGroupFormVersionData(info ReqInfo, data *types.GetGroupFor
 			case "multi-select":
 				for _, opt := range field.GetO() {
 					optLabel := sanitizeColName(opt.GetL())
-					fullColName := fmt.Sprintf("%s_%s", colName, optLabel)
 
-					colDef := fmt.Sprintf(`(submission->'%s' @> '"%s"') AS "%s"`, fieldId, opt.GetV(), fullColName)
-					dataCols = append(dataCols, colDef)
+					colDef := fmt.Sprintf(`COALESCE(submission->%s @> TO_JSONB(%s::TEXT), false)`, addArg(fieldId), addArg(opt.GetV()))
+					addColumn(colDef, &types.IProtoFormDataColumn{
+						Name:    fmt.Sprintf("%s_%s", colName, optLabel),
+						Label:   fmt.Sprintf("%s: %s", field.GetL(), opt.GetL()),
+						T:       "boolean",
+						FieldId: fieldId,
+					})
 				}
 			case "boolean":
-				colDef := fmt.Sprintf(`(submission->>'%s')::BOOLEAN AS "%s"`, fieldId, colName)
-				dataCols = append(dataCols, colDef)
+				colDef := fmt.Sprintf(formDataBooleanColumn, addArg(fieldId))
+				addColumn(colDef, &types.IProtoFormDataColumn{Name: colName, Label: field.GetL(), T: "boolean", FieldId: fieldId})
 			case "number":
-				colDef := fmt.Sprintf(`(submission->>'%s')::NUMERIC AS "%s"`, fieldId, colName)
-				dataCols = append(dataCols, colDef)
+				colDef := fmt.Sprintf(formDataNumberColumn, addArg(fieldId))
+				addColumn(colDef, &types.IProtoFormDataColumn{Name: colName, Label: field.GetL(), T: "number", FieldId: fieldId})
 			default:
-				colDef := fmt.Sprintf(`submission->>'%s' AS "%s"`, fieldId, colName)
-				dataCols = append(dataCols, colDef)
+				colDef := fmt.Sprintf(`submission->>%s`, addArg(fieldId))
+				addColumn(colDef, &types.IProtoFormDataColumn{Name: colName, Label: field.GetL(), T: "text", FieldId: fieldId})
 			}
 		}
 	}
@@ -454,12 +497,96 @@ func (h *Handlers) GetGroupFormVersionData(info ReqInfo, data *types.GetGroupFor
 		FROM dbtable_schema.form_version_submissions fvs
 		JOIN dbtable_schema.form_versions fv ON fv.id = fvs.form_version_id
 		JOIN dbtable_schema.group_forms gf ON gf.form_id = fv.form_id
-		WHERE fvs.form_version_id = '%s'
-	`, strings.Join(dataCols, ", "), data.GetFormVersionId())
+		WHERE fvs.form_version_id = $1::uuid
+		ORDER BY fvs.created_on
+	`, strings.Join(dataCols, ", "))
+
+	rows, err := info.Tx.Query(info.Ctx, query, args...)
+	if err != nil {
+		return nil, nil, util.ErrCheck(err)
+	}
+	defer rows.Close()
+
+	var dataRows [][]any
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, nil, util.ErrCheck(err)
+		}
+		dataRows = append(dataRows, values)
+	}
 
-	println("the query ", query)
+	if err := rows.Err(); err != nil {
+		return nil, nil, util.ErrCheck(err)
+	}
+
+	return columns, dataRows, nil
+}
+
+func (h *Handlers) GetGroupFormVersionData(info ReqInfo, data *types.GetGroupFormVersionDataRequest) (*types.GetGroupFormVersionDataResponse, error) {
+	columns, dataRows, err := getFormVersionData(info, data.GetFormVersionId())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	rows := make([]*types.IProtoFormDataRow, 0, len(dataRows))
+	for _, dataRow := range dataRows {
+		cells := make([]*structpb.Value, len(dataRow))
+		for i, value := range dataRow {
+			switch v := value.(type) {
+			case nil:
+				cells[i] = structpb.NewNullValue()
+			case bool:
+				cells[i] = structpb.NewBoolValue(v)
+			case float64:
+				cells[i] = structpb.NewNumberValue(v)
+			default:
+				cells[i] = structpb.NewStringValue(util.SpreadsheetCellString(v))
+			}
+		}
+		rows = append(rows, &types.IProtoFormDataRow{Cells: cells})
+	}
+
+	return &types.GetGroupFormVersionDataResponse{Columns: columns, Rows: rows}, nil
+}
+
+func (h *Handlers) GetGroupFormVersionDataExport(info ReqInfo, data *types.GetGroupFormVersionDataExportRequest) (*types.GetGroupFormVersionDataExportResponse, error) {
+	format := strings.ToLower(data.GetFormat())
+	if format != "csv" && format != "xlsx" {
+		return nil, util.ErrCheck(util.UserError("Form data can only be exported as csv or xlsx."))
+	}
+
+	columns, dataRows, err := getFormVersionData(info, data.GetFormVersionId())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.GetName()
+	}
+
+	fileName := fmt.Sprintf("form_version_%s.%s", data.GetFormVersionId(), format)
+
+	var buf bytes.Buffer
+	var contentType string
+
+	if format == "csv" {
+		contentType = util.CSVContentType
+		err = util.WriteCSV(&buf, header, dataRows)
+	} else {
+		contentType = util.XLSXContentType
+		err = util.WriteXLSX(&buf, "Submissions", header, dataRows)
+	}
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
 
-	return &types.GetGroupFormVersionDataResponse{}, nil
+	return &types.GetGroupFormVersionDataExportResponse{
+		Content:     buf.Bytes(),
+		ContentType: contentType,
+		FileName:    fileName,
+	}, nil
 }
 
 func (h *Handlers) GetGroupFormVersionReport(info ReqInfo, data *types.GetGroupFormVersionReportRequest) (*types.GetGroupFormVersionReportResponse, error) {
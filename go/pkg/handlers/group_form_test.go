@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
 	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
 )
 
 func TestHandlers_PostGroupForm(t *testing.T) {
@@ -174,3 +176,97 @@ func TestHandlers_DeleteGroupForm(t *testing.T) {
 		})
 	}
 }
+func TestHandlers_GetGroupFormVersionData(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.GetGroupFormVersionDataRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.GetGroupFormVersionDataResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.GetGroupFormVersionData(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.GetGroupFormVersionData(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.GetGroupFormVersionData(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlers_GetGroupFormVersionDataExport(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.GetGroupFormVersionDataExportRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.GetGroupFormVersionDataExportResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.GetGroupFormVersionDataExport(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.GetGroupFormVersionDataExport(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.GetGroupFormVersionDataExport(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formDataColumns(t *testing.T) {
+	_, info, done, err := setupTestEnv(true)
+	if err != nil {
+		t.Fatal(util.ErrCheck(err))
+	}
+	defer done()
+
+	tests := []struct {
+		name       string
+		column     string
+		submission string
+		want       any
+	}{
+		{"true", formDataBooleanColumn, `{"f":true}`, true},
+		{"false text", formDataBooleanColumn, `{"f":"No"}`, false},
+		{"empty boolean", formDataBooleanColumn, `{"f":""}`, nil},
+		{"missing boolean", formDataBooleanColumn, `{}`, nil},
+		{"unreadable boolean", formDataBooleanColumn, `{"f":"maybe"}`, nil},
+		{"number", formDataNumberColumn, `{"f":12.5}`, 12.5},
+		{"number text", formDataNumberColumn, `{"f":" -3e2 "}`, -300.0},
+		{"empty number", formDataNumberColumn, `{"f":""}`, nil},
+		{"unreadable number", formDataNumberColumn, `{"f":"12 apples"}`, nil},
+		{"number out of range", formDataNumberColumn, `{"f":"1e999"}`, nil},
+		{"number as an object", formDataNumberColumn, `{"f":{"n":1}}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got any
+			query := `SELECT ` + fmt.Sprintf(tt.column, "$1") + ` FROM (SELECT $2::JSONB AS submission) s`
+			if err := info.Tx.QueryRow(info.Ctx, query, "f", tt.submission).Scan(&got); err != nil {
+				t.Fatalf("form data column %s error = %v", tt.submission, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("form data column %s = %#v, want %#v", tt.submission, got, tt.want)
+			}
+		})
+	}
+}
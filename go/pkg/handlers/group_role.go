@@ -53,17 +53,10 @@ func (h *Handlers) PostGroupRole(info ReqInfo, data *types.PostGroupRoleRequest)
 		WHERE role_id = $1
 	`, roleId).Scan(&existingRoleExternalId)
 
-	var undos []func()
+	saga := h.NewIdentitySaga(info, "post_group_role")
+	defer saga.Close()
 
-	defer func() {
-		if undos != nil && len(undos) > 0 {
-			for _, undo := range undos {
-				undo()
-			}
-		}
-	}()
-
-	kcSubGroup, err := h.Keycloak.CreateOrGetSubGroup(info.Ctx, userSub, info.Session.GetGroupExternalId(), data.GetName())
+	kcSubGroup, err := h.Identity.CreateOrGetSubGroup(info.Ctx, userSub, info.Session.GetGroupExternalId(), data.GetName())
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
@@ -74,12 +67,7 @@ func (h *Handlers) PostGroupRole(info ReqInfo, data *types.PostGroupRoleRequest)
 		return nil, util.ErrCheck(util.UserError("The group already has that role."))
 	}
 
-	undos = append(undos, func() {
-		err = h.Keycloak.DeleteGroup(info.Ctx, userSub, kcSubGroup.GetId())
-		if err != nil {
-			util.ErrorLog.Println(util.ErrCheck(err))
-		}
-	})
+	saga.Compensate(IdentityAction{Action: IDENTITY_DELETE_GROUP, GroupId: kcSubGroup.GetId()})
 
 	var groupRoleId string
 	err = info.Tx.QueryRow(info.Ctx, `
@@ -104,7 +92,7 @@ func (h *Handlers) PostGroupRole(info ReqInfo, data *types.PostGroupRoleRequest)
 		}
 	}
 
-	undos = nil
+	saga.Complete()
 	return &types.PostGroupRoleResponse{GroupRoleId: groupRoleId, RoleId: roleId}, nil
 }
 
@@ -168,7 +156,7 @@ func (h *Handlers) PatchGroupRole(info ReqInfo, data *types.PatchGroupRoleReques
 		}
 
 		// update the name of the keycloak group which controls this role
-		err = h.Keycloak.UpdateGroup(info.Ctx, userSub, existingRoleExternalId, data.GetName())
+		err = h.Identity.UpdateGroup(info.Ctx, userSub, existingRoleExternalId, data.GetName())
 		if err != nil {
 			return nil, util.ErrCheck(err)
 		}
@@ -232,9 +220,12 @@ func (h *Handlers) PatchGroupRoles(info ReqInfo, data *types.PatchGroupRolesRequ
 		})
 	}
 
+	saga := h.NewIdentitySaga(info, "patch_group_roles")
+	defer saga.Close()
+
 	if len(diffs) > 0 {
 
-		kcGroup, err := h.Keycloak.GetGroup(info.Ctx, userSub, groupExternalId)
+		kcGroup, err := h.Identity.GetGroup(info.Ctx, userSub, groupExternalId)
 		if err != nil {
 			return nil, util.ErrCheck(err)
 		}
@@ -249,7 +240,8 @@ func (h *Handlers) PatchGroupRoles(info ReqInfo, data *types.PatchGroupRolesRequ
 		if len(kcGroup.SubGroups) > 0 {
 			for _, subGroup := range kcGroup.SubGroups {
 				if slices.Contains(diffRoleNames, subGroup.Name) {
-					err = h.Keycloak.DeleteGroup(info.Ctx, userSub, subGroup.Id)
+					// Deleted subgroups can't be restored, so they are removed once the records are gone
+					err = saga.AfterCommit(info.Tx, IdentityAction{Action: IDENTITY_DELETE_GROUP, GroupId: subGroup.Id})
 					if err != nil {
 						return nil, util.ErrCheck(err)
 					}
@@ -269,15 +261,27 @@ func (h *Handlers) PatchGroupRoles(info ReqInfo, data *types.PatchGroupRolesRequ
 		}
 	}
 
-	var undos []func()
+	// Existing subgroups are returned again by CreateOrGetSubGroup, and must not be undone
+	existingExternalIds := make(map[string]bool)
+	externalIdRows, err := info.Tx.Query(info.Ctx, `
+		SELECT external_id
+		FROM dbtable_schema.group_roles
+		WHERE group_id = $1
+	`, groupId)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
 
-	defer func() {
-		if undos != nil && len(undos) > 0 {
-			for _, undo := range undos {
-				undo()
-			}
+	for externalIdRows.Next() {
+		var externalId string
+		err = externalIdRows.Scan(&externalId)
+		if err != nil {
+			externalIdRows.Close()
+			return nil, util.ErrCheck(err)
 		}
-	}()
+		existingExternalIds[externalId] = true
+	}
+	externalIdRows.Close()
 
 	// Add new roles to keycloak and group records
 	for _, role := range dataRoles {
@@ -287,17 +291,14 @@ func (h *Handlers) PatchGroupRoles(info ReqInfo, data *types.PatchGroupRolesRequ
 			continue
 		}
 
-		kcSubGroup, err := h.Keycloak.CreateOrGetSubGroup(info.Ctx, userSub, groupExternalId, roleName)
+		kcSubGroup, err := h.Identity.CreateOrGetSubGroup(info.Ctx, userSub, groupExternalId, roleName)
 		if err != nil {
 			return nil, util.ErrCheck(err)
 		}
 
-		undos = append(undos, func() {
-			err = h.Keycloak.DeleteGroup(info.Ctx, userSub, kcSubGroup.GetId())
-			if err != nil {
-				util.ErrorLog.Println(util.ErrCheck(err))
-			}
-		})
+		if !existingExternalIds[kcSubGroup.GetId()] {
+			saga.Compensate(IdentityAction{Action: IDENTITY_DELETE_GROUP, GroupId: kcSubGroup.GetId()})
+		}
 
 		_, err = info.Tx.Exec(info.Ctx, `
 			INSERT INTO dbtable_schema.group_roles (group_id, role_id, external_id, created_sub)
@@ -315,7 +316,7 @@ func (h *Handlers) PatchGroupRoles(info ReqInfo, data *types.PatchGroupRolesRequ
 		}))
 	}
 
-	undos = nil
+	saga.Complete()
 	return &types.PatchGroupRolesResponse{Success: true}, nil
 }
 
@@ -365,6 +366,8 @@ func (h *Handlers) DeleteGroupRole(info ReqInfo, data *types.DeleteGroupRoleRequ
 		return nil, util.ErrCheck(util.UserError("The default role may not be deleted. Update a different role to be default, then try again."))
 	}
 
+	saga := h.NewIdentitySaga(info, "delete_group_role")
+
 	for _, id := range groupRoleIds {
 		if id != "" {
 			var name string
@@ -385,7 +388,8 @@ func (h *Handlers) DeleteGroupRole(info ReqInfo, data *types.DeleteGroupRoleRequ
 				return nil, util.ErrCheck(err)
 			}
 
-			err = h.Keycloak.DeleteGroup(info.Ctx, userSub, subGroupExternalId)
+			// The subgroup is only deleted once the role deletion commits
+			err = saga.AfterCommit(info.Tx, IdentityAction{Action: IDENTITY_DELETE_GROUP, GroupId: subGroupExternalId})
 			if err != nil {
 				return nil, util.ErrCheck(err)
 			}
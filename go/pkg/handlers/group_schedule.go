@@ -1,14 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/keybittech/awayto-v3/go/pkg/types"
 	"github.com/keybittech/awayto-v3/go/pkg/util"
 )
 
+var ErrInvalidKioskToken = errors.New("invalid kiosk token")
+
 func (h *Handlers) PostGroupSchedule(info ReqInfo, data *types.PostGroupScheduleRequest) (*types.PostGroupScheduleResponse, error) {
 	var groupScheduleId string
 	err := info.Tx.QueryRow(info.Ctx, `
@@ -45,6 +50,99 @@ func (h *Handlers) GetGroupSchedules(info ReqInfo, data *types.GetGroupSchedules
 	return &types.GetGroupSchedulesResponse{GroupSchedules: *groupSchedules}, nil
 }
 
+func (h *Handlers) GetGroupKioskSchedules(info ReqInfo, data *types.GetGroupKioskSchedulesRequest) (*types.GetGroupKioskSchedulesResponse, error) {
+	kioskSchedules := util.BatchQuery[types.GetGroupKioskSchedulesResponse](info.Batch, `
+		SELECT JSONB_BUILD_OBJECT('name', ks.name, 'code', ks.code) as "group", ks.schedules as "groupSchedules", ks.updated_on as "updatedOn"
+		FROM dbview_schema.kiosk_schedule ks
+		JOIN dbtable_schema.groups g ON g.name = ks.name
+		WHERE g.id = $1
+	`, info.Session.GetGroupId())
+
+	info.Batch.Send(info.Ctx)
+
+	if len(*kioskSchedules) == 0 {
+		return &types.GetGroupKioskSchedulesResponse{}, nil
+	}
+
+	return (*kioskSchedules)[0], nil
+}
+
+func (h *Handlers) PostGroupKioskToken(info ReqInfo, data *types.PostGroupKioskTokenRequest) (*types.PostGroupKioskTokenResponse, error) {
+	token := util.GenerateKioskToken()
+
+	kioskToken := util.BatchQueryRow[types.IGroupKioskToken](info.Batch, `
+		INSERT INTO dbtable_schema.group_kiosk_tokens (group_id, name, token_hash, created_sub)
+		VALUES ($1::uuid, $2, $3, $4::uuid)
+		RETURNING id
+	`, info.Session.GetGroupId(), data.GetName(), util.HashToken(token), info.Session.GetUserSub())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.PostGroupKioskTokenResponse{Id: (*kioskToken).GetId(), Token: token}, nil
+}
+
+func (h *Handlers) GetGroupKioskTokens(info ReqInfo, data *types.GetGroupKioskTokensRequest) (*types.GetGroupKioskTokensResponse, error) {
+	kioskTokens := util.BatchQuery[types.IGroupKioskToken](info.Batch, `
+		SELECT id, name, last_used_on as "lastUsedOn", created_on as "createdOn"
+		FROM dbtable_schema.group_kiosk_tokens
+		WHERE group_id = $1 AND enabled = true
+		ORDER BY created_on DESC
+	`, info.Session.GetGroupId())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.GetGroupKioskTokensResponse{KioskTokens: *kioskTokens}, nil
+}
+
+func (h *Handlers) DeleteGroupKioskToken(info ReqInfo, data *types.DeleteGroupKioskTokenRequest) (*types.DeleteGroupKioskTokenResponse, error) {
+	deleted := util.BatchExec(info.Batch, `
+		DELETE FROM dbtable_schema.group_kiosk_tokens
+		WHERE id = $1 AND group_id = $2
+	`, data.GetId(), info.Session.GetGroupId())
+
+	info.Batch.Send(info.Ctx)
+
+	if (*deleted).RowsAffected() == 0 {
+		return nil, util.ErrCheck(util.UserError("Kiosk token not found."))
+	}
+
+	return &types.DeleteGroupKioskTokenResponse{Success: true}, nil
+}
+
+// Used by the public kiosk route, where the only credential is a kiosk token for the named group
+func (h *Handlers) GetKioskSchedulesByToken(ctx context.Context, groupName, token string) (*types.GetGroupKioskSchedulesResponse, error) {
+	if !util.IsKioskToken(token) {
+		return nil, ErrInvalidKioskToken
+	}
+
+	batch := util.NewBatchable(h.Database.DatabaseClient.Pool, "worker", "", 0)
+
+	tokenUse := util.BatchExec(batch, `
+		UPDATE dbtable_schema.group_kiosk_tokens gkt
+		SET last_used_on = $3
+		FROM dbtable_schema.groups g
+		WHERE g.id = gkt.group_id AND g.name = $1 AND gkt.token_hash = $2 AND gkt.enabled = true
+	`, groupName, util.HashToken(token), time.Now())
+
+	kioskSchedules := util.BatchQuery[types.GetGroupKioskSchedulesResponse](batch, `
+		SELECT JSONB_BUILD_OBJECT('name', ks.name, 'code', ks.code) as "group", ks.schedules as "groupSchedules", ks.updated_on as "updatedOn"
+		FROM dbview_schema.kiosk_schedule ks
+		WHERE ks.name = $1
+	`, groupName)
+
+	batch.Send(ctx)
+
+	if tokenUse.RowsAffected() == 0 {
+		return nil, ErrInvalidKioskToken
+	}
+
+	if len(*kioskSchedules) == 0 {
+		return &types.GetGroupKioskSchedulesResponse{}, nil
+	}
+
+	return (*kioskSchedules)[0], nil
+}
+
 func (h *Handlers) GetGroupScheduleMasterById(info ReqInfo, data *types.GetGroupScheduleMasterByIdRequest) (*types.GetGroupScheduleMasterByIdResponse, error) {
 	// The schedule master is the root ISchedule, not an IGroupSchedule
 	schedule := util.BatchQueryRow[types.ISchedule](info.Batch, `
@@ -91,6 +91,118 @@ func TestHandlers_GetGroupSchedules(t *testing.T) {
 	}
 }
 
+func TestHandlers_GetGroupKioskSchedules(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.GetGroupKioskSchedulesRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.GetGroupKioskSchedulesResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.GetGroupKioskSchedules(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.GetGroupKioskSchedules(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.GetGroupKioskSchedules(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlers_PostGroupKioskToken(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.PostGroupKioskTokenRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.PostGroupKioskTokenResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.PostGroupKioskToken(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.PostGroupKioskToken(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.PostGroupKioskToken(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlers_GetGroupKioskTokens(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.GetGroupKioskTokensRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.GetGroupKioskTokensResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.GetGroupKioskTokens(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.GetGroupKioskTokens(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.GetGroupKioskTokens(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlers_DeleteGroupKioskToken(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.DeleteGroupKioskTokenRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.DeleteGroupKioskTokenResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.DeleteGroupKioskToken(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.DeleteGroupKioskToken(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.DeleteGroupKioskToken(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestHandlers_GetGroupScheduleMasterById(t *testing.T) {
 	type args struct {
 		info ReqInfo
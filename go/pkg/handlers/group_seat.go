@@ -2,13 +2,25 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"html/template"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
 	"github.com/keybittech/awayto-v3/go/pkg/types"
 	"github.com/keybittech/awayto-v3/go/pkg/util"
 )
 
+const (
+	seatPaymentMethodCheck = "check"
+	seatPaymentMethodACH   = "ach"
+)
+
+var ErrSeatPaymentUnchanged = errors.New("seat payment not found, already settled or amount mismatch")
+
 func (h *Handlers) PostGroupSeat(info ReqInfo, data *types.PostGroupSeatRequest) (*types.PostGroupSeatResponse, error) {
 	seats := data.GetSeats()
 	amount := 10 * seats
@@ -176,7 +188,7 @@ const receiptTemplate = `
       <h3>Payment Details:</h3>
       <p><strong>Date Paid:</strong> {{.PaidOn}}</p>
       <p><strong>Check / Ref #:</strong> {{.CheckNo}}</p>
-      <p><strong>Payment Method:</strong> Check</p>
+      <p><strong>Payment Method:</strong> {{.PaymentMethod}}</p>
     </div>
   </div>
 
@@ -219,19 +231,23 @@ const receiptTemplate = `
 `
 
 func (h *Handlers) GetGroupSeatReceipt(info ReqInfo, data *types.GetGroupSeatReceiptRequest) (*types.GetGroupSeatReceiptResponse, error) {
-	payment := util.BatchQueryRow[types.IGroupSeatPayment](info.Batch, `
-		SELECT status, code, seats, amount, "createdOn", "paidOn", "checkNo"
+	payments := util.BatchQuery[types.IGroupSeatPayment](info.Batch, `
+		SELECT status, code, seats, amount, "createdOn", "paidOn", "checkNo", "paymentMethod"
 		FROM dbview_schema.enabled_seat_payments
 		WHERE "groupId" = $1 AND code = $2
 	`, info.Session.GetGroupId(), data.GetCode())
 
 	info.Batch.Send(info.Ctx)
 
-	if payment == nil || *payment == nil {
+	if len(*payments) == 0 {
 		return nil, util.ErrCheck(util.UserError("Payment not found"))
 	}
 
-	p := *payment
+	p := (*payments)[0]
+
+	if p.GetStatus() != clients.PAYMENT_STATUS_PAID {
+		return nil, util.ErrCheck(util.UserError("A receipt is available once the payment has been received"))
+	}
 
 	tmplData := struct {
 		Code            string
@@ -244,6 +260,7 @@ func (h *Handlers) GetGroupSeatReceipt(info ReqInfo, data *types.GetGroupSeatRec
 		PaymentAddr2    string
 		PaidOn          string
 		CheckNo         string
+		PaymentMethod   string
 	}{
 		Code:            p.GetCode(),
 		Status:          p.GetStatus(),
@@ -255,6 +272,7 @@ func (h *Handlers) GetGroupSeatReceipt(info ReqInfo, data *types.GetGroupSeatRec
 		PaymentAddr2:    util.E_PAYMENT_ADDR2,
 		PaidOn:          p.GetPaidOn(),
 		CheckNo:         p.GetCheckNo(),
+		PaymentMethod:   seatPaymentMethodLabel(p.GetPaymentMethod()),
 	}
 
 	t, err := template.New("receipt").Parse(receiptTemplate)
@@ -269,3 +287,96 @@ func (h *Handlers) GetGroupSeatReceipt(info ReqInfo, data *types.GetGroupSeatRec
 
 	return &types.GetGroupSeatReceiptResponse{Html: buf.String()}, nil
 }
+
+// Payments settled before methods were recorded were all paid by check
+func seatPaymentMethodLabel(method string) string {
+	switch method {
+	case "", seatPaymentMethodCheck:
+		return "Check"
+	case seatPaymentMethodACH:
+		return "ACH"
+	default:
+		return "Online (" + method + ")"
+	}
+}
+
+// Site operators record checks and ACH transfers received against a purchase order code.
+// Seat payments are only writable by the worker, the super_admin option guards the endpoint.
+func (h *Handlers) PostGroupSeatPaymentReceipt(info ReqInfo, data *types.PostGroupSeatPaymentReceiptRequest) (*types.PostGroupSeatPaymentReceiptResponse, error) {
+	batch := util.NewBatchable(h.Database.DatabaseClient.Pool, "worker", "", 0)
+
+	payments := util.BatchQuery[types.IGroupSeatPayment](batch, `
+		SELECT status, amount
+		FROM dbview_schema.enabled_seat_payments
+		WHERE code = $1
+	`, data.GetCode())
+
+	batch.Send(info.Ctx)
+
+	if len(*payments) == 0 {
+		return nil, util.ErrCheck(util.UserError("Payment not found"))
+	}
+
+	if (*payments)[0].GetAmount() != data.GetAmount() {
+		return nil, util.ErrCheck(util.UserError("The amount received does not match the purchase order"))
+	}
+
+	batch.Reset()
+
+	// A failed online payment may still be settled by check, the balance trigger restores its seats
+	settled := util.BatchQuery[types.IGroupSeatPayment](batch, `
+		UPDATE dbtable_schema.seat_payments
+		SET status = 'paid', paid_on = COALESCE($2::TEXT::DATE::TIMESTAMP, $3), check_no = $4, payment_method = $5,
+			updated_on = $3, updated_sub = $6
+		WHERE code = $1 AND status IN ('pending', 'failed') AND enabled = true
+		RETURNING status, code, seats, amount, created_on as "createdOn", paid_on as "paidOn",
+			check_no as "checkNo", payment_method as "paymentMethod"
+	`, data.GetCode(), util.NewNullString(data.GetPaidOn()), time.Now().UTC(), data.GetReference(), data.GetPaymentMethod(), info.Session.GetUserSub())
+
+	batch.Send(info.Ctx)
+
+	if len(*settled) == 0 {
+		return nil, util.ErrCheck(util.UserError("Payment has already been settled"))
+	}
+
+	return &types.PostGroupSeatPaymentReceiptResponse{SeatPayment: (*settled)[0]}, nil
+}
+
+// ReconcileSeatPayment applies a verified provider webhook event. Only pending payments can fail,
+// and a paid event must carry the purchase order amount. Redelivered events change nothing.
+func (h *Handlers) ReconcileSeatPayment(ctx context.Context, provider string, event *clients.PaymentEvent) error {
+	batch := util.NewBatchable(h.Database.DatabaseClient.Pool, "worker", "", 0)
+
+	now := time.Now().UTC()
+
+	var settled *pgconn.CommandTag
+	switch event.Status {
+	case clients.PAYMENT_STATUS_PAID:
+		paidOn := now
+		if !event.PaidOn.IsZero() {
+			paidOn = event.PaidOn
+		}
+
+		settled = util.BatchExec(batch, `
+			UPDATE dbtable_schema.seat_payments
+			SET status = 'paid', paid_on = $3, check_no = $4, payment_method = $5, updated_on = $6
+			WHERE code = $1 AND amount = $2 AND status IN ('pending', 'failed') AND enabled = true
+		`, event.Code, event.Amount, paidOn, event.Reference, provider, now)
+	case clients.PAYMENT_STATUS_FAILED:
+		settled = util.BatchExec(batch, `
+			UPDATE dbtable_schema.seat_payments
+			SET status = 'failed', check_no = $2, payment_method = $3, updated_on = $4
+			WHERE code = $1 AND status = 'pending' AND enabled = true
+		`, event.Code, event.Reference, provider, now)
+	default:
+		return util.ErrCheck(fmt.Errorf("unknown payment status %s", event.Status))
+	}
+
+	batch.Send(ctx)
+
+	if settled.RowsAffected() == 0 {
+		return ErrSeatPaymentUnchanged
+	}
+
+	return nil
+}
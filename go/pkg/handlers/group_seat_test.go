@@ -34,3 +34,51 @@ func TestHandlers_PostGroupSeat(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlers_PostGroupSeatPaymentReceipt(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.PostGroupSeatPaymentReceiptRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.PostGroupSeatPaymentReceiptResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.PostGroupSeatPaymentReceipt(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.PostGroupSeatPaymentReceipt(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.PostGroupSeatPaymentReceipt(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_seatPaymentMethodLabel(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		want   string
+	}{
+		{name: "unrecorded methods were checks", method: "", want: "Check"},
+		{name: "check", method: "check", want: "Check"},
+		{name: "ach", method: "ach", want: "ACH"},
+		{name: "provider", method: "fake", want: "Online (fake)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seatPaymentMethodLabel(tt.method); got != tt.want {
+				t.Errorf("seatPaymentMethodLabel(%v) = %v, want %v", tt.method, got, tt.want)
+			}
+		})
+	}
+}
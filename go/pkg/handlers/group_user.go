@@ -33,12 +33,12 @@ func (h *Handlers) PatchGroupUser(info ReqInfo, data *types.PatchGroupUserReques
 		return nil, util.ErrCheck(err)
 	}
 
-	err = h.Keycloak.DeleteUserFromGroup(info.Ctx, userSub, data.UserSub, oldSubgroupExternalId)
+	err = h.Identity.DeleteUserFromGroup(info.Ctx, userSub, data.UserSub, oldSubgroupExternalId)
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
 
-	err = h.Keycloak.AddUserToGroup(info.Ctx, userSub, data.UserSub, newSubgroupExternalId)
+	err = h.Identity.AddUserToGroup(info.Ctx, userSub, data.UserSub, newSubgroupExternalId)
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
@@ -120,7 +120,7 @@ func (h *Handlers) DeleteGroupUser(info ReqInfo, data *types.DeleteGroupUserRequ
 	}
 
 	for _, u := range usersInfo {
-		err = h.Keycloak.DeleteUserFromGroup(info.Ctx, info.Session.GetUserSub(), u.UserSub, u.ExternalId)
+		err = h.Identity.DeleteUserFromGroup(info.Ctx, info.Session.GetUserSub(), u.UserSub, u.ExternalId)
 		if err != nil {
 			return nil, util.ErrCheck(err)
 		}
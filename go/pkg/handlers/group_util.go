@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
+	"net/url"
 	"slices"
 	"strings"
+	"time"
 
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
 	"github.com/keybittech/awayto-v3/go/pkg/types"
 	"github.com/keybittech/awayto-v3/go/pkg/util"
 )
@@ -45,12 +50,12 @@ func (h *Handlers) JoinGroup(info ReqInfo, data *types.JoinGroupRequest) (*types
 		}
 	}
 
-	_, err = info.Tx.Exec(info.Ctx, `
-		INSERT INTO dbtable_schema.group_users (user_id, group_id, external_id, created_sub)
-		SELECT id, $2, $3, $1::uuid
-		FROM dbtable_schema.users
-		WHERE created_sub = $1
-	`, userSub, groupId, kcRoleSubGroupExternalId)
+	err = insertGroupUser(info, groupId, kcRoleSubGroupExternalId)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	err = queueGroupWebhookEvent(info, groupId, GROUP_WEBHOOK_GROUP_USER_JOINED, groupWebhookGroupUser{UserSub: userSub})
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
@@ -58,7 +63,7 @@ func (h *Handlers) JoinGroup(info ReqInfo, data *types.JoinGroupRequest) (*types
 	// Skip add to group for registation joiners, keycloak must complete the registration
 	if !data.GetRegistering() {
 		// User sub twice for worker queue id + user id to add to role group
-		err = h.Keycloak.AddUserToGroup(info.Ctx, userSub, userSub, kcRoleSubGroupExternalId)
+		err = h.Identity.AddUserToGroup(info.Ctx, userSub, userSub, kcRoleSubGroupExternalId)
 		if err != nil {
 			return nil, util.ErrCheck(err)
 		}
@@ -80,6 +85,54 @@ func (h *Handlers) JoinGroup(info ReqInfo, data *types.JoinGroupRequest) (*types
 	return &types.JoinGroupResponse{Success: true}, nil
 }
 
+func insertGroupUser(info ReqInfo, groupId, kcRoleSubGroupExternalId string) error {
+	_, err := info.Tx.Exec(info.Ctx, `
+		INSERT INTO dbtable_schema.group_users (user_id, group_id, external_id, created_sub)
+		SELECT id, $2, $3, $1::uuid
+		FROM dbtable_schema.users
+		WHERE created_sub = $1
+	`, info.Session.GetUserSub(), groupId, kcRoleSubGroupExternalId)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+	return nil
+}
+
+// Places a registering user into the group role they were invited to. Keycloak has already
+// added them to the role's subgroup, using the roleGroupId from REGISTER_VALIDATE.
+func (h *Handlers) redeemGroupInvite(info ReqInfo, invite string) error {
+	err := util.VerifyInviteToken(invite)
+	if err != nil {
+		return util.ErrCheck(util.UserError("Invite is no longer valid."))
+	}
+
+	var groupId, kcRoleSubGroupExternalId string
+	err = info.Tx.QueryRow(info.Ctx, `
+		SELECT group_id, external_id
+		FROM dbfunc_schema.redeem_group_invite($1, $2)
+	`, util.HashToken(invite), info.Session.GetUserEmail()).Scan(&groupId, &kcRoleSubGroupExternalId)
+	if err != nil {
+		return util.ErrCheck(util.UserError("Invite is no longer valid."))
+	}
+
+	err = insertGroupUser(info, groupId, kcRoleSubGroupExternalId)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	err = queueGroupWebhookEvent(info, groupId, GROUP_WEBHOOK_GROUP_USER_JOINED, groupWebhookGroupUser{UserSub: info.Session.GetUserSub()})
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	_, err = h.ActivateProfile(info, nil)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
 func (h *Handlers) LeaveGroup(info ReqInfo, data *types.LeaveGroupRequest) (*types.LeaveGroupResponse, error) {
 	var userId, groupId, allowedDomains, defaultRoleId string
 
@@ -102,7 +155,7 @@ func (h *Handlers) LeaveGroup(info ReqInfo, data *types.LeaveGroupRequest) (*typ
 		return nil, util.ErrCheck(err)
 	}
 
-	err = h.Keycloak.DeleteUserFromGroup(info.Ctx, info.Session.GetUserSub(), info.Session.GetUserSub(), groupId)
+	err = h.Identity.DeleteUserFromGroup(info.Ctx, info.Session.GetUserSub(), info.Session.GetUserSub(), groupId)
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
@@ -172,3 +225,140 @@ func (h *Handlers) CompleteOnboarding(info ReqInfo, data *types.CompleteOnboardi
 
 	return onboardingResponse, nil
 }
+
+const (
+	groupInviteDuration = 7 * 24 * time.Hour
+	inviteMailTimeout   = time.Minute
+)
+
+func (h *Handlers) InviteGroupUsers(info ReqInfo, data *types.InviteGroupUsersRequest) (*types.InviteGroupUsersResponse, error) {
+	groupId := info.Session.GetGroupId()
+	userSub := info.Session.GetUserSub()
+
+	var groupName, allowedDomains string
+	err := info.Tx.QueryRow(info.Ctx, `
+		SELECT display_name, COALESCE(allowed_domains, '')
+		FROM dbtable_schema.groups
+		WHERE id = $1
+	`, groupId).Scan(&groupName, &allowedDomains)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	expiresOn := time.Now().UTC().Add(groupInviteDuration)
+
+	invites := make([]*types.IGroupInvite, 0, len(data.GetUsers()))
+	inviteMails := make([]*clients.MailMessage, 0, len(data.GetUsers()))
+
+	for _, user := range data.GetUsers() {
+		email := strings.ToLower(strings.TrimSpace(user.GetEmail()))
+
+		_, domain, ok := strings.Cut(email, "@")
+		if !ok || domain == "" || strings.ContainsAny(email, " \r\n") {
+			return nil, util.ErrCheck(util.UserError("Invalid email " + email + "."))
+		}
+
+		if allowedDomains != "" && !slices.Contains(strings.Split(allowedDomains, ","), domain) {
+			return nil, util.ErrCheck(util.UserError("Group access is restricted for " + email + "."))
+		}
+
+		token, err := util.GenerateInviteToken(expiresOn)
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+
+		// Re-inviting replaces any outstanding invite for the same email
+		_, err = info.Tx.Exec(info.Ctx, `
+			UPDATE dbtable_schema.group_invites
+			SET revoked_on = $3, updated_on = $3, updated_sub = $4
+			WHERE group_id = $1 AND LOWER(email) = $2 AND accepted_on IS NULL AND revoked_on IS NULL
+		`, groupId, email, time.Now().UTC(), userSub)
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+
+		invite := &types.IGroupInvite{Email: email, RoleId: user.GetRoleId()}
+
+		err = info.Tx.QueryRow(info.Ctx, `
+			INSERT INTO dbtable_schema.group_invites (group_id, group_role_id, email, token_hash, expires_on, created_sub)
+			SELECT gr.group_id, gr.id, $3, $4, $5, $6::uuid
+			FROM dbtable_schema.group_roles gr
+			WHERE gr.group_id = $1 AND gr.role_id = $2
+			RETURNING id
+		`, groupId, user.GetRoleId(), email, util.HashToken(token), expiresOn, userSub).Scan(&invite.Id)
+		if err != nil {
+			return nil, util.ErrCheck(util.UserError("Invalid role for " + email + "."))
+		}
+
+		invite.ExpiresOn = expiresOn.Format(time.RFC3339)
+		invites = append(invites, invite)
+
+		inviteMails = append(inviteMails, &clients.MailMessage{
+			To:      email,
+			Subject: "You're invited to join " + groupName,
+			Text: "You've been invited to join " + groupName + ".\n\n" +
+				"Use the link below to create your account. It can be used once and expires on " + expiresOn.Format("January 2, 2006") + ".\n\n" +
+				util.E_APP_HOST_URL + "/app/join?invite=" + url.QueryEscape(token) + "\n",
+		})
+	}
+
+	// Mail is only sent once the invites are committed, and in the background so a slow mail server
+	// doesn't hold up the response. An invite whose mail fails can be sent again by re-inviting the
+	// same email.
+	sendInviteMails := func(committed bool) {
+		if !committed {
+			return
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(info.Ctx), inviteMailTimeout)
+			defer cancel()
+
+			for _, inviteMail := range inviteMails {
+				err := h.Mail.Send(ctx, inviteMail)
+				if err != nil {
+					util.ErrorLog.Println(util.ErrCheck(fmt.Errorf("group invite mail to %s: %w", inviteMail.To, err)))
+				}
+			}
+		}()
+	}
+
+	if info.TxHooks != nil {
+		info.TxHooks.OnDone(sendInviteMails)
+	} else {
+		sendInviteMails(true)
+	}
+
+	return &types.InviteGroupUsersResponse{Invites: invites}, nil
+}
+
+func (h *Handlers) GetGroupInvites(info ReqInfo, data *types.GetGroupInvitesRequest) (*types.GetGroupInvitesResponse, error) {
+	invites := util.BatchQuery[types.IGroupInvite](info.Batch, `
+		SELECT gi.id, gi.email, gr.role_id as "roleId", r.name as "roleName", gi.expires_on as "expiresOn",
+			gi.accepted_on as "acceptedOn", gi.revoked_on as "revokedOn", gi.created_on as "createdOn"
+		FROM dbtable_schema.group_invites gi
+		JOIN dbtable_schema.group_roles gr ON gr.id = gi.group_role_id
+		JOIN dbtable_schema.roles r ON r.id = gr.role_id
+		WHERE gi.group_id = $1 AND gi.enabled = true
+		ORDER BY gi.created_on DESC
+	`, info.Session.GetGroupId())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.GetGroupInvitesResponse{Invites: *invites}, nil
+}
+
+func (h *Handlers) RevokeGroupInvite(info ReqInfo, data *types.RevokeGroupInviteRequest) (*types.RevokeGroupInviteResponse, error) {
+	revoked := util.BatchExec(info.Batch, `
+		UPDATE dbtable_schema.group_invites
+		SET revoked_on = $3, updated_on = $3, updated_sub = $4
+		WHERE id = $1 AND group_id = $2 AND accepted_on IS NULL AND revoked_on IS NULL
+	`, data.GetId(), info.Session.GetGroupId(), time.Now().UTC(), info.Session.GetUserSub())
+
+	info.Batch.Send(info.Ctx)
+
+	if revoked.RowsAffected() == 0 {
+		return nil, util.ErrCheck(util.UserError("Invite was already used or revoked."))
+	}
+
+	return &types.RevokeGroupInviteResponse{Success: true}, nil
+}
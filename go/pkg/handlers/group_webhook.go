@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+	"github.com/lib/pq"
+)
+
+const (
+	GROUP_WEBHOOK_QUOTE_CREATED     = "quote.created"
+	GROUP_WEBHOOK_BOOKING_CREATED   = "booking.created"
+	GROUP_WEBHOOK_BOOKING_CANCELLED = "booking.cancelled"
+	GROUP_WEBHOOK_GROUP_USER_JOINED = "group_user.joined"
+	GROUP_WEBHOOK_FORM_SUBMITTED    = "form.submitted"
+
+	groupWebhookLimit           = 10
+	groupWebhookLogLimit        = 100
+	groupWebhookBatchSize       = 20
+	groupWebhookMaxAttempts     = 8
+	groupWebhookBackoff         = 30 * time.Second
+	groupWebhookMaxBackoff      = 6 * time.Hour
+	groupWebhookClaimLease      = 5 * time.Minute // longer than posting a whole batch takes
+	groupWebhookEventHeader     = "X-Awayto-Event"
+	groupWebhookDeliveryHeader  = "X-Awayto-Delivery"
+	groupWebhookDisabledMessage = "webhook is disabled"
+)
+
+var groupWebhookEvents = []string{
+	GROUP_WEBHOOK_QUOTE_CREATED,
+	GROUP_WEBHOOK_BOOKING_CREATED,
+	GROUP_WEBHOOK_BOOKING_CANCELLED,
+	GROUP_WEBHOOK_GROUP_USER_JOINED,
+	GROUP_WEBHOOK_FORM_SUBMITTED,
+}
+
+// Event payloads, sent as the data of each delivery
+
+type groupWebhookQuote struct {
+	QuoteId               string `json:"quoteId"`
+	SeriesId              string `json:"seriesId,omitempty"`
+	SlotDate              string `json:"slotDate"`
+	ScheduleBracketSlotId string `json:"scheduleBracketSlotId"`
+	ServiceTierId         string `json:"serviceTierId"`
+	UserSub               string `json:"userSub"`
+}
+
+type groupWebhookBooking struct {
+	BookingId             string `json:"bookingId"`
+	QuoteId               string `json:"quoteId,omitempty"`
+	SlotDate              string `json:"slotDate,omitempty"`
+	ScheduleBracketSlotId string `json:"scheduleBracketSlotId,omitempty"`
+	UserSub               string `json:"userSub"`
+}
+
+type groupWebhookGroupUser struct {
+	UserSub string `json:"userSub"`
+}
+
+type groupWebhookFormSubmission struct {
+	FormVersionSubmissionId string          `json:"formVersionSubmissionId"`
+	FormId                  string          `json:"formId"`
+	FormVersionId           string          `json:"formVersionId"`
+	QuoteIds                []string        `json:"quoteIds"`
+	Submission              json.RawMessage `json:"submission"`
+	UserSub                 string          `json:"userSub"`
+}
+
+// Webhooks are sent to https urls only, and each event type is subscribed to once
+func validateGroupWebhook(rawUrl string, events []string) ([]string, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+		return nil, util.ErrCheck(util.UserError("Webhook urls must be https addresses."))
+	}
+
+	// Hostnames are checked against the address dialed at delivery, but an address given directly can be
+	// turned away now
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil && !clients.IsPublicWebhookAddr(addr) {
+		return nil, util.ErrCheck(util.UserError("Webhook urls must be public addresses."))
+	}
+
+	if len(events) == 0 {
+		return nil, util.ErrCheck(util.UserError("Select at least one event."))
+	}
+
+	subscribed := make([]string, 0, len(events))
+	for _, event := range events {
+		if !slices.Contains(groupWebhookEvents, event) {
+			return nil, util.ErrCheck(util.UserError("Unknown webhook event " + event + "."))
+		}
+		subscribed = append(subscribed, event)
+	}
+	slices.Sort(subscribed)
+
+	return slices.Compact(subscribed), nil
+}
+
+// Retries wait twice as long each time, up to groupWebhookMaxBackoff
+func groupWebhookRetryDelay(attempts int) time.Duration {
+	delay := groupWebhookBackoff
+	for i := 1; i < attempts && delay < groupWebhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, groupWebhookMaxBackoff)
+}
+
+// queueGroupWebhookEvent records an event for each of the group's webhooks subscribed to it. The
+// deliveries are part of the request, so they are only sent if the request commits.
+func queueGroupWebhookEvent(info ReqInfo, groupId, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return requestExec(info, `
+		SELECT dbfunc_schema.queue_group_webhook_event($1::uuid, $2, $3::jsonb)
+	`, groupId, event, payload)
+}
+
+func (h *Handlers) PostGroupWebhook(info ReqInfo, data *types.PostGroupWebhookRequest) (*types.PostGroupWebhookResponse, error) {
+	events, err := validateGroupWebhook(data.GetUrl(), data.GetEvents())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	secret := util.GenerateWebhookSecret()
+
+	webhooks := util.BatchQuery[types.IGroupWebhook](info.Batch, `
+		INSERT INTO dbtable_schema.group_webhooks (group_id, url, secret, events, created_sub)
+		SELECT $1::uuid, $2, $3, $4::TEXT[], $5::uuid
+		WHERE (SELECT COUNT(*) FROM dbtable_schema.group_webhooks WHERE group_id = $1::uuid) < $6
+		RETURNING id
+	`, info.Session.GetGroupId(), data.GetUrl(), secret, pq.Array(events), info.Session.GetUserSub(), groupWebhookLimit)
+
+	info.Batch.Send(info.Ctx)
+
+	if len(*webhooks) == 0 {
+		return nil, util.ErrCheck(util.UserError(fmt.Sprintf("Groups can have up to %d webhooks.", groupWebhookLimit)))
+	}
+
+	return &types.PostGroupWebhookResponse{Id: (*webhooks)[0].GetId(), Secret: secret}, nil
+}
+
+func (h *Handlers) PatchGroupWebhook(info ReqInfo, data *types.PatchGroupWebhookRequest) (*types.PatchGroupWebhookResponse, error) {
+	events, err := validateGroupWebhook(data.GetUrl(), data.GetEvents())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	patched := util.BatchExec(info.Batch, `
+		UPDATE dbtable_schema.group_webhooks
+		SET url = $3, events = $4::TEXT[], enabled = $5, updated_sub = $6, updated_on = $7
+		WHERE id = $1 AND group_id = $2
+	`, data.GetId(), info.Session.GetGroupId(), data.GetUrl(), pq.Array(events), data.GetEnabled(), info.Session.GetUserSub(), time.Now())
+
+	info.Batch.Send(info.Ctx)
+
+	if (*patched).RowsAffected() == 0 {
+		return nil, util.ErrCheck(util.UserError("Webhook not found."))
+	}
+
+	return &types.PatchGroupWebhookResponse{Success: true}, nil
+}
+
+func (h *Handlers) GetGroupWebhooks(info ReqInfo, data *types.GetGroupWebhooksRequest) (*types.GetGroupWebhooksResponse, error) {
+	webhooks := util.BatchQuery[types.IGroupWebhook](info.Batch, `
+		SELECT id, url, events, enabled, created_on::TEXT as "createdOn"
+		FROM dbtable_schema.group_webhooks
+		WHERE group_id = $1
+		ORDER BY created_on
+	`, info.Session.GetGroupId())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.GetGroupWebhooksResponse{Webhooks: *webhooks}, nil
+}
+
+func (h *Handlers) DeleteGroupWebhook(info ReqInfo, data *types.DeleteGroupWebhookRequest) (*types.DeleteGroupWebhookResponse, error) {
+	deleted := util.BatchExec(info.Batch, `
+		DELETE FROM dbtable_schema.group_webhooks
+		WHERE id = $1 AND group_id = $2
+	`, data.GetId(), info.Session.GetGroupId())
+
+	info.Batch.Send(info.Ctx)
+
+	if (*deleted).RowsAffected() == 0 {
+		return nil, util.ErrCheck(util.UserError("Webhook not found."))
+	}
+
+	return &types.DeleteGroupWebhookResponse{Success: true}, nil
+}
+
+const groupWebhookDeliveryColumns = `
+	d.id,
+	d.group_webhook_id as "groupWebhookId",
+	gw.url,
+	d.event_id as "eventId",
+	d.event,
+	CASE
+		WHEN d.delivered_on IS NOT NULL THEN 'delivered'
+		WHEN d.failed_on IS NOT NULL THEN 'failed'
+		ELSE 'pending'
+	END as status,
+	d.attempts,
+	COALESCE(d.response_status, 0) as "responseStatus",
+	COALESCE(d.last_error, '') as "lastError",
+	COALESCE(d.replay_of::TEXT, '') as "replayOf",
+	d.created_on::TEXT as "createdOn",
+	COALESCE(d.delivered_on::TEXT, '') as "deliveredOn"
+`
+
+func (h *Handlers) GetGroupWebhookDeliveries(info ReqInfo, data *types.GetGroupWebhookDeliveriesRequest) (*types.GetGroupWebhookDeliveriesResponse, error) {
+	deliveries := util.BatchQuery[types.IGroupWebhookDelivery](info.Batch, `
+		SELECT `+groupWebhookDeliveryColumns+`
+		FROM dbtable_schema.group_webhook_deliveries d
+		JOIN dbtable_schema.group_webhooks gw ON gw.id = d.group_webhook_id
+		WHERE d.group_id = $1 AND ($2 = '' OR d.group_webhook_id::TEXT = $2)
+		ORDER BY d.created_on DESC
+		LIMIT $3
+	`, info.Session.GetGroupId(), data.GetGroupWebhookId(), groupWebhookLogLimit)
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.GetGroupWebhookDeliveriesResponse{Deliveries: *deliveries}, nil
+}
+
+// Replays are new deliveries of the same event, the failed delivery stays in the log as it was
+func (h *Handlers) PostGroupWebhookDeliveryReplay(info ReqInfo, data *types.PostGroupWebhookDeliveryReplayRequest) (*types.PostGroupWebhookDeliveryReplayResponse, error) {
+	deliveries := util.BatchQuery[types.IGroupWebhookDelivery](info.Batch, `
+		WITH d AS (
+			INSERT INTO dbtable_schema.group_webhook_deliveries (group_webhook_id, group_id, event_id, event, payload, replay_of, created_sub)
+			SELECT failed.group_webhook_id, failed.group_id, failed.event_id, failed.event, failed.payload, failed.id, $3::uuid
+			FROM dbtable_schema.group_webhook_deliveries failed
+			JOIN dbtable_schema.group_webhooks gw ON gw.id = failed.group_webhook_id
+			WHERE failed.id = $1 AND failed.group_id = $2 AND failed.failed_on IS NOT NULL AND gw.enabled = true
+			RETURNING *
+		)
+		SELECT `+groupWebhookDeliveryColumns+`
+		FROM d
+		JOIN dbtable_schema.group_webhooks gw ON gw.id = d.group_webhook_id
+	`, data.GetId(), info.Session.GetGroupId(), info.Session.GetUserSub())
+
+	info.Batch.Send(info.Ctx)
+
+	if len(*deliveries) == 0 {
+		return nil, util.ErrCheck(util.UserError("Only failed deliveries of enabled webhooks can be replayed."))
+	}
+
+	return &types.PostGroupWebhookDeliveryReplayResponse{Delivery: (*deliveries)[0]}, nil
+}
+
+// The body posted to webhooks, the id is the event's and is the same for replays
+type groupWebhookEnvelope struct {
+	Id        string          `json:"id"`
+	Event     string          `json:"event"`
+	GroupId   string          `json:"groupId"`
+	CreatedOn time.Time       `json:"createdOn"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type groupWebhookDelivery struct {
+	id, url, secret string
+	enabled         bool
+	attempts        int
+	envelope        groupWebhookEnvelope
+}
+
+// ProcessGroupWebhookDeliveries sends due deliveries, oldest first. A batch is claimed by moving its next
+// attempt groupWebhookClaimLease ahead before anything is sent, so no transaction is held open while posting,
+// and each result is recorded as soon as it's known. Failed deliveries are retried with groupWebhookRetryDelay
+// until groupWebhookMaxAttempts, after which they are failed and can be replayed by group admins. It returns
+// how many deliveries were sent or failed for good.
+func (h *Handlers) ProcessGroupWebhookDeliveries(ctx context.Context) (int, error) {
+	db := h.workerDb()
+
+	rows, done, err := db.SessionBatchQuery(ctx, `
+		UPDATE dbtable_schema.group_webhook_deliveries d
+		SET next_attempt_on = TIMEZONE('utc', NOW()) + make_interval(secs => $2)
+		FROM dbtable_schema.group_webhooks gw
+		WHERE gw.id = d.group_webhook_id AND d.id IN (
+			SELECT id
+			FROM dbtable_schema.group_webhook_deliveries
+			WHERE delivered_on IS NULL AND failed_on IS NULL
+				AND next_attempt_on <= TIMEZONE('utc', NOW())
+			ORDER BY next_attempt_on
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, gw.url, gw.secret, gw.enabled, d.attempts, d.event_id, d.event, d.group_id, d.created_on, d.payload
+	`, groupWebhookBatchSize, groupWebhookClaimLease.Seconds())
+	if err != nil {
+		return 0, util.ErrCheck(err)
+	}
+
+	var pending []groupWebhookDelivery
+	for rows.Next() {
+		var d groupWebhookDelivery
+		err = rows.Scan(&d.id, &d.url, &d.secret, &d.enabled, &d.attempts, &d.envelope.Id, &d.envelope.Event, &d.envelope.GroupId, &d.envelope.CreatedOn, &d.envelope.Data)
+		if err != nil {
+			done()
+			return 0, util.ErrCheck(err)
+		}
+		pending = append(pending, d)
+	}
+	done()
+
+	finished := 0
+	for _, d := range pending {
+		if !d.enabled {
+			_, err = db.SessionBatchExec(ctx, `
+				UPDATE dbtable_schema.group_webhook_deliveries
+				SET failed_on = TIMEZONE('utc', NOW()), last_error = $2, updated_on = TIMEZONE('utc', NOW())
+				WHERE id = $1
+			`, d.id, groupWebhookDisabledMessage)
+			if err != nil {
+				return finished, util.ErrCheck(err)
+			}
+			finished++
+			continue
+		}
+
+		body, err := json.Marshal(d.envelope)
+		if err != nil {
+			return finished, util.ErrCheck(err)
+		}
+
+		header := http.Header{}
+		header.Set(groupWebhookEventHeader, d.envelope.Event)
+		header.Set(groupWebhookDeliveryHeader, d.id)
+
+		status, sendErr := h.Webhooks.Post(ctx, d.url, []byte(d.secret), header, body)
+
+		attempts := d.attempts + 1
+		if sendErr != nil {
+			util.ErrorLog.Println(util.ErrCheck(fmt.Errorf("group webhook delivery %s of %s: %w", d.id, d.envelope.Event, sendErr)))
+
+			gaveUp := attempts >= groupWebhookMaxAttempts
+			_, err = db.SessionBatchExec(ctx, `
+				UPDATE dbtable_schema.group_webhook_deliveries
+				SET attempts = $2, response_status = NULLIF($3, 0), last_error = $4,
+					next_attempt_on = TIMEZONE('utc', NOW()) + make_interval(secs => $5),
+					failed_on = CASE WHEN $6 THEN TIMEZONE('utc', NOW()) END,
+					updated_on = TIMEZONE('utc', NOW())
+				WHERE id = $1
+			`, d.id, attempts, status, sendErr.Error(), groupWebhookRetryDelay(attempts).Seconds(), gaveUp)
+			if err != nil {
+				return finished, util.ErrCheck(err)
+			}
+			if gaveUp {
+				finished++
+			}
+			continue
+		}
+
+		_, err = db.SessionBatchExec(ctx, `
+			UPDATE dbtable_schema.group_webhook_deliveries
+			SET attempts = $2, response_status = $3, last_error = NULL,
+				delivered_on = TIMEZONE('utc', NOW()), updated_on = TIMEZONE('utc', NOW())
+			WHERE id = $1
+		`, d.id, attempts, status)
+		if err != nil {
+			return finished, util.ErrCheck(err)
+		}
+		finished++
+	}
+
+	return finished, nil
+}
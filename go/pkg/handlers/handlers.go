@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"slices"
 
 	"github.com/keybittech/awayto-v3/go/pkg/clients"
 	"github.com/keybittech/awayto-v3/go/pkg/types"
@@ -17,6 +18,37 @@ type ReqInfo struct {
 	Session *types.ConcurrentUserSession
 	Tx      *clients.PoolTx
 	Batch   *util.Batchable
+	TxHooks *TxHooks
+}
+
+// TxHooks are run by the executor once the request transaction has committed or rolled back, so
+// changes made outside of the db can follow its outcome
+type TxHooks struct {
+	hooks []func(committed bool)
+}
+
+// OnDone registers a hook to run when the transaction is done, hooks run newest first
+func (t *TxHooks) OnDone(hook func(committed bool)) {
+	t.hooks = append(t.hooks, hook)
+}
+
+func (t *TxHooks) Run(committed bool) {
+	hooks := t.hooks
+	t.hooks = nil
+	for _, hook := range slices.Backward(hooks) {
+		hook(committed)
+	}
+}
+
+// requestExec runs with the request transaction when the handler has one, otherwise it is queued
+// with the request batch and runs when the handler sends it
+func requestExec(info ReqInfo, query string, args ...any) error {
+	if info.Tx != nil {
+		_, err := info.Tx.Exec(info.Ctx, query, args...)
+		return util.ErrCheck(err)
+	}
+	util.BatchExec(info.Batch, query, args...)
+	return nil
 }
 
 type TypedProtoHandler[ReqMsg, ResMsg proto.Message] func(info ReqInfo, message ReqMsg) (ResMsg, error)
@@ -24,27 +56,47 @@ type TypedProtoHandler[ReqMsg, ResMsg proto.Message] func(info ReqInfo, message
 type ProtoHandler func(info ReqInfo, message proto.Message) (proto.Message, error)
 
 type Handlers struct {
-	Functions map[string]ProtoHandler
-	Options   map[string]*util.HandlerOptions
-	LLM       *clients.LLM
-	Database  *clients.Database
-	Redis     *clients.Redis
-	Keycloak  *clients.Keycloak
-	Socket    *clients.Socket
-	Cache     *util.Cache
+	Functions     map[string]ProtoHandler
+	Options       map[string]*util.HandlerOptions
+	LLM           *clients.LLM
+	Database      *clients.Database
+	Redis         *clients.Redis
+	Identity      clients.IdentityProvider
+	Socket        *clients.Socket
+	Mail          *clients.Mail
+	Notifications *clients.Notifications
+	Webhooks      *clients.Webhooks
+	Payments      *clients.Payments
+	Cache         *util.Cache
 }
 
-func NewHandlers() *Handlers {
+// NewHandlers connects the clients handlers use. The identity provider is given by the caller, so the
+// api can use keycloak while tests use a MemoryIdentity.
+func NewHandlers(identity clients.IdentityProvider) *Handlers {
+	redis := clients.InitRedis()
+	mail := clients.InitMail()
 	h := &Handlers{
-		Functions: make(map[string]ProtoHandler),
-		LLM:       clients.InitLLM(),
-		Database:  clients.InitDatabase(),
-		Redis:     clients.InitRedis(),
-		Keycloak:  clients.InitKeycloak(),
-		Socket:    clients.InitSocket(),
-		Cache:     util.NewCache(),
-		Options:   util.GenerateOptions(),
+		Functions:     make(map[string]ProtoHandler),
+		LLM:           clients.InitLLM(),
+		Database:      clients.InitDatabase(),
+		Redis:         redis,
+		Identity:      identity,
+		Socket:        clients.InitSocket(redis),
+		Mail:          mail,
+		Notifications: clients.InitNotifications(mail),
+		Webhooks:      clients.InitWebhooks(),
+		Payments:      clients.InitPayments(),
+		Cache:         util.NewCache(),
+		Options:       util.GenerateOptions(),
 	}
 	registerHandlers(h)
 	return h
 }
+
+// workerDb runs statements as the worker, each committing on its own
+func (h *Handlers) workerDb() clients.DbSession {
+	return clients.DbSession{
+		Pool:                  h.Database.DatabaseClient.Pool,
+		ConcurrentUserSession: workerSession,
+	}
+}
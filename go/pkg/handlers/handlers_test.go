@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/bufbuild/protovalidate-go"
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
 	"github.com/keybittech/awayto-v3/go/pkg/testutil"
 	"github.com/keybittech/awayto-v3/go/pkg/types"
 	"github.com/keybittech/awayto-v3/go/pkg/util"
@@ -22,7 +23,7 @@ func TestMain(m *testing.M) {
 }
 
 func setupTestEnv(useTx bool) (*Handlers, ReqInfo, func(), error) {
-	h := NewHandlers()
+	h := NewHandlers(clients.NewMemoryIdentity(nil))
 
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/test", nil)
@@ -80,7 +81,8 @@ func TestNewHandlers(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := NewHandlers()
+			identity := clients.NewMemoryIdentity(nil)
+			got := NewHandlers(identity)
 			if got == nil {
 				t.Error("NewHandlers() returned nil")
 			}
@@ -96,8 +98,8 @@ func TestNewHandlers(t *testing.T) {
 			if got.Redis == nil {
 				t.Error("Redis client was not initialized")
 			}
-			if got.Keycloak == nil {
-				t.Error("Keycloak client was not initialized")
+			if got.Identity != identity {
+				t.Error("Identity provider was not the one given")
 			}
 			if got.Socket == nil {
 				t.Error("Socket client was not initialized")
@@ -107,7 +109,7 @@ func TestNewHandlers(t *testing.T) {
 }
 
 func FuzzPostSchedule(f *testing.F) {
-	handlers := NewHandlers()
+	handlers := NewHandlers(clients.NewMemoryIdentity(nil))
 	testUser := testutil.IntegrationTest.TestUsers[0]
 	session, err := testUser.GetUserSession(handlers.Database.DatabaseClient.Pool)
 	if err != nil {
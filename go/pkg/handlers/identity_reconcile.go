@@ -0,0 +1,427 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const (
+	identityOutboxBatchSize   = 50
+	identityOutboxMaxAttempts = 10
+	identityOutboxBackoff     = 30 * time.Second
+	// long enough for a batch of identity provider calls, after which another worker may take the rows
+	identityOutboxClaimLease = 5 * time.Minute
+)
+
+var workerSession = types.NewConcurrentUserSession(&types.UserSession{UserSub: "worker"})
+
+// Deleting or detaching something which is already gone has nothing left to do
+func identityResourceGone(err error) bool {
+	return errors.Is(err, clients.ErrIdentityNotFound)
+}
+
+type identityOutboxRow struct {
+	id, operation string
+	action        IdentityAction
+	createdOn     time.Time
+}
+
+// ProcessIdentityOutbox applies queued identity actions, oldest first. Actions are claimed for
+// identityOutboxClaimLease and applied outside of any transaction. Failed actions stay queued
+// with their error and are retried on later passes, at most every identityOutboxBackoff and up to
+// identityOutboxMaxAttempts. It returns how many actions were applied.
+func (h *Handlers) ProcessIdentityOutbox(ctx context.Context) (int, error) {
+	db := h.workerDb()
+
+	rows, done, err := db.SessionBatchQuery(ctx, `
+		UPDATE dbtable_schema.identity_outbox
+		SET claimed_until = TIMEZONE('utc', NOW()) + make_interval(secs => $4)
+		WHERE id IN (
+			SELECT id
+			FROM dbtable_schema.identity_outbox
+			WHERE completed_on IS NULL AND attempts < $1
+				AND (updated_on IS NULL OR updated_on < TIMEZONE('utc', NOW()) - make_interval(secs => $3))
+				AND (claimed_until IS NULL OR claimed_until < TIMEZONE('utc', NOW()))
+			ORDER BY created_on
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, operation, action, created_on
+	`, identityOutboxMaxAttempts, identityOutboxBatchSize, identityOutboxBackoff.Seconds(), identityOutboxClaimLease.Seconds())
+	if err != nil {
+		return 0, util.ErrCheck(err)
+	}
+
+	var claimed []identityOutboxRow
+	for rows.Next() {
+		var row identityOutboxRow
+		var actionBytes []byte
+		err = rows.Scan(&row.id, &row.operation, &actionBytes, &row.createdOn)
+		if err == nil {
+			err = json.Unmarshal(actionBytes, &row.action)
+		}
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(err))
+			continue
+		}
+		claimed = append(claimed, row)
+	}
+	done()
+
+	// RETURNING doesn't keep the order of the subquery
+	slices.SortStableFunc(claimed, func(a, b identityOutboxRow) int {
+		return a.createdOn.Compare(b.createdOn)
+	})
+
+	applied := 0
+	for _, row := range claimed {
+		applyErr := h.applyOutboxAction(ctx, db, row.action)
+		if applyErr != nil && !identityResourceGone(applyErr) {
+			util.ErrorLog.Println(util.ErrCheck(fmt.Errorf("%s outbox %s on %s: %w", row.operation, row.action.Action, row.action.GroupId, applyErr)))
+
+			_, err = db.SessionBatchExec(ctx, `
+				UPDATE dbtable_schema.identity_outbox
+				SET attempts = attempts + 1, last_error = $2, claimed_until = NULL, updated_on = TIMEZONE('utc', NOW())
+				WHERE id = $1
+			`, row.id, applyErr.Error())
+			if err != nil {
+				return applied, util.ErrCheck(err)
+			}
+			continue
+		}
+
+		_, err = db.SessionBatchExec(ctx, `
+			UPDATE dbtable_schema.identity_outbox
+			SET completed_on = TIMEZONE('utc', NOW()), claimed_until = NULL, updated_on = TIMEZONE('utc', NOW())
+			WHERE id = $1
+		`, row.id)
+		if err != nil {
+			return applied, util.ErrCheck(err)
+		}
+		applied++
+	}
+
+	return applied, nil
+}
+
+func (h *Handlers) applyOutboxAction(ctx context.Context, db clients.DbSession, action IdentityAction) error {
+	// A later request may have taken the group back into use, as role subgroups are found by name
+	if action.Action == IDENTITY_DELETE_GROUP {
+		row, done, err := db.SessionBatchQueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM dbtable_schema.group_roles WHERE external_id = $1)
+				OR EXISTS (SELECT 1 FROM dbtable_schema.groups WHERE external_id::TEXT = $1)
+		`, action.GroupId)
+		if err != nil {
+			return util.ErrCheck(err)
+		}
+
+		var referenced bool
+		err = row.Scan(&referenced)
+		done()
+		if err != nil {
+			return util.ErrCheck(err)
+		}
+		if referenced {
+			return nil
+		}
+	}
+
+	return h.ApplyIdentityAction(ctx, "worker", action)
+}
+
+type groupRoleRef struct {
+	Id, ExternalId, Name string
+}
+
+// identityDrift compares the role subgroups of one group in the identity provider with its group_roles
+type identityDrift struct {
+	// group role ids whose external id is stale, but a subgroup with the role name exists
+	Relink map[string]*types.KeycloakGroup
+	// group roles with no subgroup at all
+	Missing []groupRoleRef
+	// subgroups which no group role refers to
+	Orphans []*types.KeycloakGroup
+}
+
+func findIdentityDrift(groupRoles []groupRoleRef, subGroups []*types.KeycloakGroup) identityDrift {
+	drift := identityDrift{Relink: make(map[string]*types.KeycloakGroup)}
+
+	byId := make(map[string]*types.KeycloakGroup, len(subGroups))
+	byName := make(map[string]*types.KeycloakGroup, len(subGroups))
+	for _, sg := range subGroups {
+		byId[sg.GetId()] = sg
+		byName[sg.GetName()] = sg
+	}
+
+	used := make(map[string]bool, len(groupRoles))
+	for _, gr := range groupRoles {
+		if _, ok := byId[gr.ExternalId]; ok {
+			used[gr.ExternalId] = true
+		}
+	}
+
+	for _, gr := range groupRoles {
+		if used[gr.ExternalId] {
+			continue
+		}
+		if sg, ok := byName[gr.Name]; ok && !used[sg.GetId()] {
+			drift.Relink[gr.Id] = sg
+			used[sg.GetId()] = true
+			continue
+		}
+		drift.Missing = append(drift.Missing, gr)
+	}
+
+	for _, sg := range subGroups {
+		if !used[sg.GetId()] {
+			drift.Orphans = append(drift.Orphans, sg)
+		}
+	}
+
+	return drift
+}
+
+// IdentityReconciler repairs drift between group_roles.external_id and the role subgroups in the
+// identity provider, left behind by failed commits or changes made outside of the app.
+type IdentityReconciler struct {
+	h *Handlers
+	// orphans are only deleted when seen by two passes in a row, so the subgroups
+	// of requests which have not committed yet are left alone
+	orphans map[string]bool
+}
+
+func (h *Handlers) NewIdentityReconciler() *IdentityReconciler {
+	return &IdentityReconciler{
+		h:       h,
+		orphans: make(map[string]bool),
+	}
+}
+
+func (r *IdentityReconciler) ReconcileGroupRoles(ctx context.Context) error {
+	batch := util.NewBatchable(r.h.Database.DatabaseClient.Pool, "worker", "", 0)
+	groupsReq := util.BatchQuery[types.IGroup](batch, `
+		SELECT id, name, sub, external_id as "externalId"
+		FROM dbtable_schema.groups
+	`)
+	batch.Send(ctx)
+
+	seen := make(map[string]bool)
+
+	for _, group := range *groupsReq {
+		err := r.reconcileGroup(ctx, group, seen)
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(fmt.Errorf("could not reconcile group %s: %w", group.GetId(), err)))
+		}
+	}
+
+	r.orphans = seen
+
+	return nil
+}
+
+// reconcileGroup calls the identity provider outside of any transaction, each group role
+// being relinked in a short transaction of its own once its subgroup is ready
+func (r *IdentityReconciler) reconcileGroup(ctx context.Context, group *types.IGroup, seen map[string]bool) error {
+	h := r.h
+
+	subGroups, err := h.Identity.GetGroupSubGroups(ctx, "worker", group.GetExternalId())
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	groupDb := clients.NewGroupDbSession(h.Database.DatabaseClient.Pool, types.NewConcurrentUserSession(&types.UserSession{
+		GroupSub: group.GetSub(),
+		GroupId:  group.GetId(),
+	}))
+
+	rows, done, err := groupDb.SessionBatchQuery(ctx, `
+		SELECT gr.id, gr.external_id, r.name
+		FROM dbtable_schema.group_roles gr
+		JOIN dbtable_schema.roles r ON r.id = gr.role_id
+		WHERE gr.group_id = $1
+	`, group.GetId())
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	var groupRoles []groupRoleRef
+	for rows.Next() {
+		var gr groupRoleRef
+		if err := rows.Scan(&gr.Id, &gr.ExternalId, &gr.Name); err != nil {
+			done()
+			return util.ErrCheck(err)
+		}
+		groupRoles = append(groupRoles, gr)
+	}
+	done()
+
+	drift := findIdentityDrift(groupRoles, subGroups)
+
+	for _, gr := range groupRoles {
+		subGroup, ok := drift.Relink[gr.Id]
+		if !ok {
+			continue
+		}
+
+		util.ErrorLog.Printf("relinking group role %s of group %s from %s to subgroup %s", gr.Id, group.GetId(), gr.ExternalId, subGroup.GetId())
+
+		err = r.relinkGroupRole(ctx, groupDb, group, gr, subGroup.GetId())
+		if err != nil {
+			return util.ErrCheck(err)
+		}
+	}
+
+	for _, gr := range drift.Missing {
+		util.ErrorLog.Printf("recreating missing subgroup %s for group role %s of group %s", gr.Name, gr.Id, group.GetId())
+
+		subGroup, err := h.Identity.CreateOrGetSubGroup(ctx, "worker", group.GetExternalId(), gr.Name)
+		if err != nil {
+			return util.ErrCheck(err)
+		}
+
+		// Admin mappings are known, other role mappings are restored by group admins through assignments
+		if gr.Name == "Admin" {
+			roles, err := h.Identity.GetGroupAdminRoles(ctx, "worker")
+			if err != nil {
+				return util.ErrCheck(err)
+			}
+
+			err = h.Identity.AddRolesToGroup(ctx, "worker", subGroup.GetId(), roles)
+			if err != nil {
+				return util.ErrCheck(err)
+			}
+		}
+
+		err = r.relinkGroupRole(ctx, groupDb, group, gr, subGroup.GetId())
+		if err != nil {
+			return util.ErrCheck(err)
+		}
+	}
+
+	for _, orphan := range drift.Orphans {
+		if !r.orphans[orphan.GetId()] {
+			seen[orphan.GetId()] = true
+			continue
+		}
+
+		util.ErrorLog.Printf("deleting orphaned subgroup %s of group %s", orphan.GetPath(), group.GetId())
+
+		err = h.Identity.DeleteGroup(ctx, "worker", orphan.GetId())
+		if err != nil && !identityResourceGone(err) {
+			util.ErrorLog.Println(util.ErrCheck(err))
+		}
+	}
+
+	// Cached subgroups hold the external ids which changed
+	if len(drift.Relink) > 0 || len(drift.Missing) > 0 {
+		groupPath := "/" + group.GetName()
+		if cachedGroup, ok := h.Cache.Groups.Get(groupPath); ok {
+			for _, sgPath := range cachedGroup.GetSubGroupPaths() {
+				h.Cache.SubGroups.Delete(sgPath)
+			}
+		}
+		h.Cache.Groups.Delete(groupPath)
+	}
+
+	return nil
+}
+
+// Points the group role and its members at the subgroup, making sure the members belong to it.
+// Members are added to the subgroup before the rows are updated, and anyone who joined the role
+// in between is added after, so no member is left pointing at a subgroup they aren't in.
+func (r *IdentityReconciler) relinkGroupRole(ctx context.Context, groupDb clients.DbSession, group *types.IGroup, gr groupRoleRef, subGroupId string) error {
+	rows, done, err := groupDb.SessionBatchQuery(ctx, `
+		SELECT u.sub
+		FROM dbtable_schema.group_users gu
+		JOIN dbtable_schema.users u ON u.id = gu.user_id
+		WHERE gu.group_id = $1 AND gu.external_id = $2
+	`, group.GetId(), gr.ExternalId)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	memberSubs, err := scanMemberSubs(rows)
+	done()
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	added := make(map[string]bool, len(memberSubs))
+	for _, sub := range memberSubs {
+		err = r.h.Identity.AddUserToGroup(ctx, "worker", sub, subGroupId)
+		if err != nil {
+			return util.ErrCheck(err)
+		}
+		added[sub] = true
+	}
+
+	tx, err := groupDb.SessionOpenTx(ctx)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE dbtable_schema.group_roles
+		SET external_id = $2, updated_sub = $3, updated_on = TIMEZONE('utc', NOW())
+		WHERE id = $1
+	`, gr.Id, subGroupId, group.GetSub())
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	rows, err = tx.Query(ctx, `
+		UPDATE dbtable_schema.group_users gu
+		SET external_id = $3, updated_sub = $4, updated_on = TIMEZONE('utc', NOW())
+		FROM dbtable_schema.users u
+		WHERE u.id = gu.user_id AND gu.group_id = $1 AND gu.external_id = $2
+		RETURNING u.sub
+	`, group.GetId(), gr.ExternalId, subGroupId, group.GetSub())
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	memberSubs, err = scanMemberSubs(rows)
+	rows.Close()
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	err = groupDb.SessionCloseTx(ctx, tx)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	for _, sub := range memberSubs {
+		if added[sub] {
+			continue
+		}
+		err = r.h.Identity.AddUserToGroup(ctx, "worker", sub, subGroupId)
+		if err != nil {
+			return util.ErrCheck(err)
+		}
+	}
+
+	return nil
+}
+
+func scanMemberSubs(rows pgx.Rows) ([]string, error) {
+	var memberSubs []string
+	for rows.Next() {
+		var sub string
+		if err := rows.Scan(&sub); err != nil {
+			return nil, util.ErrCheck(err)
+		}
+		memberSubs = append(memberSubs, sub)
+	}
+	return memberSubs, util.ErrCheck(rows.Err())
+}
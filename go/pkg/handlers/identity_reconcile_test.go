@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+func Test_identityResourceGone(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "memory not found", err: fmt.Errorf("group x: %w", clients.ErrIdentityNotFound), want: true},
+		{name: "checked not found", err: util.ErrCheck(util.ErrCheck(fmt.Errorf("group x: %w", clients.ErrIdentityNotFound))), want: true},
+		{name: "untyped not found text", err: errors.New("Not Found"), want: false},
+		{name: "conflict", err: clients.ErrIdentityConflict, want: false},
+		{name: "server error", err: errors.New("Internal Server Error"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := identityResourceGone(tt.err); got != tt.want {
+				t.Errorf("identityResourceGone(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findIdentityDrift(t *testing.T) {
+	admin := &types.KeycloakGroup{Id: "kc-admin", Name: "Admin"}
+	baker := &types.KeycloakGroup{Id: "kc-baker", Name: "Baker"}
+	stray := &types.KeycloakGroup{Id: "kc-stray", Name: "Stray"}
+
+	tests := []struct {
+		name       string
+		groupRoles []groupRoleRef
+		subGroups  []*types.KeycloakGroup
+		want       identityDrift
+	}{
+		{
+			name:       "in sync",
+			groupRoles: []groupRoleRef{{Id: "gr-admin", ExternalId: "kc-admin", Name: "Admin"}, {Id: "gr-baker", ExternalId: "kc-baker", Name: "Baker"}},
+			subGroups:  []*types.KeycloakGroup{admin, baker},
+			want:       identityDrift{Relink: map[string]*types.KeycloakGroup{}},
+		},
+		{
+			name:       "stale external id",
+			groupRoles: []groupRoleRef{{Id: "gr-admin", ExternalId: "kc-admin", Name: "Admin"}, {Id: "gr-baker", ExternalId: "kc-old", Name: "Baker"}},
+			subGroups:  []*types.KeycloakGroup{admin, baker},
+			want:       identityDrift{Relink: map[string]*types.KeycloakGroup{"gr-baker": baker}},
+		},
+		{
+			name:       "missing and orphaned",
+			groupRoles: []groupRoleRef{{Id: "gr-admin", ExternalId: "kc-admin", Name: "Admin"}, {Id: "gr-cook", ExternalId: "kc-cook", Name: "Cook"}},
+			subGroups:  []*types.KeycloakGroup{admin, stray},
+			want: identityDrift{
+				Relink:  map[string]*types.KeycloakGroup{},
+				Missing: []groupRoleRef{{Id: "gr-cook", ExternalId: "kc-cook", Name: "Cook"}},
+				Orphans: []*types.KeycloakGroup{stray},
+			},
+		},
+		{
+			name:       "subgroup already linked by id is not relinked",
+			groupRoles: []groupRoleRef{{Id: "gr-baker", ExternalId: "kc-baker", Name: "Baker"}, {Id: "gr-copy", ExternalId: "kc-gone", Name: "Baker"}},
+			subGroups:  []*types.KeycloakGroup{baker},
+			want: identityDrift{
+				Relink:  map[string]*types.KeycloakGroup{},
+				Missing: []groupRoleRef{{Id: "gr-copy", ExternalId: "kc-gone", Name: "Baker"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findIdentityDrift(tt.groupRoles, tt.subGroups); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findIdentityDrift(%v, %v) = %+v, want %+v", tt.groupRoles, tt.subGroups, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlers_ProcessIdentityOutbox(t *testing.T) {
+	tests := []struct {
+		name    string
+		h       *Handlers
+		ctx     context.Context
+		want    int
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.ProcessIdentityOutbox(tt.ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.ProcessIdentityOutbox() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Handlers.ProcessIdentityOutbox() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentityReconciler_ReconcileGroupRoles(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       *IdentityReconciler
+		ctx     context.Context
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.r.ReconcileGroupRoles(tt.ctx); (err != nil) != tt.wantErr {
+				t.Errorf("IdentityReconciler.ReconcileGroupRoles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const (
+	IDENTITY_DELETE_GROUP  = "delete_group"
+	IDENTITY_ADD_ROLES     = "add_roles"
+	IDENTITY_DELETE_ROLES  = "delete_roles"
+	IDENTITY_ADD_MEMBER    = "add_member"
+	IDENTITY_DELETE_MEMBER = "delete_member"
+)
+
+// IdentityAction is a single identity provider change, stored as json in the identity outbox
+type IdentityAction struct {
+	Action  string                `json:"action"`
+	GroupId string                `json:"groupId"`
+	UserId  string                `json:"userId,omitempty"`
+	Roles   []*types.KeycloakRole `json:"roles,omitempty"`
+}
+
+func (h *Handlers) ApplyIdentityAction(ctx context.Context, userSub string, action IdentityAction) error {
+	var err error
+
+	switch action.Action {
+	case IDENTITY_DELETE_GROUP:
+		err = h.Identity.DeleteGroup(ctx, userSub, action.GroupId)
+	case IDENTITY_ADD_ROLES:
+		err = h.Identity.AddRolesToGroup(ctx, userSub, action.GroupId, action.Roles)
+	case IDENTITY_DELETE_ROLES:
+		err = h.Identity.DeleteRolesFromGroup(ctx, userSub, action.GroupId, action.Roles)
+	case IDENTITY_ADD_MEMBER:
+		err = h.Identity.AddUserToGroup(ctx, userSub, action.UserId, action.GroupId)
+	case IDENTITY_DELETE_MEMBER:
+		err = h.Identity.DeleteUserFromGroup(ctx, userSub, action.UserId, action.GroupId)
+	default:
+		err = fmt.Errorf("unknown identity action %s", action.Action)
+	}
+
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
+// IdentitySaga follows a request that changes both the app db and the identity provider. Each
+// identity change registers the action which undoes it. Unless the handler completes and its
+// transaction commits, the changes are undone newest first, and any undo which fails is queued
+// in the identity outbox to be retried. Changes which can't be undone, like deletions, are queued
+// with AfterCommit so they only happen once the db transaction commits.
+type IdentitySaga struct {
+	h         *Handlers
+	ctx       context.Context
+	operation string
+	userSub   string
+	txHooks   *TxHooks
+	undos     []IdentityAction
+	completed bool
+}
+
+func (h *Handlers) NewIdentitySaga(info ReqInfo, operation string) *IdentitySaga {
+	return &IdentitySaga{
+		h:         h,
+		ctx:       info.Ctx,
+		operation: operation,
+		userSub:   info.Session.GetUserSub(),
+		txHooks:   info.TxHooks,
+	}
+}
+
+// Compensate registers the action which undoes an identity change that just succeeded
+func (s *IdentitySaga) Compensate(action IdentityAction) {
+	s.undos = append(s.undos, action)
+}
+
+// AfterCommit queues an identity change in the request transaction, it is applied once committed
+func (s *IdentitySaga) AfterCommit(tx *clients.PoolTx, action IdentityAction) error {
+	actionBytes, err := json.Marshal(action)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	_, err = tx.Exec(s.ctx, `
+		INSERT INTO dbtable_schema.identity_outbox (operation, action, created_sub)
+		VALUES ($1, $2::jsonb, $3::uuid)
+	`, s.operation, actionBytes, s.userSub)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
+
+// Complete keeps the identity changes once the request transaction commits, it is called when
+// the handler has nothing left that can fail
+func (s *IdentitySaga) Complete() {
+	s.completed = true
+}
+
+// Close undoes the changes of a handler which didn't complete. A completed handler's changes
+// are undone if its transaction then fails to commit.
+func (s *IdentitySaga) Close() {
+	if len(s.undos) == 0 {
+		return
+	}
+
+	if !s.completed {
+		s.undo()
+		return
+	}
+
+	if s.txHooks != nil {
+		s.txHooks.OnDone(func(committed bool) {
+			if !committed {
+				s.undo()
+			}
+		})
+	}
+}
+
+func (s *IdentitySaga) undo() {
+	// The request may have been cancelled, which is often why the saga is being undone
+	ctx := context.WithoutCancel(s.ctx)
+
+	for _, undo := range slices.Backward(s.undos) {
+		err := s.h.ApplyIdentityAction(ctx, s.userSub, undo)
+		if err == nil {
+			continue
+		}
+
+		util.ErrorLog.Println(util.ErrCheck(fmt.Errorf("%s could not undo %s on %s: %w", s.operation, undo.Action, undo.GroupId, err)))
+
+		err = s.h.queueIdentityAction(ctx, s.operation, s.userSub, undo, err)
+		if err != nil {
+			util.ErrorLog.Println(util.ErrCheck(err))
+		}
+	}
+
+	s.undos = nil
+}
+
+// Queues an action outside of any request transaction, which has usually been rolled back by now
+func (h *Handlers) queueIdentityAction(ctx context.Context, operation, userSub string, action IdentityAction, cause error) error {
+	if h.Database == nil {
+		return util.ErrCheck(errors.New("no database to queue identity action"))
+	}
+
+	actionBytes, err := json.Marshal(action)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	ds := clients.DbSession{
+		Pool:                  h.Database.DatabaseClient.Pool,
+		ConcurrentUserSession: types.NewConcurrentUserSession(&types.UserSession{UserSub: "worker"}),
+	}
+
+	_, err = ds.SessionBatchExec(ctx, `
+		INSERT INTO dbtable_schema.identity_outbox (operation, action, attempts, last_error, created_sub)
+		VALUES ($1, $2::jsonb, 1, $3, $4::uuid)
+	`, operation, actionBytes, cause.Error(), userSub)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
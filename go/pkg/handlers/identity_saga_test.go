@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+)
+
+func newSagaTestGroup(t *testing.T) (*Handlers, ReqInfo, string, string) {
+	t.Helper()
+
+	mi := clients.NewMemoryIdentity([]*types.KeycloakRole{{Id: "role-users", Name: "APP_GROUP_USERS"}})
+	h := &Handlers{Identity: mi}
+	info := ReqInfo{
+		Ctx:     context.Background(),
+		Session: types.NewConcurrentUserSession(&types.UserSession{UserSub: "admin"}),
+	}
+
+	group, err := mi.CreateGroup(info.Ctx, "admin", "bakery")
+	if err != nil {
+		t.Fatalf("MemoryIdentity.CreateGroup() error = %v", err)
+	}
+
+	adminGroup, err := mi.CreateOrGetSubGroup(info.Ctx, "admin", group.Id, "Admin")
+	if err != nil {
+		t.Fatalf("MemoryIdentity.CreateOrGetSubGroup() error = %v", err)
+	}
+
+	return h, info, group.Id, adminGroup.Id
+}
+
+func TestIdentitySaga_Close(t *testing.T) {
+	h, info, groupId, adminGroupId := newSagaTestGroup(t)
+	mi := h.Identity.(*clients.MemoryIdentity)
+
+	saga := h.NewIdentitySaga(info, "test")
+
+	roles, _ := mi.GetGroupAdminRoles(info.Ctx, "admin")
+	mi.AddRolesToGroup(info.Ctx, "admin", adminGroupId, roles)
+	saga.Compensate(IdentityAction{Action: IDENTITY_DELETE_ROLES, GroupId: adminGroupId, Roles: roles})
+
+	mi.AddUserToGroup(info.Ctx, "admin", "admin", adminGroupId)
+	saga.Compensate(IdentityAction{Action: IDENTITY_DELETE_MEMBER, GroupId: adminGroupId, UserId: "admin"})
+
+	bakerGroup, _ := mi.CreateOrGetSubGroup(info.Ctx, "admin", groupId, "Baker")
+	saga.Compensate(IdentityAction{Action: IDENTITY_DELETE_GROUP, GroupId: bakerGroup.Id})
+
+	saga.Close()
+
+	if _, err := mi.GetGroup(info.Ctx, "admin", bakerGroup.Id); !errors.Is(err, clients.ErrIdentityNotFound) {
+		t.Errorf("IdentitySaga.Close() left the created subgroup, err = %v", err)
+	}
+
+	if paths := mi.GetUserGroupPaths("admin"); len(paths) != 0 {
+		t.Errorf("IdentitySaga.Close() left the membership, paths = %v", paths)
+	}
+
+	if mappings, _ := mi.GetGroupSiteRoles(info.Ctx, "admin", adminGroupId); len(mappings) != 0 {
+		t.Errorf("IdentitySaga.Close() left the role mappings, %v", mappings)
+	}
+
+	if _, err := mi.GetGroup(info.Ctx, "admin", groupId); err != nil {
+		t.Errorf("IdentitySaga.Close() removed a group it did not create, err = %v", err)
+	}
+}
+
+func TestIdentitySaga_Complete(t *testing.T) {
+	h, info, groupId, _ := newSagaTestGroup(t)
+	mi := h.Identity.(*clients.MemoryIdentity)
+
+	saga := h.NewIdentitySaga(info, "test")
+
+	bakerGroup, _ := mi.CreateOrGetSubGroup(info.Ctx, "admin", groupId, "Baker")
+	saga.Compensate(IdentityAction{Action: IDENTITY_DELETE_GROUP, GroupId: bakerGroup.Id})
+
+	saga.Complete()
+	saga.Close()
+
+	if _, err := mi.GetGroup(info.Ctx, "admin", bakerGroup.Id); err != nil {
+		t.Errorf("IdentitySaga.Close() after Complete() undid the subgroup, err = %v", err)
+	}
+}
+
+func TestIdentitySaga_CompleteTxHooks(t *testing.T) {
+	tests := []struct {
+		name      string
+		committed bool
+		wantKept  bool
+	}{
+		{name: "committed", committed: true, wantKept: true},
+		{name: "commit failed", committed: false, wantKept: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, info, groupId, _ := newSagaTestGroup(t)
+			mi := h.Identity.(*clients.MemoryIdentity)
+			info.TxHooks = &TxHooks{}
+
+			saga := h.NewIdentitySaga(info, "test")
+
+			bakerGroup, _ := mi.CreateOrGetSubGroup(info.Ctx, "admin", groupId, "Baker")
+			saga.Compensate(IdentityAction{Action: IDENTITY_DELETE_GROUP, GroupId: bakerGroup.Id})
+
+			saga.Complete()
+			saga.Close()
+
+			if _, err := mi.GetGroup(info.Ctx, "admin", bakerGroup.Id); err != nil {
+				t.Fatalf("IdentitySaga.Close() undid the subgroup before the transaction was done, err = %v", err)
+			}
+
+			info.TxHooks.Run(tt.committed)
+
+			_, err := mi.GetGroup(info.Ctx, "admin", bakerGroup.Id)
+			if kept := err == nil; kept != tt.wantKept {
+				t.Errorf("TxHooks.Run(%v) kept the subgroup = %v, want %v", tt.committed, kept, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestHandlers_ApplyIdentityAction(t *testing.T) {
+	h, info, groupId, adminGroupId := newSagaTestGroup(t)
+	roles := []*types.KeycloakRole{{Id: "role-users", Name: "APP_GROUP_USERS"}}
+
+	tests := []struct {
+		name    string
+		action  IdentityAction
+		wantErr bool
+	}{
+		{name: "add roles", action: IdentityAction{Action: IDENTITY_ADD_ROLES, GroupId: adminGroupId, Roles: roles}},
+		{name: "delete roles", action: IdentityAction{Action: IDENTITY_DELETE_ROLES, GroupId: adminGroupId, Roles: roles}},
+		{name: "add member", action: IdentityAction{Action: IDENTITY_ADD_MEMBER, GroupId: adminGroupId, UserId: "member"}},
+		{name: "delete member", action: IdentityAction{Action: IDENTITY_DELETE_MEMBER, GroupId: adminGroupId, UserId: "member"}},
+		{name: "delete group", action: IdentityAction{Action: IDENTITY_DELETE_GROUP, GroupId: groupId}},
+		{name: "missing group", action: IdentityAction{Action: IDENTITY_DELETE_GROUP, GroupId: groupId}, wantErr: true},
+		{name: "unknown action", action: IdentityAction{Action: "rename_group", GroupId: groupId}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := h.ApplyIdentityAction(info.Ctx, "admin", tt.action)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.ApplyIdentityAction(%v) error = %v, wantErr %v", tt.action, err, tt.wantErr)
+			}
+		})
+	}
+}
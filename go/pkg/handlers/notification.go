@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+	"github.com/lib/pq"
+)
+
+const (
+	NOTIFY_QUOTE_CREATED    = "quote_created"
+	NOTIFY_QUOTE_APPROVED   = "quote_approved"
+	NOTIFY_QUOTE_CANCELLED  = "quote_cancelled"
+	NOTIFY_BOOKING_REMINDER = "booking_reminder"
+
+	notificationBatchSize           = 50
+	notificationMaxAttempts         = 5
+	notificationBackoff             = 5 * time.Minute
+	notificationClaimLease          = 10 * time.Minute
+	notificationDefaultReminderHour = 24
+	notificationTimeFormat          = "Monday, January 2, 2006 at 3:04 PM"
+)
+
+// Resolves the wall clock start of quote q in its group schedule timezone, see bookingCalendarEventsQuery
+const notificationOccurrenceJoins = `
+	JOIN dbtable_schema.schedule_bracket_slots sbs ON sbs.id = q.schedule_bracket_slot_id
+	JOIN dbtable_schema.schedule_brackets sb ON sb.id = sbs.schedule_bracket_id
+	JOIN dbtable_schema.group_user_schedules gus ON gus.user_schedule_id = sb.schedule_id
+	JOIN dbtable_schema.schedules gs ON gs.id = gus.group_schedule_id
+	JOIN dbtable_schema.time_units gstu ON gstu.id = gs.schedule_time_unit_id
+	CROSS JOIN LATERAL (
+		SELECT dbfunc_schema.slot_occurrence_start(q.slot_date, sbs.start_time, gstu.name, gs.start_date::DATE) as start
+	) occurrence
+`
+
+func notificationReminderHours() int {
+	if util.E_NOTIFY_REMINDER_HOURS > 0 {
+		return util.E_NOTIFY_REMINDER_HOURS
+	}
+	return notificationDefaultReminderHour
+}
+
+// queueQuoteNotifications lets the other side of each quote know about a change made by the requester,
+// the quotes are given directly or through their bookings
+func (h *Handlers) queueQuoteNotifications(info ReqInfo, kind string, quoteIds, bookingIds []string) error {
+	channels := h.Notifications.Channels()
+	if len(channels) == 0 || len(quoteIds)+len(bookingIds) == 0 {
+		return nil
+	}
+
+	return requestExec(info, `
+		INSERT INTO dbtable_schema.notification_jobs (kind, channel, recipient_sub, group_id, quote_id, created_sub)
+		SELECT $1, channel, CASE WHEN q.created_sub = $4 THEN q.slot_created_sub ELSE q.created_sub END, q.group_id, q.id, $4
+		FROM dbtable_schema.quotes q
+		CROSS JOIN UNNEST($5::TEXT[]) channel
+		WHERE q.id = ANY($2::uuid[])
+			OR q.id IN (SELECT b.quote_id FROM dbtable_schema.bookings b WHERE b.id = ANY($3::uuid[]))
+		ON CONFLICT DO NOTHING
+	`, kind, pq.Array(quoteIds), pq.Array(bookingIds), info.Session.GetUserSub(), pq.Array(channels))
+}
+
+// queueBookingNotifications tells clients their quotes were approved, and schedules reminders for both
+// the client and staff ahead of each booking which hasn't started yet
+func (h *Handlers) queueBookingNotifications(info ReqInfo, bookingIds []string) error {
+	channels := h.Notifications.Channels()
+	if len(channels) == 0 || len(bookingIds) == 0 {
+		return nil
+	}
+
+	return requestExec(info, `
+		INSERT INTO dbtable_schema.notification_jobs (kind, channel, recipient_sub, group_id, quote_id, booking_id, run_at, created_sub)
+		SELECT job.kind, channel, job.recipient_sub, q.group_id, q.id, b.id, job.run_at, $2
+		FROM dbtable_schema.bookings b
+		JOIN dbtable_schema.quotes q ON q.id = b.quote_id
+		`+notificationOccurrenceJoins+`
+		CROSS JOIN LATERAL (
+			SELECT (occurrence.start AT TIME ZONE gs.timezone) AT TIME ZONE 'UTC' as start_utc
+		) starts
+		CROSS JOIN LATERAL (VALUES
+			($3::TEXT, q.created_sub, TIMEZONE('utc', NOW())),
+			($4::TEXT, q.created_sub, starts.start_utc - make_interval(hours => $5)),
+			($4::TEXT, q.slot_created_sub, starts.start_utc - make_interval(hours => $5))
+		) job (kind, recipient_sub, run_at)
+		CROSS JOIN UNNEST($6::TEXT[]) channel
+		WHERE b.id = ANY($1::uuid[])
+			AND (job.kind <> $4 OR starts.start_utc > TIMEZONE('utc', NOW()))
+		ON CONFLICT DO NOTHING
+	`, pq.Array(bookingIds), info.Session.GetUserSub(), NOTIFY_QUOTE_APPROVED, NOTIFY_BOOKING_REMINDER, notificationReminderHours(), pq.Array(channels))
+}
+
+type notificationJob struct {
+	id, kind, channel, recipientSub, groupId, email string
+	quoteId, bookingId                              *string
+}
+
+type notificationDetails struct {
+	Service, Schedule, TimeZone string
+	Start                       time.Time
+	Active, Started             bool
+}
+
+func notificationMessage(kind string, details notificationDetails) (string, string, bool) {
+	when := details.Start.Format(notificationTimeFormat)
+	if details.TimeZone != "" {
+		when += " (" + details.TimeZone + ")"
+	}
+
+	var subject, text string
+	switch kind {
+	case NOTIFY_QUOTE_CREATED:
+		subject = "New appointment request"
+		text = fmt.Sprintf("%s was requested for %s.", details.Service, when)
+	case NOTIFY_QUOTE_APPROVED:
+		subject = "Appointment confirmed"
+		text = fmt.Sprintf("Your appointment for %s on %s is confirmed.", details.Service, when)
+	case NOTIFY_QUOTE_CANCELLED:
+		subject = "Appointment cancelled"
+		text = fmt.Sprintf("The appointment for %s on %s was cancelled.", details.Service, when)
+	case NOTIFY_BOOKING_REMINDER:
+		subject = "Appointment reminder"
+		text = fmt.Sprintf("Your appointment for %s is coming up on %s.", details.Service, when)
+	default:
+		return "", "", false
+	}
+
+	if details.Schedule != "" {
+		text += "\n\nSchedule: " + details.Schedule
+	}
+	text += "\n\n" + util.E_APP_HOST_URL
+
+	return subject, text, true
+}
+
+// ProcessNotifications delivers queued notifications which are due, oldest first. A batch is claimed for
+// notificationClaimLease before anything is sent, so no transaction is held open while sending, and each
+// result is recorded as soon as it's known. Failed deliveries stay queued with their error and are retried
+// on later passes, at most every notificationBackoff and up to notificationMaxAttempts. It returns how many
+// jobs were finished, whether or not anything was sent.
+func (h *Handlers) ProcessNotifications(ctx context.Context) (int, error) {
+	db := h.workerDb()
+
+	rows, done, err := db.SessionBatchQuery(ctx, `
+		UPDATE dbtable_schema.notification_jobs nj
+		SET claimed_until = TIMEZONE('utc', NOW()) + make_interval(secs => $4)
+		FROM dbtable_schema.users u
+		WHERE u.sub = nj.recipient_sub AND nj.id IN (
+			SELECT id
+			FROM dbtable_schema.notification_jobs
+			WHERE completed_on IS NULL AND enabled = true AND attempts < $1
+				AND run_at <= TIMEZONE('utc', NOW())
+				AND (updated_on IS NULL OR updated_on < TIMEZONE('utc', NOW()) - make_interval(secs => $3))
+				AND (claimed_until IS NULL OR claimed_until < TIMEZONE('utc', NOW()))
+			ORDER BY run_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING nj.id, nj.kind, nj.channel, nj.recipient_sub, nj.group_id, nj.quote_id, nj.booking_id, COALESCE(u.email, '')
+	`, notificationMaxAttempts, notificationBatchSize, notificationBackoff.Seconds(), notificationClaimLease.Seconds())
+	if err != nil {
+		return 0, util.ErrCheck(err)
+	}
+
+	var pending []notificationJob
+	for rows.Next() {
+		var job notificationJob
+		err = rows.Scan(&job.id, &job.kind, &job.channel, &job.recipientSub, &job.groupId, &job.quoteId, &job.bookingId, &job.email)
+		if err != nil {
+			done()
+			return 0, util.ErrCheck(err)
+		}
+		pending = append(pending, job)
+	}
+	done()
+
+	finished := 0
+	for _, job := range pending {
+		sendErr := h.sendNotification(ctx, job)
+		if sendErr != nil {
+			util.ErrorLog.Println(util.ErrCheck(fmt.Errorf("%s notification %s over %s: %w", job.kind, job.id, job.channel, sendErr)))
+
+			_, err = db.SessionBatchExec(ctx, `
+				UPDATE dbtable_schema.notification_jobs
+				SET attempts = attempts + 1, last_error = $2, claimed_until = NULL, updated_on = TIMEZONE('utc', NOW())
+				WHERE id = $1
+			`, job.id, sendErr.Error())
+			if err != nil {
+				return finished, util.ErrCheck(err)
+			}
+			continue
+		}
+
+		_, err = db.SessionBatchExec(ctx, `
+			UPDATE dbtable_schema.notification_jobs
+			SET completed_on = TIMEZONE('utc', NOW()), claimed_until = NULL, updated_on = TIMEZONE('utc', NOW())
+			WHERE id = $1
+		`, job.id)
+		if err != nil {
+			return finished, util.ErrCheck(err)
+		}
+		finished++
+	}
+
+	return finished, nil
+}
+
+// Details are read as the recipient, so a notification never says more than they could see in the app.
+// Nothing is written, the transaction is only there to hold the recipient's session.
+func (h *Handlers) readNotificationDetails(ctx context.Context, job notificationJob) (*notificationDetails, error) {
+	recipientTx, err := h.Database.DatabaseClient.OpenPoolSessionTx(ctx, types.NewConcurrentUserSession(&types.UserSession{
+		UserSub: job.recipientSub,
+		GroupId: job.groupId,
+	}))
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+	defer recipientTx.Rollback(ctx)
+
+	var details notificationDetails
+	err = recipientTx.QueryRow(ctx, `
+		SELECT
+			se.name || ' - ' || st.name,
+			gs.name,
+			gs.timezone,
+			occurrence.start,
+			q.enabled AND COALESCE(b.enabled, true),
+			(occurrence.start AT TIME ZONE gs.timezone) <= NOW()
+		FROM dbtable_schema.quotes q
+		LEFT JOIN dbtable_schema.bookings b ON b.id = $2
+		JOIN dbtable_schema.service_tiers st ON st.id = q.service_tier_id
+		JOIN dbtable_schema.services se ON se.id = st.service_id
+		`+notificationOccurrenceJoins+`
+		WHERE q.id = $1
+	`, *job.quoteId, job.bookingId).Scan(&details.Service, &details.Schedule, &details.TimeZone, &details.Start, &details.Active, &details.Started)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return &details, nil
+}
+
+// Jobs whose quote or booking is gone or no longer active finish without sending anything
+func (h *Handlers) sendNotification(ctx context.Context, job notificationJob) error {
+	if job.quoteId == nil {
+		return nil
+	}
+
+	details, err := h.readNotificationDetails(ctx, job)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+	if details == nil {
+		return nil
+	}
+
+	// Cancellations are about inactive quotes, everything else is moot once the quote or booking is
+	if job.kind != NOTIFY_QUOTE_CANCELLED && !details.Active {
+		return nil
+	}
+	if job.kind == NOTIFY_BOOKING_REMINDER && details.Started {
+		return nil
+	}
+
+	subject, text, ok := notificationMessage(job.kind, *details)
+	if !ok {
+		return util.ErrCheck(fmt.Errorf("unknown notification kind %s", job.kind))
+	}
+
+	notification := &clients.Notification{
+		Id:      job.id,
+		Kind:    job.kind,
+		UserSub: job.recipientSub,
+		To:      job.email,
+		Subject: subject,
+		Text:    text,
+		QuoteId: *job.quoteId,
+	}
+	if job.bookingId != nil {
+		notification.BookingId = *job.bookingId
+	}
+
+	return h.Notifications.Notify(ctx, job.channel, notification)
+}
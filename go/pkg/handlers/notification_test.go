@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_notificationMessage(t *testing.T) {
+	details := notificationDetails{
+		Service:  "Haircut - Premium",
+		Schedule: "Main schedule",
+		TimeZone: "America/New_York",
+		Start:    time.Date(2025, 3, 3, 9, 30, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name        string
+		kind        string
+		wantSubject string
+		wantOk      bool
+	}{
+		{name: "created", kind: NOTIFY_QUOTE_CREATED, wantSubject: "New appointment request", wantOk: true},
+		{name: "approved", kind: NOTIFY_QUOTE_APPROVED, wantSubject: "Appointment confirmed", wantOk: true},
+		{name: "cancelled", kind: NOTIFY_QUOTE_CANCELLED, wantSubject: "Appointment cancelled", wantOk: true},
+		{name: "reminder", kind: NOTIFY_BOOKING_REMINDER, wantSubject: "Appointment reminder", wantOk: true},
+		{name: "unknown", kind: "quote_rescheduled", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, text, ok := notificationMessage(tt.kind, details)
+			if ok != tt.wantOk {
+				t.Fatalf("notificationMessage(%s) ok = %v, want %v", tt.kind, ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("notificationMessage(%s) subject = %q, want %q", tt.kind, subject, tt.wantSubject)
+			}
+			for _, want := range []string{"Haircut - Premium", "Monday, March 3, 2025 at 9:30 AM (America/New_York)", "Schedule: Main schedule"} {
+				if !strings.Contains(text, want) {
+					t.Errorf("notificationMessage(%s) text = %q, want it to contain %q", tt.kind, text, want)
+				}
+			}
+		})
+	}
+}
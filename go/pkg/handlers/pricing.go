@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	json "encoding/json"
+	"math"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const (
+	PRICE_LINE_SERVICE = "service"
+	PRICE_LINE_TIER    = "tier"
+	PRICE_LINE_BRACKET = "bracket"
+	PRICE_LINE_ADDON   = "addon"
+
+	// Tier and bracket multipliers are both stored as percentages, 150 being 1.5x. Anything saved without
+	// one is charged at 1x.
+	priceMultiplierBase = 100
+	priceCentsPerUnit   = 100
+)
+
+type priceAddon struct {
+	Name string
+	Cost int32
+}
+
+// The catalog values a quote is priced from, costs are whole currency units
+type priceInputs struct {
+	ServiceName       string
+	Cost              int32
+	TierName          string
+	TierMultiplier    int32
+	BracketMultiplier int32
+	Addons            []priceAddon
+}
+
+func normalizeMultiplier(multiplier int32) int32 {
+	if multiplier <= 0 {
+		return priceMultiplierBase
+	}
+	return multiplier
+}
+
+// Tiers were saved as whole multipliers, 1 to 5, before they were percentages, and can't be set below
+// 1x. Anything under 100 is one of those until the 0001_service_tier_multiplier_percent migration has
+// run. Brackets are left to normalizeMultiplier, as they can discount.
+func normalizeTierMultiplier(multiplier int32) int32 {
+	if multiplier > 0 && multiplier < priceMultiplierBase {
+		return multiplier * priceMultiplierBase
+	}
+	return normalizeMultiplier(multiplier)
+}
+
+// The difference a multiplier makes to an amount, rounded to the nearest cent
+func multiplierAdjustment(amount int64, multiplier int32) int64 {
+	scaled := (amount*int64(multiplier) + priceMultiplierBase/2) / priceMultiplierBase
+	return scaled - amount
+}
+
+// computePrice works out the line items of a quote. The tier multiplier applies to the service cost and
+// the bracket multiplier to the result, so evening brackets scale whichever tier was chosen. Addons are
+// flat and only listed when they carry a cost. Amounts are worked out in int64 and the quote is refused
+// if the running total outgrows the int32 cents a snapshot stores, which also bounds every line.
+func computePrice(in priceInputs, pricedOn time.Time) (*types.IPriceSnapshot, error) {
+	var lineItems []*types.IPriceLineItem
+	var subtotal int64
+
+	addLine := func(kind, name string, multiplier int32, amount int64) error {
+		subtotal += amount
+		if subtotal > math.MaxInt32 {
+			return util.ErrCheck(util.UserError("The price is too large to quote."))
+		}
+		lineItems = append(lineItems, &types.IPriceLineItem{
+			Kind:       kind,
+			Name:       name,
+			Multiplier: multiplier,
+			Amount:     int32(amount),
+		})
+		return nil
+	}
+
+	tierMultiplier := normalizeTierMultiplier(in.TierMultiplier)
+	bracketMultiplier := normalizeMultiplier(in.BracketMultiplier)
+
+	if err := addLine(PRICE_LINE_SERVICE, in.ServiceName, priceMultiplierBase, int64(in.Cost)*priceCentsPerUnit); err != nil {
+		return nil, err
+	}
+
+	if tierMultiplier != priceMultiplierBase {
+		if err := addLine(PRICE_LINE_TIER, in.TierName, tierMultiplier, multiplierAdjustment(subtotal, tierMultiplier)); err != nil {
+			return nil, err
+		}
+	}
+
+	if bracketMultiplier != priceMultiplierBase {
+		if err := addLine(PRICE_LINE_BRACKET, "Scheduled time", bracketMultiplier, multiplierAdjustment(subtotal, bracketMultiplier)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, addon := range in.Addons {
+		if addon.Cost <= 0 {
+			continue
+		}
+		if err := addLine(PRICE_LINE_ADDON, addon.Name, priceMultiplierBase, int64(addon.Cost)*priceCentsPerUnit); err != nil {
+			return nil, err
+		}
+	}
+
+	return &types.IPriceSnapshot{
+		LineItems: lineItems,
+		Total:     int32(subtotal),
+		PricedOn:  pricedOn.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// priceQuote reads the current service, tier, bracket and addon costs for a requested slot and returns
+// the snapshot along with its json for storage
+func priceQuote(info ReqInfo, serviceTierId, scheduleBracketSlotId string) (*types.IPriceSnapshot, []byte, error) {
+	var in priceInputs
+	err := info.Tx.QueryRow(info.Ctx, `
+		SELECT s.name, COALESCE(s.cost, 0), st.name, st.multiplier, sb.multiplier
+		FROM dbtable_schema.service_tiers st
+		JOIN dbtable_schema.services s ON s.id = st.service_id
+		JOIN dbtable_schema.schedule_bracket_slots sbs ON sbs.id = $2::uuid
+		JOIN dbtable_schema.schedule_brackets sb ON sb.id = sbs.schedule_bracket_id
+		WHERE st.id = $1::uuid
+	`, serviceTierId, scheduleBracketSlotId).Scan(&in.ServiceName, &in.Cost, &in.TierName, &in.TierMultiplier, &in.BracketMultiplier)
+	if err != nil {
+		return nil, nil, util.ErrCheck(err)
+	}
+
+	rows, err := info.Tx.Query(info.Ctx, `
+		SELECT sa.name, COALESCE(sta.cost, 0)
+		FROM dbtable_schema.service_tier_addons sta
+		JOIN dbtable_schema.service_addons sa ON sa.id = sta.service_addon_id
+		WHERE sta.service_tier_id = $1::uuid AND sta.enabled = true
+		ORDER BY sta.created_on ASC
+	`, serviceTierId)
+	if err != nil {
+		return nil, nil, util.ErrCheck(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var addon priceAddon
+		if err := rows.Scan(&addon.Name, &addon.Cost); err != nil {
+			return nil, nil, util.ErrCheck(err)
+		}
+		in.Addons = append(in.Addons, addon)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, util.ErrCheck(err)
+	}
+
+	price, err := computePrice(in, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	priceJson, err := json.Marshal(price)
+	if err != nil {
+		return nil, nil, util.ErrCheck(err)
+	}
+
+	return price, priceJson, nil
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_computePrice(t *testing.T) {
+	pricedOn := time.Date(2025, 3, 3, 15, 0, 0, 0, time.UTC)
+
+	type line struct {
+		kind   string
+		amount int32
+	}
+
+	tests := []struct {
+		name      string
+		in        priceInputs
+		wantLines []line
+		wantTotal int32
+		wantErr   bool
+	}{
+		{
+			name:      "service only",
+			in:        priceInputs{ServiceName: "Tutoring", Cost: 40, TierMultiplier: 100, BracketMultiplier: 100},
+			wantLines: []line{{PRICE_LINE_SERVICE, 4000}},
+			wantTotal: 4000,
+		},
+		{
+			name:      "unset multipliers are 1x",
+			in:        priceInputs{ServiceName: "Tutoring", Cost: 40},
+			wantLines: []line{{PRICE_LINE_SERVICE, 4000}},
+			wantTotal: 4000,
+		},
+		{
+			name:      "tier then bracket",
+			in:        priceInputs{ServiceName: "Tutoring", Cost: 40, TierName: "Advanced", TierMultiplier: 125, BracketMultiplier: 150},
+			wantLines: []line{{PRICE_LINE_SERVICE, 4000}, {PRICE_LINE_TIER, 1000}, {PRICE_LINE_BRACKET, 2500}},
+			wantTotal: 7500,
+		},
+		{
+			name:      "legacy whole number tier multiplier",
+			in:        priceInputs{ServiceName: "Tutoring", Cost: 40, TierName: "Advanced", TierMultiplier: 2, BracketMultiplier: 100},
+			wantLines: []line{{PRICE_LINE_SERVICE, 4000}, {PRICE_LINE_TIER, 4000}},
+			wantTotal: 8000,
+		},
+		{
+			name:      "discounted bracket",
+			in:        priceInputs{ServiceName: "Tutoring", Cost: 40, TierMultiplier: 100, BracketMultiplier: 80},
+			wantLines: []line{{PRICE_LINE_SERVICE, 4000}, {PRICE_LINE_BRACKET, -800}},
+			wantTotal: 3200,
+		},
+		{
+			name:      "rounds to the nearest cent",
+			in:        priceInputs{ServiceName: "Tutoring", Cost: 1, TierMultiplier: 133, BracketMultiplier: 133},
+			wantLines: []line{{PRICE_LINE_SERVICE, 100}, {PRICE_LINE_TIER, 33}, {PRICE_LINE_BRACKET, 44}},
+			wantTotal: 177,
+		},
+		{
+			name: "addons are flat",
+			in: priceInputs{ServiceName: "Tutoring", Cost: 40, TierMultiplier: 200, BracketMultiplier: 100, Addons: []priceAddon{
+				{Name: "Materials", Cost: 5},
+				{Name: "Notes", Cost: 0},
+			}},
+			wantLines: []line{{PRICE_LINE_SERVICE, 4000}, {PRICE_LINE_TIER, 4000}, {PRICE_LINE_ADDON, 500}},
+			wantTotal: 8500,
+		},
+		{
+			name:      "free service",
+			in:        priceInputs{ServiceName: "Consultation", TierMultiplier: 150, BracketMultiplier: 150},
+			wantLines: []line{{PRICE_LINE_SERVICE, 0}, {PRICE_LINE_TIER, 0}, {PRICE_LINE_BRACKET, 0}},
+			wantTotal: 0,
+		},
+		{
+			name:    "service cost past int32 cents",
+			in:      priceInputs{ServiceName: "Tutoring", Cost: 30000000},
+			wantErr: true,
+		},
+		{
+			name:    "multiplied past int32 cents",
+			in:      priceInputs{ServiceName: "Tutoring", Cost: 20000000, TierMultiplier: 500},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := computePrice(tt.in, pricedOn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("computePrice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(got.GetLineItems()) != len(tt.wantLines) {
+				t.Fatalf("computePrice() returned %d line items, want %d: %v", len(got.GetLineItems()), len(tt.wantLines), got.GetLineItems())
+			}
+
+			var sum int32
+			for i, item := range got.GetLineItems() {
+				if item.GetKind() != tt.wantLines[i].kind || item.GetAmount() != tt.wantLines[i].amount {
+					t.Errorf("computePrice() line %d = %s %d, want %s %d", i, item.GetKind(), item.GetAmount(), tt.wantLines[i].kind, tt.wantLines[i].amount)
+				}
+				sum += item.GetAmount()
+			}
+
+			if got.GetTotal() != tt.wantTotal || sum != got.GetTotal() {
+				t.Errorf("computePrice() total = %d with line items summing to %d, want %d", got.GetTotal(), sum, tt.wantTotal)
+			}
+			if got.GetPricedOn() != "2025-03-03T15:00:00Z" {
+				t.Errorf("computePrice() pricedOn = %s", got.GetPricedOn())
+			}
+		})
+	}
+}
+
+func Test_normalizeTierMultiplier(t *testing.T) {
+	tests := []struct {
+		name       string
+		multiplier int32
+		want       int32
+	}{
+		{"unset", 0, 100},
+		{"legacy 1x", 1, 100},
+		{"legacy 2x", 2, 200},
+		{"legacy 5x", 5, 500},
+		{"percentage", 150, 150},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeTierMultiplier(tt.multiplier); got != tt.want {
+				t.Errorf("normalizeTierMultiplier(%d) = %d, want %d", tt.multiplier, got, tt.want)
+			}
+		})
+	}
+
+	// brackets can discount, so they keep values under 100
+	if got := normalizeMultiplier(80); got != 80 {
+		t.Errorf("normalizeMultiplier(80) = %d, want 80", got)
+	}
+}
@@ -30,7 +30,7 @@ func (h *Handlers) PatchUserProfile(info ReqInfo, data *types.PatchUserProfileRe
 
 	info.Batch.Send(info.Ctx)
 
-	err := h.Keycloak.UpdateUser(info.Ctx, userSub, userSub, data.FirstName, data.LastName)
+	err := h.Identity.UpdateUser(info.Ctx, userSub, userSub, data.FirstName, data.LastName)
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
@@ -161,7 +161,7 @@ func (h *Handlers) DeleteProfile(info ReqInfo, data *types.DeleteProfileRequest)
 		return nil, util.ErrCheck(err)
 	}
 
-	err = h.Keycloak.DeleteUser(info.Ctx, userSub)
+	err = h.Identity.DeleteUser(info.Ctx, userSub)
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
 	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
 )
 
 func TestHandlers_PostUserProfile(t *testing.T) {
@@ -35,32 +39,64 @@ func TestHandlers_PostUserProfile(t *testing.T) {
 	}
 }
 
+// failingIdentity refuses user updates, as keycloak would for a user it no longer has
+type failingIdentity struct {
+	*clients.MemoryIdentity
+}
+
+func (fi failingIdentity) UpdateUser(ctx context.Context, userSub, id, firstName, lastName string) error {
+	return util.ErrCheck(clients.ErrIdentityNotFound)
+}
+
 func TestHandlers_PatchUserProfile(t *testing.T) {
-	type args struct {
-		info ReqInfo
-		data *types.PatchUserProfileRequest
+	h, info, done, err := setupTestEnv(false)
+	if err != nil {
+		t.Fatal(util.ErrCheck(err))
 	}
-	tests := []struct {
-		name    string
-		h       *Handlers
-		args    args
-		want    *types.PatchUserProfileResponse
-		wantErr bool
-	}{
-		// TODO: Add test cases.
+	defer done()
+
+	userSub := info.Session.GetUserSub()
+
+	// patch the profile with its current values so the test user is left as it was
+	details, err := h.GetUserProfileDetails(info, &types.GetUserProfileDetailsRequest{})
+	if err != nil {
+		t.Fatalf("Handlers.GetUserProfileDetails() error = %v", err)
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := tt.h.PatchUserProfile(tt.args.info, tt.args.data)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Handlers.PatchUserProfile(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("Handlers.PatchUserProfile(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
-			}
-		})
+	profile := details.GetUserProfile()
+
+	data := &types.PatchUserProfileRequest{
+		Id:        profile.GetId(),
+		FirstName: profile.GetFirstName(),
+		LastName:  profile.GetLastName(),
+		Username:  profile.GetUsername(),
+		Email:     profile.GetEmail(),
+		Image:     profile.GetImage(),
 	}
+
+	t.Run("updates the identity provider", func(t *testing.T) {
+		got, err := h.PatchUserProfile(info, data)
+		if err != nil {
+			t.Fatalf("Handlers.PatchUserProfile() error = %v", err)
+		}
+		if !got.GetSuccess() {
+			t.Errorf("Handlers.PatchUserProfile() = %v, want success", got)
+		}
+
+		mi := h.Identity.(*clients.MemoryIdentity)
+		if firstName, lastName := mi.GetUserName(userSub); firstName != data.GetFirstName() || lastName != data.GetLastName() {
+			t.Errorf("identity user name = %s %s, want %s %s", firstName, lastName, data.GetFirstName(), data.GetLastName())
+		}
+	})
+
+	t.Run("identity provider errors are returned", func(t *testing.T) {
+		failing := *h
+		failing.Identity = failingIdentity{clients.NewMemoryIdentity(nil)}
+
+		_, err := failing.PatchUserProfile(info, data)
+		if !errors.Is(err, clients.ErrIdentityNotFound) {
+			t.Errorf("Handlers.PatchUserProfile() error = %v, want %v", err, clients.ErrIdentityNotFound)
+		}
+	})
 }
 
 func TestHandlers_GetUserProfileDetails(t *testing.T) {
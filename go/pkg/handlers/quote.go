@@ -10,21 +10,31 @@ import (
 	"github.com/lib/pq"
 )
 
-func (h *Handlers) PostQuote(info ReqInfo, data *types.PostQuoteRequest) (*types.PostQuoteResponse, error) {
-	userSub := info.Session.GetUserSub()
-
-	// Validate quote access and time
-
+// Quotes can't be requested while the group's account is paused
+func checkGroupStanding(info ReqInfo) error {
 	var goodStanding bool
 	err := info.Tx.QueryRow(info.Ctx, `
 		SELECT dbfunc_schema.check_group_standing($1)
 	`, info.Session.GetGroupId()).Scan(&goodStanding)
 	if err != nil {
-		return nil, util.ErrCheck(err)
+		return util.ErrCheck(err)
 	}
 
 	if !goodStanding {
-		return nil, util.ErrCheck(util.UserError("Service temporarily paused due to group account status."))
+		return util.ErrCheck(util.UserError("Service temporarily paused due to group account status."))
+	}
+
+	return nil
+}
+
+func (h *Handlers) PostQuote(info ReqInfo, data *types.PostQuoteRequest) (*types.PostQuoteResponse, error) {
+	userSub := info.Session.GetUserSub()
+
+	// Validate quote access and time
+
+	err := checkGroupStanding(info)
+	if err != nil {
+		return nil, util.ErrCheck(err)
 	}
 
 	var slotReserved bool
@@ -52,20 +62,64 @@ func (h *Handlers) PostQuote(info ReqInfo, data *types.PostQuoteRequest) (*types
 		return nil, util.ErrCheck(err)
 	}
 
+	price, priceJson, err := priceQuote(info, data.GetServiceTierId(), data.GetScheduleBracketSlotId())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
 	err = info.Tx.QueryRow(info.Ctx, `
-		INSERT INTO dbtable_schema.quotes (slot_date, schedule_bracket_slot_id, service_tier_id, created_sub, group_id, slot_created_sub)
-		VALUES ($1::date, $2::uuid, $3::uuid, $4::uuid, $5::uuid, $6::uuid)
+		INSERT INTO dbtable_schema.quotes (slot_date, schedule_bracket_slot_id, service_tier_id, created_sub, group_id, slot_created_sub, price)
+		VALUES ($1::date, $2::uuid, $3::uuid, $4::uuid, $5::uuid, $6::uuid, $7::jsonb)
 		RETURNING id
-	`, data.SlotDate, data.ScheduleBracketSlotId, data.ServiceTierId, userSub, info.Session.GetGroupId(), slotCreatedSub).Scan(&quoteId)
+	`, data.SlotDate, data.ScheduleBracketSlotId, data.ServiceTierId, userSub, info.Session.GetGroupId(), slotCreatedSub, priceJson).Scan(&quoteId)
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
 
-	// Handle quote intake forms for both service and tier
+	err = h.postQuoteIntake(info, []string{quoteId}, data.GetServiceTierId(), data.GetServiceFormVersionSubmissions(), data.GetTierFormVersionSubmissions(), data.GetFiles())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	err = h.queueQuoteNotifications(info, NOTIFY_QUOTE_CREATED, []string{quoteId}, nil)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	err = queueGroupWebhookEvent(info, info.Session.GetGroupId(), GROUP_WEBHOOK_QUOTE_CREATED, groupWebhookQuote{
+		QuoteId:               quoteId,
+		SlotDate:              data.GetSlotDate(),
+		ScheduleBracketSlotId: data.GetScheduleBracketSlotId(),
+		ServiceTierId:         data.GetServiceTierId(),
+		UserSub:               userSub,
+	})
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
 
-	serviceFormSubmissions, tierFormSubmissions := data.GetServiceFormVersionSubmissions(), data.GetTierFormVersionSubmissions()
+	// Ping staff if they're online
 
-	formSubmissions := make([]*types.IProtoFormVersionSubmission, len(serviceFormSubmissions)+len(tierFormSubmissions))
+	if err := h.Socket.RoleCall(slotCreatedSub); err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return &types.PostQuoteResponse{
+		Quote: &types.IQuote{
+			Id:                    quoteId,
+			SlotDate:              data.SlotDate,
+			ScheduleBracketSlotId: data.ScheduleBracketSlotId,
+			Price:                 price,
+		},
+	}, nil
+}
+
+// Stores the intake forms and files of a request once, linking them to each of its quotes
+func (h *Handlers) postQuoteIntake(info ReqInfo, quoteIds []string, serviceTierId string, serviceFormSubmissions, tierFormSubmissions []*types.IProtoFormVersionSubmission, files []*types.IFile) error {
+	userSub := info.Session.GetUserSub()
+
+	// Handle quote intake forms for both service and tier
+
+	formSubmissions := make([]*types.IProtoFormVersionSubmission, 0, len(serviceFormSubmissions)+len(tierFormSubmissions))
 	formSubmissions = append(formSubmissions, serviceFormSubmissions...)
 	formSubmissions = append(formSubmissions, tierFormSubmissions...)
 
@@ -73,7 +127,7 @@ func (h *Handlers) PostQuote(info ReqInfo, data *types.PostQuoteRequest) (*types
 		if form.GetSubmission() != nil {
 			formSubmission, err := json.Marshal(form.GetSubmission())
 			if err != nil {
-				return nil, util.ErrCheck(err)
+				return util.ErrCheck(err)
 			}
 
 			err = info.Tx.QueryRow(info.Ctx, `
@@ -82,7 +136,19 @@ func (h *Handlers) PostQuote(info ReqInfo, data *types.PostQuoteRequest) (*types
 				RETURNING id
 			`, form.GetFormVersionId(), formSubmission, userSub).Scan(&form.Id)
 			if err != nil {
-				return nil, util.ErrCheck(err)
+				return util.ErrCheck(err)
+			}
+
+			err = queueGroupWebhookEvent(info, info.Session.GetGroupId(), GROUP_WEBHOOK_FORM_SUBMITTED, groupWebhookFormSubmission{
+				FormVersionSubmissionId: form.GetId(),
+				FormId:                  form.GetFormId(),
+				FormVersionId:           form.GetFormVersionId(),
+				QuoteIds:                quoteIds,
+				Submission:              formSubmission,
+				UserSub:                 userSub,
+			})
+			if err != nil {
+				return util.ErrCheck(err)
 			}
 		}
 	}
@@ -90,22 +156,24 @@ func (h *Handlers) PostQuote(info ReqInfo, data *types.PostQuoteRequest) (*types
 	for _, formSubmission := range serviceFormSubmissions {
 		if formSubmission.GetId() != "" {
 			var serviceFormId string
-			err = info.Tx.QueryRow(info.Ctx, `
+			err := info.Tx.QueryRow(info.Ctx, `
 				SELECT sf.id
 				FROM dbtable_schema.service_tiers st
 				JOIN dbtable_schema.service_forms sf ON sf.service_id = st.service_id AND sf.form_id = $2::uuid
 				WHERE st.id = $1::uuid
-			`, data.GetServiceTierId(), formSubmission.GetFormId()).Scan(&serviceFormId)
+			`, serviceTierId, formSubmission.GetFormId()).Scan(&serviceFormId)
 			if err != nil {
-				return nil, util.ErrCheck(err)
+				return util.ErrCheck(err)
 			}
 
-			_, err = info.Tx.Exec(info.Ctx, `
-				INSERT INTO dbtable_schema.quote_service_form_version_submissions (quote_id, service_form_id, form_version_submission_id, created_sub)
-				VALUES ($1::uuid, $2::uuid, $3::uuid, $4::uuid)
-			`, quoteId, serviceFormId, formSubmission.GetId(), userSub)
-			if err != nil {
-				return nil, util.ErrCheck(err)
+			for _, quoteId := range quoteIds {
+				_, err = info.Tx.Exec(info.Ctx, `
+					INSERT INTO dbtable_schema.quote_service_form_version_submissions (quote_id, service_form_id, form_version_submission_id, created_sub)
+					VALUES ($1::uuid, $2::uuid, $3::uuid, $4::uuid)
+				`, quoteId, serviceFormId, formSubmission.GetId(), userSub)
+				if err != nil {
+					return util.ErrCheck(err)
+				}
 			}
 		}
 	}
@@ -113,80 +181,81 @@ func (h *Handlers) PostQuote(info ReqInfo, data *types.PostQuoteRequest) (*types
 	for _, formSubmission := range tierFormSubmissions {
 		if formSubmission.GetId() != "" {
 			var tierFormId string
-			err = info.Tx.QueryRow(info.Ctx, `
+			err := info.Tx.QueryRow(info.Ctx, `
 				SELECT id
 				FROM dbtable_schema.service_tier_forms
 				WHERE service_tier_id = $1::uuid AND form_id = $2::uuid
-			`, data.GetServiceTierId(), formSubmission.GetFormId()).Scan(&tierFormId)
+			`, serviceTierId, formSubmission.GetFormId()).Scan(&tierFormId)
 			if err != nil {
-				return nil, util.ErrCheck(err)
+				return util.ErrCheck(err)
 			}
 
-			_, err = info.Tx.Exec(info.Ctx, `
-				INSERT INTO dbtable_schema.quote_service_tier_form_version_submissions (quote_id, service_tier_form_id, form_version_submission_id, created_sub)
-				VALUES ($1::uuid, $2::uuid, $3::uuid, $4::uuid)
-			`, quoteId, tierFormId, formSubmission.GetId(), userSub)
-			if err != nil {
-				return nil, util.ErrCheck(err)
+			for _, quoteId := range quoteIds {
+				_, err = info.Tx.Exec(info.Ctx, `
+					INSERT INTO dbtable_schema.quote_service_tier_form_version_submissions (quote_id, service_tier_form_id, form_version_submission_id, created_sub)
+					VALUES ($1::uuid, $2::uuid, $3::uuid, $4::uuid)
+				`, quoteId, tierFormId, formSubmission.GetId(), userSub)
+				if err != nil {
+					return util.ErrCheck(err)
+				}
 			}
 		}
 	}
 
 	// Handle quote files
 
-	for _, file := range data.GetFiles() {
+	for _, file := range files {
 		fileRes, err := h.PostFile(info, &types.PostFileRequest{File: file})
 		if err != nil {
-			return nil, util.ErrCheck(err)
+			return util.ErrCheck(err)
 		}
-		_, err = info.Tx.Exec(info.Ctx, `
-			INSERT INTO dbtable_schema.quote_files (quote_id, file_id, created_sub)
-			VALUES ($1::uuid, $2::uuid, $3::uuid)
-		`, quoteId, fileRes.GetId(), userSub)
-		if err != nil {
-			return nil, util.ErrCheck(err)
+
+		for _, quoteId := range quoteIds {
+			_, err = info.Tx.Exec(info.Ctx, `
+				INSERT INTO dbtable_schema.quote_files (quote_id, file_id, created_sub)
+				VALUES ($1::uuid, $2::uuid, $3::uuid)
+			`, quoteId, fileRes.GetId(), userSub)
+			if err != nil {
+				return util.ErrCheck(err)
+			}
 		}
 	}
 
-	// Ping staff if they're online
+	return nil
+}
 
-	var staffSub string
-	err = info.Tx.QueryRow(info.Ctx, `
-		SELECT created_sub
-		FROM dbtable_schema.schedule_bracket_slots
+// Changing the tier of a request reprices it, the new price is what gets copied to the booking
+func (h *Handlers) PatchQuote(info ReqInfo, data *types.PatchQuoteRequest) (*types.PatchQuoteResponse, error) {
+	var scheduleBracketSlotId string
+	err := info.Tx.QueryRow(info.Ctx, `
+		SELECT schedule_bracket_slot_id
+		FROM dbtable_schema.quotes
 		WHERE id = $1
-	`, data.GetScheduleBracketSlotId()).Scan(&staffSub)
+	`, data.Id).Scan(&scheduleBracketSlotId)
 	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
 
-	if err := h.Socket.RoleCall(staffSub); err != nil {
+	_, priceJson, err := priceQuote(info, data.GetServiceTierId(), scheduleBracketSlotId)
+	if err != nil {
 		return nil, util.ErrCheck(err)
 	}
 
-	return &types.PostQuoteResponse{
-		Quote: &types.IQuote{
-			Id:                    quoteId,
-			SlotDate:              data.SlotDate,
-			ScheduleBracketSlotId: data.ScheduleBracketSlotId,
-		},
-	}, nil
-}
-
-func (h *Handlers) PatchQuote(info ReqInfo, data *types.PatchQuoteRequest) (*types.PatchQuoteResponse, error) {
-	util.BatchExec(info.Batch, `
+	_, err = info.Tx.Exec(info.Ctx, `
 		UPDATE dbtable_schema.quotes
-		SET service_tier_id = $2, updated_sub = $3, updated_on = $4 
+		SET service_tier_id = $2, price = $3::jsonb, updated_sub = $4, updated_on = $5
 		WHERE id = $1
-	`, data.Id, data.ServiceTierId, info.Session.GetUserSub(), time.Now())
-	info.Batch.Send(info.Ctx)
+	`, data.Id, data.ServiceTierId, priceJson, info.Session.GetUserSub(), time.Now())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
 
 	return &types.PatchQuoteResponse{Success: true}, nil
 }
 
 func (h *Handlers) GetQuotes(info ReqInfo, data *types.GetQuotesRequest) (*types.GetQuotesResponse, error) {
 	quotes := util.BatchQuery[types.IQuote](info.Batch, `
-		SELECT q.id, q."startTime", q."scheduleBracketSlotId", q."serviceTierId", q."serviceTierName", q."serviceName", q."serviceFormVersionSubmissionId", q."tierFormVersionSubmissionId", q."createdOn"
+		SELECT q.id, q."startTime", q."scheduleBracketSlotId", q."serviceTierId", q."serviceTierName", q."serviceName", q."serviceFormVersionSubmissionId", q."tierFormVersionSubmissionId", q."seriesId", q.price, q."createdOn"
 		FROM dbview_schema.enabled_quotes q
 		JOIN dbtable_schema.schedule_bracket_slots sbs ON sbs.id = q."scheduleBracketSlotId"
 		WHERE sbs.created_sub = $1
@@ -198,7 +267,7 @@ func (h *Handlers) GetQuotes(info ReqInfo, data *types.GetQuotesRequest) (*types
 
 func (h *Handlers) GetQuoteById(info ReqInfo, data *types.GetQuoteByIdRequest) (*types.GetQuoteByIdResponse, error) {
 	quote := util.BatchQueryRow[types.IQuote](info.Batch, `
-		SELECT id, "slotDate", "scheduleBracketSlotId", "serviceFormVersionSubmissionId", "tierFormVersionSubmissionId", "createdOn"
+		SELECT id, "slotDate", "scheduleBracketSlotId", "serviceFormVersionSubmissionId", "tierFormVersionSubmissionId", "seriesId", price, "createdOn"
 		FROM dbview_schema.enabled_quotes
 		WHERE id = $1
 	`, data.Id)
@@ -218,11 +287,19 @@ func (h *Handlers) DeleteQuote(info ReqInfo, data *types.DeleteQuoteRequest) (*t
 }
 
 func (h *Handlers) DisableQuote(info ReqInfo, data *types.DisableQuoteRequest) (*types.DisableQuoteResponse, error) {
+	quoteIds := strings.Split(data.Ids, ",")
+
 	util.BatchExec(info.Batch, `
 		UPDATE dbtable_schema.quotes
 		SET enabled = false, updated_on = $2, updated_sub = $3
 		WHERE id = ANY($1)
-	`, pq.Array(strings.Split(data.Ids, ",")), time.Now(), info.Session.GetUserSub())
+	`, pq.Array(quoteIds), time.Now(), info.Session.GetUserSub())
+
+	err := h.queueQuoteNotifications(info, NOTIFY_QUOTE_CANCELLED, quoteIds, nil)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
 	info.Batch.Send(info.Ctx)
 
 	return &types.DisableQuoteResponse{Success: true}, nil
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+	"github.com/lib/pq"
+)
+
+const (
+	quoteSeriesMaxOccurrences   = 26
+	quoteSeriesMaxIntervalWeeks = 4
+	quoteSeriesDateFormat       = "2006-01-02"
+)
+
+// Lists the dates of a series starting on slotDate, every intervalWeeks, until either count
+// occurrences or untilDate is reached
+func quoteSeriesDates(slotDate string, recurrence *types.IQuoteRecurrence) ([]string, error) {
+	start, err := time.Parse(quoteSeriesDateFormat, slotDate)
+	if err != nil {
+		return nil, util.ErrCheck(util.UserError("The first appointment date is invalid."))
+	}
+
+	intervalWeeks := int(recurrence.GetIntervalWeeks())
+	if intervalWeeks < 1 || intervalWeeks > quoteSeriesMaxIntervalWeeks {
+		return nil, util.ErrCheck(util.UserError(fmt.Sprintf("Appointments may repeat every 1 to %d weeks.", quoteSeriesMaxIntervalWeeks)))
+	}
+
+	count := int(recurrence.GetCount())
+	if count < 0 || count > quoteSeriesMaxOccurrences {
+		return nil, util.ErrCheck(util.UserError(fmt.Sprintf("A series may have at most %d appointments.", quoteSeriesMaxOccurrences)))
+	}
+
+	var until time.Time
+	if recurrence.GetUntilDate() != "" {
+		until, err = time.Parse(quoteSeriesDateFormat, recurrence.GetUntilDate())
+		if err != nil || until.Before(start) {
+			return nil, util.ErrCheck(util.UserError("The series must end on or after the first appointment."))
+		}
+	}
+
+	if count == 0 && until.IsZero() {
+		return nil, util.ErrCheck(util.UserError("A series needs a number of appointments or an end date."))
+	}
+
+	var dates []string
+	for date := start; count == 0 || len(dates) < count; date = date.AddDate(0, 0, 7*intervalWeeks) {
+		if !until.IsZero() && date.After(until) {
+			break
+		}
+
+		if len(dates) == quoteSeriesMaxOccurrences {
+			return nil, util.ErrCheck(util.UserError(fmt.Sprintf("A series may have at most %d appointments.", quoteSeriesMaxOccurrences)))
+		}
+
+		dates = append(dates, date.Format(quoteSeriesDateFormat))
+	}
+
+	return dates, nil
+}
+
+// Lists the dates on which a slot can't be requested, because they're booked, excluded, or
+// after the end of the slot's schedule
+func getUnavailableSlotDates(info ReqInfo, scheduleBracketSlotId string, dates []string) ([]string, error) {
+	rows, err := info.Tx.Query(info.Ctx, `
+		SELECT TO_CHAR(d, 'YYYY-MM-DD')
+		FROM UNNEST($2::DATE[]) d
+		JOIN dbtable_schema.schedule_bracket_slots sbs ON sbs.id = $1
+		JOIN dbtable_schema.schedule_brackets sb ON sb.id = sbs.schedule_bracket_id
+		JOIN dbtable_schema.schedules s ON s.id = sb.schedule_id
+		WHERE dbfunc_schema.is_slot_taken($1, d) OR d > COALESCE(s.end_date::DATE, 'infinity')
+		ORDER BY d
+	`, scheduleBracketSlotId, pq.Array(dates))
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	unavailable, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return unavailable, nil
+}
+
+func (h *Handlers) PostQuoteSeries(info ReqInfo, data *types.PostQuoteSeriesRequest) (*types.PostQuoteSeriesResponse, error) {
+	userSub := info.Session.GetUserSub()
+	groupId := info.Session.GetGroupId()
+
+	err := checkGroupStanding(info)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	dates, err := quoteSeriesDates(data.GetSlotDate(), data.GetRecurrence())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	// Slots are positioned within a week, so only weekly schedules have the same slot each week
+	var slotCreatedSub, scheduleTimeUnitName string
+	err = info.Tx.QueryRow(info.Ctx, `
+		SELECT sbs.created_sub, tu.name
+		FROM dbtable_schema.schedule_bracket_slots sbs
+		JOIN dbtable_schema.schedule_brackets sb ON sb.id = sbs.schedule_bracket_id
+		JOIN dbtable_schema.schedules s ON s.id = sb.schedule_id
+		JOIN dbtable_schema.time_units tu ON tu.id = s.schedule_time_unit_id
+		WHERE sbs.id = $1 AND sbs.enabled = true
+	`, data.GetScheduleBracketSlotId()).Scan(&slotCreatedSub, &scheduleTimeUnitName)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	if scheduleTimeUnitName != "week" {
+		return nil, util.ErrCheck(util.UserError("Recurring appointments are only available on weekly schedules."))
+	}
+
+	unavailable, err := getUnavailableSlotDates(info, data.GetScheduleBracketSlotId(), dates)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	if len(unavailable) > 0 {
+		return nil, util.ErrCheck(util.UserError("The selected time is unavailable on " + strings.Join(unavailable, ", ") + ". Please adjust the series."))
+	}
+
+	var seriesId string
+	err = info.Tx.QueryRow(info.Ctx, `
+		INSERT INTO dbtable_schema.quote_series (group_id, schedule_bracket_slot_id, service_tier_id, start_date, interval_weeks, occurrences, until_date, slot_created_sub, created_sub)
+		VALUES ($1::uuid, $2::uuid, $3::uuid, $4::date, $5, $6, NULLIF($7, '')::date, $8::uuid, $9::uuid)
+		RETURNING id
+	`, groupId, data.GetScheduleBracketSlotId(), data.GetServiceTierId(), data.GetSlotDate(), data.GetRecurrence().GetIntervalWeeks(), len(dates), data.GetRecurrence().GetUntilDate(), slotCreatedSub, userSub).Scan(&seriesId)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	// Every occurrence is charged the price of the series when it was requested
+	price, priceJson, err := priceQuote(info, data.GetServiceTierId(), data.GetScheduleBracketSlotId())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	quotes := make([]*types.IQuote, 0, len(dates))
+	quoteIds := make([]string, 0, len(dates))
+
+	for _, date := range dates {
+		var quoteId string
+		err = info.Tx.QueryRow(info.Ctx, `
+			INSERT INTO dbtable_schema.quotes (slot_date, schedule_bracket_slot_id, service_tier_id, created_sub, group_id, slot_created_sub, series_id, price)
+			VALUES ($1::date, $2::uuid, $3::uuid, $4::uuid, $5::uuid, $6::uuid, $7::uuid, $8::jsonb)
+			RETURNING id
+		`, date, data.GetScheduleBracketSlotId(), data.GetServiceTierId(), userSub, groupId, slotCreatedSub, seriesId, priceJson).Scan(&quoteId)
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+
+		err = queueGroupWebhookEvent(info, groupId, GROUP_WEBHOOK_QUOTE_CREATED, groupWebhookQuote{
+			QuoteId:               quoteId,
+			SeriesId:              seriesId,
+			SlotDate:              date,
+			ScheduleBracketSlotId: data.GetScheduleBracketSlotId(),
+			ServiceTierId:         data.GetServiceTierId(),
+			UserSub:               userSub,
+		})
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+
+		quoteIds = append(quoteIds, quoteId)
+		quotes = append(quotes, &types.IQuote{
+			Id:                    quoteId,
+			SlotDate:              date,
+			ScheduleBracketSlotId: data.GetScheduleBracketSlotId(),
+			SeriesId:              &seriesId,
+			Price:                 price,
+		})
+	}
+
+	err = h.postQuoteIntake(info, quoteIds, data.GetServiceTierId(), data.GetServiceFormVersionSubmissions(), data.GetTierFormVersionSubmissions(), data.GetFiles())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	// One request for the whole series, staff see each occurrence in the app
+	err = h.queueQuoteNotifications(info, NOTIFY_QUOTE_CREATED, quoteIds[:1], nil)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	if err := h.Socket.RoleCall(slotCreatedSub); err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return &types.PostQuoteSeriesResponse{SeriesId: seriesId, Quotes: quotes}, nil
+}
+
+// Adds the series' remaining requests to the bookings being approved
+func addSeriesBookings(info ReqInfo, seriesId string, bookings []*types.IBooking) ([]*types.IBooking, error) {
+	rows, err := info.Tx.Query(info.Ctx, `
+		SELECT q.id, TO_CHAR(q.slot_date, 'YYYY-MM-DD') as "slotDate", q.schedule_bracket_slot_id as "scheduleBracketSlotId", q.series_id as "seriesId"
+		FROM dbtable_schema.quotes q
+		LEFT JOIN dbtable_schema.bookings b ON b.quote_id = q.id
+		WHERE q.series_id = $1 AND q.enabled = true AND b.id IS NULL AND q.slot_date >= CURRENT_DATE
+		ORDER BY q.slot_date
+	`, seriesId)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	seriesQuotes, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByNameLax[types.IQuote])
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	if len(seriesQuotes) == 0 {
+		return nil, util.ErrCheck(util.UserError("The series has no requests left to approve."))
+	}
+
+	dates := make([]string, 0, len(seriesQuotes))
+	for _, quote := range seriesQuotes {
+		dates = append(dates, quote.GetSlotDate())
+
+		alreadyAdded := slices.ContainsFunc(bookings, func(booking *types.IBooking) bool {
+			return booking.GetQuote().GetId() == quote.GetId()
+		})
+		if !alreadyAdded {
+			bookings = append(bookings, &types.IBooking{Quote: quote})
+		}
+	}
+
+	unavailable, err := getUnavailableSlotDates(info, seriesQuotes[0].GetScheduleBracketSlotId(), dates)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	if len(unavailable) > 0 {
+		return nil, util.ErrCheck(util.UserError("The series can't be approved, the time is unavailable on " + strings.Join(unavailable, ", ") + ". Cancel those dates, then try again."))
+	}
+
+	return bookings, nil
+}
+
+// DisableQuoteSeries cancels one occurrence of a series, or it and every later occurrence.
+// Requesters can only cancel occurrences which haven't been booked, staff can cancel either.
+func (h *Handlers) DisableQuoteSeries(info ReqInfo, data *types.DisableQuoteSeriesRequest) (*types.DisableQuoteSeriesResponse, error) {
+	userSub := info.Session.GetUserSub()
+
+	var seriesId *string
+	var slotDate time.Time
+	var quoteCreatedSub, slotCreatedSub string
+	err := info.Tx.QueryRow(info.Ctx, `
+		SELECT series_id, slot_date, created_sub, slot_created_sub
+		FROM dbtable_schema.quotes
+		WHERE id = $1 AND enabled = true AND (created_sub = $2 OR slot_created_sub = $2)
+	`, data.GetQuoteId(), userSub).Scan(&seriesId, &slotDate, &quoteCreatedSub, &slotCreatedSub)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, util.ErrCheck(util.UserError("The appointment could not be found."))
+		}
+		return nil, util.ErrCheck(err)
+	}
+
+	following := data.GetScope() == types.QuoteSeriesScope_SERIES_THIS_AND_FOLLOWING && seriesId != nil
+
+	rows, err := info.Tx.Query(info.Ctx, `
+		UPDATE dbtable_schema.quotes q
+		SET enabled = false, updated_on = $5, updated_sub = $4
+		WHERE q.enabled = true
+			AND (q.id = $1 OR ($6 AND q.series_id = $2 AND q.slot_date >= $3))
+			AND (q.slot_created_sub = $4 OR NOT EXISTS (
+				SELECT 1 FROM dbtable_schema.bookings b WHERE b.quote_id = q.id AND b.enabled = true
+			))
+		RETURNING q.id
+	`, data.GetQuoteId(), seriesId, slotDate, userSub, time.Now(), following)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	quoteIds, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	if len(quoteIds) == 0 {
+		return nil, util.ErrCheck(util.UserError("Booked appointments can only be cancelled by staff."))
+	}
+
+	err = h.queueQuoteNotifications(info, NOTIFY_QUOTE_CANCELLED, quoteIds, nil)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	// Bookings can only be changed by the staff member who made them
+	var bookingIds []string
+	if userSub == slotCreatedSub {
+		rows, err := info.Tx.Query(info.Ctx, `
+			UPDATE dbtable_schema.bookings
+			SET enabled = false, updated_on = $2, updated_sub = $3
+			WHERE quote_id = ANY($1::uuid[]) AND enabled = true
+			RETURNING id
+		`, pq.Array(quoteIds), time.Now(), userSub)
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+
+		bookingIds, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+
+		for _, bookingId := range bookingIds {
+			err = queueGroupWebhookEvent(info, info.Session.GetGroupId(), GROUP_WEBHOOK_BOOKING_CANCELLED, groupWebhookBooking{
+				BookingId: bookingId,
+				UserSub:   userSub,
+			})
+			if err != nil {
+				return nil, util.ErrCheck(err)
+			}
+		}
+	}
+
+	if seriesId != nil {
+		_, err = info.Tx.Exec(info.Ctx, `
+			UPDATE dbtable_schema.quote_series qs
+			SET enabled = false, updated_on = $2, updated_sub = $3
+			WHERE qs.id = $1 AND NOT EXISTS (
+				SELECT 1 FROM dbtable_schema.quotes q WHERE q.series_id = qs.id AND q.enabled = true
+			)
+		`, *seriesId, time.Now(), userSub)
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+	}
+
+	h.Redis.Client().Del(info.Ctx, quoteCreatedSub+"/api/v1/profile/details")
+
+	for _, sub := range []string{quoteCreatedSub, slotCreatedSub} {
+		if sub != userSub {
+			if err := h.Socket.RoleCall(sub); err != nil {
+				return nil, util.ErrCheck(err)
+			}
+		}
+	}
+
+	return &types.DisableQuoteSeriesResponse{Quotes: int32(len(quoteIds)), Bookings: int32(len(bookingIds))}, nil
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+)
+
+func Test_quoteSeriesDates(t *testing.T) {
+	tests := []struct {
+		name       string
+		slotDate   string
+		recurrence *types.IQuoteRecurrence
+		want       []string
+		wantErr    bool
+	}{
+		{
+			name:       "weekly by count",
+			slotDate:   "2025-03-03",
+			recurrence: &types.IQuoteRecurrence{IntervalWeeks: 1, Count: 3},
+			want:       []string{"2025-03-03", "2025-03-10", "2025-03-17"},
+		},
+		{
+			name:       "biweekly until a date",
+			slotDate:   "2025-03-03",
+			recurrence: &types.IQuoteRecurrence{IntervalWeeks: 2, UntilDate: "2025-04-01"},
+			want:       []string{"2025-03-03", "2025-03-17", "2025-03-31"},
+		},
+		{
+			name:       "count ends before the until date",
+			slotDate:   "2025-03-03",
+			recurrence: &types.IQuoteRecurrence{IntervalWeeks: 1, Count: 2, UntilDate: "2025-06-01"},
+			want:       []string{"2025-03-03", "2025-03-10"},
+		},
+		{
+			name:       "until date on the first appointment",
+			slotDate:   "2025-03-03",
+			recurrence: &types.IQuoteRecurrence{IntervalWeeks: 1, UntilDate: "2025-03-03"},
+			want:       []string{"2025-03-03"},
+		},
+		{
+			name:       "crosses daylight saving and year end",
+			slotDate:   "2025-12-22",
+			recurrence: &types.IQuoteRecurrence{IntervalWeeks: 1, Count: 3},
+			want:       []string{"2025-12-22", "2025-12-29", "2026-01-05"},
+		},
+		{name: "no end", slotDate: "2025-03-03", recurrence: &types.IQuoteRecurrence{IntervalWeeks: 1}, wantErr: true},
+		{name: "no interval", slotDate: "2025-03-03", recurrence: &types.IQuoteRecurrence{Count: 3}, wantErr: true},
+		{name: "interval too long", slotDate: "2025-03-03", recurrence: &types.IQuoteRecurrence{IntervalWeeks: quoteSeriesMaxIntervalWeeks + 1, Count: 3}, wantErr: true},
+		{name: "too many by count", slotDate: "2025-03-03", recurrence: &types.IQuoteRecurrence{IntervalWeeks: 1, Count: quoteSeriesMaxOccurrences + 1}, wantErr: true},
+		{name: "too many by until date", slotDate: "2025-03-03", recurrence: &types.IQuoteRecurrence{IntervalWeeks: 1, UntilDate: "2026-03-03"}, wantErr: true},
+		{name: "until before start", slotDate: "2025-03-03", recurrence: &types.IQuoteRecurrence{IntervalWeeks: 1, UntilDate: "2025-03-01"}, wantErr: true},
+		{name: "bad slot date", slotDate: "03/03/2025", recurrence: &types.IQuoteRecurrence{IntervalWeeks: 1, Count: 3}, wantErr: true},
+		{name: "no recurrence", slotDate: "2025-03-03", recurrence: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := quoteSeriesDates(tt.slotDate, tt.recurrence)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("quoteSeriesDates(%v, %v) error = %v, wantErr %v", tt.slotDate, tt.recurrence, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("quoteSeriesDates(%v, %v) = %v, want %v", tt.slotDate, tt.recurrence, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlers_PostQuoteSeries(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.PostQuoteSeriesRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.PostQuoteSeriesResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.PostQuoteSeries(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.PostQuoteSeries(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.PostQuoteSeries(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlers_DisableQuoteSeries(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.DisableQuoteSeriesRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.DisableQuoteSeriesResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.DisableQuoteSeries(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.DisableQuoteSeries(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.DisableQuoteSeries(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
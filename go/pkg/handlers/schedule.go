@@ -52,9 +52,13 @@ var (
 	startDateRequiredWithEndDate = util.UserError("A start date must be provided when using end date.")
 	endDateMustBeAfterStartDate  = util.UserError("End time must be after start time.")
 	onlyOneMasterScheduleError   = util.UserError("You can only join a master schedule once. Instead, edit that schedule, then add another bracket to it.")
+	exclusionTargetRequiredError = util.UserError("A schedule, bracket or slot must be provided to exclude.")
+	exclusionRangeTooLongError   = util.UserError("Exclusions can cover at most one year at a time.")
 	zeroTime                     time.Time
 )
 
+const maxExclusionDays = 366
+
 func parseScheduleDateRange(start, end string) (*time.Time, *time.Time, error) {
 	var err error
 
@@ -294,6 +298,108 @@ func (h *Handlers) DisableSchedule(info ReqInfo, data *types.DisableScheduleRequ
 	return &types.DisableScheduleResponse{Success: true}, nil
 }
 
+// PostScheduleBracketSlotExclusions blocks out the occurrences of a slot, of every slot in a bracket, or of
+// every slot in a user schedule between the start and end dates. Only the creator's own schedules are affected.
+// Quotes already made for an excluded slot and date are surfaced through group_user_schedule_stubs, the same
+// as quotes on slots disabled by schedule edits, so that admins can reschedule them.
+func (h *Handlers) PostScheduleBracketSlotExclusions(info ReqInfo, data *types.PostScheduleBracketSlotExclusionsRequest) (*types.PostScheduleBracketSlotExclusionsResponse, error) {
+	if data.GetUserScheduleId() == "" && data.GetScheduleBracketId() == "" && data.GetScheduleBracketSlotId() == "" {
+		return nil, util.ErrCheck(exclusionTargetRequiredError)
+	}
+
+	startDate, err := time.Parse(time.DateOnly, data.GetStartDate())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	endDate := startDate
+	if data.GetEndDate() != "" {
+		endDate, err = time.Parse(time.DateOnly, data.GetEndDate())
+		if err != nil {
+			return nil, util.ErrCheck(err)
+		}
+	}
+
+	if endDate.Before(startDate) {
+		return nil, util.ErrCheck(endDateMustBeAfterStartDate)
+	}
+
+	if endDate.Sub(startDate) > maxExclusionDays*24*time.Hour {
+		return nil, util.ErrCheck(exclusionRangeTooLongError)
+	}
+
+	// Slot start times are offsets from the start of the week, or from the start of the 28 day cycle
+	// of the group schedule, so each day in the range is checked for an occurrence of the slot
+	rows, err := info.Tx.Query(info.Ctx, `
+		INSERT INTO dbtable_schema.schedule_bracket_slot_exclusions (group_id, exclusion_date, schedule_bracket_slot_id, created_sub)
+		SELECT DISTINCT $1::uuid, occurrence.exclusion_date, slot.id, $2::uuid
+		FROM dbtable_schema.schedule_bracket_slots slot
+		JOIN dbtable_schema.schedule_brackets bracket ON bracket.id = slot.schedule_bracket_id
+		JOIN dbtable_schema.schedules schedule ON schedule.id = bracket.schedule_id
+		JOIN dbtable_schema.group_user_schedules gus ON gus.user_schedule_id = schedule.id
+		JOIN dbtable_schema.schedules group_schedule ON group_schedule.id = gus.group_schedule_id
+		JOIN dbtable_schema.time_units tu ON tu.id = group_schedule.schedule_time_unit_id
+		CROSS JOIN generate_series($3::DATE, $4::DATE, INTERVAL '1 day') AS day
+		CROSS JOIN LATERAL (
+			SELECT dbfunc_schema.slot_occurrence_start(day::DATE, slot.start_time, tu.name, group_schedule.start_date::DATE)::DATE AS exclusion_date
+		) occurrence
+		WHERE
+			slot.enabled = true
+			AND schedule.created_sub = $2::uuid
+			AND occurrence.exclusion_date = day::DATE
+			AND ($5::uuid IS NULL OR schedule.id = $5::uuid)
+			AND ($6::uuid IS NULL OR bracket.id = $6::uuid)
+			AND ($7::uuid IS NULL OR slot.id = $7::uuid)
+		ON CONFLICT (schedule_bracket_slot_id, exclusion_date) WHERE enabled = true DO NOTHING
+		RETURNING id, TO_CHAR(exclusion_date, 'YYYY-MM-DD') as "exclusionDate", schedule_bracket_slot_id as "scheduleBracketSlotId"
+	`, info.Session.GetGroupId(), info.Session.GetUserSub(), startDate, endDate,
+		util.NewNullString(data.GetUserScheduleId()),
+		util.NewNullString(data.GetScheduleBracketId()),
+		util.NewNullString(data.GetScheduleBracketSlotId()),
+	)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	exclusions, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByNameLax[types.IScheduleBracketSlotExclusion])
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return &types.PostScheduleBracketSlotExclusionsResponse{Exclusions: exclusions}, nil
+}
+
+func (h *Handlers) GetScheduleBracketSlotExclusions(info ReqInfo, data *types.GetScheduleBracketSlotExclusionsRequest) (*types.GetScheduleBracketSlotExclusionsResponse, error) {
+	exclusions := util.BatchQuery[types.IScheduleBracketSlotExclusion](info.Batch, `
+		SELECT
+			exclusion.id,
+			TO_CHAR(exclusion.exclusion_date, 'YYYY-MM-DD') as "exclusionDate",
+			exclusion.schedule_bracket_slot_id as "scheduleBracketSlotId",
+			slot.schedule_bracket_id as "scheduleBracketId",
+			slot.start_time::TEXT as "startTime"
+		FROM dbtable_schema.schedule_bracket_slot_exclusions exclusion
+		JOIN dbtable_schema.schedule_bracket_slots slot ON slot.id = exclusion.schedule_bracket_slot_id
+		JOIN dbtable_schema.schedule_brackets bracket ON bracket.id = slot.schedule_bracket_id
+		WHERE bracket.schedule_id = $1 AND exclusion.created_sub = $2 AND exclusion.enabled = true
+		ORDER BY exclusion.exclusion_date, slot.start_time
+	`, data.GetUserScheduleId(), info.Session.GetUserSub())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.GetScheduleBracketSlotExclusionsResponse{Exclusions: *exclusions}, nil
+}
+
+func (h *Handlers) DeleteScheduleBracketSlotExclusions(info ReqInfo, data *types.DeleteScheduleBracketSlotExclusionsRequest) (*types.DeleteScheduleBracketSlotExclusionsResponse, error) {
+	util.BatchExec(info.Batch, `
+		DELETE FROM dbtable_schema.schedule_bracket_slot_exclusions
+		WHERE id = ANY($1::uuid[]) AND created_sub = $2
+	`, pq.Array(strings.Split(data.GetIds(), ",")), info.Session.GetUserSub())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.DeleteScheduleBracketSlotExclusionsResponse{Success: true}, nil
+}
+
 func (h *Handlers) HandleExistingBrackets(ctx context.Context, existingBracketIds []string, brackets map[string]*types.IScheduleBracket, info ReqInfo) error {
 
 	// Step 1. Get all existing slots and services ids
@@ -204,6 +204,113 @@ func TestHandlers_DisableSchedule(t *testing.T) {
 	}
 }
 
+func TestHandlers_PostScheduleBracketSlotExclusions(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.PostScheduleBracketSlotExclusionsRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.PostScheduleBracketSlotExclusionsResponse
+		wantErr bool
+	}{
+		{
+			name:    "requires a schedule, bracket or slot",
+			h:       &Handlers{},
+			args:    args{data: &types.PostScheduleBracketSlotExclusionsRequest{StartDate: "2025-03-11"}},
+			wantErr: true,
+		},
+		{
+			name:    "rejects an invalid start date",
+			h:       &Handlers{},
+			args:    args{data: &types.PostScheduleBracketSlotExclusionsRequest{ScheduleBracketSlotId: "3b5a7bd2-5d43-4a67-9e3a-2f0b2c63b8c0", StartDate: "03-11-2025"}},
+			wantErr: true,
+		},
+		{
+			name:    "rejects an end date before the start date",
+			h:       &Handlers{},
+			args:    args{data: &types.PostScheduleBracketSlotExclusionsRequest{ScheduleBracketId: "3b5a7bd2-5d43-4a67-9e3a-2f0b2c63b8c0", StartDate: "2025-03-11", EndDate: "2025-03-10"}},
+			wantErr: true,
+		},
+		{
+			name:    "rejects ranges longer than a year",
+			h:       &Handlers{},
+			args:    args{data: &types.PostScheduleBracketSlotExclusionsRequest{UserScheduleId: "3b5a7bd2-5d43-4a67-9e3a-2f0b2c63b8c0", StartDate: "2025-03-11", EndDate: "2026-06-11"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.PostScheduleBracketSlotExclusions(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.PostScheduleBracketSlotExclusions(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.PostScheduleBracketSlotExclusions(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlers_GetScheduleBracketSlotExclusions(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.GetScheduleBracketSlotExclusionsRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.GetScheduleBracketSlotExclusionsResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.GetScheduleBracketSlotExclusions(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.GetScheduleBracketSlotExclusions(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.GetScheduleBracketSlotExclusions(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlers_DeleteScheduleBracketSlotExclusions(t *testing.T) {
+	type args struct {
+		info ReqInfo
+		data *types.DeleteScheduleBracketSlotExclusionsRequest
+	}
+	tests := []struct {
+		name    string
+		h       *Handlers
+		args    args
+		want    *types.DeleteScheduleBracketSlotExclusionsResponse
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.h.DeleteScheduleBracketSlotExclusions(tt.args.info, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Handlers.DeleteScheduleBracketSlotExclusions(%v, %v) error = %v, wantErr %v", tt.args.info, tt.args.data, err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handlers.DeleteScheduleBracketSlotExclusions(%v, %v) = %v, want %v", tt.args.info, tt.args.data, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestHandlers_HandleExistingBrackets(t *testing.T) {
 	type args struct {
 		ctx                context.Context
@@ -76,9 +76,9 @@ func (h *Handlers) PatchService(info ReqInfo, data *types.PatchServiceRequest) (
 
 			err = info.Tx.QueryRow(info.Ctx, `
 				INSERT INTO dbtable_schema.service_tiers (name, service_id, multiplier, created_sub)
-				VALUES ($1, $2::uuid, $3::decimal, $4::uuid)
+				VALUES ($1, $2::uuid, $3::integer, $4::uuid)
 				ON CONFLICT (name, service_id) DO UPDATE
-				SET enabled = true, multiplier = $3::decimal, updated_sub = $4::uuid, updated_on = $5
+				SET enabled = true, multiplier = $3::integer, updated_sub = $4::uuid, updated_on = $5
 				RETURNING id
 			`, tier.GetName(), serviceId, tier.GetMultiplier(), userSub, time.Now()).Scan(&tierId)
 			if err != nil {
@@ -105,11 +105,11 @@ func (h *Handlers) PatchService(info ReqInfo, data *types.PatchServiceRequest) (
 		insertedTierAddonIds := make([]string, 0)
 		for _, addon := range tier.GetAddons() {
 			_, err = info.Tx.Exec(info.Ctx, `
-				INSERT INTO dbtable_schema.service_tier_addons (service_addon_id, service_tier_id, created_sub)
-				VALUES ($1, $2, $3::uuid)
+				INSERT INTO dbtable_schema.service_tier_addons (service_addon_id, service_tier_id, created_sub, cost)
+				VALUES ($1, $2, $3::uuid, $4::integer)
 				ON CONFLICT (service_addon_id, service_tier_id) DO UPDATE
-				SET enabled = true
-			`, addon.GetId(), tierId, userSub)
+				SET enabled = true, cost = $4::integer
+			`, addon.GetId(), tierId, userSub, addon.Cost)
 			if err != nil {
 				return nil, util.ErrCheck(err)
 			}
@@ -48,6 +48,10 @@ func (h *Handlers) GenerateLoginOrRegisterParams(req *http.Request) string {
 		params.Set("groupCode", groupCode)
 	}
 
+	if invite := req.URL.Query().Get("invite"); invite != "" {
+		params.Set("invite", invite)
+	}
+
 	return "?" + params.Encode()
 }
 
@@ -75,7 +79,7 @@ func (h *Handlers) GetCachedGroups(ctx context.Context, subGroupPath string) (cc
 
 		group := *groupReq
 
-		kcSubGroups, err := h.Keycloak.GetGroupSubGroups(ctx, "worker", group.ExternalId)
+		kcSubGroups, err := h.Identity.GetGroupSubGroups(ctx, "worker", group.ExternalId)
 		if err != nil {
 			return nil, err
 		}
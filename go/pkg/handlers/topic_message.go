@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+func (h *Handlers) GetBookingMessageReceipts(info ReqInfo, data *types.GetBookingMessageReceiptsRequest) (*types.GetBookingMessageReceiptsResponse, error) {
+	// everyone who has written or read on one of the booking's exchange topics
+	receipts := util.BatchQuery[types.ITopicMessageReceipt](info.Batch, `
+		WITH participants AS (
+			SELECT topic, created_sub FROM dbtable_schema.topic_messages WHERE booking_id = $1
+			UNION
+			SELECT topic, created_sub FROM dbtable_schema.topic_message_reads WHERE booking_id = $1
+		)
+		SELECT
+			p.topic,
+			p.created_sub::TEXT as "userSub",
+			COALESCE(r.last_read_id::TEXT, '') as "lastReadId",
+			COALESCE(COALESCE(r.updated_on, r.created_on)::TEXT, '') as "lastReadOn",
+			(
+				SELECT COUNT(*)
+				FROM dbtable_schema.topic_messages tm
+				WHERE tm.topic = p.topic AND tm.enabled = true AND tm.created_sub <> p.created_sub
+					AND tm.created_on > COALESCE(lr.created_on, '-infinity'::TIMESTAMP)
+			)::INTEGER as "unreadCount"
+		FROM participants p
+		LEFT JOIN dbtable_schema.topic_message_reads r ON r.topic = p.topic AND r.created_sub = p.created_sub
+		LEFT JOIN dbtable_schema.topic_messages lr ON lr.id = r.last_read_id
+		ORDER BY p.topic, p.created_sub
+	`, data.GetBookingId())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.GetBookingMessageReceiptsResponse{Receipts: *receipts}, nil
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+func (h *Handlers) PostUserNote(info ReqInfo, data *types.PostUserNoteRequest) (*types.PostUserNoteResponse, error) {
+	userSub := info.Session.GetUserSub()
+	userNote := data.GetUserNote()
+
+	var isGroupUser bool
+	err := info.Tx.QueryRow(info.Ctx, `
+		SELECT EXISTS(
+			SELECT 1
+			FROM dbtable_schema.group_users gu
+			JOIN dbtable_schema.users u ON u.id = gu.user_id
+			WHERE u.sub = $1::uuid AND gu.group_id = $2::uuid
+		)
+	`, userNote.GetUserSub(), info.Session.GetGroupId()).Scan(&isGroupUser)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	if !isGroupUser {
+		return nil, util.ErrCheck(util.UserError("Notes can only be made about users in the group."))
+	}
+
+	var userNoteId string
+	err = info.Tx.QueryRow(info.Ctx, `
+		INSERT INTO dbtable_schema.user_notes (group_id, user_sub, name, created_sub)
+		VALUES ($1::uuid, $2::uuid, $3, $4::uuid)
+		RETURNING id
+	`, info.Session.GetGroupId(), userNote.GetUserSub(), userNote.GetName(), userSub).Scan(&userNoteId)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	formJson, err := userNote.GetVersion().GetForm().MarshalJSON()
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	submissionJson, err := userNote.GetVersion().GetSubmission().MarshalJSON()
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	_, err = info.Tx.Exec(info.Ctx, `
+		INSERT INTO dbtable_schema.user_note_versions (user_note_id, form, submission, created_sub)
+		VALUES ($1::uuid, $2::jsonb, $3::jsonb, $4::uuid)
+	`, userNoteId, formJson, submissionJson, userSub)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return &types.PostUserNoteResponse{Id: userNoteId}, nil
+}
+
+func (h *Handlers) PostUserNoteVersion(info ReqInfo, data *types.PostUserNoteVersionRequest) (*types.PostUserNoteVersionResponse, error) {
+	userSub := info.Session.GetUserSub()
+
+	formJson, err := data.GetVersion().GetForm().MarshalJSON()
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	submissionJson, err := data.GetVersion().GetSubmission().MarshalJSON()
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	// The select is subject to the user_notes RLS, so only the creator or a group admin can add versions
+	var versionId string
+	err = info.Tx.QueryRow(info.Ctx, `
+		INSERT INTO dbtable_schema.user_note_versions (user_note_id, form, submission, created_sub)
+		SELECT un.id, $2::jsonb, $3::jsonb, $4::uuid
+		FROM dbtable_schema.user_notes un
+		WHERE un.id = $1::uuid AND un.enabled = true
+		RETURNING id
+	`, data.GetUserNoteId(), formJson, submissionJson, userSub).Scan(&versionId)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	_, err = info.Tx.Exec(info.Ctx, `
+		UPDATE dbtable_schema.user_notes
+		SET name = $1, updated_on = $2, updated_sub = $3
+		WHERE id = $4
+	`, data.GetName(), time.Now(), userSub, data.GetUserNoteId())
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return &types.PostUserNoteVersionResponse{Id: versionId}, nil
+}
+
+func (h *Handlers) PatchUserNote(info ReqInfo, data *types.PatchUserNoteRequest) (*types.PatchUserNoteResponse, error) {
+	patched := util.BatchExec(info.Batch, `
+		UPDATE dbtable_schema.user_notes
+		SET name = $1, updated_on = $2, updated_sub = $3
+		WHERE id = $4
+	`, data.GetName(), time.Now(), info.Session.GetUserSub(), data.GetId())
+
+	info.Batch.Send(info.Ctx)
+
+	if (*patched).RowsAffected() == 0 {
+		return nil, util.ErrCheck(util.UserError("User note not found."))
+	}
+
+	return &types.PatchUserNoteResponse{Success: true}, nil
+}
+
+func (h *Handlers) GetUserNotes(info ReqInfo, data *types.GetUserNotesRequest) (*types.GetUserNotesResponse, error) {
+	userNotes := util.BatchQuery[types.IProtoUserNote](info.Batch, `
+		SELECT id, name, version, "userSub", "createdSub", "createdOn"
+		FROM dbview_schema.enabled_user_notes
+		WHERE "userSub" = $1
+		ORDER BY "createdOn" DESC
+	`, data.GetUserSub())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.GetUserNotesResponse{UserNotes: *userNotes}, nil
+}
+
+func (h *Handlers) GetUserNoteById(info ReqInfo, data *types.GetUserNoteByIdRequest) (*types.GetUserNoteByIdResponse, error) {
+	userNote := util.BatchQueryRow[types.IProtoUserNote](info.Batch, `
+		SELECT id, name, version, "userSub", "createdSub", "createdOn"
+		FROM dbview_schema.enabled_user_notes
+		WHERE id = $1
+	`, data.GetId())
+
+	versions := util.BatchQuery[types.IProtoUserNoteVersion](info.Batch, `
+		SELECT id, "userNoteId", form, submission, "createdSub", "createdOn"
+		FROM dbview_schema.enabled_user_note_versions
+		WHERE "userNoteId" = $1
+		ORDER BY "createdOn" DESC
+	`, data.GetId())
+
+	info.Batch.Send(info.Ctx)
+
+	return &types.GetUserNoteByIdResponse{UserNote: *userNote, Versions: *versions}, nil
+}
+
+func (h *Handlers) DeleteUserNote(info ReqInfo, data *types.DeleteUserNoteRequest) (*types.DeleteUserNoteResponse, error) {
+	deleted := util.BatchExec(info.Batch, `
+		DELETE FROM dbtable_schema.user_notes
+		WHERE id = $1
+	`, data.GetId())
+
+	info.Batch.Send(info.Ctx)
+
+	if (*deleted).RowsAffected() == 0 {
+		return nil, util.ErrCheck(util.UserError("User note not found."))
+	}
+
+	return &types.DeleteUserNoteResponse{Success: true}, nil
+}
+
+func (h *Handlers) DisableUserNote(info ReqInfo, data *types.DisableUserNoteRequest) (*types.DisableUserNoteResponse, error) {
+	disabled := util.BatchExec(info.Batch, `
+		UPDATE dbtable_schema.user_notes
+		SET enabled = false, updated_on = $2, updated_sub = $3
+		WHERE id = $1
+	`, data.GetId(), time.Now(), info.Session.GetUserSub())
+
+	info.Batch.Send(info.Ctx)
+
+	if (*disabled).RowsAffected() == 0 {
+		return nil, util.ErrCheck(util.UserError("User note not found."))
+	}
+
+	return &types.DisableUserNoteResponse{Success: true}, nil
+}
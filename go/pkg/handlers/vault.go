@@ -6,8 +6,10 @@ import (
 )
 
 func (h *Handlers) GetVaultKey(info ReqInfo, data *types.GetVaultKeyRequest) (*types.GetVaultKeyResponse, error) {
+	vaultKey := crypto.VaultKey.Current()
 	return &types.GetVaultKeyResponse{
-		Key: crypto.EncodedVaultKey,
-		Sid: info.Session.GetId(),
+		Key:   vaultKey.Encoded,
+		Sid:   info.Session.GetId(),
+		KeyId: vaultKey.Id,
 	}, nil
 }
@@ -17,6 +17,34 @@ import (
 )
 
 func (tus *TestUsersStruct) RegisterKeycloakUserViaForm(code ...string) error {
+	formData := url.Values{}
+	if len(code) > 0 {
+		formData.Set("groupCode", code[0])
+	}
+
+	_, err := tus.registerKeycloakUser(nil, formData)
+	return err
+}
+
+// Invites are read by keycloak from the registration url, then carried through the form
+func (tus *TestUsersStruct) RegisterKeycloakUserViaInvite(invite string) error {
+	formData := url.Values{}
+	formData.Set("invite", invite)
+
+	finalURL, err := tus.registerKeycloakUser(url.Values{"invite": {invite}}, formData)
+	if err != nil {
+		return err
+	}
+
+	// keycloak re-renders the form with a 200 when validation fails
+	if !strings.HasPrefix(finalURL, util.E_APP_HOST_URL+"/app") {
+		return util.ErrCheck(errors.New("registration with invite was not accepted"))
+	}
+
+	return nil
+}
+
+func (tus *TestUsersStruct) registerKeycloakUser(registrationParams, formData url.Values) (string, error) {
 	jar, _ := cookiejar.New(nil)
 	client := &http.Client{
 		Jar: jar,
@@ -38,36 +66,34 @@ func (tus *TestUsersStruct) RegisterKeycloakUserViaForm(code ...string) error {
 		util.E_APP_HOST_URL, util.E_KC_REALM, util.E_KC_USER_CLIENT, redirectUri, state, nonce, codeChallenge,
 	)
 
+	if len(registrationParams) > 0 {
+		registrationURL += "&" + registrationParams.Encode()
+	}
+
 	resp, err := client.Get(registrationURL)
 	if err != nil {
-		return util.ErrCheck(err)
+		return "", util.ErrCheck(err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return util.ErrCheck(err)
+		return "", util.ErrCheck(err)
 	}
 
 	startTag := `<form id="kc-register-form" class="form-horizontal" action="`
 	startIdx := strings.Index(string(body), startTag)
 	if startIdx == -1 {
-		return util.ErrCheck(errors.New("couldn't find register action start"))
+		return "", util.ErrCheck(errors.New("couldn't find register action start"))
 	}
 	startIdx += len(startTag)
 	endIdx := strings.Index(string(body[startIdx:]), `"`)
 	if endIdx == -1 {
-		return util.ErrCheck(errors.New("couldn't find register action end"))
+		return "", util.ErrCheck(errors.New("couldn't find register action end"))
 	}
 	formAction := string(body[startIdx : startIdx+endIdx])
 
 	// Prepare and submit form data
-	formData := url.Values{}
-
-	if len(code) > 0 {
-		formData.Set("groupCode", code[0])
-	}
-
 	formData.Set("email", tus.GetTestEmail())
 	formData.Set("firstName", "first-name")
 	formData.Set("lastName", "last-name")
@@ -76,24 +102,24 @@ func (tus *TestUsersStruct) RegisterKeycloakUserViaForm(code ...string) error {
 
 	req, err := http.NewRequest("POST", formAction, strings.NewReader(formData.Encode()))
 	if err != nil {
-		return util.ErrCheck(err)
+		return "", util.ErrCheck(err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	submitResp, err := client.Do(req)
 	if err != nil {
-		return util.ErrCheck(err)
+		return "", util.ErrCheck(err)
 	}
 	defer submitResp.Body.Close()
 
 	// Registration success check
 	if submitResp.StatusCode != http.StatusOK {
-		return util.ErrCheck(fmt.Errorf("registration failed with code %d", submitResp.StatusCode))
+		return "", util.ErrCheck(fmt.Errorf("registration failed with code %d", submitResp.StatusCode))
 	}
 
 	println(fmt.Sprintf("Registered user %s with pass %s", tus.GetTestEmail(), tus.GetTestPass()))
 
-	return nil
+	return submitResp.Request.URL.String(), nil
 }
 
 func handlerFollowRedirects(handler *http.ServeMux, w *httptest.ResponseRecorder, req *http.Request, cookies []*http.Cookie) (*httptest.ResponseRecorder, []*http.Cookie, error) {
@@ -11,7 +11,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/keybittech/awayto-v3/go/pkg/types"
 	"github.com/keybittech/awayto-v3/go/pkg/util"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 func doAndRead(client *http.Client, req *http.Request) ([]byte, error) {
@@ -65,6 +67,21 @@ func GetTestReq(method, target string, body io.Reader) *http.Request {
 	return testReq
 }
 
+// Calendar apps fetch the feed url as is, without a session or vault headers
+func GetBookingsCalendar(feedUrl string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, feedUrl, nil)
+	if err != nil {
+		return "", util.ErrCheck(err)
+	}
+
+	body, err := doAndRead(nil, req)
+	if err != nil {
+		return "", util.ErrCheck(err)
+	}
+
+	return string(body), nil
+}
+
 func CheckServer() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancel()
@@ -81,3 +98,27 @@ func CheckServer() error {
 
 	return nil
 }
+
+func GetKioskSchedules(groupName, kioskToken string) (*types.GetGroupKioskSchedulesResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, util.E_APP_HOST_URL+"/api/kiosk/gs/"+groupName+".json", nil)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	if kioskToken != "" {
+		req.Header.Set("Authorization", "Bearer "+kioskToken)
+	}
+
+	body, err := doAndRead(nil, req)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	kioskSchedules := &types.GetGroupKioskSchedulesResponse{}
+	err = protojson.Unmarshal(body, kioskSchedules)
+	if err != nil {
+		return nil, util.ErrCheck(err)
+	}
+
+	return kioskSchedules, nil
+}
@@ -0,0 +1,54 @@
+package testutil
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+var inviteLinkRegex = regexp.MustCompile(`/app/join\?invite=(\S+)`)
+
+// Reads the newest invite sent to email by the file mail sender
+func GetInviteFromMail(email string) (string, error) {
+	entries, err := os.ReadDir(util.E_MAIL_DIR)
+	if err != nil {
+		return "", util.ErrCheck(err)
+	}
+
+	var newestName string
+	var newestTime int64
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), email+"_") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return "", util.ErrCheck(err)
+		}
+		if info.ModTime().UnixNano() > newestTime {
+			newestName = entry.Name()
+			newestTime = info.ModTime().UnixNano()
+		}
+	}
+
+	if newestName == "" {
+		return "", util.ErrCheck(errors.New("no mail sent to " + email))
+	}
+
+	mailBytes, err := os.ReadFile(filepath.Join(util.E_MAIL_DIR, newestName))
+	if err != nil {
+		return "", util.ErrCheck(err)
+	}
+
+	match := inviteLinkRegex.FindSubmatch(mailBytes)
+	if match == nil {
+		return "", util.ErrCheck(errors.New("no invite link in mail to " + email))
+	}
+
+	return url.QueryUnescape(string(match[1]))
+}
@@ -0,0 +1,56 @@
+package testutil
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/clients"
+	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+// Grants the keycloak super admin realm role, the user needs to log in again to receive it
+func (tus *TestUsersStruct) GrantSuperAdmin() error {
+	profile, err := tus.GetProfileDetails()
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	kc := clients.KeycloakClient{}
+	kc.Token, err = kc.DirectGrantAuthentication()
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	role, err := kc.GetRealmRole(util.SuperAdminRole)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return kc.MutateUserRealmRoles(http.MethodPost, profile.GetSub(), []*types.KeycloakRole{role})
+}
+
+// Delivers a seat payment event to the fake provider webhook, signed at the given time with the configured secret
+func PostFakePaymentWebhook(body []byte, signedAt time.Time) error {
+	secret, err := util.GetEnvFilePath("PAYMENT_WEBHOOK_SECRET_FILE", 128)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, util.E_APP_HOST_URL+"/api/webhooks/payments/"+clients.PAYMENT_PROVIDER_FAKE, bytes.NewReader(body))
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	fp := &clients.FakePaymentProvider{Secret: []byte(secret)}
+	req.Header.Set(fp.Sign(body, signedAt))
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = doAndRead(nil, req)
+	if err != nil {
+		return util.ErrCheck(err)
+	}
+
+	return nil
+}
@@ -26,6 +26,7 @@ type TestUsersStruct struct {
 	Client         *http.Client
 	CookieData     []http.Cookie
 	VaultKey       []byte
+	VaultKeyId     string
 	VaultSessionId string
 	*types.TestUser
 }
@@ -123,7 +124,15 @@ func (tus *TestUsersStruct) getUserClient() *http.Client {
 	}
 }
 
-func (tus *TestUsersStruct) apiRequest(method, path string, body []byte, queryParams map[string]string, responseObj proto.Message) error {
+type apiRequestData struct {
+	method, url  string
+	body         []byte
+	headers      map[string]string
+	sharedSecret []byte
+}
+
+// Builds and encrypts a request, the result can be sent more than once to replay it
+func (tus *TestUsersStruct) newAPIRequest(method, path string, body []byte, queryParams map[string]string) (*apiRequestData, error) {
 	reqURL := util.E_APP_HOST_URL + path
 
 	if len(queryParams) > 0 {
@@ -158,9 +167,11 @@ func (tus *TestUsersStruct) apiRequest(method, path string, body []byte, queryPa
 		var blob []byte
 		blob, sharedSecret, err = crypto.ClientEncrypt(tus.VaultKey, plaintext, tus.VaultSessionId)
 		if err != nil {
-			return fmt.Errorf("encryption failed: %w", err)
+			return nil, fmt.Errorf("encryption failed: %w", err)
 		}
 
+		headers["X-Awayto-Vault-Key-Id"] = tus.VaultKeyId
+
 		if isMutation {
 			reqBody = blob
 			headers["Content-Type"] = "application/x-awayto-vault"
@@ -175,12 +186,22 @@ func (tus *TestUsersStruct) apiRequest(method, path string, body []byte, queryPa
 		}
 	}
 
-	req, err := http.NewRequest(method, reqURL, bytes.NewBuffer(reqBody))
+	return &apiRequestData{
+		method:       method,
+		url:          reqURL,
+		body:         reqBody,
+		headers:      headers,
+		sharedSecret: sharedSecret,
+	}, nil
+}
+
+func (tus *TestUsersStruct) sendAPIRequest(ar *apiRequestData, responseObj proto.Message) error {
+	req, err := http.NewRequest(ar.method, ar.url, bytes.NewBuffer(ar.body))
 	if err != nil {
 		return fmt.Errorf("error creating request: %w", err)
 	}
 
-	for k, v := range headers {
+	for k, v := range ar.headers {
 		req.Header.Set(k, v)
 	}
 
@@ -190,13 +211,13 @@ func (tus *TestUsersStruct) apiRequest(method, path string, body []byte, queryPa
 		return fmt.Errorf("error sending request: %w", err)
 	}
 
-	if len(resp) > 0 && sharedSecret != nil {
+	if len(resp) > 0 && ar.sharedSecret != nil {
 		encryptedBytes, err := base64.StdEncoding.DecodeString(string(resp))
 		if err != nil {
 			return fmt.Errorf("response base64 decode error: %w", err)
 		}
 
-		decrypted, err := crypto.ClientDecrypt(encryptedBytes, sharedSecret, tus.VaultSessionId)
+		decrypted, err := crypto.ClientDecrypt(encryptedBytes, ar.sharedSecret, tus.VaultSessionId)
 		if err != nil {
 			return fmt.Errorf("decrypt response error: %w", err)
 		}
@@ -212,10 +233,34 @@ func (tus *TestUsersStruct) apiRequest(method, path string, body []byte, queryPa
 	return nil
 }
 
+func (tus *TestUsersStruct) apiRequest(method, path string, body []byte, queryParams map[string]string, responseObj proto.Message) error {
+	ar, err := tus.newAPIRequest(method, path, body, queryParams)
+	if err != nil {
+		return err
+	}
+
+	return tus.sendAPIRequest(ar, responseObj)
+}
+
 func (tus *TestUsersStruct) DoHandler(method, path string, body []byte, queryParams map[string]string, responseObj proto.Message) error {
 	return tus.apiRequest(method, path, body, queryParams, responseObj)
 }
 
+// Sends the same encrypted request twice, returning the error of the second attempt
+func (tus *TestUsersStruct) ReplayHandler(method, path string, body []byte, queryParams map[string]string, responseObj proto.Message) error {
+	ar, err := tus.newAPIRequest(method, path, body, queryParams)
+	if err != nil {
+		return err
+	}
+
+	err = tus.sendAPIRequest(ar, responseObj)
+	if err != nil {
+		return fmt.Errorf("first request failed: %w", err)
+	}
+
+	return tus.sendAPIRequest(ar, responseObj)
+}
+
 func (tus *TestUsersStruct) GetVaultKey() error {
 	vaultResp := &types.GetVaultKeyResponse{}
 	// Reuse apiRequest to ensure headers (UA, TZ) match the session
@@ -230,6 +275,7 @@ func (tus *TestUsersStruct) GetVaultKey() error {
 	}
 
 	tus.VaultKey = keyBytes
+	tus.VaultKeyId = vaultResp.KeyId
 	tus.VaultSessionId = vaultResp.Sid
 	return nil
 }
@@ -447,3 +493,24 @@ func (tus *TestUsersStruct) PostBooking(bookingRequests []*types.IBooking) ([]*t
 
 	return postBookingResponse.Bookings, nil
 }
+
+func (tus *TestUsersStruct) PatchBookingRating(bookingId string, rating int32) error {
+	patchBookingRatingBytes, err := protojson.Marshal(&types.PatchBookingRatingRequest{
+		Id:     bookingId,
+		Rating: rating,
+	})
+	if err != nil {
+		return errors.New(fmt.Sprintf("error marshalling patch booking rating request %v", err))
+	}
+
+	patchBookingRatingResponse := &types.PatchBookingRatingResponse{}
+	err = tus.apiRequest(http.MethodPatch, "/api/v1/bookings/rating", patchBookingRatingBytes, nil, patchBookingRatingResponse)
+	if err != nil {
+		return errors.New(fmt.Sprintf("error patch booking rating request error: %v", err))
+	}
+	if !patchBookingRatingResponse.Success {
+		return errors.New("patch booking rating was unsuccessful")
+	}
+
+	return nil
+}
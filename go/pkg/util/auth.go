@@ -8,6 +8,7 @@ import (
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
@@ -15,6 +16,8 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,6 +27,9 @@ import (
 
 const (
 	maxTokenBytes = 30 * 1024
+
+	// Realm role granted in keycloak to site operators, independent of any group
+	SuperAdminRole = "app_super_admin"
 )
 
 func SetSessionCookie(w http.ResponseWriter, duration int64, value string) {
@@ -79,6 +85,87 @@ func GenerateSessionId() string {
 	return base64.RawURLEncoding.EncodeToString(b)
 }
 
+// bearerTokenBytes of randomness are in each bearer token, 43 characters once encoded
+const bearerTokenBytes = 32
+
+// generateBearerToken makes a random token for urls which authorize whoever holds them
+func generateBearerToken() string {
+	b := make([]byte, bearerTokenBytes)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func GenerateKioskToken() string {
+	return generateBearerToken()
+}
+
+// IsKioskToken checks a kiosk token has the shape GenerateKioskToken gives it, so anything else can be turned
+// away without a lookup
+func IsKioskToken(token string) bool {
+	if len(token) != base64.RawURLEncoding.EncodedLen(bearerTokenBytes) {
+		return false
+	}
+
+	for i := 0; i < len(token); i++ {
+		c := token[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func GenerateCalendarToken() string {
+	return generateBearerToken()
+}
+
+// Webhook secrets sign outgoing deliveries, so unlike tokens they are stored as they are
+func GenerateWebhookSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Bearer style tokens (kiosk, invite, calendar) are stored as a sha256 hex digest and compared by hash
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+const inviteTokenName = "group_invite"
+
+// Invite tokens are signed so tampered or expired tokens are rejected before touching the db.
+// The unsigned part is the expiry unix time and a random nonce, single use is enforced by the db.
+func GenerateInviteToken(expiresOn time.Time) (string, error) {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return WriteSigned(inviteTokenName, strconv.FormatInt(expiresOn.Unix(), 10)+"."+base64.RawURLEncoding.EncodeToString(b))
+}
+
+func VerifyInviteToken(token string) error {
+	value, err := VerifySigned(inviteTokenName, token)
+	if err != nil {
+		return ErrCheck(err)
+	}
+
+	expiresUnix, _, ok := strings.Cut(value, ".")
+	if !ok {
+		return ErrCheck(errors.New("invalid invite token format"))
+	}
+
+	expires, err := strconv.ParseInt(expiresUnix, 10, 64)
+	if err != nil {
+		return ErrCheck(errors.New("invalid invite token expiry"))
+	}
+
+	if time.Now().Unix() > expires {
+		return ErrCheck(errors.New("invite token expired"))
+	}
+
+	return nil
+}
+
 func FetchPublicKey() (*rsa.PublicKey, error) {
 	if E_KC_PUBLIC_KEY != nil {
 		return E_KC_PUBLIC_KEY, nil
@@ -169,6 +256,9 @@ type KeycloakUserWithClaims struct {
 	ResourceAccess map[string]struct {
 		Roles []string `json:"roles,omitempty"`
 	} `json:"resource_access,omitempty"`
+	RealmAccess struct {
+		Roles []string `json:"roles,omitempty"`
+	} `json:"realm_access,omitempty"`
 }
 
 func decodeJWTSegment(seg string) ([]byte, error) {
@@ -246,6 +336,7 @@ func ValidateToken(tokens *types.OIDCToken, userAgent, timezone, anonIp string)
 		UserEmail:        claims.Email,
 		SubGroupPaths:    claims.Groups,
 		RoleBits:         roleBits,
+		SuperAdmin:       slices.Contains(claims.RealmAccess.Roles, SuperAdminRole),
 		UserAgent:        userAgent,
 		Timezone:         timezone,
 		AnonIp:           anonIp,
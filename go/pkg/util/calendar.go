@@ -0,0 +1,122 @@
+package util
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	CalendarContentType = "text/calendar; charset=utf-8"
+
+	calendarProductId  = "-//Awayto//Bookings//EN"
+	calendarLineOctets = 75
+	calendarUTCFormat  = "20060102T150405Z"
+)
+
+// Start and End are wall clock times in TimeZone, an IANA zone name, otherwise they are taken as
+// they are. Times are always written in UTC, so no VTIMEZONE definitions are needed. Stamp is when
+// the event was last changed, and defaults to now.
+type CalendarEvent struct {
+	Uid         string
+	Summary     string
+	Description string
+	TimeZone    string
+	Start       time.Time
+	End         time.Time
+	Stamp       time.Time
+}
+
+// Content lines are escaped and folded as described in RFC 5545
+func escapeCalendarText(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", "",
+	).Replace(text)
+}
+
+func writeCalendarLine(w *bufio.Writer, line string) {
+	for len(line) > calendarLineOctets {
+		cut := calendarLineOctets
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+func calendarTime(name string, t time.Time) string {
+	return name + ":" + t.UTC().Format(calendarUTCFormat)
+}
+
+// inCalendarZone reads the wall clock of t in the named zone
+func inCalendarZone(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+func WriteCalendar(w io.Writer, name string, events []*CalendarEvent) error {
+	bw := bufio.NewWriter(w)
+
+	writeCalendarLine(bw, "BEGIN:VCALENDAR")
+	writeCalendarLine(bw, "VERSION:2.0")
+	writeCalendarLine(bw, "PRODID:"+calendarProductId)
+	writeCalendarLine(bw, "CALSCALE:GREGORIAN")
+	writeCalendarLine(bw, "METHOD:PUBLISH")
+	if name != "" {
+		writeCalendarLine(bw, "X-WR-CALNAME:"+escapeCalendarText(name))
+	}
+
+	now := time.Now()
+	zones := make(map[string]*time.Location)
+	for _, event := range events {
+		stamp := event.Stamp
+		if stamp.IsZero() {
+			stamp = now
+		}
+
+		var loc *time.Location
+		if event.TimeZone != "" {
+			var ok bool
+			if loc, ok = zones[event.TimeZone]; !ok {
+				var err error
+				loc, err = time.LoadLocation(event.TimeZone)
+				if err != nil {
+					return ErrCheck(err)
+				}
+				zones[event.TimeZone] = loc
+			}
+		}
+
+		writeCalendarLine(bw, "BEGIN:VEVENT")
+		writeCalendarLine(bw, "UID:"+escapeCalendarText(event.Uid))
+		writeCalendarLine(bw, calendarTime("DTSTAMP", stamp))
+		writeCalendarLine(bw, calendarTime("DTSTART", inCalendarZone(event.Start, loc)))
+		writeCalendarLine(bw, calendarTime("DTEND", inCalendarZone(event.End, loc)))
+		writeCalendarLine(bw, "SUMMARY:"+escapeCalendarText(event.Summary))
+		if event.Description != "" {
+			writeCalendarLine(bw, "DESCRIPTION:"+escapeCalendarText(event.Description))
+		}
+		writeCalendarLine(bw, "END:VEVENT")
+	}
+
+	writeCalendarLine(bw, "END:VCALENDAR")
+
+	if err := bw.Flush(); err != nil {
+		return ErrCheck(err)
+	}
+
+	return nil
+}
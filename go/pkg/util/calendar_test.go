@@ -0,0 +1,135 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_escapeCalendarText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain", text: "Haircut", want: "Haircut"},
+		{name: "separators", text: "Cut, wash; dry", want: `Cut\, wash\; dry`},
+		{name: "backslash", text: `a\b`, want: `a\\b`},
+		{name: "newlines", text: "one\r\ntwo\nthree", want: `one\ntwo\nthree`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeCalendarText(tt.text); got != tt.want {
+				t.Errorf("escapeCalendarText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCalendar(t *testing.T) {
+	stamp := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("zoned and utc events", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := WriteCalendar(&buf, "My bookings", []*CalendarEvent{
+			{
+				Uid:         "b1@awayto",
+				Summary:     "Haircut, Premium",
+				Description: "Main schedule",
+				TimeZone:    "America/New_York",
+				Start:       time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC),
+				End:         time.Date(2025, 3, 3, 9, 30, 0, 0, time.UTC),
+				Stamp:       stamp,
+			},
+			{
+				Uid:      "b4@awayto",
+				Summary:  "Haircut, Summer",
+				TimeZone: "America/New_York",
+				Start:    time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC),
+				End:      time.Date(2025, 7, 1, 9, 30, 0, 0, time.UTC),
+				Stamp:    stamp,
+			},
+			{
+				Uid:     "b2@awayto",
+				Summary: "Consult",
+				Start:   time.Date(2025, 3, 4, 14, 0, 0, 0, time.FixedZone("", -5*3600)),
+				End:     time.Date(2025, 3, 4, 15, 0, 0, 0, time.FixedZone("", -5*3600)),
+				Stamp:   stamp,
+			},
+		})
+		if err != nil {
+			t.Fatalf("WriteCalendar() error = %v", err)
+		}
+
+		want := strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"VERSION:2.0",
+			"PRODID:" + calendarProductId,
+			"CALSCALE:GREGORIAN",
+			"METHOD:PUBLISH",
+			"X-WR-CALNAME:My bookings",
+			"BEGIN:VEVENT",
+			"UID:b1@awayto",
+			"DTSTAMP:20250301T120000Z",
+			"DTSTART:20250303T140000Z",
+			"DTEND:20250303T143000Z",
+			`SUMMARY:Haircut\, Premium`,
+			"DESCRIPTION:Main schedule",
+			"END:VEVENT",
+			"BEGIN:VEVENT",
+			"UID:b4@awayto",
+			"DTSTAMP:20250301T120000Z",
+			"DTSTART:20250701T130000Z",
+			"DTEND:20250701T133000Z",
+			`SUMMARY:Haircut\, Summer`,
+			"END:VEVENT",
+			"BEGIN:VEVENT",
+			"UID:b2@awayto",
+			"DTSTAMP:20250301T120000Z",
+			"DTSTART:20250304T190000Z",
+			"DTEND:20250304T200000Z",
+			"SUMMARY:Consult",
+			"END:VEVENT",
+			"END:VCALENDAR",
+			"",
+		}, "\r\n")
+
+		if got := buf.String(); got != want {
+			t.Errorf("WriteCalendar() =\n%q\nwant\n%q", got, want)
+		}
+	})
+
+	t.Run("unknown time zone", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := WriteCalendar(&buf, "", []*CalendarEvent{{Uid: "b5", Summary: "Consult", TimeZone: "Nowhere/Special", Stamp: stamp}})
+		if err == nil {
+			t.Errorf("WriteCalendar() error = nil, want an error for an unknown time zone")
+		}
+	})
+
+	t.Run("long lines are folded", func(t *testing.T) {
+		var buf bytes.Buffer
+		summary := strings.Repeat("é", 60)
+		err := WriteCalendar(&buf, "", []*CalendarEvent{{Uid: "b3", Summary: summary, Stamp: stamp}})
+		if err != nil {
+			t.Fatalf("WriteCalendar() error = %v", err)
+		}
+
+		var unfolded string
+		for _, line := range strings.Split(buf.String(), "\r\n") {
+			if len(line) > calendarLineOctets {
+				t.Errorf("WriteCalendar() line is %d octets, want at most %d", len(line), calendarLineOctets)
+			}
+			if rest, ok := strings.CutPrefix(line, " "); ok {
+				unfolded += rest
+				continue
+			}
+			unfolded += "\n" + line
+		}
+
+		if !strings.Contains(unfolded, "\nSUMMARY:"+summary+"\n") {
+			t.Errorf("WriteCalendar() folded summary did not unfold to the original, got %q", unfolded)
+		}
+	})
+}
@@ -26,9 +26,11 @@ var (
 	E_APP_HOST_PROTOCOL, E_APP_HOST_URL, E_APP_HOST_NAME, E_API_PATH, E_BINARY_NAME, E_CERT_LOC, E_CERT_KEY_LOC, E_DB_DRIVER, E_KC_USER_CLIENT_SECRET,
 	E_KC_OPENID_TOKEN_URL, E_KC_OPENID_REGISTER_URL, E_KC_OPENID_AUTH_URL, E_KC_OPENID_LOGOUT_URL, E_KC_API_CLIENT, E_KC_USER_CLIENT,
 	E_KC_REALM, E_KC_INTERNAL, E_KC_URL, E_KC_ADMIN_URL, E_LOG_LEVEL, E_LOG_DIR, E_PG_WORKER, E_PG_DB, E_PROJECT_DIR, E_REDIS_URL,
-	E_TS_DEV_SERVER_URL, E_UNIX_AUTH_SOCK_FILE, E_UNIX_AUTH_PATH, E_PAYMENT_TO, E_PAYMENT_ADDR1, E_PAYMENT_ADDR2 string
+	E_TS_DEV_SERVER_URL, E_UNIX_AUTH_SOCK_FILE, E_UNIX_AUTH_PATH, E_PAYMENT_TO, E_PAYMENT_ADDR1, E_PAYMENT_ADDR2,
+	E_MAIL_SENDER, E_MAIL_FROM, E_MAIL_DIR, E_SMTP_ADDR, E_SMTP_USER, E_VAULT_KEY_FILE, E_PAYMENT_PROVIDER,
+	E_LLM_PROVIDER, E_LLM_URL, E_LLM_MODEL, E_NOTIFY_CHANNELS, E_NOTIFY_WEBHOOK_URL string
 
-	E_API_PATH_LEN, E_GO_HTTP_PORT, E_GO_HTTPS_PORT, E_RATE_LIMIT, E_RATE_LIMIT_BURST int
+	E_API_PATH_LEN, E_GO_HTTP_PORT, E_GO_HTTPS_PORT, E_RATE_LIMIT, E_RATE_LIMIT_BURST, E_VAULT_KEY_ROTATE_HOURS, E_VAULT_KEY_GRACE_MINUTES, E_NOTIFY_REMINDER_HOURS int
 
 	E_KC_PUBLIC_KEY *rsa.PublicKey
 )
@@ -64,6 +66,21 @@ func ParseEnv() {
 	E_RATE_LIMIT = ParseEnvFileVar[int]("RATE_LIMIT")
 	E_RATE_LIMIT_BURST = ParseEnvFileVar[int]("RATE_LIMIT_BURST")
 	E_REDIS_URL = ParseEnvFileVar[string]("REDIS_URL")
+	E_MAIL_SENDER = ParseEnvFileVar[string]("MAIL_SENDER")
+	E_MAIL_FROM = ParseEnvFileVar[string]("MAIL_FROM")
+	E_MAIL_DIR = ParseEnvFileVar[string]("MAIL_DIR")
+	E_SMTP_ADDR = ParseEnvFileVar[string]("SMTP_ADDR")
+	E_SMTP_USER = ParseEnvFileVar[string]("SMTP_USER")
+	E_PAYMENT_PROVIDER = ParseEnvFileVar[string]("PAYMENT_PROVIDER")
+	E_LLM_PROVIDER = ParseEnvFileVar[string]("LLM_PROVIDER")
+	E_LLM_URL = ParseEnvFileVar[string]("LLM_URL")
+	E_LLM_MODEL = ParseEnvFileVar[string]("LLM_MODEL")
+	E_NOTIFY_CHANNELS = ParseEnvFileVar[string]("NOTIFY_CHANNELS")
+	E_NOTIFY_WEBHOOK_URL = ParseEnvFileVar[string]("NOTIFY_WEBHOOK_URL")
+	E_NOTIFY_REMINDER_HOURS = ParseEnvFileVar[int]("NOTIFY_REMINDER_HOURS")
+	E_VAULT_KEY_FILE = filepath.Join(E_PROJECT_DIR, ParseEnvFileVar[string]("VAULT_KEY_FILE"))
+	E_VAULT_KEY_ROTATE_HOURS = ParseEnvFileVar[int]("VAULT_KEY_ROTATE_HOURS")
+	E_VAULT_KEY_GRACE_MINUTES = ParseEnvFileVar[int]("VAULT_KEY_GRACE_MINUTES")
 	E_TS_DEV_SERVER_URL = ParseEnvFileVar[string]("TS_DEV_SERVER_URL")
 	E_UNIX_AUTH_SOCK_FILE = ParseEnvFileVar[string]("UNIX_AUTH_SOCK_FILE")
 	E_UNIX_AUTH_PATH = filepath.Join(E_PROJECT_DIR, E_UNIX_SOCK_DIR, "auth", E_UNIX_AUTH_SOCK_FILE)
@@ -101,6 +101,21 @@ func WriteCallerErr(n int, err any, sb *strings.Builder) {
 	sb.WriteString(strconv.Itoa(line))
 }
 
+// callerError adds where an error was checked to its message, keeping the error it wraps
+// reachable by errors.Is and errors.As
+type callerError struct {
+	msg string
+	err error
+}
+
+func (e *callerError) Error() string {
+	return e.msg
+}
+
+func (e *callerError) Unwrap() error {
+	return e.err
+}
+
 func ErrCheck(err error) error {
 	if err == nil {
 		return nil
@@ -109,7 +124,7 @@ func ErrCheck(err error) error {
 	var sb strings.Builder
 	WriteCallerErr(1, err, &sb)
 
-	return errors.New(sb.String())
+	return &callerError{msg: sb.String(), err: err}
 }
 
 func ErrCheckN(n int, err any) error {
@@ -168,6 +168,18 @@ func TestErrCheck(t *testing.T) {
 	}
 }
 
+func TestErrCheck_Wrapped(t *testing.T) {
+	target := errors.New("target error")
+
+	err := ErrCheck(ErrCheck(target))
+	if !errors.Is(err, target) {
+		t.Errorf("ErrCheck() error = %v, want it to wrap %v", err, target)
+	}
+	if !strings.HasPrefix(err.Error(), "target error ") || !strings.Contains(err.Error(), "errors_test.go:") {
+		t.Errorf("ErrCheck() error = %v, want the message followed by the caller", err)
+	}
+}
+
 func BenchmarkErrCheck(b *testing.B) {
 	reset(b)
 	for b.Loop() {
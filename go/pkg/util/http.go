@@ -22,6 +22,15 @@ type ErrRes struct {
 	ErrorMessage string `json:"errorMessage"`
 }
 
+// StatusError is returned for a response outside of 2xx, so callers can act on the status code
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return http.StatusText(e.StatusCode)
+}
+
 func successStatus(statusCode int) bool {
 	return statusCode >= 200 && statusCode < 300
 }
@@ -47,7 +56,7 @@ func Get(url string, headers http.Header) ([]byte, error) {
 	defer resp.Body.Close()
 
 	if !successStatus(resp.StatusCode) {
-		return nil, ErrCheck(errors.New(http.StatusText(resp.StatusCode)))
+		return nil, ErrCheck(&StatusError{StatusCode: resp.StatusCode})
 	}
 
 	respBody, err := io.ReadAll(resp.Body)
@@ -79,7 +88,7 @@ func GetWithParams(url string, headers http.Header, queryParams url.Values) ([]b
 	defer resp.Body.Close()
 
 	if !successStatus(resp.StatusCode) {
-		return nil, ErrCheck(errors.New(http.StatusText(resp.StatusCode)))
+		return nil, ErrCheck(&StatusError{StatusCode: resp.StatusCode})
 	}
 
 	respBody, err := io.ReadAll(resp.Body)
@@ -111,7 +120,7 @@ func Mutate(method string, url string, headers http.Header, dataBody []byte) ([]
 	defer resp.Body.Close()
 
 	if !successStatus(resp.StatusCode) {
-		return nil, ErrCheck(errors.New(http.StatusText(resp.StatusCode)))
+		return nil, ErrCheck(&StatusError{StatusCode: resp.StatusCode})
 	}
 
 	respBody, err := io.ReadAll(resp.Body)
@@ -3,6 +3,7 @@ package util
 import (
 	"bytes"
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -182,6 +183,21 @@ func TestMutate(t *testing.T) {
 	}
 }
 
+func TestMutate_StatusError(t *testing.T) {
+	server := mockServer()
+	defer server.Close()
+
+	_, err := Mutate("DELETE", server.URL+"/error", http.Header{}, nil)
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Mutate() error = %v, want a StatusError", err)
+	}
+	if statusErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Mutate() status = %d, want %d", statusErr.StatusCode, http.StatusInternalServerError)
+	}
+}
+
 func BenchmarkMutate(b *testing.B) {
 	server := mockServer()
 	defer server.Close()
@@ -77,6 +77,18 @@ func makeLoggers() {
 	DebugLog.Println("Log files generated")
 }
 
+// Syncs log files to disk, used before the process exits
+func FlushLogs() {
+	for _, logger := range []*CustomLogger{AccessLog, AuthLog, DebugLog, ErrorLog, SockLog} {
+		if logger == nil {
+			continue
+		}
+		if logFile, ok := logger.Writer().(*os.File); ok {
+			logFile.Sync()
+		}
+	}
+}
+
 func getIp(req *http.Request, ip ...string) string {
 	if len(ip) > 0 {
 		return ip[0]
@@ -138,3 +138,16 @@ func TestRunTimer(t *testing.T) {
 		})
 	}
 }
+
+func TestFlushLogs(t *testing.T) {
+	tests := []struct {
+		name string
+	}{
+		{name: "flushes without error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			FlushLogs()
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/keybittech/awayto-v3/go/pkg/types"
@@ -39,6 +40,7 @@ type HandlerOptionsConfig struct {
 	ShouldStore            bool
 	ShouldSkip             bool
 	UseTx                  bool
+	SuperAdmin             bool
 }
 
 type HandlerOptions struct {
@@ -51,7 +53,7 @@ type HandlerOptions struct {
 	Pattern                string
 
 	packedNumeric  uint32
-	packedBooleans uint8
+	packedBooleans uint16
 }
 
 type UnpackedOptionsData struct {
@@ -68,6 +70,7 @@ type UnpackedOptionsData struct {
 	ShouldStore       bool
 	ShouldSkip        bool
 	UseTx             bool
+	SuperAdmin        bool
 }
 
 const (
@@ -99,11 +102,12 @@ const (
 	multipartResponseBit = 1 << 5
 	hasPathParamsBit     = 1 << 6
 	hasQueryParamsBit    = 1 << 7
+	superAdminBit        = 1 << 8
 )
 
 func NewHandlerOptions(config HandlerOptionsConfig) (*HandlerOptions, error) {
 	var packedNumeric uint32
-	var packedBooleans uint8
+	var packedBooleans uint16
 
 	if config.CacheDuration > cacheDurationMask {
 		return nil, fmt.Errorf("CacheDuration %d out of range (max %d)", config.CacheDuration, cacheDurationMask)
@@ -161,6 +165,9 @@ func NewHandlerOptions(config HandlerOptionsConfig) (*HandlerOptions, error) {
 	if config.HasQueryParams {
 		packedBooleans |= hasQueryParamsBit
 	}
+	if config.SuperAdmin {
+		packedBooleans |= superAdminBit
+	}
 
 	return &HandlerOptions{
 		Invalidations:          config.Invalidations,
@@ -203,6 +210,7 @@ func (h *HandlerOptions) Unpack() UnpackedOptionsData {
 	data.MultipartResponse = (h.packedBooleans & multipartResponseBit) != 0
 	data.HasPathParams = (h.packedBooleans & hasPathParamsBit) != 0
 	data.HasQueryParams = (h.packedBooleans & hasQueryParamsBit) != 0
+	data.SuperAdmin = (h.packedBooleans & superAdminBit) != 0
 
 	return data
 }
@@ -308,6 +316,10 @@ func ParseHandlerOptions(md protoreflect.MethodDescriptor) *HandlerOptions {
 		parsedOptions.UseTx = useTx
 	}
 
+	if superAdmin, ok := proto.GetExtension(inputOpts, types.E_SuperAdmin).(bool); ok {
+		parsedOptions.SuperAdmin = superAdmin
+	}
+
 	hops, err := NewHandlerOptions(parsedOptions)
 	if err != nil {
 		log.Fatalf("error making new handler options %v", err)
@@ -386,10 +398,19 @@ func setProtoFieldValue(msg proto.Message, jsonName string, value string) {
 
 	for i := range fields.Len() {
 		field := fields.Get(i)
-		if field.JSONName() == jsonName && field.Kind() == protoreflect.StringKind {
+		if field.JSONName() != jsonName {
+			continue
+		}
+
+		switch field.Kind() {
+		case protoreflect.StringKind:
 			reflectMsg.Set(field, protoreflect.ValueOfString(value))
-			return
+		case protoreflect.Int32Kind:
+			if intValue, err := strconv.ParseInt(value, 10, 32); err == nil {
+				reflectMsg.Set(field, protoreflect.ValueOfInt32(int32(intValue)))
+			}
 		}
+		return
 	}
 }
 
@@ -149,6 +149,13 @@ func TestParseHandlerOptions(t *testing.T) {
 				return got.Unpack().UseTx == true
 			},
 		},
+		{
+			name: "super_admin=true",
+			md:   getMethodDescriptor(t, "PostGroupSeatPaymentReceipt"),
+			validate: func(got *HandlerOptions) bool {
+				return got.Unpack().SuperAdmin == true
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -243,6 +250,15 @@ func TestParseProtoPathParams(t *testing.T) {
 				Date:            "date-value",
 			},
 		},
+		{
+			name:   "serializes integer path parameters into pb struct",
+			method: getMethodDescriptor(t, "GetBookingTranscripts"),
+			req:    makeParseTestReq("/api/v1/booking_transcripts/bookings/booking-id/pages/2"),
+			want: &types.GetBookingTranscriptsRequest{
+				BookingId: "booking-id",
+				Page:      2,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
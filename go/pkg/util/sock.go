@@ -6,8 +6,18 @@ import (
 	"strings"
 
 	"github.com/keybittech/awayto-v3/go/pkg/types"
+	"google.golang.org/protobuf/proto"
 )
 
+// Sec-WebSocket-Protocol values accepted on /sock, in order of preference. Connections which don't
+// request one use the padded text format.
+const (
+	SOCKET_PROTOCOL_PROTO = "awayto.proto.v2"
+	SOCKET_PROTOCOL_TEXT  = "awayto.text.v1"
+)
+
+var SOCKET_PROTOCOLS = []string{SOCKET_PROTOCOL_PROTO, SOCKET_PROTOCOL_TEXT}
+
 var MAX_SOCKET_MESSAGE_LENGTH = 65535
 var malformedSocketError = errors.New("malformed socket id")
 
@@ -72,3 +82,66 @@ func ParseMessage(padTo, cursor int, data []byte) (int, string, error) {
 
 	return valEnd, val, nil
 }
+
+// Reverses GenerateMessage. Unreadable fields are left empty, but the action must parse.
+func ParseSocketMessage(padTo int, data []byte) (*types.SocketMessage, error) {
+	messageParams := make([]string, 7)
+
+	cursor := 0
+	var curr string
+	var err error
+	for i := range 7 {
+		cursor, curr, err = ParseMessage(padTo, cursor, data)
+		if err != nil {
+			ErrorLog.Println(ErrCheck(err))
+			messageParams[i] = ""
+			continue
+		}
+		messageParams[i] = curr
+	}
+
+	actionId, err := strconv.ParseInt(messageParams[0], 10, 32)
+	if err != nil {
+		return nil, ErrCheck(err)
+	}
+
+	return &types.SocketMessage{
+		Action:     types.SocketActions(actionId),
+		Store:      messageParams[1] == "t",
+		Historical: messageParams[2] == "t",
+		Timestamp:  messageParams[3],
+		Topic:      messageParams[4],
+		Sender:     messageParams[5],
+		Payload:    messageParams[6],
+	}, nil
+}
+
+// Messages are stored and relayed between nodes in the padded text format, so protobuf connections
+// receive a re-encoded copy
+func TextToProtoMessage(padTo int, data []byte) ([]byte, error) {
+	message, err := ParseSocketMessage(padTo, data)
+	if err != nil {
+		return nil, ErrCheck(err)
+	}
+
+	protoBytes, err := proto.Marshal(message)
+	if err != nil {
+		return nil, ErrCheck(err)
+	}
+
+	return protoBytes, nil
+}
+
+// Reads a message sent by a client in the format of the protocol it negotiated
+func ParseProtocolSocketMessage(protocol string, data []byte) (*types.SocketMessage, error) {
+	if protocol != SOCKET_PROTOCOL_PROTO {
+		return ParseSocketMessage(DefaultPadding, data)
+	}
+
+	message := &types.SocketMessage{}
+	if err := proto.Unmarshal(data, message); err != nil {
+		return nil, ErrCheck(err)
+	}
+
+	return message, nil
+}
@@ -304,3 +304,128 @@ func BenchmarkParseMessage(b *testing.B) {
 		_, _, _ = ParseMessage(padTo, cursor, message)
 	}
 }
+
+func TestParseSocketMessage(t *testing.T) {
+	type args struct {
+		padTo int
+		data  []byte
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *types.SocketMessage
+		wantErr bool
+	}{
+		{
+			name: "parses a generated message",
+			args: args{
+				padTo: 5,
+				data:  []byte("000021200001t00001f00009timestamp00005topic00006sender00007payload"),
+			},
+			want: &types.SocketMessage{
+				Action:    types.SocketActions_TEXT,
+				Store:     true,
+				Timestamp: "timestamp",
+				Topic:     "topic",
+				Sender:    "sender",
+				Payload:   "payload",
+			},
+			wantErr: false,
+		},
+		{
+			name: "errors without an action",
+			args: args{
+				padTo: 5,
+				data:  []byte("00003abc00001t"),
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSocketMessage(tt.args.padTo, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSocketMessage() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("ParseSocketMessage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextToProtoMessage(t *testing.T) {
+	want := &types.SocketMessage{
+		Action:    types.SocketActions_TEXT,
+		Store:     true,
+		Timestamp: "timestamp",
+		Topic:     "topic",
+		Sender:    "sender",
+		Payload:   "payload",
+	}
+
+	got, err := TextToProtoMessage(5, []byte("000021200001t00001f00009timestamp00005topic00006sender00007payload"))
+	if err != nil {
+		t.Fatalf("TextToProtoMessage() error = %v", err)
+	}
+
+	message := &types.SocketMessage{}
+	if err := proto.Unmarshal(got, message); err != nil {
+		t.Fatalf("TextToProtoMessage() produced an unreadable message: %v", err)
+	}
+	if !proto.Equal(message, want) {
+		t.Errorf("TextToProtoMessage() = %v, want %v", message, want)
+	}
+
+	if _, err := TextToProtoMessage(5, []byte("00003abc00001t")); err == nil {
+		t.Error("TextToProtoMessage() expected an error without an action")
+	}
+}
+
+func TestParseProtocolSocketMessage(t *testing.T) {
+	message := &types.SocketMessage{
+		Action:  types.SocketActions_DRAW_LINES,
+		Topic:   "topic",
+		Sender:  "sender",
+		Payload: `{"lines":[]}`,
+	}
+
+	protoBytes, err := proto.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		protocol string
+		data     []byte
+		wantErr  bool
+	}{
+		{name: "no protocol is text", protocol: "", data: GenerateMessage(DefaultPadding, message)},
+		{name: "text protocol", protocol: SOCKET_PROTOCOL_TEXT, data: GenerateMessage(DefaultPadding, message)},
+		{name: "proto protocol", protocol: SOCKET_PROTOCOL_PROTO, data: protoBytes},
+		{name: "text sent on proto protocol", protocol: SOCKET_PROTOCOL_PROTO, data: []byte("000021800000"), wantErr: true},
+		{name: "proto sent on text protocol", protocol: SOCKET_PROTOCOL_TEXT, data: protoBytes, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProtocolSocketMessage(tt.protocol, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProtocolSocketMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !proto.Equal(got, message) {
+				t.Errorf("ParseProtocolSocketMessage() = %v, want %v", got, message)
+			}
+		})
+	}
+}
+
+func BenchmarkTextToProtoMessage(b *testing.B) {
+	message := []byte("000021200001t00001f00009timestamp00005topic00006sender00007payload")
+	reset(b)
+	for b.Loop() {
+		_, _ = TextToProtoMessage(5, message)
+	}
+}
@@ -0,0 +1,182 @@
+package util
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CSVContentType  = "text/csv; charset=utf-8"
+	XLSXContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// Cell values are expected to be nil, string, bool, float64, int64 or time.Time
+func SpreadsheetCellString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Spreadsheet apps evaluate text cells starting with these as formulas
+func escapeCSVFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func WriteCSV(w io.Writer, header []string, rows [][]any) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(header); err != nil {
+		return ErrCheck(err)
+	}
+
+	record := make([]string, len(header))
+	for _, row := range rows {
+		for i := range record {
+			record[i] = ""
+			if i >= len(row) {
+				continue
+			}
+			cell := SpreadsheetCellString(row[i])
+			if _, ok := row[i].(string); ok {
+				cell = escapeCSVFormula(cell)
+			}
+			record[i] = cell
+		}
+		if err := cw.Write(record); err != nil {
+			return ErrCheck(err)
+		}
+	}
+
+	cw.Flush()
+	return ErrCheck(cw.Error())
+}
+
+var xlsxStaticParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+// Excel rejects sheet names over 31 characters or containing any of these
+var xlsxSheetNameReplacer = strings.NewReplacer(":", " ", "\\", " ", "/", " ", "?", " ", "*", " ", "[", " ", "]", " ")
+
+// Column letters are base 26 without a zero, A..Z, AA..AZ, ...
+func xlsxColumnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func writeXLSXCell(b *strings.Builder, ref string, value any) {
+	switch v := value.(type) {
+	case nil:
+		return
+	case bool:
+		cell := "0"
+		if v {
+			cell = "1"
+		}
+		fmt.Fprintf(b, `<c r="%s" t="b"><v>%s</v></c>`, ref, cell)
+	case float64, int64:
+		fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, SpreadsheetCellString(v))
+	default:
+		b.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(b, []byte(SpreadsheetCellString(v)))
+		b.WriteString(`</t></is></c>`)
+	}
+}
+
+// Writes a single sheet workbook using inline strings, so no shared string table or styles are needed
+func WriteXLSX(w io.Writer, sheetName string, header []string, rows [][]any) error {
+	sheetName = strings.TrimSpace(xlsxSheetNameReplacer.Replace(sheetName))
+	if sheetName == "" {
+		sheetName = "Sheet1"
+	}
+	if len([]rune(sheetName)) > 31 {
+		sheetName = string([]rune(sheetName)[:31])
+	}
+
+	zw := zip.NewWriter(w)
+
+	for _, part := range xlsxStaticParts {
+		pw, err := zw.Create(part.name)
+		if err != nil {
+			return ErrCheck(err)
+		}
+		if _, err := io.WriteString(pw, part.body); err != nil {
+			return ErrCheck(err)
+		}
+	}
+
+	var workbook strings.Builder
+	workbook.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="`)
+	xml.EscapeText(&workbook, []byte(sheetName))
+	workbook.WriteString(`" sheetId="1" r:id="rId1"/></sheets></workbook>`)
+
+	ww, err := zw.Create("xl/workbook.xml")
+	if err != nil {
+		return ErrCheck(err)
+	}
+	if _, err := io.WriteString(ww, workbook.String()); err != nil {
+		return ErrCheck(err)
+	}
+
+	var sheet strings.Builder
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	sheet.WriteString(`<row r="1">`)
+	for i, col := range header {
+		writeXLSXCell(&sheet, xlsxColumnName(i)+"1", col)
+	}
+	sheet.WriteString(`</row>`)
+
+	for r, row := range rows {
+		rowNum := strconv.Itoa(r + 2)
+		sheet.WriteString(`<row r="` + rowNum + `">`)
+		for i, value := range row {
+			writeXLSXCell(&sheet, xlsxColumnName(i)+rowNum, value)
+		}
+		sheet.WriteString(`</row>`)
+	}
+
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	sw, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return ErrCheck(err)
+	}
+	if _, err := io.WriteString(sw, sheet.String()); err != nil {
+		return ErrCheck(err)
+	}
+
+	return ErrCheck(zw.Close())
+}
@@ -0,0 +1,139 @@
+package util
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpreadsheetCellString(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "nil", value: nil, want: ""},
+		{name: "string", value: "text", want: "text"},
+		{name: "bool", value: true, want: "true"},
+		{name: "float", value: 1.5, want: "1.5"},
+		{name: "whole float", value: float64(3), want: "3"},
+		{name: "int", value: int64(42), want: "42"},
+		{name: "time", value: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), want: "2025-01-02T03:04:05Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SpreadsheetCellString(tt.value); got != tt.want {
+				t.Errorf("SpreadsheetCellString(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		rows   [][]any
+		want   string
+	}{
+		{
+			name:   "typed cells",
+			header: []string{"id", "agree", "count"},
+			rows:   [][]any{{"a", true, 2.0}, {"b", nil, float64(-1)}},
+			want:   "id,agree,count\na,true,2\nb,,-1\n",
+		},
+		{
+			name:   "formula text is escaped",
+			header: []string{"note"},
+			rows:   [][]any{{"=SUM(A1)"}, {"@cmd"}},
+			want:   "note\n'=SUM(A1)\n'@cmd\n",
+		},
+		{
+			name:   "short rows are padded",
+			header: []string{"a", "b"},
+			rows:   [][]any{{"x"}},
+			want:   "a,b\nx,\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteCSV(&buf, tt.header, tt.rows); err != nil {
+				t.Fatalf("WriteCSV() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteCSV() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_xlsxColumnName(t *testing.T) {
+	tests := []struct {
+		i    int
+		want string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := xlsxColumnName(tt.i); got != tt.want {
+				t.Errorf("xlsxColumnName(%d) = %v, want %v", tt.i, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteXLSX(&buf, "Intake: 2025/01", []string{"name", "agree", "count"}, [][]any{{"<Jane & Co>", false, 3.0}})
+	if err != nil {
+		t.Fatalf("WriteXLSX() error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("WriteXLSX() did not write a zip, %v", err)
+	}
+
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(body)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("WriteXLSX() missing part %s", name)
+		}
+	}
+
+	if !strings.Contains(parts["xl/workbook.xml"], `name="Intake  2025 01"`) {
+		t.Errorf("WriteXLSX() sheet name was not sanitized, %s", parts["xl/workbook.xml"])
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">&lt;Jane &amp; Co&gt;</t></is></c>`,
+		`<c r="B2" t="b"><v>0</v></c>`,
+		`<c r="C2"><v>3</v></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("WriteXLSX() sheet missing %s", want)
+		}
+	}
+}
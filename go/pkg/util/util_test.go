@@ -127,6 +127,28 @@ func BenchmarkIsUUIDNegative(b *testing.B) {
 	}
 }
 
+func TestIsKioskToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{name: "Generated token", token: GenerateKioskToken(), want: true},
+		{name: "Empty", token: "", want: false},
+		{name: "Short", token: "not-a-kiosk-token", want: false},
+		{name: "Too long", token: GenerateKioskToken() + "a", want: false},
+		{name: "Not base64url", token: "+" + GenerateKioskToken()[1:], want: false},
+		{name: "Padded", token: GenerateKioskToken()[1:] + "=", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsKioskToken(tt.token); got != tt.want {
+				t.Errorf("IsKioskToken(%q) = %v, want %v", tt.token, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestIsEpoch(t *testing.T) {
 	type args struct {
 		id string
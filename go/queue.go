@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const queueListenRetry = 5 * time.Second
+
+type queueListener func(ctx context.Context, changed chan<- struct{}) error
+
+type queueProcessor func(ctx context.Context) (int, error)
+
+// listenForChanges keeps listen running until ctx ends, restarting it queueListenRetry after it stops.
+// The changed channel is also signalled each time listening starts, as anything could have changed
+// while not listening. The stopped channel is closed once the listener has returned for good.
+func listenForChanges(ctx context.Context, name string, listen queueListener) (changed <-chan struct{}, stopped <-chan struct{}) {
+	changedChan := make(chan struct{}, 1)
+	stoppedChan := make(chan struct{})
+
+	go func() {
+		defer close(stoppedChan)
+
+		for ctx.Err() == nil {
+			select {
+			case changedChan <- struct{}{}:
+			default:
+			}
+
+			err := listen(ctx, changedChan)
+			if err != nil {
+				util.ErrorLog.Printf("%s listener stopped, err: %v", name, err)
+			}
+
+			select {
+			case <-time.After(queueListenRetry):
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return changedChan, stoppedChan
+}
+
+// drainQueue processes batches until one finishes nothing
+func drainQueue(ctx context.Context, name string, process queueProcessor) {
+	for {
+		processed, err := process(ctx)
+		if err != nil {
+			util.ErrorLog.Printf("could not process %s, err: %v", name, err)
+			return
+		}
+		if processed == 0 {
+			return
+		}
+	}
+}
+
+// runQueueWorker drains a queue as soon as new rows are committed, and every pollEvery for rows
+// which have since become due, until ctx ends and its listener has stopped
+func runQueueWorker(ctx context.Context, name string, pollEvery time.Duration, listen queueListener, process queueProcessor) {
+	changed, stopped := listenForChanges(ctx, name, listen)
+	defer func() { <-stopped }()
+
+	pollTicker := time.NewTicker(pollEvery)
+	defer pollTicker.Stop()
+
+	for {
+		select {
+		case <-changed:
+			drainQueue(ctx, name, process)
+		case <-pollTicker.C:
+			drainQueue(ctx, name, process)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/crypto"
+	"github.com/keybittech/awayto-v3/go/pkg/util"
+)
+
+const vaultKeySyncInterval = time.Minute
+
+func newVaultKeyStore() *crypto.VaultKeyStore {
+	vaultKeyPass, err := util.GetEnvFilePath("VAULT_KEY_PASS_FILE", 128)
+	if err != nil {
+		log.Fatal(util.ErrCheck(err))
+	}
+
+	return &crypto.VaultKeyStore{
+		Path:        util.E_VAULT_KEY_FILE,
+		Secret:      []byte(vaultKeyPass),
+		RotateEvery: time.Duration(util.E_VAULT_KEY_ROTATE_HOURS) * time.Hour,
+		Grace:       time.Duration(util.E_VAULT_KEY_GRACE_MINUTES) * time.Minute,
+	}
+}
+
+// Rotates the vault key when it expires, and picks up keys rotated by other instances sharing the key file.
+// The previous key stops being accepted once its grace period passes.
+func setupVaultKeyRotation(ctx context.Context, store *crypto.VaultKeyStore) {
+	ticker := time.NewTicker(vaultKeySyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			lastKeyId := crypto.VaultKey.Current().Id
+
+			err := store.Sync(crypto.VaultKey, time.Now())
+			if err != nil {
+				util.ErrorLog.Printf("vault key sync failed, err: %v", err)
+				continue
+			}
+
+			if keyId := crypto.VaultKey.Current().Id; keyId != lastKeyId {
+				util.DebugLog.Printf("vault key rotated from %s to %s", lastKeyId, keyId)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/keybittech/awayto-v3/go/pkg/api"
+)
+
+const groupWebhookPollEvery = time.Minute
+
+// Sends group webhook deliveries. New events are sent as soon as their request commits, and retries
+// are picked up every groupWebhookPollEvery once their backoff has passed.
+func setupGroupWebhookDelivery(ctx context.Context, a *api.API) {
+	runQueueWorker(ctx, "group webhook deliveries", groupWebhookPollEvery, a.Handlers.Database.DatabaseClient.ListenGroupWebhookDeliveries, a.Handlers.ProcessGroupWebhookDeliveries)
+}
@@ -46,6 +46,13 @@ public class CustomUserRegistration extends RegistrationUserCreation {
 
     HttpRequest request = context.getHttpRequest();
 
+    String invite = getRequestParameter(request, "invite");
+
+    if (invite != null && invite.length() > 0) {
+      buildInvitePage(context, form, invite);
+      return;
+    }
+
     String groupCode = request.getUri().getQueryParameters().getFirst("groupCode");
 
     if (groupCode == null && request.getDecodedFormParameters() != null) {
@@ -110,6 +117,13 @@ public class CustomUserRegistration extends RegistrationUserCreation {
     List<FormMessage> validationErrors = new ArrayList<>();
     MultivaluedMap<String, String> formData = context.getHttpRequest().getDecodedFormParameters();
 
+    String invite = formData.getFirst("invite");
+
+    if (invite != null && invite.length() > 0) {
+      validateInvite(context, formData, invite);
+      return;
+    }
+
     if (formData.containsKey("groupCode")) {
       String groupCode = formData.getFirst("groupCode");
       String email = formData.getFirst("email");
@@ -195,7 +209,13 @@ public class CustomUserRegistration extends RegistrationUserCreation {
     if (groupIdObj != null) {
       GroupModel gr = context.getRealm().getGroupById(groupIdObj.toString());
       context.getUser().joinGroup(gr);
-      registrationSuccessPayload.put("groupCode", session.getAttribute("groupCode").toString());
+
+      Object invite = session.getAttribute("invite");
+      if (invite != null) {
+        registrationSuccessPayload.put("invite", invite.toString());
+      } else {
+        registrationSuccessPayload.put("groupCode", session.getAttribute("groupCode").toString());
+      }
     }
 
     registrationSuccessPayload.put("userId", up.getId());
@@ -206,4 +226,81 @@ public class CustomUserRegistration extends RegistrationUserCreation {
 
     BackchannelAuth.sendUnixMessage("REGISTER", registrationSuccessPayload);
   }
+
+  private static String getRequestParameter(HttpRequest request, String name) {
+    String value = request.getUri().getQueryParameters().getFirst(name);
+
+    if (value == null && request.getDecodedFormParameters() != null) {
+      value = request.getDecodedFormParameters().getFirst(name);
+    }
+
+    return value;
+  }
+
+  // Invites are checked but not used up by REGISTER_VALIDATE, the api redeems them on REGISTER
+  private static JSONObject validateInviteToken(String invite) {
+    JSONObject registrationValidationPayload = new JSONObject();
+    registrationValidationPayload.put("invite", invite);
+    return BackchannelAuth.sendUnixMessage("REGISTER_VALIDATE", registrationValidationPayload);
+  }
+
+  private void buildInvitePage(FormContext context, LoginFormsProvider form, String invite) {
+    HttpRequest request = context.getHttpRequest();
+
+    MultivaluedMap<String, String> formData = new MultivaluedHashMap<>();
+    if (request.getDecodedFormParameters() != null) {
+      formData.putAll(request.getDecodedFormParameters());
+    }
+    formData.putSingle("invite", invite);
+
+    JSONObject inviteValidationResponse = validateInviteToken(invite);
+
+    if (inviteValidationResponse.getBoolean("success")) {
+      if (!formData.containsKey("email")) {
+        formData.putSingle("email", inviteValidationResponse.getString("email"));
+      }
+      form.setAttribute("groupName", inviteValidationResponse.getString("groupName"));
+    } else {
+      form.setErrors(List.of(new FormMessage("invite", "invalidInvite")));
+    }
+
+    form.setFormData(formData);
+  }
+
+  private void validateInvite(ValidationContext context, MultivaluedMap<String, String> formData, String invite) {
+    KeycloakSession session = context.getSession();
+
+    List<FormMessage> validationErrors = new ArrayList<>();
+    String email = formData.getFirst("email");
+
+    JSONObject inviteValidationResponse = validateInviteToken(invite);
+
+    if (inviteValidationResponse.getBoolean("success")) {
+      String groupId = inviteValidationResponse.getString("roleGroupId");
+
+      if (context.getRealm().getGroupById(groupId) == null) {
+        validationErrors.add(new FormMessage("invite", "invalidInvite"));
+      } else if (email == null || !email.equalsIgnoreCase(inviteValidationResponse.getString("email"))) {
+        validationErrors.add(new FormMessage("email", "inviteEmailMismatch"));
+      } else {
+        session.setAttribute("groupId", groupId);
+        session.setAttribute("invite", invite);
+        session.setAttribute("groupName", inviteValidationResponse.getString("groupName"));
+      }
+    } else {
+      validationErrors.add(new FormMessage("invite", "invalidInvite"));
+    }
+
+    if (email != null && session.users().getUserByEmail(context.getRealm(), email) != null) {
+      validationErrors.add(new FormMessage("email", "emailInUse"));
+    }
+
+    if (!validationErrors.isEmpty()) {
+      context.error("VALIDATION_ERROR");
+      context.validationError(formData, validationErrors);
+      return;
+    }
+
+    super.validate(context);
+  }
 }
@@ -71,6 +71,18 @@
                 </#if>
             </@userProfileCommons.userProfileFormFields>
 
+            <#if (register.formData.invite!'')?has_content>
+            <#-- invited users join the group and role from their invite, so there is no group code to enter -->
+            <input type="hidden" id="invite" name="invite" value="${register.formData.invite}" />
+
+            <#if messagesPerField.existsError('invite')>
+                <div class="${properties.kcFormGroupClass!}">
+                    <span id="input-error-invite" class="${properties.kcInputErrorMessageClass!}" aria-live="polite">
+                        ${kcSanitize(messagesPerField.get('invite'))?no_esc}
+                    </span>
+                </div>
+            </#if>
+            <#else>
             <div class="${properties.kcFormGroupClass!}">
                 <div class="${properties.kcLabelWrapperClass!}">
                     <label for="groupCode" class="${properties.kcLabelClass!}">${msg("groupCode")}</label>
@@ -87,6 +99,7 @@
                     </#if>
                 </div>
             </div>
+            </#if>
 
             <@registerCommons.termsAcceptance/>
 
@@ -61,7 +61,7 @@ const customBaseQuery: BaseQueryFn<FetchArgs, unknown, FetchBaseQueryError, Cust
   (cArgs.headers as Headers).set('X-Tz', tz);
 
   const state = api.getState() as RootState;
-  const { sessionId, vaultKey } = state.auth;
+  const { sessionId, vaultKey, vaultKeyId } = state.auth;
   const isMutation = ['POST', 'PUT', 'PATCH'].includes(cArgs.method || '');
   const cacheKey = getCacheKey(cArgs.url, sessionId);
 
@@ -76,6 +76,10 @@ const customBaseQuery: BaseQueryFn<FetchArgs, unknown, FetchBaseQueryError, Cust
       if (crypto) {
         extraOptions.vaultSecret = crypto.secretB64;
 
+        if (vaultKeyId) {
+          (cArgs.headers as Headers).set('X-Awayto-Vault-Key-Id', vaultKeyId);
+        }
+
         if (isMutation) {
           cArgs.body = crypto.blobBytes;
           (cArgs.headers as Headers).set('Content-Type', 'application/x-awayto-vault');
@@ -115,9 +119,9 @@ const customBaseQuery: BaseQueryFn<FetchArgs, unknown, FetchBaseQueryError, Cust
       handle401(tz, refreshResult.error);
 
       if (refreshResult.data) {
-        const newKey = (refreshResult.data as { key: string }).key;
+        const { key: newKey, keyId: newKeyId } = refreshResult.data as { key: string, keyId: string };
         if (newKey.length) {
-          api.dispatch(authSlice.actions.setVault({ vaultKey: newKey }));
+          api.dispatch(authSlice.actions.setVault({ vaultKey: newKey, vaultKeyId: newKeyId }));
           return customBaseQuery(oArgs, api, { ...extraOptions, hasRetriedVault: true });
         }
       }
@@ -15,6 +15,7 @@ export type IAuth = {
   authenticated?: boolean;
   sessionId?: string;
   vaultKey?: string;
+  vaultKeyId?: string;
 }
 
 export async function logout() {
@@ -32,15 +33,17 @@ export const authConfig = {
   initialState: {
     authenticated: undefined,
     sessionId: '',
-    vaultKey: ''
+    vaultKey: '',
+    vaultKeyId: ''
   } as IAuth,
   reducers: {
     setAuthenticated: (state: IAuth, action: { payload: IAuth }) => {
       state.authenticated = action.payload.authenticated;
     },
     setVault: (state: IAuth, action: { payload: IAuth }) => {
-      const { vaultKey, sessionId } = action.payload;
+      const { vaultKey, vaultKeyId, sessionId } = action.payload;
       if (vaultKey) state.vaultKey = vaultKey;
+      if (vaultKeyId) state.vaultKeyId = vaultKeyId;
       if (sessionId) state.sessionId = sessionId;
     },
   },
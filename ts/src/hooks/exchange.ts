@@ -1,4 +1,4 @@
-import { IBooking, IFile } from './api';
+import { IBooking, ICanvasElement, IFile } from './api';
 import { SocketMessage } from './web_socket';
 
 export enum ExchangeActions {
@@ -44,8 +44,16 @@ export interface IWhiteboard {
     position: number[];
   }>;
   boxes: DraggableBoxData[];
+  elements?: ICanvasElement[];
+  operation?: string;
 }
 
+/**
+ * @category Exchange
+ * @purpose the most points sent in one line element, longer strokes are split into several lines
+ */
+export const CANVAS_LINE_MAX_POINTS = 300;
+
 /**
  * @category Exchange
  * @purpose maps websocket responses which contain common WebRTC protocol objects
@@ -1,5 +1,6 @@
 import { IGroup, ISchedule } from './api';
-export type IKiosk = IGroup & {
-  schedules: Record<string, ISchedule>;
+export type IKiosk = {
+  group: IGroup;
+  groupSchedules: Record<string, ISchedule>;
   updatedOn: string;
 }
@@ -46,6 +46,9 @@ export function useWebSocketSubscribe<T>(topic: string, callback: SocketResponse
               if (sub) {
                 sub.cids = Array.from(new Set([...sub.cids, ...participant.cids]))
                 sub.online = participant.online
+                if (participant.lastReadId && participant.lastReadId > (sub.lastReadId || '')) {
+                  sub.lastReadId = participant.lastReadId
+                }
                 ul[sub.scid] = sub;
               } else {
                 participant.color = generateLightBgColor();
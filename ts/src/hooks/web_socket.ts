@@ -25,6 +25,13 @@ export enum SocketActions {
   ROLE_CALL = 23,
   PING_PONG = 24,
   SET_BOX = 25,
+  SET_ELEMENTS = 26,
+  DELETE_ELEMENTS = 27,
+  UNDO_ELEMENTS = 28,
+  REDO_ELEMENTS = 29,
+  EDIT_MESSAGE = 30,
+  DELETE_MESSAGE = 31,
+  READ_MESSAGES = 32,
 }
 
 /**
@@ -59,6 +66,8 @@ export type SocketParticipant = {
   color: string;
   exists: boolean;
   online: boolean;
+  lastReadId?: string; // the newest topic message the participant has seen
+  unreadCount?: number;
 }
 
 /**
@@ -66,6 +75,8 @@ export type SocketParticipant = {
  * @purpose provides structure to chat messages during interactions
  */
 export type SocketMessage = SocketParticipant & {
+  id?: string; // stored messages only, ids are ordered by time
+  edited?: boolean;
   style: 'utterance' | 'written';
   action?: () => React.JSX.Element;
   sender: string;
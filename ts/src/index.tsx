@@ -77,7 +77,7 @@ async function loadExternal() {
 
       const tzParam = `tz=${Intl.DateTimeFormat().resolvedOptions().timeZone}`
 
-      if (currentPathname.endsWith('/join') && currentSearch.includes('groupCode')) {
+      if (currentPathname.endsWith('/join') && (currentSearch.includes('groupCode') || currentSearch.includes('invite'))) {
         window.location.href = `/auth/register${currentSearch}&${tzParam}`;
         return;
       } else if (currentPathname.endsWith('/register')) {
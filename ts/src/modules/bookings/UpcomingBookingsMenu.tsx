@@ -1,16 +1,18 @@
-import React, { useContext } from 'react';
+import React, { useCallback, useContext } from 'react';
 import { useNavigate } from 'react-router-dom';
 
 // import Button from '@mui/material/Button';
+import IconButton from '@mui/material/IconButton';
 import Menu from '@mui/material/Menu';
 import MenuItem from '@mui/material/MenuItem';
 import ListItem from '@mui/material/ListItem';
 import ListItemIcon from '@mui/material/ListItemIcon';
 import ListItemText from '@mui/material/ListItemText';
 
+import EventIcon from '@mui/icons-material/Event';
 import JoinFullIcon from '@mui/icons-material/JoinFull';
 
-import { bookingFormat, targets } from 'awayto/hooks';
+import { bookingFormat, decryptData, encryptData, targets, useAppSelector, useUtil } from 'awayto/hooks';
 
 import BookingContext, { BookingContextType } from './BookingContext';
 
@@ -30,6 +32,40 @@ export function UpcomingBookingsMenu({ handleMenuClose, upcomingBookingsAnchorEl
 
   const { bookingValues: upcomingBookings } = useContext(BookingContext) as BookingContextType;
 
+  const { setSnack } = useUtil();
+  const { vaultKey, sessionId } = useAppSelector(state => state.auth);
+
+  // calendar files are fetched manually like form exports, as RTK Query expects json
+  const downloadBookingCalendar = useCallback(async (bookingId: string) => {
+    if (!vaultKey || !sessionId) return;
+
+    const crypto = encryptData(vaultKey, sessionId, ' ');
+    if (!crypto) return;
+
+    const response = await fetch(`/api/v1/bookings/${bookingId}/ics`, {
+      credentials: 'include',
+      headers: {
+        'X-Awayto-Vault': crypto.blobB64,
+        'X-Tz': Intl.DateTimeFormat().resolvedOptions().timeZone,
+      },
+    });
+
+    const decrypted = decryptData(crypto.secretB64, sessionId, await response.text());
+
+    if (response.status !== 200 || !decrypted) {
+      setSnack({ snackType: 'error', snackOn: 'The calendar event could not be downloaded.' });
+      return;
+    }
+
+    const url = window.URL.createObjectURL(new Blob([decrypted.bytes], { type: 'text/calendar' }));
+
+    const link = document.createElement('a');
+    link.href = url;
+    link.setAttribute('download', `booking_${bookingId}.ics`);
+    link.click();
+    window.URL.revokeObjectURL(url);
+  }, [vaultKey, sessionId]);
+
   return <Menu
     anchorEl={upcomingBookingsAnchorEl}
     anchorOrigin={{
@@ -77,6 +113,19 @@ export function UpcomingBookingsMenu({ handleMenuClose, upcomingBookingsAnchorEl
           >
             Test
           </ListItemText>
+          <IconButton
+            {...targets(
+              `add to calendar ${booking.slotDate} ${booking.scheduleBracketSlot.startTime}`,
+              `download a calendar event for ${bookingFormat(booking.slotDate, booking.scheduleBracketSlot.startTime)}`
+            )}
+            color="info"
+            onClick={e => {
+              e.stopPropagation();
+              void downloadBookingCalendar(booking.id);
+            }}
+          >
+            <EventIcon />
+          </IconButton>
         </MenuItem>
       } else {
         return <span key={`appt_placeholder${i}`} />;
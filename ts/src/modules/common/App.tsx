@@ -30,7 +30,8 @@ export default function App(props: IComponent): React.JSX.Element {
     if (keyIsSuccess && isSecureEnvironment()) {
       setVault({
         sessionId: keyRequest?.sid,
-        vaultKey: keyRequest?.key
+        vaultKey: keyRequest?.key,
+        vaultKeyId: keyRequest?.keyId
       });
     }
   }, [keyIsSuccess, keyRequest]);
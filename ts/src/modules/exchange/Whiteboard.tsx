@@ -13,10 +13,13 @@ import Box from '@mui/material/Box';
 import Grid from '@mui/material/Grid';
 import Stack from '@mui/material/Stack';
 
-import { IFile, IWhiteboard, SocketActions, DraggableBoxData, useWebSocketSubscribe, useFileContents, useUtil, getRelativeCoordinates, generateLightBgColor } from 'awayto/hooks';
+import { IFile, IWhiteboard, ICanvasElement, SocketActions, DraggableBoxData, CANVAS_LINE_MAX_POINTS, siteApi, useWebSocketSubscribe, useFileContents, useUtil, getRelativeCoordinates, generateLightBgColor } from 'awayto/hooks';
 import WhiteboardOptionsMenu from './WhiteboardOptionsMenu';
 import WhiteboardBoxes from './WhiteboardBoxes';
 
+// keeps element messages well under the socket read limit
+const CANVAS_BATCH_MAX_BYTES = 6000;
+
 interface WhiteboardProps extends IComponent {
   topicId: string;
   optionsMenu: React.JSX.Element;
@@ -37,6 +40,9 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
   const boxDidUpdate = useRef<boolean>(false);
   const isDrawing = useRef<boolean>(false);
   const lastTouchPoint = useRef<{ x: number, y: number } | null>(null);
+  const strokePoints = useRef<{ x: number, y: number }[]>([]);
+  const elements = useRef(new Map<string, ICanvasElement>());
+  const pendingElements = useRef(new Map<string, ICanvasElement>());
 
   const whiteboard = useRef<IWhiteboard>({
     boxes: [],
@@ -51,6 +57,8 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
 
   const { openConfirm } = useUtil();
 
+  const { data: profileRequest } = siteApi.useUserProfileServiceGetUserProfileDetailsQuery();
+
   const [canvasPointerEvents, setCanvasPointerEvents] = useState('none');
   const [zoom, setZoom] = useState(1);
   const [currentFile, setCurrentFile] = useState<IFile | null | undefined>(sharedFile);
@@ -67,7 +75,6 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
   const {
     connectionId,
     userList,
-    storeMessage: storeWhiteboardMessage,
     sendMessage: sendWhiteboardMessage
   } = useWebSocketSubscribe<IWhiteboard>(topicId, ({ sender, action, payload }) => {
     setBoards(b => {
@@ -87,9 +94,6 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
         if (connectionId !== sender) {
           handleLines(payload.lines, board.settings?.stroke, board.settings?.highlight);
         }
-        if (!payload.lines?.length && whiteboardRef.current) {
-          whiteboardRef.current.width = whiteboardRef.current.width;
-        }
       } else if (SocketActions.SHARE_FILE === action) {
         setNumPages(0);
         setPageNumber(1);
@@ -120,19 +124,23 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
         if (payload.selectedText?.length) {
           setSelectedText({ [sender]: payload.selectedText });
         }
-      } else if (SocketActions.SET_BOX === action) {
-        const pb = (payload.boxes || []);
-        if (connectionId !== sender) {
-          whiteboard.current.boxes = pb;
-          setBoxes(pb);
+      } else if (SocketActions.SET_ELEMENTS === action) {
+        // our own changes are already drawn, only their stored versions are new
+        const ownChange = connectionId === sender && 'set' === payload.operation;
+        for (const element of payload.elements || []) {
+          if (!element.id) continue;
+          const current = elements.current.get(element.id);
+          if (ownChange && current) {
+            elements.current.set(element.id, { ...current, version: element.version, createdSub: element.createdSub });
+          } else if (element.deleted) {
+            elements.current.delete(element.id);
+          } else {
+            elements.current.set(element.id, element);
+          }
         }
-        // Check for deletions
-        const validBoxes = pb.filter(b => b && b.text != '');
-        if (validBoxes.length != pb.length) {
-          whiteboard.current.boxes = validBoxes;
-          setBoxes(validBoxes);
+        if (!ownChange) {
+          drawElements();
         }
-
       } else if (SocketActions.CHANGE_SETTING === action) {
       }
       return { ...b, [sender]: board };
@@ -167,6 +175,59 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
     requestAnimationFrame(draw);
   };
 
+  // Redraws the canvas and boxes from the stored elements, which also clears any live strokes
+  const drawElements = () => {
+    if (whiteboardRef.current) {
+      whiteboardRef.current.width = whiteboardRef.current.width;
+    }
+
+    const elementBoxes: DraggableBoxData[] = [];
+    for (const element of elements.current.values()) {
+      if (element.line?.points?.length) {
+        const points = element.line.points.map(p => ({ x: p.x || 0, y: p.y || 0 }));
+        handleLines(points.slice(1).map((endPoint, i) => ({ startPoint: points[i], endPoint })), element.line.stroke, element.line.highlight);
+      } else if (element.box && element.id) {
+        elementBoxes.push({ ...element.box, id: element.id } as DraggableBoxData);
+      }
+    }
+
+    const stroke = strokePoints.current;
+    if (stroke.length > 1) {
+      handleLines(stroke.slice(1).map((endPoint, i) => ({ startPoint: stroke[i], endPoint })), whiteboard.current.settings.stroke, whiteboard.current.settings.highlight);
+    }
+
+    whiteboard.current.boxes = elementBoxes;
+    setBoxes(elementBoxes);
+  };
+
+  // A finished stroke is kept as one line element
+  const finishStroke = () => {
+    const points = strokePoints.current;
+    strokePoints.current = [];
+    if (points.length < 2) return;
+
+    const element: ICanvasElement = {
+      id: crypto.randomUUID(),
+      version: 0,
+      line: {
+        points,
+        stroke: whiteboard.current.settings.stroke,
+        highlight: whiteboard.current.settings.highlight,
+      },
+    };
+    elements.current.set(element.id!, element);
+    pendingElements.current.set(element.id!, element);
+  };
+
+  const addStrokePoint = (point: { x: number, y: number }) => {
+    const rounded = { x: Math.round(point.x), y: Math.round(point.y) };
+    strokePoints.current.push(rounded);
+    if (strokePoints.current.length >= CANVAS_LINE_MAX_POINTS) {
+      finishStroke();
+      strokePoints.current = [rounded];
+    }
+  };
+
   // Modified to support both mouse and touch events
   const handleMouseDown = useCallback((event: React.MouseEvent<HTMLCanvasElement>) => {
     event.preventDefault();
@@ -176,6 +237,8 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
     isDrawing.current = true;
     const startPoint = getRelativeCoordinates(event, canvas);
     lastTouchPoint.current = startPoint;
+    strokePoints.current = [];
+    addStrokePoint(startPoint);
 
     const onMouseMove = (e: MouseEvent) => {
       if (!isDrawing.current) return;
@@ -194,6 +257,7 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
       }
 
       handleLines([newLine], whiteboard.current.settings.stroke, whiteboard.current.settings.highlight);
+      addStrokePoint(endPoint);
 
       // Update lastPoint
       lastTouchPoint.current = endPoint;
@@ -201,6 +265,7 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
 
     const onMouseUp = () => {
       isDrawing.current = false;
+      finishStroke();
       window.removeEventListener('mousemove', onMouseMove);
       window.removeEventListener('mouseup', onMouseUp);
     };
@@ -223,6 +288,8 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
       y: touch.clientY - rect.top
     };
     lastTouchPoint.current = startPoint;
+    strokePoints.current = [];
+    addStrokePoint(startPoint);
   }, []);
 
   const handleTouchMove = useCallback((event: React.TouchEvent<HTMLCanvasElement>) => {
@@ -253,12 +320,14 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
     };
 
     handleLines([newLine], whiteboard.current.settings.stroke, whiteboard.current.settings.highlight);
+    addStrokePoint(endPoint);
     lastTouchPoint.current = endPoint;
   }, []);
 
   const handleTouchEnd = useCallback(() => {
     isDrawing.current = false;
     lastTouchPoint.current = null;
+    finishStroke();
   }, []);
 
   // New scroll handling for touch events
@@ -287,9 +356,43 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
     return newText;
   }, [selectedText, userList]);
 
+  // Queues boxes which differ from their stored elements, and deletes the elements of removed boxes
+  const queueBoxChanges = () => {
+    const boxIds = new Set<string>();
+    for (const { id, x, y, width, height, color, text } of whiteboard.current.boxes) {
+      boxIds.add(id);
+      const current = elements.current.get(id);
+      const box = current?.box;
+      if (box && box.x === x && box.y === y && box.width === width && box.height === height && box.color === color && box.text === text) {
+        continue;
+      }
+      const element: ICanvasElement = { id, version: current?.version || 0, box: { x, y, width, height, color, text } };
+      elements.current.set(id, element);
+      pendingElements.current.set(id, element);
+    }
+
+    const removed = [...elements.current.values()].filter(e => e.box && e.id && !boxIds.has(e.id));
+    if (removed.length) {
+      deleteElements(removed);
+    }
+  };
+
+  const deleteElements = (removed: ICanvasElement[]) => {
+    const deleted: ICanvasElement[] = [];
+    for (const { id, version } of removed) {
+      if (!id) continue;
+      elements.current.delete(id);
+      pendingElements.current.delete(id);
+      deleted.push({ id, version });
+    }
+    for (let i = 0; i < deleted.length; i += 50) {
+      sendWhiteboardMessage(SocketActions.DELETE_ELEMENTS, { elements: deleted.slice(i, i + 50) });
+    }
+  };
+
   const sendBatchedData = () => {
     if (whiteboard.current.lines.length > 0) {
-      storeWhiteboardMessage(SocketActions.DRAW_LINES, {
+      sendWhiteboardMessage(SocketActions.DRAW_LINES, {
         lines: whiteboard.current.lines,
         settings: {
           stroke: whiteboard.current.settings.stroke,
@@ -300,9 +403,19 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
     }
     if (boxDidUpdate.current) {
       boxDidUpdate.current = false;
-      storeWhiteboardMessage(SocketActions.SET_BOX, {
-        boxes: whiteboard.current.boxes
-      });
+      queueBoxChanges();
+    }
+    if (pendingElements.current.size > 0) {
+      const batch: ICanvasElement[] = [];
+      let batchBytes = 0;
+      for (const [id, element] of pendingElements.current) {
+        const elementBytes = JSON.stringify(element).length;
+        if (batch.length && batchBytes + elementBytes > CANVAS_BATCH_MAX_BYTES) break;
+        batch.push(element);
+        batchBytes += elementBytes;
+        pendingElements.current.delete(id);
+      }
+      sendWhiteboardMessage(SocketActions.SET_ELEMENTS, { elements: batch });
     }
   };
 
@@ -367,7 +480,12 @@ export default function Whiteboard({ chatOpen, chatBox, callBox, optionsMenu, sh
         whiteboard: whiteboard.current,
         whiteboardRef: whiteboardRef.current,
         sendWhiteboardMessage,
-        storeWhiteboardMessage,
+        onClearDrawings() {
+          // only your own drawings can be deleted, new ones have no creator until they're stored
+          const userSub = profileRequest?.userProfile?.sub;
+          deleteElements([...elements.current.values()].filter(e => e.line && (!e.createdSub || e.createdSub === userSub)));
+          drawElements();
+        },
         onCanvasInputChanged(inputMethod) {
           setCanvasPointerEvents('auto');
           switch (inputMethod) {
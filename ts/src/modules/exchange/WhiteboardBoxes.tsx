@@ -191,12 +191,9 @@ export default function WhiteboardBoxes({ boxes, setBoxes, whiteboardRef, didUpd
           onClick={e => {
             e.preventDefault();
             setBoxes(prevBoxes => {
-              const bid = prevBoxes.find(b => b.id == box.id);
-              if (bid) {
-                bid.text = "";
-              }
-              didUpdate(prevBoxes);
-              return prevBoxes;
+              const updatedBoxes = prevBoxes.filter(b => b.id !== box.id);
+              didUpdate(updatedBoxes);
+              return updatedBoxes;
             });
           }}
         >
@@ -16,6 +16,8 @@ import TextField from '@mui/material/TextField';
 import EditIcon from '@mui/icons-material/Edit';
 import BrushIcon from '@mui/icons-material/Brush';
 import LayersClearIcon from '@mui/icons-material/LayersClear';
+import UndoIcon from '@mui/icons-material/Undo';
+import RedoIcon from '@mui/icons-material/Redo';
 import PanToolIcon from '@mui/icons-material/PanTool';
 import ZoomInIcon from '@mui/icons-material/ZoomIn';
 import MenuBookIcon from '@mui/icons-material/MenuBook';
@@ -37,7 +39,7 @@ interface WhiteboardOptionsMenuProps extends IComponent {
   whiteboard: IWhiteboard;
   whiteboardRef: HTMLCanvasElement | null;
   sendWhiteboardMessage: (action: SocketActions, payload?: Partial<IWhiteboard> | undefined) => void;
-  storeWhiteboardMessage: (action: SocketActions, payload?: Partial<IWhiteboard> | undefined) => void;
+  onClearDrawings: () => void;
   onCanvasInputChanged: (inputMethod: string) => void;
   onBoxAdded: (box: DraggableBoxData) => void;
   pageNumber: number;
@@ -49,7 +51,7 @@ export function WhiteboardOptionsMenu({
   whiteboard,
   whiteboardRef,
   sendWhiteboardMessage,
-  storeWhiteboardMessage,
+  onClearDrawings,
   onCanvasInputChanged,
   onBoxAdded,
   pageNumber,
@@ -91,7 +93,7 @@ export function WhiteboardOptionsMenu({
     setBoxText('');
     onCanvasInputChanged('addedBox');
     onBoxAdded({
-      id: (new Date()).getTime().toString(),
+      id: crypto.randomUUID(),
       color: generateLightBgColor(),
       x: 100,
       y: 100,
@@ -186,13 +188,29 @@ export function WhiteboardOptionsMenu({
               </IconButton>
             </Tooltip>
 
-            <Tooltip title="Clear Canvas">
+            <Tooltip title="Undo">
               <IconButton
-                {...targets(`whiteboard clear canvas`, `remove all drawings from whiteboard`)}
+                {...targets(`whiteboard undo`, `undo your last change to the whiteboard`)}
+                onClick={() => sendWhiteboardMessage(SocketActions.UNDO_ELEMENTS)}
+              >
+                <UndoIcon />
+              </IconButton>
+            </Tooltip>
+
+            <Tooltip title="Redo">
+              <IconButton
+                {...targets(`whiteboard redo`, `redo your last undone change to the whiteboard`)}
+                onClick={() => sendWhiteboardMessage(SocketActions.REDO_ELEMENTS)}
+              >
+                <RedoIcon />
+              </IconButton>
+            </Tooltip>
+
+            <Tooltip title="Clear Your Drawings">
+              <IconButton
+                {...targets(`whiteboard clear canvas`, `remove your drawings from the whiteboard`)}
                 color="error"
-                onClick={() => {
-                  storeWhiteboardMessage(SocketActions.DRAW_LINES, { lines: [] });
-                }}
+                onClick={onClearDrawings}
               >
                 <LayersClearIcon />
               </IconButton>
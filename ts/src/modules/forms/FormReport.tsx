@@ -1,18 +1,20 @@
-import { useEffect, useMemo, useState } from 'react';
+import { useCallback, useEffect, useMemo, useState } from 'react';
 import { useParams } from 'react-router-dom';
 
 import { ChartTypeRegistry } from 'chart.js/auto';
 
+import Button from '@mui/material/Button';
 import Card from '@mui/material/Card';
 import CardHeader from '@mui/material/CardHeader';
 import CardContent from '@mui/material/CardContent';
 import CardActionArea from '@mui/material/CardActionArea';
+import CardActions from '@mui/material/CardActions';
 import Grid from '@mui/material/Grid';
 import MenuItem from '@mui/material/MenuItem';
 import TextField from '@mui/material/TextField';
 import Typography from '@mui/material/Typography';
 
-import { IField, IFormTemplate, IProtoFormDataPoint, siteApi, targets } from 'awayto/hooks';
+import { IField, IFormTemplate, IProtoFormDataPoint, decryptData, encryptData, siteApi, targets, useAppSelector, useUtil } from 'awayto/hooks';
 
 import FormChart from './FormChart';
 
@@ -35,18 +37,41 @@ export function FormReport(_: IComponent): React.JSX.Element {
 
   const { data: formRequest } = siteApi.useGroupFormServiceGetGroupFormByIdQuery({ formId });
   const [getVersionFieldReport] = siteApi.useLazyGroupFormServiceGetGroupFormVersionReportQuery();
-  // const [getFormVersionData] = siteApi.useLazyFormServiceGetFormVersionDataQuery();
-  {/* <Tooltip key={'download_data'} title="Download Data"> */ }
-  {/*   <Button */ }
-  {/*     {...targets(`manage forms download`, `download the data for the selected form`)} */ }
-  {/*     color="info" */ }
-  {/*     onClick={() => getFormVersionData({ formVersionId: selected[0] })} */ }
-  {/*   > */ }
-  {/*     <Typography variant="button" sx={{ display: { xs: 'none', md: 'flex' } }}>Download</Typography> */ }
-  {/*     <DownloadIcon sx={classes.variableButtonIcon} /> */ }
-  {/*   </Button> */ }
-  {/* </Tooltip>, */ }
 
+  const { setSnack } = useUtil();
+  const { vaultKey, sessionId } = useAppSelector(state => state.auth);
+
+  // exports are binary, so fetched manually like file contents instead of through RTK Query
+  const downloadVersionData = useCallback(async (format: 'csv' | 'xlsx') => {
+    if (!formVersionId || !vaultKey || !sessionId) return;
+
+    const crypto = encryptData(vaultKey, sessionId, ' ');
+    if (!crypto) return;
+
+    const response = await fetch(`/api/v1/group/forms/version/${formVersionId}/data/export/${format}`, {
+      credentials: 'include',
+      headers: {
+        'X-Awayto-Vault': crypto.blobB64,
+        'X-Tz': Intl.DateTimeFormat().resolvedOptions().timeZone,
+      },
+    });
+
+    const decrypted = decryptData(crypto.secretB64, sessionId, await response.text());
+
+    if (response.status !== 200 || !decrypted) {
+      setSnack({ snackType: 'error', snackOn: 'Form data could not be downloaded.' });
+      return;
+    }
+
+    const type = format === 'csv' ? 'text/csv' : 'application/vnd.openxmlformats-officedocument.spreadsheetml.sheet';
+    const url = window.URL.createObjectURL(new Blob([decrypted.bytes], { type }));
+
+    const link = document.createElement('a');
+    link.href = url;
+    link.setAttribute('download', `${formRequest?.groupForm.form?.name || 'form'}_data.${format}`);
+    link.click();
+    window.URL.revokeObjectURL(url);
+  }, [formVersionId, vaultKey, sessionId, formRequest?.groupForm.form?.name]);
 
   useEffect(() => {
     if (formRequest?.versionIds.length && !formVersionId) {
@@ -158,6 +183,19 @@ export function FormReport(_: IComponent): React.JSX.Element {
       >
         <Typography sx={{ m: 2, display: 'flex' }} color="secondary" variant="button">Generate</Typography>
       </CardActionArea>
+
+      <CardActions>
+        <Button
+          {...targets(`form report download csv`, `download the submissions of the selected version as csv`)}
+          color="info"
+          onClick={() => downloadVersionData('csv')}
+        >Download CSV</Button>
+        <Button
+          {...targets(`form report download xlsx`, `download the submissions of the selected version as xlsx`)}
+          color="info"
+          onClick={() => downloadVersionData('xlsx')}
+        >Download XLSX</Button>
+      </CardActions>
     </Card>
 
     {field && !!fieldData.length && <FormChart field={field} chartType={chartType} data={fieldData} />}
@@ -25,6 +25,7 @@ import ManageServices from '../services/ManageServices';
 import ManageGroupModal from './ManageGroupModal';
 import GroupSeatsModal from '../seats/GroupSeatsModal';
 import ManageGroupSeats from '../seats/ManageGroupSeats';
+import ManageGroupWebhooks from '../webhooks/ManageGroupWebhooks';
 import { useTheme } from '@mui/material';
 
 const { APP_GROUP_ADMIN, APP_GROUP_ROLES, APP_GROUP_SCHEDULE_KEYS, APP_GROUP_SERVICES, APP_GROUP_USERS } = SiteRoles;
@@ -56,6 +57,7 @@ export function ManageGroupHome(props: IComponent): React.JSX.Element {
     feedback: [APP_GROUP_ADMIN],
     forms: [APP_GROUP_ADMIN],
     seats: [APP_GROUP_ADMIN],
+    webhooks: [APP_GROUP_ADMIN],
   }
 
   useEffect(() => {
@@ -103,6 +105,8 @@ export function ManageGroupHome(props: IComponent): React.JSX.Element {
         return <ManageForms {...props} />
       case 'seats':
         return <ManageGroupSeats {...props} />
+      case 'webhooks':
+        return <ManageGroupWebhooks {...props} />
       case 'services':
       default:
         return <ManageServices {...props} />
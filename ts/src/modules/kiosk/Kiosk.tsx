@@ -1,5 +1,5 @@
 import React, { useState, useEffect, useMemo } from 'react';
-import { useParams } from 'react-router-dom';
+import { useParams, useSearchParams } from 'react-router-dom';
 
 import Typography from '@mui/material/Typography';
 import Box from '@mui/material/Box';
@@ -19,12 +19,23 @@ import ScheduleDisplay from '../schedules/ScheduleDisplay';
 
 export function Kiosk(): React.JSX.Element {
   const { groupName, scheduleName } = useParams();
+  const [searchParams] = useSearchParams();
   if (!groupName) return <></>;
 
+  // kiosk tokens are created by group admins and given to the display once, e.g. /kiosk/group?token=...
+  const kioskToken = useMemo(() => {
+    const token = searchParams.get('token');
+    if (token) {
+      localStorage.setItem('kioskToken', token);
+      return token;
+    }
+    return localStorage.getItem('kioskToken') || '';
+  }, [searchParams]);
+
   const [scheduleId, setScheduleId] = useState(scheduleName);
   const [kiosk, setKiosk] = useState<IKiosk | undefined>();
 
-  const scheduleKeys = useMemo(() => Object.keys(kiosk?.schedules || {}), [kiosk]);
+  const scheduleKeys = useMemo(() => Object.keys(kiosk?.groupSchedules || {}), [kiosk]);
 
   if (scheduleKeys.length && !scheduleId) {
     setScheduleId(scheduleKeys[0]);
@@ -33,7 +44,8 @@ export function Kiosk(): React.JSX.Element {
   const getData = () => {
     fetch(window.location.origin + `/api/kiosk/gs/${groupName}.json`, {
       headers: {
-        'Content-Type': 'application/json'
+        'Content-Type': 'application/json',
+        'Authorization': `Bearer ${kioskToken}`
       }
     }).then(async res => {
       if (res.ok) {
@@ -60,7 +72,7 @@ export function Kiosk(): React.JSX.Element {
   }, []);
 
   return <Box sx={{ display: 'flex', placeContent: 'center', width: '100%', height: '100vh' }}>
-    {!kiosk?.schedules || !scheduleId ? <Alert sx={{ placeSelf: 'center' }} severity="info">
+    {!kiosk?.groupSchedules || !scheduleId ? <Alert sx={{ placeSelf: 'center' }} severity="info">
       No schedules are currently active.
     </Alert> : <Card sx={{ width: '100%' }}>
       <CardHeader title="Group Name Services" subheader="The following times are currently available." />
@@ -78,12 +90,12 @@ export function Kiosk(): React.JSX.Element {
           <Typography variant="caption">Last Update: {kiosk.updatedOn}</Typography>
         </Box>
         <Box sx={{ my: 2, width: '100%', height: '100%' }}>
-          <ScheduleDisplay isKiosk={true} schedule={kiosk.schedules[scheduleId]} />
+          <ScheduleDisplay isKiosk={true} schedule={kiosk.groupSchedules[scheduleId]} />
           <Button
             {...targets(`kiosk join group`, `join the group to schedule an appointment`)}
             variant="contained"
             sx={{ mt: 4, fontSize: '1.5rem' }}
-            onClick={() => window.location.assign(`${window.location.origin}/join/${kiosk.code}`)}
+            onClick={() => window.location.assign(`${window.location.origin}/join/${kiosk.group.code}`)}
             endIcon={<DoubleArrorIcon />}
           >
             Create account to schedule your appointment
@@ -14,6 +14,11 @@ export function Profile(props: IComponent): React.JSX.Element {
 
   const { setSnack } = useUtil();
   const [patchUserProfile] = siteApi.useUserProfileServicePatchUserProfileMutation();
+  const [postBookingCalendarToken] = siteApi.useBookingServicePostBookingCalendarTokenMutation();
+  const [deleteBookingCalendarToken] = siteApi.useBookingServiceDeleteBookingCalendarTokenMutation();
+
+  // the feed url is only returned when created, so it isn't shown again after leaving the page
+  const [calendarFeedUrl, setCalendarFeedUrl] = useState('');
 
   // const fileStore = useFileStore();
 
@@ -83,6 +88,20 @@ export function Profile(props: IComponent): React.JSX.Element {
     void go();
   }
 
+  const handleCreateCalendarFeed = () => {
+    postBookingCalendarToken({ postBookingCalendarTokenRequest: {} }).unwrap().then(({ url }) => {
+      setCalendarFeedUrl(url);
+      setSnack({ snackType: 'success', snackOn: 'Calendar feed created. Any previous feed url no longer works.' });
+    }).catch(console.error);
+  }
+
+  const handleRemoveCalendarFeed = () => {
+    deleteBookingCalendarToken().unwrap().then(() => {
+      setCalendarFeedUrl('');
+      setSnack({ snackType: 'success', snackOn: 'Calendar feed removed.' });
+    }).catch(console.error);
+  }
+
   return <>
     <Grid container spacing={6}>
       <Grid size={{ sm: 12, md: 4 }}>
@@ -157,6 +176,31 @@ export function Profile(props: IComponent): React.JSX.Element {
           <Grid>
             <PickTheme {...props} />
           </Grid>
+          <Grid>
+            <Typography variant="h6">Calendar</Typography>
+            <Typography variant="caption">Subscribe to your upcoming bookings from a calendar app with a private feed url.</Typography>
+          </Grid>
+          {calendarFeedUrl && <Grid>
+            <TextField
+              {...targets(`profile calendar feed url`, `Calendar Feed URL`, `copy the private url of your bookings calendar feed`)}
+              fullWidth
+              value={calendarFeedUrl}
+              slotProps={{ input: { readOnly: true } }}
+              onFocus={e => e.target.select()}
+            />
+          </Grid>}
+          <Grid>
+            <Button
+              {...targets(`profile calendar feed create`, `create a new private calendar feed url, replacing any previous one`)}
+              color="info"
+              onClick={handleCreateCalendarFeed}
+            >{calendarFeedUrl ? 'Reset Feed' : 'Create Feed'}</Button>
+            <Button
+              {...targets(`profile calendar feed remove`, `remove your private calendar feed url`)}
+              color="error"
+              onClick={handleRemoveCalendarFeed}
+            >Remove Feed</Button>
+          </Grid>
         </Grid>
       </Grid>
       <Grid size={{ sm: 12, md: 8 }}>
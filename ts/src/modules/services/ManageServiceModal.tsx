@@ -26,6 +26,7 @@ const serviceSchema = {
 
 const serviceTierSchema = {
   name: '',
+  multiplier: 100,
   addons: {}
 } as IServiceTier;
 
@@ -417,8 +418,8 @@ export function ManageServiceModal({ groupDisplayName, groupPurpose, editGroupSe
             {/* <Box>
               <Typography variant="h6">Multiplier</Typography>
               <Box sx={{ display: 'flex', alignItems: 'baseline' }}>
-                <span>{newServiceTier.multiplier}x <span>&nbsp;</span> &nbsp;</span>
-                <Slider value={parseFloat(newServiceTier.multiplier)} onChange={(e, val) => setNewServiceTier({ ...newServiceTier, multiplier: parseFloat(val.toString()).toFixed(2) })} step={.01} min={1} max={5} />
+                <span>{((newServiceTier.multiplier || 100) / 100).toFixed(2)}x <span>&nbsp;</span> &nbsp;</span>
+                <Slider value={newServiceTier.multiplier || 100} onChange={(_, val) => setNewServiceTier({ ...newServiceTier, multiplier: val as number })} step={1} min={100} max={500} />
               </Box>
             </Box> */}
             <Grid container size="grow" justifyContent="space-between">
@@ -1,4 +1,4 @@
-import React from 'react';
+import React, { useState } from 'react';
 
 import Grid from '@mui/material/Grid';
 import Tooltip from '@mui/material/Tooltip';
@@ -8,36 +8,62 @@ import Chip from '@mui/material/Chip';
 import Avatar from '@mui/material/Avatar';
 import CardContent from '@mui/material/CardContent';
 import Typography from '@mui/material/Typography';
+import TextField from '@mui/material/TextField';
+import Box from '@mui/material/Box';
 
 import RecordVoiceOverIcon from '@mui/icons-material/RecordVoiceOver';
 import TextFieldsIcon from '@mui/icons-material/TextFields';
+import EditIcon from '@mui/icons-material/Edit';
+import DeleteIcon from '@mui/icons-material/Delete';
+import DoneIcon from '@mui/icons-material/Done';
+import DoneAllIcon from '@mui/icons-material/DoneAll';
 
-import { SocketMessage, utcDTLocal } from 'awayto/hooks';
+import { SocketMessage, SocketParticipant, targets, utcDTLocal } from 'awayto/hooks';
 import { capitalize } from '@mui/material/utils';
 
 interface IComponent {
   topicMessages?: SocketMessage[];
   action?: () => void;
+  userSub?: string;
+  userList?: Record<string, SocketParticipant>;
+  receipts?: Record<string, string>; // scid to the newest message id they've seen
+  onEditMessage?: (id: string, message: string) => void;
+  onDeleteMessage?: (id: string) => void;
 }
 
 interface GroupedMessage extends Omit<SocketMessage, 'message'> {
-  messages: string[];
+  messages: SocketMessage[];
 }
 
-function GroupedMessages({ topicMessages: messages }: IComponent): React.JSX.Element {
+function GroupedMessages({ topicMessages: messages, userSub, userList, receipts, onEditMessage, onDeleteMessage }: IComponent): React.JSX.Element {
+  const [editing, setEditing] = useState<{ id: string, message: string }>();
+
   if (!messages) return <></>;
 
+  // message ids are ordered by time, so anyone whose newest seen id is at least this one has seen it
+  const seenBy = (msg: SocketMessage) => Object.entries(receipts || {})
+    .filter(([scid, lastReadId]) => scid !== msg.scid && !!msg.id && lastReadId >= msg.id)
+    .map(([scid]) => userList?.[scid]?.name || '')
+    .filter(Boolean);
+
+  const saveEdit = () => {
+    if (editing && editing.message.trim() && onEditMessage) {
+      onEditMessage(editing.id, editing.message);
+    }
+    setEditing(undefined);
+  };
+
   const groupedMessages: GroupedMessage[] = [];
   let currentGroup: GroupedMessage | null = null;
   messages.forEach((msg, i) => {
     if (i === 0 || messages[i - 1].scid !== msg.scid || messages[i - 1].style !== msg.style) {
       currentGroup = {
         ...msg,
-        messages: [msg.message]
+        messages: [msg]
       };
       groupedMessages.push(currentGroup);
     } else if (currentGroup) {
-      currentGroup.messages.push(msg.message);
+      currentGroup.messages.push(msg);
     }
   });
 
@@ -65,11 +91,69 @@ function GroupedMessages({ topicMessages: messages }: IComponent): React.JSX.Ele
               </Tooltip>
             </Grid>
           </Grid>
-          {group.messages.map((message, j) => (
-            <Typography style={{ overflowWrap: 'anywhere', whiteSpace: 'pre-wrap' }} key={`${group.scid}_msg_${j}`}>
-              {message}
-            </Typography>
-          ))}
+          {group.messages.map((msg, j) => {
+            const own = !!userSub && msg.scid === userSub && !!msg.id;
+            const seen = own ? seenBy(msg) : [];
+
+            if (editing && editing.id === msg.id) {
+              return <TextField
+                {...targets(`grouped messages edit message ${msg.id}`, `Edit your message then press enter...`, `change the text of your message`)}
+                key={`${group.scid}_msg_${j}`}
+                fullWidth
+                multiline
+                autoFocus
+                value={editing.message}
+                onChange={e => setEditing({ ...editing, message: e.target.value })}
+                slotProps={{
+                  htmlInput: {
+                    maxLength: 1500
+                  },
+                  input: {
+                    onKeyDown: e => {
+                      if ('Enter' === e.key && !e.shiftKey) {
+                        e.preventDefault();
+                        saveEdit();
+                      } else if ('Escape' === e.key) {
+                        setEditing(undefined);
+                      }
+                    }
+                  }
+                }}
+              />
+            }
+
+            return <Box key={`${group.scid}_msg_${j}`} sx={{ display: 'flex', alignItems: 'flex-start' }}>
+              <Typography sx={{ flex: 1 }} style={{ overflowWrap: 'anywhere', whiteSpace: 'pre-wrap' }}>
+                {msg.message}
+                {msg.edited && <Typography component="span" variant="caption" sx={{ ml: 1 }}>(edited)</Typography>}
+              </Typography>
+              {own && <>
+                <Tooltip title={seen.length ? `Seen by ${seen.join(', ')}` : 'Sent'}>
+                  <IconButton size="small" disableRipple>
+                    {seen.length ? <DoneAllIcon fontSize="small" color="primary" /> : <DoneIcon fontSize="small" />}
+                  </IconButton>
+                </Tooltip>
+                {onEditMessage && <Tooltip title="Edit">
+                  <IconButton
+                    {...targets(`grouped messages edit ${msg.id}`, `edit your message`)}
+                    size="small"
+                    onClick={() => setEditing({ id: msg.id!, message: msg.message })}
+                  >
+                    <EditIcon fontSize="small" />
+                  </IconButton>
+                </Tooltip>}
+                {onDeleteMessage && <Tooltip title="Delete">
+                  <IconButton
+                    {...targets(`grouped messages delete ${msg.id}`, `delete your message`)}
+                    size="small"
+                    onClick={() => onDeleteMessage(msg.id!)}
+                  >
+                    <DeleteIcon fontSize="small" />
+                  </IconButton>
+                </Tooltip>}
+              </>}
+            </Box>
+          })}
           {Action && <Action />}
         </CardContent>
       </Card>
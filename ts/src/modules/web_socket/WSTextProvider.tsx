@@ -5,7 +5,7 @@ import Typography from '@mui/material/Typography';
 import Card from '@mui/material/Card';
 import CardHeader from '@mui/material/CardHeader';
 
-import { SocketMessage, SocketActions, plural, useWebSocketSubscribe } from 'awayto/hooks';
+import { SocketMessage, SocketActions, plural, siteApi, useWebSocketSubscribe } from 'awayto/hooks';
 
 import WSTextContext, { WSTextContextType } from './WSTextContext';
 import GroupedMessages from './GroupedMessages';
@@ -22,9 +22,14 @@ export function WSTextProvider({ children, topicId, topicMessages, setTopicMessa
 
   const messagesEndRef = useRef<HTMLDivElement>(null);
   const lastMessage = useRef<string>(undefined);
+  const lastReadId = useRef<string>('');
 
   const [hasMore, setHasMore] = useState(false);
   const [page, setPage] = useState(1);
+  const [receipts, setReceipts] = useState<Record<string, string>>({});
+
+  const { data: profileRequest } = siteApi.useUserProfileServiceGetUserProfileDetailsQuery();
+  const userSub = profileRequest?.userProfile?.sub;
 
   const {
     userList,
@@ -32,7 +37,7 @@ export function WSTextProvider({ children, topicId, topicMessages, setTopicMessa
     connected,
     sendMessage,
     storeMessage
-  } = useWebSocketSubscribe<{ page: number, pageSize: number, message: string, style: SocketMessage['style'] }>(topicId, ({
+  } = useWebSocketSubscribe<{ page: number, pageSize: number, id: string, edited: boolean, scid: string, message: string, style: SocketMessage['style'] }>(topicId, ({
     timestamp,
     sender,
     action,
@@ -45,7 +50,31 @@ export function WSTextProvider({ children, topicId, topicMessages, setTopicMessa
       return
     }
 
-    const { message, style } = payload;
+    if (action == SocketActions.EDIT_MESSAGE) {
+      const { id, message } = payload;
+      if (id && message) {
+        setTopicMessages(m => m.map(msg => msg.id == id ? { ...msg, message, edited: true } : msg));
+      }
+      return
+    }
+
+    if (action == SocketActions.DELETE_MESSAGE) {
+      const { id } = payload;
+      if (id) {
+        setTopicMessages(m => m.filter(msg => msg.id != id));
+      }
+      return
+    }
+
+    if (action == SocketActions.READ_MESSAGES) {
+      const { id, scid } = payload;
+      if (id && scid) {
+        setReceipts(r => (r[scid] || '') < id ? { ...r, [scid]: id } : r);
+      }
+      return
+    }
+
+    const { id, edited, message, style } = payload;
 
     if (message && style && setTopicMessages) {
       for (const user of Object.values(userList)) {
@@ -53,13 +82,15 @@ export function WSTextProvider({ children, topicId, topicMessages, setTopicMessa
           setTopicMessages(m => {
             const newMessage = {
               ...user,
+              id,
+              edited,
               sender,
               style,
               message,
               timestamp
             }
 
-            if (m.filter(msg => msg.timestamp == timestamp).length) {
+            if (m.filter(msg => id ? msg.id == id : msg.timestamp == timestamp).length) {
               return m;
             }
 
@@ -81,6 +112,38 @@ export function WSTextProvider({ children, topicId, topicMessages, setTopicMessa
     setHasMore(false);
   }, [page]);
 
+  // seed receipts with what each participant had seen when they were loaded
+  useEffect(() => {
+    setReceipts(r => {
+      const loaded = Object.values(userList).filter(u => u?.lastReadId && (r[u.scid] || '') < u.lastReadId);
+      if (!loaded.length) return r;
+      return { ...r, ...Object.fromEntries(loaded.map(u => [u.scid, u.lastReadId as string])) };
+    });
+  }, [userList]);
+
+  // let others know we've seen their newest message, once things settle so it has been stored
+  useEffect(() => {
+    if (!userSub || !topicMessages?.length) return;
+
+    const newest = [...topicMessages].reverse().find(msg => msg.id && msg.scid != userSub)?.id;
+    if (!newest || newest <= lastReadId.current || newest <= (receipts[userSub] || '')) return;
+
+    const readTimer = setTimeout(() => {
+      lastReadId.current = newest;
+      sendMessage(SocketActions.READ_MESSAGES, { id: newest });
+    }, 1000);
+
+    return () => clearTimeout(readTimer);
+  }, [userSub, topicMessages, receipts]);
+
+  const editMessage = useCallback((id: string, message: string) => {
+    sendMessage(SocketActions.EDIT_MESSAGE, { id, message });
+  }, [sendMessage]);
+
+  const deleteMessage = useCallback((id: string) => {
+    sendMessage(SocketActions.DELETE_MESSAGE, { id });
+  }, [sendMessage]);
+
   useEffect(() => {
     if (topicMessages && topicMessages.length) {
       const lt = topicMessages[topicMessages.length - 1].timestamp;
@@ -102,8 +165,15 @@ export function WSTextProvider({ children, topicId, topicMessages, setTopicMessa
       {hasMore && <Box onClick={getMore} sx={{ flex: 1, textAlign: 'center', cursor: 'pointer', }}>
         Load previous...
       </Box>}
-      <GroupedMessages topicMessages={topicMessages} />
-    </>, [topicMessages, hasMore, getMore]),
+      <GroupedMessages
+        topicMessages={topicMessages}
+        userSub={userSub}
+        userList={userList}
+        receipts={receipts}
+        onEditMessage={editMessage}
+        onDeleteMessage={deleteMessage}
+      />
+    </>, [topicMessages, hasMore, getMore, userSub, userList, receipts, editMessage, deleteMessage]),
     submitMessageForm: useMemo(() => {
       const userListNames = Object.keys(userList).length ? Object.values(userList).filter(u => u?.online).map(u => u?.name || '') : []
       return <>
@@ -9,6 +9,14 @@ const {
 } = import.meta.env;
 
 const defaultPadding = 5;
+const SERVICE_RESTART_CLOSE_CODE = 1012;
+
+// Offered in order of preference, the server answers with the first one it supports
+const SOCKET_PROTOCOL_PROTO = 'awayto.proto.v2';
+const SOCKET_PROTOCOL_TEXT = 'awayto.text.v1';
+
+const textEncoder = new TextEncoder();
+const textDecoder = new TextDecoder();
 
 function paddedLen(len: number) {
   let strLen = len.toString();
@@ -76,6 +84,108 @@ function parseMessage(eventData: string) {
   return socketResponse;
 }
 
+function writeVarint(bytes: number[], value: number) {
+  while (value > 0x7f) {
+    bytes.push((value & 0x7f) | 0x80);
+    value >>>= 7;
+  }
+  bytes.push(value);
+}
+
+// Encodes a SocketMessage (proto/sock.proto) as protobuf binary
+function generateProtoMessage(action: SocketActions, store: boolean, historical: boolean, timestamp: string, topic: string, cid: string, payload: string) {
+  const bytes: number[] = [];
+
+  const writeString = (field: number, value: string) => {
+    if (!value) return;
+    const encoded = textEncoder.encode(value);
+    bytes.push(field << 3 | 2);
+    writeVarint(bytes, encoded.length);
+    for (const b of encoded) bytes.push(b);
+  };
+
+  writeString(1, topic);
+  writeString(2, cid);
+  writeString(3, payload);
+  writeString(4, timestamp);
+  if (action) {
+    bytes.push(5 << 3);
+    writeVarint(bytes, action);
+  }
+  if (store) bytes.push(6 << 3, 1);
+  if (historical) bytes.push(7 << 3, 1);
+
+  return Uint8Array.from(bytes);
+}
+
+function parseProtoMessage(data: Uint8Array) {
+  const strings = ['', '', '', '']; // topic, sender, payload, timestamp
+  let action = 0, store = false, historical = false;
+
+  let cursor = 0;
+  const readVarint = () => {
+    let value = 0, shift = 0, b = 0;
+    do {
+      if (cursor >= data.length) throw new Error("varint index out of range");
+      b = data[cursor++];
+      value += (b & 0x7f) * 2 ** shift;
+      shift += 7;
+    } while (b & 0x80);
+    return value;
+  };
+
+  try {
+    while (cursor < data.length) {
+      const tag = readVarint();
+      const field = Math.floor(tag / 8);
+      switch (tag % 8) {
+        case 0: {
+          const value = readVarint();
+          if (5 == field) action = value;
+          else if (6 == field) store = value != 0;
+          else if (7 == field) historical = value != 0;
+          break;
+        }
+        case 2: {
+          const valLen = readVarint();
+          const valEnd = cursor + valLen;
+          if (data.length < valEnd) throw new Error("value index out of range");
+          if (field >= 1 && field <= strings.length) {
+            strings[field - 1] = textDecoder.decode(data.subarray(cursor, valEnd));
+          }
+          cursor = valEnd;
+          break;
+        }
+        case 1: cursor += 8; break;
+        case 5: cursor += 4; break;
+        default: throw new Error("unsupported wire type");
+      }
+    }
+  } catch (e) {
+    console.error(e);
+    return
+  }
+
+  const socketResponse: SocketResponse<string> = {
+    action,
+    store,
+    historical,
+    timestamp: strings[3] || (new Date()).toISOString(),
+    topic: strings[0],
+    sender: strings[1],
+    payload: strings[2],
+  };
+
+  return socketResponse;
+}
+
+function sendMessage(ws: WebSocket, action: SocketActions, store: boolean, topic: string, cid: string, payload: string) {
+  ws.send(SOCKET_PROTOCOL_PROTO == ws.protocol ?
+    generateProtoMessage(action, store, false, "", topic, cid, payload) :
+    generateMessage(action, store, false, "", topic, cid, payload)
+  );
+}
+
 function WebSocketProvider({ children }: IComponent): React.JSX.Element {
   console.log('websocket provider load');
 
@@ -97,7 +207,8 @@ function WebSocketProvider({ children }: IComponent): React.JSX.Element {
       const { ticket } = res;
       const [_, connId] = ticket.split(":");
 
-      const ws = new WebSocket(`wss://${VITE_REACT_APP_APP_HOST_NAME}/sock?ticket=${ticket}`)
+      const ws = new WebSocket(`wss://${VITE_REACT_APP_APP_HOST_NAME}/sock?ticket=${ticket}`, [SOCKET_PROTOCOL_PROTO, SOCKET_PROTOCOL_TEXT]);
+      ws.binaryType = 'arraybuffer';
       var roleChecking = false;
 
       ws.onopen = () => {
@@ -112,10 +223,15 @@ function WebSocketProvider({ children }: IComponent): React.JSX.Element {
         localStorage.setItem('oncall', 'true');
       };
 
-      ws.onclose = () => {
+      ws.onclose = (event) => {
         if (!localStorage.getItem('oncall')) {
           return
         }
+        if (SERVICE_RESTART_CLOSE_CODE == event.code) {
+          // the server is restarting and asked us to reconnect, spread out reconnects to avoid a burst
+          setTimeout(connect, 500 + Math.random() * 2000);
+          return
+        }
         if ('blurred' == localStorage.getItem('oncall')) {
           // setSnack({ snackOn: 'closing a blurred socket', snackType: 'warning' });
           localStorage.removeItem('oncall');
@@ -144,16 +260,16 @@ function WebSocketProvider({ children }: IComponent): React.JSX.Element {
       };
 
       ws.onmessage = async (event) => {
-        const socketResponse = parseMessage(event.data);
+        const socketResponse = 'string' == typeof event.data ?
+          parseMessage(event.data) :
+          parseProtoMessage(new Uint8Array(event.data as ArrayBuffer));
 
         if (!socketResponse) {
           return
         }
 
         if (socketResponse.payload == "PING") {
-          ws.send(
-            generateMessage(SocketActions.PING_PONG, false, false, "", "", "", "PONG")
-          );
+          sendMessage(ws, SocketActions.PING_PONG, false, "", "", "PONG");
         } else if (SocketActions.ROLE_CALL == socketResponse.action) {
           if (!roleChecking) {
             roleChecking = true;
@@ -201,9 +317,7 @@ function WebSocketProvider({ children }: IComponent): React.JSX.Element {
     connected: socket.current?.readyState === WebSocket.OPEN,
     transmit(store, action, topic, payload) {
       if (socket.current && socket.current.readyState === WebSocket.OPEN) {
-        socket.current.send(
-          generateMessage(action, store, false, "", topic, connectionId, JSON.stringify(payload) || '')
-        );
+        sendMessage(socket.current, action, store, topic, connectionId, JSON.stringify(payload) || '');
       }
     },
     subscribe(topic, callback) {
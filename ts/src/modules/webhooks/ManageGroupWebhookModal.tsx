@@ -0,0 +1,168 @@
+import React, { useState, useCallback } from 'react';
+
+import Alert from '@mui/material/Alert';
+import Grid from '@mui/material/Grid';
+import Typography from '@mui/material/Typography';
+import Card from '@mui/material/Card';
+import CardHeader from '@mui/material/CardHeader';
+import CardContent from '@mui/material/CardContent';
+import CardActions from '@mui/material/CardActions';
+import Button from '@mui/material/Button';
+import IconButton from '@mui/material/IconButton';
+import TextField from '@mui/material/TextField';
+import Checkbox from '@mui/material/Checkbox';
+import Switch from '@mui/material/Switch';
+import FormGroup from '@mui/material/FormGroup';
+import FormControlLabel from '@mui/material/FormControlLabel';
+
+import ContentCopyIcon from '@mui/icons-material/ContentCopy';
+
+import { useUtil, siteApi, IGroupWebhook, targets } from 'awayto/hooks';
+
+export const groupWebhookEvents: Record<string, string> = {
+  'booking.cancelled': 'A booking was cancelled',
+  'booking.created': 'A booking was scheduled',
+  'form.submitted': 'A form was submitted with a request',
+  'group_user.joined': 'A user joined the group',
+  'quote.created': 'A request was submitted',
+};
+
+interface ManageGroupWebhookModalProps extends IComponent {
+  editWebhook?: IGroupWebhook;
+}
+
+export function ManageGroupWebhookModal({ editWebhook, closeModal }: ManageGroupWebhookModalProps): React.JSX.Element {
+  const { setSnack } = useUtil();
+
+  const [postGroupWebhook] = siteApi.useGroupWebhookServicePostGroupWebhookMutation();
+  const [patchGroupWebhook] = siteApi.useGroupWebhookServicePatchGroupWebhookMutation();
+
+  const [webhook, setWebhook] = useState({
+    url: '',
+    events: [],
+    enabled: true,
+    ...editWebhook
+  } as Required<IGroupWebhook>);
+
+  const [secret, setSecret] = useState('');
+
+  const toggleEvent = (event: string) => {
+    const events = webhook.events.includes(event) ? webhook.events.filter(e => e !== event) : [...webhook.events, event];
+    setWebhook({ ...webhook, events });
+  }
+
+  const handleSubmit = useCallback(async () => {
+    const { id, url, events, enabled } = webhook;
+
+    if (!url.startsWith('https://') || !events.length) {
+      setSnack({ snackType: 'error', snackOn: 'Webhooks must use an https url and subscribe to at least 1 event.' });
+      return;
+    }
+
+    try {
+      if (id) {
+        await patchGroupWebhook({ id, patchGroupWebhookRequest: { id, url, events, enabled } }).unwrap();
+        if (closeModal)
+          closeModal();
+      } else {
+        const created = await postGroupWebhook({ postGroupWebhookRequest: { url, events } }).unwrap();
+        setSecret(created.secret);
+      }
+    } catch (error) {
+      console.error(error);
+    }
+  }, [webhook]);
+
+  const copySecret = () => {
+    window.navigator.clipboard.writeText(secret).catch(console.error);
+    setSnack({ snackType: 'success', snackOn: 'Signing secret copied to clipboard!' });
+  }
+
+  return <Card>
+    <CardHeader
+      title={`${editWebhook?.id ? 'Edit' : 'Create'} Webhook`}
+      subheader="Events are sent as a signed POST request to the url. Deliveries which fail are retried with increasing delays."
+    />
+    <CardContent>
+      {secret ? <Grid container direction="column" spacing={2}>
+        <Alert color="warning" variant="outlined">
+          <Typography variant="caption">
+            This is the only time the signing secret will be shown. Use it to verify the X-Awayto-Signature header on each delivery.
+          </Typography>
+        </Alert>
+        <TextField
+          {...targets(`manage group webhook modal secret`, `Signing Secret`, `the secret used to sign webhook deliveries`)}
+          fullWidth
+          value={secret}
+          slotProps={{
+            input: {
+              readOnly: true,
+              endAdornment: <IconButton
+                {...targets(`manage group webhook modal copy secret`, `copy the signing secret to your system clipboard`)}
+                color="info"
+                onClick={copySecret}
+              >
+                <ContentCopyIcon />
+              </IconButton>
+            }
+          }}
+        />
+      </Grid> : <Grid container direction="column" spacing={2}>
+        <TextField
+          {...targets(`manage group webhook modal url`, `URL`, `the https url which will receive events`)}
+          fullWidth
+          autoFocus
+          placeholder="https://"
+          value={webhook.url}
+          onChange={e => setWebhook({ ...webhook, url: e.target.value })}
+        />
+        <Typography variant="h6">Events</Typography>
+        <FormGroup>
+          {Object.entries(groupWebhookEvents).map(([event, description]) => <FormControlLabel
+            key={`group_webhook_event_${event}`}
+            {...targets(`manage group webhook modal event ${event}`, `${event} - ${description}`, `toggle whether the ${event} event is sent to this webhook`)}
+            control={
+              <Checkbox
+                checked={webhook.events.includes(event)}
+                onChange={() => toggleEvent(event)}
+              />
+            }
+          />)}
+        </FormGroup>
+        {webhook.id && <FormGroup>
+          <FormControlLabel
+            {...targets(`manage group webhook modal enabled`, `Enabled`, `toggle whether events are sent to this webhook`)}
+            control={
+              <Switch
+                checked={webhook.enabled}
+                onChange={e => setWebhook({ ...webhook, enabled: e.target.checked })}
+              />
+            }
+          />
+        </FormGroup>}
+      </Grid>}
+    </CardContent>
+    <CardActions>
+      <Grid size="grow" container justifyContent={secret ? 'flex-end' : 'space-between'}>
+        {secret ? <Button
+          {...targets(`manage group webhook modal done`, `close the webhook management modal`)}
+          color="info"
+          onClick={closeModal}
+        >Done</Button> : <>
+          <Button
+            {...targets(`manage group webhook modal close`, `close the webhook management modal`)}
+            color="error"
+            onClick={closeModal}
+          >Cancel</Button>
+          <Button
+            {...targets(`manage group webhook modal submit`, `submit the current webhook for creation or update`)}
+            color="info"
+            onClick={() => void handleSubmit()}
+          >Submit</Button>
+        </>}
+      </Grid>
+    </CardActions>
+  </Card>
+}
+
+export default ManageGroupWebhookModal;
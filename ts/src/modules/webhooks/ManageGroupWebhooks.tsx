@@ -0,0 +1,161 @@
+import React, { useState, useMemo, Suspense } from 'react';
+
+import Box from '@mui/material/Box';
+import Typography from '@mui/material/Typography';
+import Button from '@mui/material/Button';
+import Dialog from '@mui/material/Dialog';
+import Tooltip from '@mui/material/Tooltip';
+import IconButton from '@mui/material/IconButton';
+
+import CreateIcon from '@mui/icons-material/Create';
+import AddIcon from '@mui/icons-material/Add';
+import DeleteIcon from '@mui/icons-material/Delete';
+import ReplayIcon from '@mui/icons-material/Replay';
+
+import { DataGrid } from '@mui/x-data-grid';
+
+import { siteApi, useGrid, useStyles, useUtil, dayjs, IGroupWebhook, IGroupWebhookDelivery, targets } from 'awayto/hooks';
+
+import ManageGroupWebhookModal from './ManageGroupWebhookModal';
+
+export function ManageGroupWebhooks(props: IComponent): React.JSX.Element {
+  const classes = useStyles();
+  const { setSnack } = useUtil();
+
+  const [deleteGroupWebhook] = siteApi.useGroupWebhookServiceDeleteGroupWebhookMutation();
+  const [postGroupWebhookDeliveryReplay] = siteApi.useGroupWebhookServicePostGroupWebhookDeliveryReplayMutation();
+  const { data: groupWebhooksRequest, refetch: getGroupWebhooks } = siteApi.useGroupWebhookServiceGetGroupWebhooksQuery();
+
+  const [groupWebhook, setGroupWebhook] = useState<IGroupWebhook>();
+  const [selected, setSelected] = useState<string[]>([]);
+  const [dialog, setDialog] = useState('');
+
+  const groupWebhookId = selected.length == 1 ? selected[0] : '';
+
+  const { data: deliveriesRequest, refetch: getDeliveries } = siteApi.useGroupWebhookServiceGetGroupWebhookDeliveriesQuery({ groupWebhookId });
+
+  const replayDelivery = (id: string) => {
+    postGroupWebhookDeliveryReplay({ id, postGroupWebhookDeliveryReplayRequest: { id } }).unwrap().then(() => {
+      setSnack({ snackType: 'success', snackOn: 'The delivery will be sent again.' });
+      void getDeliveries();
+    }).catch(console.error);
+  }
+
+  const actions = useMemo(() => {
+    const { length } = selected;
+    const acts = length == 1 ? [
+      <Tooltip key={'manage_webhook'} title="Edit">
+        <Button
+          {...targets(`manage group webhooks edit`, `edit the selected webhook`)}
+          color="info"
+          onClick={() => {
+            setGroupWebhook(groupWebhooksRequest?.webhooks?.find(w => w.id === selected[0]));
+            setDialog('manage_webhook');
+            setSelected([]);
+          }}
+        >
+          <Typography variant="button" sx={{ display: { xs: 'none', md: 'flex' } }}>Edit</Typography>
+          <CreateIcon sx={classes.variableButtonIcon} />
+        </Button>
+      </Tooltip>
+    ] : [];
+
+    return [
+      ...acts,
+      <Tooltip key={'delete_webhook'} title="Delete">
+        <Button
+          {...targets(`manage group webhooks delete`, `delete the selected webhook or webhooks`)}
+          color="error"
+          onClick={() => {
+            if (selected.length) {
+              Promise.all(selected.map(id => deleteGroupWebhook({ id }).unwrap())).then(() => {
+                setSelected([]);
+                void getGroupWebhooks();
+              }).catch(console.error);
+            }
+          }}>
+          <Typography variant="button" sx={{ display: { xs: 'none', md: 'flex' } }}>Delete</Typography>
+          <DeleteIcon sx={classes.variableButtonIcon} />
+        </Button>
+      </Tooltip>
+    ]
+  }, [selected, groupWebhooksRequest]);
+
+  const webhookGridProps = useGrid<IGroupWebhook>({
+    rows: groupWebhooksRequest?.webhooks || [],
+    columns: [
+      { flex: 1, headerName: 'URL', field: 'url' },
+      { flex: 1, headerName: 'Events', field: 'events', renderCell: ({ row }) => row.events?.join(', ') },
+      { flex: 1, headerName: 'Enabled', field: 'enabled', renderCell: ({ row }) => row.enabled ? 'Yes' : 'No' },
+      { flex: 1, headerName: 'Created', field: 'createdOn', renderCell: ({ row }) => dayjs().to(dayjs.utc(row.createdOn)) },
+    ],
+    selected,
+    onSelected: selection => setSelected(selection as string[]),
+    toolbar: () => <>
+      <Typography variant="button">Webhooks:</Typography>
+      <Tooltip key={'manage_webhook'} title="Create">
+        <Button
+          {...targets(`manage group webhooks create`, `create a new webhook`)}
+          color="info"
+          onClick={() => {
+            setGroupWebhook(undefined);
+            setDialog('manage_webhook');
+          }}
+        >
+          <Typography variant="button" sx={{ display: { xs: 'none', md: 'flex' } }}>Create</Typography>
+          <AddIcon sx={classes.variableButtonIcon} />
+        </Button>
+      </Tooltip>
+      {!!selected.length && <Box sx={{ flexGrow: 1, textAlign: 'right' }}>{actions}</Box>}
+    </>
+  });
+
+  const deliveryGridProps = useGrid<IGroupWebhookDelivery>({
+    rows: deliveriesRequest?.deliveries || [],
+    initialSort: { field: 'createdOn', sort: 'desc' },
+    columns: [
+      { flex: 1, headerName: 'Event', field: 'event' },
+      { flex: 1, headerName: 'URL', field: 'url' },
+      { flex: 1, headerName: 'Status', field: 'status' },
+      { flex: 1, headerName: 'Attempts', field: 'attempts' },
+      { flex: 1, headerName: 'Response', field: 'responseStatus', renderCell: ({ row }) => row.responseStatus || row.lastError || '' },
+      { flex: 1, headerName: 'Created', field: 'createdOn', renderCell: ({ row }) => dayjs().to(dayjs.utc(row.createdOn)) },
+      {
+        flex: 1, field: 'replay', headerName: 'Replay', sortable: false,
+        renderCell: ({ row }) => row.status == 'failed' ? (
+          <Tooltip placement="left" title="Send this event again">
+            <IconButton
+              {...targets(`manage group webhooks replay ${row.id}`, `send the failed delivery again`)}
+              onClick={() => row.id && replayDelivery(row.id)}
+              size="small"
+            >
+              <ReplayIcon />
+            </IconButton>
+          </Tooltip>
+        ) : <></>
+      },
+    ],
+    toolbar: () => <>
+      <Typography variant="button">Recent Deliveries{groupWebhookId ? ' (selected webhook)' : ''}:</Typography>
+    </>
+  });
+
+  return <>
+    <Dialog onClose={setDialog} open={dialog === 'manage_webhook'} fullWidth maxWidth="sm">
+      <Suspense>
+        <ManageGroupWebhookModal {...props} editWebhook={groupWebhook} closeModal={() => {
+          setDialog('');
+          void getGroupWebhooks();
+        }} />
+      </Suspense>
+    </Dialog>
+
+    <DataGrid {...webhookGridProps} />
+
+    <Box mt={2}>
+      <DataGrid {...deliveryGridProps} />
+    </Box>
+  </>
+}
+
+export default ManageGroupWebhooks;